@@ -103,15 +103,37 @@ func NewLilRagMCPServer() (*LilRagMCPServer, error) {
 	} else {
 		// Convert profile config to RAG config
 		ragConfig = &lilrag.Config{
-			DatabasePath: profileConfig.StoragePath,
-			DataDir:      profileConfig.DataDir,
-			OllamaURL:    profileConfig.Ollama.Endpoint,
-			Model:        profileConfig.Ollama.EmbeddingModel,
-			ChatModel:    profileConfig.Ollama.ChatModel,
-			VectorSize:   profileConfig.Ollama.VectorSize,
-			MaxTokens:    profileConfig.Chunking.MaxTokens,
-			Overlap:      profileConfig.Chunking.Overlap,
-			ImageMaxSize: profileConfig.Ollama.ImageMaxSize,
+			DatabasePath:         profileConfig.StoragePath,
+			DataDir:              profileConfig.DataDir,
+			OllamaURL:            profileConfig.Ollama.Endpoint,
+			Model:                profileConfig.Ollama.EmbeddingModel,
+			ChatModel:            profileConfig.Ollama.ChatModel,
+			VectorSize:           profileConfig.Ollama.VectorSize,
+			MaxTokens:            profileConfig.Chunking.MaxTokens,
+			Overlap:              profileConfig.Chunking.Overlap,
+			HierarchicalChunking: profileConfig.Chunking.Hierarchical,
+			ParentMaxTokens:      profileConfig.Chunking.ParentMaxTokens,
+			ChildMaxTokens:       profileConfig.Chunking.ChildMaxTokens,
+			ChunkStrategy:        profileConfig.Chunking.Strategy,
+			SemanticPercentile:   profileConfig.Chunking.SemanticPercentile,
+			MinTokens:            profileConfig.Chunking.MinTokens,
+			TokenizerType:        profileConfig.Tokenizer.Type,
+			TokenizerPath:        profileConfig.Tokenizer.Path,
+			EmbeddingMaxTokens:   profileConfig.Ollama.EmbeddingMaxTokens,
+			ChatContextTokens:    profileConfig.Ollama.ChatContextTokens,
+			PDFOCR:               profileConfig.PDF.OCR,
+			PDFOCRMaxPages:       profileConfig.PDF.OCRMaxPages,
+			PDFOCRConcurrency:    profileConfig.PDF.OCRConcurrency,
+			EmailAttachments:     profileConfig.Email.Attachments,
+			DOCXNotes:            profileConfig.DOCX.Notes,
+			HTMLFullPage:         profileConfig.HTML.FullPage,
+			NotebookOutputs:      profileConfig.Notebook.Outputs,
+			ArchiveCombined:      profileConfig.Archive.Combined,
+			ArchiveMaxEntries:    profileConfig.Archive.MaxEntries,
+			ArchiveMaxSizeMB:     profileConfig.Archive.MaxSizeMB,
+			TableQuery:           profileConfig.Tables.Query,
+			TableQueryMaxRows:    profileConfig.Tables.MaxRows,
+			ImageMaxSize:         profileConfig.Ollama.ImageMaxSize,
 		}
 	}
 
@@ -229,8 +251,9 @@ func (s *LilRagMCPServer) handleToolsList(message MCPMessage) *MCPMessage {
 				"type": "object",
 				"properties": map[string]interface{}{
 					"file_path": map[string]interface{}{
-						"type":        "string",
-						"description": "Path to the file to index (supports multiple formats: .txt, .pdf, .docx, .xlsx, .html, .csv)",
+						"type": "string",
+						"description": "Path to the file to index (text, PDF, Office, OpenDocument, EPUB, email, " +
+							"Jupyter notebook, HTML, CSV, JSON/YAML/XML, code, or a .zip/.tar.gz archive of them)",
 					},
 					"id": map[string]interface{}{
 						"type":        "string",
@@ -240,6 +263,69 @@ func (s *LilRagMCPServer) handleToolsList(message MCPMessage) *MCPMessage {
 				"required": []string{"file_path"},
 			},
 		},
+		{
+			Name: "lilrag_index_directory",
+			Description: "Recursively index the files below a directory, each as its own document with an ID " +
+				"derived from its relative path; .lilragignore files, hidden files and binary files are skipped",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to the directory to index",
+					},
+					"include": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Optional globs of files to index, e.g. [\"*.md\", \"docs/**\"]",
+					},
+					"exclude": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Optional globs of files and directories to skip, e.g. [\"vendor/\"]",
+					},
+					"id_prefix": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional prefix of the document IDs",
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name: "lilrag_index_url",
+			Description: "Fetch and index a web page or remote file by URL, " +
+				"optionally crawling the pages it links to on the same site",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"url": map[string]interface{}{
+						"type":        "string",
+						"description": "The http or https URL to index",
+					},
+					"id": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional document ID. If not provided, one is derived from the URL",
+					},
+					"crawl": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Follow links from the page, honoring robots.txt (default: false)",
+						"default":     false,
+					},
+					"depth": map[string]interface{}{
+						"type":        "integer",
+						"description": "Link hops to follow when crawling (default: 2)",
+						"default":     2,
+					},
+					"max_pages": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum pages to visit when crawling (default: 100)",
+						"default":     100,
+					},
+				},
+				"required": []string{"url"},
+			},
+		},
 		{
 			Name:        "lilrag_search",
 			Description: "Search for relevant content in the RAG system using semantic similarity",
@@ -255,6 +341,16 @@ func (s *LilRagMCPServer) handleToolsList(message MCPMessage) *MCPMessage {
 						"description": "Maximum number of results to return (default: 10, max: 50)",
 						"default":     10,
 					},
+					"language": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional language filter for source code results (e.g. go, python)",
+					},
+					"metadata": map[string]interface{}{
+						"type": "object",
+						"description": "Optional metadata filters such as email headers (e.g. {\"from\": \"alice\"}); " +
+							"values match case-insensitively as substrings",
+						"additionalProperties": map[string]interface{}{"type": "string"},
+					},
 				},
 				"required": []string{"query"},
 			},
@@ -330,6 +426,10 @@ func (s *LilRagMCPServer) handleToolsCall(message MCPMessage) *MCPMessage {
 		return s.handleIndex(message.ID, callParams.Arguments)
 	case "lilrag_index_file":
 		return s.handleIndexFile(message.ID, callParams.Arguments)
+	case "lilrag_index_directory":
+		return s.handleIndexDirectory(message.ID, callParams.Arguments)
+	case "lilrag_index_url":
+		return s.handleIndexURL(message.ID, callParams.Arguments)
 	case "lilrag_search":
 		return s.handleSearch(message.ID, callParams.Arguments)
 	case "lilrag_chat":
@@ -414,6 +514,126 @@ func (s *LilRagMCPServer) handleIndexFile(id interface{}, args map[string]interf
 	}
 }
 
+func (s *LilRagMCPServer) handleIndexDirectory(id interface{}, args map[string]interface{}) *MCPMessage {
+	// Extract parameters
+	dirPath, ok := args["path"].(string)
+	if !ok || dirPath == "" {
+		return s.errorResponse(id, -32602, "path parameter is required and must be a non-empty string")
+	}
+
+	opts := lilrag.DirectoryOptions{
+		Include: stringArgs(args["include"]),
+		Exclude: stringArgs(args["exclude"]),
+	}
+	opts.IDPrefix, _ = args["id_prefix"].(string)
+
+	report, err := s.rag.IndexDirectory(context.Background(), dirPath, opts)
+	if err != nil && report == nil {
+		return s.errorResponse(id, -32603, fmt.Sprintf("Failed to index directory: %v", err))
+	}
+
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "Indexed %d, skipped %d, failed %d files in %s\n",
+		report.Count(lilrag.FileStatusIndexed), report.Count(lilrag.FileStatusSkipped),
+		report.Count(lilrag.FileStatusFailed), dirPath)
+	for _, file := range report.Files {
+		if file.Status == lilrag.FileStatusIndexed {
+			fmt.Fprintf(&summary, "\n- %s %s (ID: %s)", file.Status, file.Path, file.ID)
+		} else {
+			fmt.Fprintf(&summary, "\n- %s %s: %s", file.Status, file.Path, file.Reason)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(&summary, "\n\nStopped: %v", err)
+	}
+
+	return &MCPMessage{
+		JSONRPC: "2.0",
+		ID:      id,
+		Result: MCPCallToolResult{
+			Content: []struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			}{{
+				Type: "text",
+				Text: summary.String(),
+			}},
+		},
+	}
+}
+
+// stringArgs reads a tool argument that is a list of strings
+func stringArgs(arg interface{}) []string {
+	values, _ := arg.([]interface{})
+	strs := make([]string, 0, len(values))
+	for _, value := range values {
+		if str, ok := value.(string); ok && str != "" {
+			strs = append(strs, str)
+		}
+	}
+	return strs
+}
+
+func (s *LilRagMCPServer) handleIndexURL(id interface{}, args map[string]interface{}) *MCPMessage {
+	// Extract parameters
+	rawURL, ok := args["url"].(string)
+	if !ok || rawURL == "" {
+		return s.errorResponse(id, -32602, "url parameter is required and must be a non-empty string")
+	}
+	docID, _ := args["id"].(string)
+
+	ctx := context.Background()
+	var text string
+	if crawl, _ := args["crawl"].(bool); crawl {
+		opts := lilrag.DefaultCrawlOptions()
+		if depth, ok := args["depth"].(float64); ok {
+			opts.MaxDepth = int(depth)
+		}
+		if maxPages, ok := args["max_pages"].(float64); ok {
+			opts.MaxPages = int(maxPages)
+		}
+
+		report, err := s.rag.Crawl(ctx, rawURL, opts)
+		if err != nil {
+			return s.errorResponse(id, -32603, fmt.Sprintf("Failed to crawl URL: %v", err))
+		}
+
+		var summary strings.Builder
+		fmt.Fprintf(&summary, "Crawled %d pages from %s: %d indexed, %d unchanged, %d blocked by robots.txt, %d failed\n",
+			len(report.Pages), rawURL, report.Count(lilrag.CrawlStatusIndexed),
+			report.Count(lilrag.CrawlStatusUnchanged), report.Count(lilrag.CrawlStatusBlocked),
+			report.Count(lilrag.CrawlStatusFailed))
+		for _, page := range report.Pages {
+			fmt.Fprintf(&summary, "\n- %s %s (ID: %s)", page.Status, page.URL, page.ID)
+		}
+		text = summary.String()
+	} else {
+		page, err := s.rag.IndexURL(ctx, rawURL, docID)
+		if err != nil {
+			return s.errorResponse(id, -32603, fmt.Sprintf("Failed to index URL: %v", err))
+		}
+		if page.Status == lilrag.CrawlStatusUnchanged {
+			text = fmt.Sprintf("'%s' has not changed since it was indexed with ID: %s", page.URL, page.ID)
+		} else {
+			text = fmt.Sprintf("Successfully indexed '%s' with ID: %s", page.URL, page.ID)
+		}
+	}
+
+	return &MCPMessage{
+		JSONRPC: "2.0",
+		ID:      id,
+		Result: MCPCallToolResult{
+			Content: []struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			}{{
+				Type: "text",
+				Text: text,
+			}},
+		},
+	}
+}
+
 func (s *LilRagMCPServer) handleSearch(id interface{}, args map[string]interface{}) *MCPMessage {
 	// Extract parameters
 	query, ok := args["query"].(string)
@@ -434,9 +654,20 @@ func (s *LilRagMCPServer) handleSearch(id interface{}, args map[string]interface
 		limit = 50
 	}
 
+	var opts lilrag.SearchOptions
+	if language, ok := args["language"].(string); ok {
+		opts.Language = language
+	}
+	if filters, ok := args["metadata"].(map[string]interface{}); ok {
+		opts.Metadata = make(map[string]string, len(filters))
+		for key, value := range filters {
+			opts.Metadata[key] = fmt.Sprint(value)
+		}
+	}
+
 	// Perform search
 	ctx := context.Background()
-	results, err := s.rag.Search(ctx, query, limit)
+	results, err := s.rag.SearchWithOptions(ctx, query, limit, opts)
 	if err != nil {
 		return s.errorResponse(id, -32603, fmt.Sprintf("Search failed: %v", err))
 	}
@@ -464,6 +695,10 @@ func (s *LilRagMCPServer) handleSearch(id interface{}, args map[string]interface
 	for i, result := range results {
 		response.WriteString(fmt.Sprintf("## Result %d (Score: %.4f)\n", i+1, result.Score))
 		response.WriteString(fmt.Sprintf("**Document ID:** %s\n", result.ID))
+		if symbol, ok := result.Metadata["symbol_name"].(string); ok {
+			response.WriteString(fmt.Sprintf("**Symbol:** %s %s (lines %v-%v)\n",
+				result.Metadata["symbol_kind"], symbol, result.Metadata["start_line"], result.Metadata["end_line"]))
+		}
 		response.WriteString(fmt.Sprintf("**Content:**\n%s\n\n", result.Text))
 		response.WriteString("---\n\n")
 	}
@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sync"
 	"syscall"
 	"time"
 
@@ -76,17 +78,39 @@ func run() error {
 	}
 
 	lilragConfig := &lilrag.Config{
-		DatabasePath:   profileConfig.StoragePath,
-		DataDir:        profileConfig.DataDir,
-		OllamaURL:      profileConfig.Ollama.Endpoint,
-		Model:          profileConfig.Ollama.EmbeddingModel,
-		ChatModel:      profileConfig.Ollama.ChatModel,
-		VisionModel:    profileConfig.Ollama.VisionModel,
-		TimeoutSeconds: profileConfig.Ollama.TimeoutSeconds,
-		VectorSize:     profileConfig.Ollama.VectorSize,
-		MaxTokens:      profileConfig.Chunking.MaxTokens,
-		Overlap:        profileConfig.Chunking.Overlap,
-		ImageMaxSize:   profileConfig.Ollama.ImageMaxSize,
+		DatabasePath:         profileConfig.StoragePath,
+		DataDir:              profileConfig.DataDir,
+		OllamaURL:            profileConfig.Ollama.Endpoint,
+		Model:                profileConfig.Ollama.EmbeddingModel,
+		ChatModel:            profileConfig.Ollama.ChatModel,
+		VisionModel:          profileConfig.Ollama.VisionModel,
+		TimeoutSeconds:       profileConfig.Ollama.TimeoutSeconds,
+		VectorSize:           profileConfig.Ollama.VectorSize,
+		MaxTokens:            profileConfig.Chunking.MaxTokens,
+		Overlap:              profileConfig.Chunking.Overlap,
+		HierarchicalChunking: profileConfig.Chunking.Hierarchical,
+		ParentMaxTokens:      profileConfig.Chunking.ParentMaxTokens,
+		ChildMaxTokens:       profileConfig.Chunking.ChildMaxTokens,
+		ChunkStrategy:        profileConfig.Chunking.Strategy,
+		SemanticPercentile:   profileConfig.Chunking.SemanticPercentile,
+		MinTokens:            profileConfig.Chunking.MinTokens,
+		TokenizerType:        profileConfig.Tokenizer.Type,
+		TokenizerPath:        profileConfig.Tokenizer.Path,
+		EmbeddingMaxTokens:   profileConfig.Ollama.EmbeddingMaxTokens,
+		ChatContextTokens:    profileConfig.Ollama.ChatContextTokens,
+		PDFOCR:               profileConfig.PDF.OCR,
+		PDFOCRMaxPages:       profileConfig.PDF.OCRMaxPages,
+		PDFOCRConcurrency:    profileConfig.PDF.OCRConcurrency,
+		EmailAttachments:     profileConfig.Email.Attachments,
+		DOCXNotes:            profileConfig.DOCX.Notes,
+		HTMLFullPage:         profileConfig.HTML.FullPage,
+		NotebookOutputs:      profileConfig.Notebook.Outputs,
+		ArchiveCombined:      profileConfig.Archive.Combined,
+		ArchiveMaxEntries:    profileConfig.Archive.MaxEntries,
+		ArchiveMaxSizeMB:     profileConfig.Archive.MaxSizeMB,
+		TableQuery:           profileConfig.Tables.Query,
+		TableQueryMaxRows:    profileConfig.Tables.MaxRows,
+		ImageMaxSize:         profileConfig.Ollama.ImageMaxSize,
 	}
 
 	rag, err := lilrag.New(lilragConfig)
@@ -100,13 +124,24 @@ func run() error {
 	defer rag.Close()
 
 	handler := handlers.NewWithVersion(rag, version, profileConfig.DataDir)
+	handler.SetIndexRoot(profileConfig.Server.IndexRoot)
+
+	// File uploads are indexed in the background so large files do not time out the client
+	jobs, err := lilrag.NewJobQueue(rag, filepath.Join(profileConfig.DataDir, "uploads"))
+	if err != nil {
+		return fmt.Errorf("failed to create job queue: %w", err)
+	}
+	handler.SetJobQueue(jobs)
 	mux := http.NewServeMux()
 
 	mux.Handle("/api/index", handler.Index())
+	mux.Handle("/api/index/directory", handler.IndexDirectory())
 	mux.Handle("/api/search", handler.Search())
 	mux.Handle("/api/chat", handler.Chat())
 	mux.Handle("/api/documents", handler.Documents())
 	mux.Handle("/api/documents/", handler.DocumentRouter())
+	mux.Handle("/api/jobs", handler.Jobs())
+	mux.Handle("/api/jobs/", handler.Jobs())
 	mux.HandleFunc("/api/chunks/", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "PUT" {
 			handler.UpdateChunk()(w, r)
@@ -157,6 +192,18 @@ func run() error {
 		}
 	}()
 
+	// Process queued uploads and keep the watched directories indexed until the server
+	// shuts down
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	background := startWatchers(backgroundCtx, rag, profileConfig.Watch)
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		if err := jobs.Run(backgroundCtx); err != nil {
+			log.Printf("Stopped processing jobs: %v", err)
+		}
+	}()
+
 	go func() {
 		log.Printf("Starting lil-rag-server version %s on %s", version, addr)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -167,8 +214,48 @@ func run() error {
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopBackground()
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	return server.Shutdown(ctx)
+	err = server.Shutdown(ctx)
+	background.Wait()
+	return err
+}
+
+// startWatchers watches the directories of the profile's watch config in the background,
+// prefixing the document IDs of each directory with its base name
+func startWatchers(ctx context.Context, rag *lilrag.LilRag, watchConfig config.WatchConfig) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	for _, dir := range watchConfig.Dirs {
+		opts := lilrag.WatchOptions{
+			DirectoryOptions: lilrag.DirectoryOptions{
+				Include:  watchConfig.Include,
+				Exclude:  watchConfig.Exclude,
+				IDPrefix: filepath.Base(filepath.Clean(dir)),
+			},
+			Interval: time.Duration(watchConfig.IntervalSeconds) * time.Second,
+			Debounce: time.Duration(watchConfig.DebounceSeconds) * time.Second,
+			OnEvent: func(event lilrag.WatchEvent) {
+				switch {
+				case event.Error != "":
+					log.Printf("Watch %s: failed to update %s: %s", dir, event.Path, event.Error)
+				case event.OldPath != "":
+					log.Printf("Watch %s: %s %s -> %s (%s)", dir, event.Type, event.OldPath, event.Path, event.ID)
+				default:
+					log.Printf("Watch %s: %s %s (%s)", dir, event.Type, event.Path, event.ID)
+				}
+			},
+		}
+
+		wg.Add(1)
+		go func(dir string) {
+			defer wg.Done()
+			log.Printf("Watching %s for changes", dir)
+			if err := rag.Watch(ctx, dir, opts); err != nil {
+				log.Printf("Stopped watching %s: %v", dir, err)
+			}
+		}(dir)
+	}
+	return &wg
 }
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
 	"time"
 
@@ -85,14 +87,36 @@ func run() error {
 	}
 
 	lilragConfig := &lilrag.Config{
-		DatabasePath: profileConfig.StoragePath,
-		DataDir:      profileConfig.DataDir,
-		OllamaURL:    profileConfig.Ollama.Endpoint,
-		Model:        profileConfig.Ollama.EmbeddingModel,
-		ChatModel:    profileConfig.Ollama.ChatModel,
-		VectorSize:   profileConfig.Ollama.VectorSize,
-		MaxTokens:    profileConfig.Chunking.MaxTokens,
-		Overlap:      profileConfig.Chunking.Overlap,
+		DatabasePath:         profileConfig.StoragePath,
+		DataDir:              profileConfig.DataDir,
+		OllamaURL:            profileConfig.Ollama.Endpoint,
+		Model:                profileConfig.Ollama.EmbeddingModel,
+		ChatModel:            profileConfig.Ollama.ChatModel,
+		VectorSize:           profileConfig.Ollama.VectorSize,
+		MaxTokens:            profileConfig.Chunking.MaxTokens,
+		Overlap:              profileConfig.Chunking.Overlap,
+		HierarchicalChunking: profileConfig.Chunking.Hierarchical,
+		ParentMaxTokens:      profileConfig.Chunking.ParentMaxTokens,
+		ChildMaxTokens:       profileConfig.Chunking.ChildMaxTokens,
+		ChunkStrategy:        profileConfig.Chunking.Strategy,
+		SemanticPercentile:   profileConfig.Chunking.SemanticPercentile,
+		MinTokens:            profileConfig.Chunking.MinTokens,
+		TokenizerType:        profileConfig.Tokenizer.Type,
+		TokenizerPath:        profileConfig.Tokenizer.Path,
+		EmbeddingMaxTokens:   profileConfig.Ollama.EmbeddingMaxTokens,
+		ChatContextTokens:    profileConfig.Ollama.ChatContextTokens,
+		PDFOCR:               profileConfig.PDF.OCR,
+		PDFOCRMaxPages:       profileConfig.PDF.OCRMaxPages,
+		PDFOCRConcurrency:    profileConfig.PDF.OCRConcurrency,
+		EmailAttachments:     profileConfig.Email.Attachments,
+		DOCXNotes:            profileConfig.DOCX.Notes,
+		HTMLFullPage:         profileConfig.HTML.FullPage,
+		NotebookOutputs:      profileConfig.Notebook.Outputs,
+		ArchiveCombined:      profileConfig.Archive.Combined,
+		ArchiveMaxEntries:    profileConfig.Archive.MaxEntries,
+		ArchiveMaxSizeMB:     profileConfig.Archive.MaxSizeMB,
+		TableQuery:           profileConfig.Tables.Query,
+		TableQueryMaxRows:    profileConfig.Tables.MaxRows,
 	}
 
 	rag, err := lilrag.New(lilragConfig)
@@ -110,7 +134,28 @@ func run() error {
 
 	switch command {
 	case "index":
+		if indexesDirectory(args[1:]) {
+			// Directories are indexed until done or interrupted, not for the usual five minutes
+			dirCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			return handleIndex(dirCtx, rag, args[1:])
+		}
 		return handleIndex(ctx, rag, args[1:])
+	case "crawl":
+		// A crawl runs until it is done or interrupted, not for the usual five minutes
+		crawlCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		return handleCrawl(crawlCtx, rag, args[1:])
+	case "watch":
+		// Watching runs until interrupted
+		watchCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		return handleWatch(watchCtx, rag, args[1:])
+	case "sync":
+		// Re-indexing stale documents runs until done or interrupted
+		syncCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		return handleSync(syncCtx, rag, args[1:])
 	case "search":
 		return handleSearch(ctx, rag, args[1:])
 	case "chat":
@@ -131,8 +176,21 @@ func run() error {
 }
 
 func handleIndex(ctx context.Context, rag *lilrag.LilRag, args []string) error {
+	args, dirOpts, err := parseDirectoryFlags(args)
+	if err != nil {
+		return err
+	}
 	if len(args) == 0 {
-		return fmt.Errorf("usage: lil-rag index [id] <text|file|-> or just: lil-rag index <text|file|->")
+		return fmt.Errorf("usage: lil-rag index [id] <text|file|dir|url|-> or just: lil-rag index <text|file|dir|url|->")
+	}
+	if target := args[len(args)-1]; isDir(target) {
+		if len(args) == 2 {
+			dirOpts.IDPrefix = args[0]
+		}
+		return indexDirectory(ctx, rag, target, dirOpts)
+	}
+	if len(dirOpts.Include) > 0 || len(dirOpts.Exclude) > 0 {
+		return fmt.Errorf("--include and --exclude only apply to directories")
 	}
 
 	var id string
@@ -156,7 +214,7 @@ func handleIndex(ctx context.Context, rag *lilrag.LilRag, args []string) error {
 			// Generate ID automatically
 			id = lilrag.GenerateDocumentID()
 			fmt.Printf("Indexing text with auto-generated ID '%s'...\n", id)
-			if err := rag.Index(ctx, text, id); err != nil {
+			if err := rag.IndexWithOptions(ctx, text, id, progressOptions()); err != nil {
 				return fmt.Errorf("failed to index: %w", err)
 			}
 
@@ -164,11 +222,16 @@ func handleIndex(ctx context.Context, rag *lilrag.LilRag, args []string) error {
 			return nil
 		}
 
+		if isURL(arg) {
+			// Fetch the page and derive the ID from its URL
+			return indexURL(ctx, rag, arg, "")
+		}
+
 		if fileExists(arg) {
 			// File exists, index with auto-generated ID
 			id = lilrag.GenerateDocumentID()
 			fmt.Printf("Indexing file '%s' with auto-generated ID '%s'...\n", arg, id)
-			if err := rag.IndexFile(ctx, arg, id); err != nil {
+			if err := rag.IndexFileWithOptions(ctx, arg, id, progressOptions()); err != nil {
 				return fmt.Errorf("failed to index file: %w", err)
 			}
 			fmt.Printf("Successfully indexed file '%s' with ID '%s'\n", arg, id)
@@ -183,7 +246,7 @@ func handleIndex(ctx context.Context, rag *lilrag.LilRag, args []string) error {
 
 		id = lilrag.GenerateDocumentID()
 		fmt.Printf("Indexing text with auto-generated ID '%s'...\n", id)
-		if err := rag.Index(ctx, text, id); err != nil {
+		if err := rag.IndexWithOptions(ctx, text, id, progressOptions()); err != nil {
 			return fmt.Errorf("failed to index: %w", err)
 		}
 
@@ -207,7 +270,7 @@ func handleIndex(ctx context.Context, rag *lilrag.LilRag, args []string) error {
 		}
 
 		fmt.Printf("Indexing text with ID '%s'...\n", id)
-		if err := rag.Index(ctx, text, id); err != nil {
+		if err := rag.IndexWithOptions(ctx, text, id, progressOptions()); err != nil {
 			return fmt.Errorf("failed to index: %w", err)
 		}
 
@@ -215,10 +278,15 @@ func handleIndex(ctx context.Context, rag *lilrag.LilRag, args []string) error {
 		return nil
 	}
 
+	if isURL(input) {
+		return indexURL(ctx, rag, input, id)
+	}
+
 	if fileExists(input) {
-		// Handle file using the document handler (supports PDF, DOCX, XLSX, HTML, CSV, etc.)
+		// Handle file using the document handler (supports PDF, Office, ODF, EPUB, notebooks, archives,
+		// HTML, CSV, JSON, etc.)
 		fmt.Printf("Indexing file '%s' with ID '%s'...\n", input, id)
-		if err := rag.IndexFile(ctx, input, id); err != nil {
+		if err := rag.IndexFileWithOptions(ctx, input, id, progressOptions()); err != nil {
 			return fmt.Errorf("failed to index file: %w", err)
 		}
 		fmt.Printf("Successfully indexed file '%s' with ID '%s'\n", input, id)
@@ -232,7 +300,7 @@ func handleIndex(ctx context.Context, rag *lilrag.LilRag, args []string) error {
 	}
 
 	fmt.Printf("Indexing text with ID '%s'...\n", id)
-	if err := rag.Index(ctx, text, id); err != nil {
+	if err := rag.IndexWithOptions(ctx, text, id, progressOptions()); err != nil {
 		return fmt.Errorf("failed to index: %w", err)
 	}
 
@@ -240,22 +308,375 @@ func handleIndex(ctx context.Context, rag *lilrag.LilRag, args []string) error {
 	return nil
 }
 
+// parseDirectoryFlags removes the --include and --exclude flags of directory indexing
+// from the index arguments
+func parseDirectoryFlags(args []string) ([]string, lilrag.DirectoryOptions, error) {
+	var opts lilrag.DirectoryOptions
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		if args[i] != "--include" && args[i] != "--exclude" {
+			rest = append(rest, args[i])
+			continue
+		}
+		if i+1 >= len(args) {
+			return nil, opts, fmt.Errorf("%s requires a glob", args[i])
+		}
+		if args[i] == "--include" {
+			opts.Include = append(opts.Include, args[i+1])
+		} else {
+			opts.Exclude = append(opts.Exclude, args[i+1])
+		}
+		i++
+	}
+	return rest, opts, nil
+}
+
+// indexesDirectory reports whether index arguments name a directory
+func indexesDirectory(args []string) bool {
+	args, _, err := parseDirectoryFlags(args)
+	return err == nil && len(args) > 0 && isDir(args[len(args)-1])
+}
+
+// indexDirectory indexes the files below a directory and prints a summary
+func indexDirectory(ctx context.Context, rag *lilrag.LilRag, dir string, opts lilrag.DirectoryOptions) error {
+	fmt.Printf("Indexing directory '%s'...\n", dir)
+	report, err := rag.IndexDirectory(ctx, dir, opts)
+	if report != nil {
+		for _, file := range report.Files {
+			switch file.Status {
+			case lilrag.FileStatusIndexed:
+				fmt.Printf("  %-8s %s -> %s\n", file.Status, file.Path, file.ID)
+			default:
+				fmt.Printf("  %-8s %s (%s)\n", file.Status, file.Path, file.Reason)
+			}
+		}
+		fmt.Printf("\nIndexed %d, skipped %d, failed %d files\n", report.Count(lilrag.FileStatusIndexed),
+			report.Count(lilrag.FileStatusSkipped), report.Count(lilrag.FileStatusFailed))
+	}
+	if err != nil {
+		return err
+	}
+	if failed := report.Count(lilrag.FileStatusFailed); failed > 0 {
+		return fmt.Errorf("%d files failed to index", failed)
+	}
+	return nil
+}
+
+// indexURL fetches a web page or remote file and indexes it
+func indexURL(ctx context.Context, rag *lilrag.LilRag, rawURL, id string) error {
+	fmt.Printf("Fetching '%s'...\n", rawURL)
+	page, err := rag.IndexURL(ctx, rawURL, id)
+	if err != nil {
+		return fmt.Errorf("failed to index URL: %w", err)
+	}
+	if page.Status == lilrag.CrawlStatusUnchanged {
+		fmt.Printf("'%s' has not changed since it was indexed with ID '%s'\n", page.URL, page.ID)
+		return nil
+	}
+	fmt.Printf("Successfully indexed '%s' with ID '%s'\n", page.URL, page.ID)
+	return nil
+}
+
+// progressWidth is the number of characters of a progress bar
+const progressWidth = 30
+
+// progressBar renders the index events of documents as progress bars on stderr, redrawn
+// in place on a terminal and printed once per document otherwise
+type progressBar struct {
+	out      io.Writer
+	terminal bool
+	drawn    bool // A bar is shown on the current line
+}
+
+// progressOptions returns index options that render the progress as a progress bar
+func progressOptions() lilrag.IndexOptions {
+	bar := &progressBar{out: os.Stderr}
+	if stat, err := os.Stderr.Stat(); err == nil {
+		bar.terminal = stat.Mode()&os.ModeCharDevice != 0
+	}
+	return lilrag.IndexOptions{OnProgress: bar.update}
+}
+
+func (p *progressBar) update(event lilrag.IndexEvent) {
+	switch event.Type {
+	case lilrag.IndexEventParsed:
+		p.draw(fmt.Sprintf("%s: parsed", event.DocumentID))
+	case lilrag.IndexEventChunked, lilrag.IndexEventEmbedded:
+		if !p.terminal {
+			if event.Type == lilrag.IndexEventChunked {
+				p.draw(fmt.Sprintf("%s: embedding %d chunks", event.DocumentID, event.Total))
+			}
+			return
+		}
+		// Streamed files report no total until they are read, so only a count is shown
+		if event.Total == 0 {
+			p.draw(fmt.Sprintf("%s: %d chunks embedded", event.DocumentID, event.Embedded))
+			return
+		}
+		filled := progressWidth * event.Embedded / event.Total
+		p.draw(fmt.Sprintf("%s: [%s%s] %d/%d chunks embedded", event.DocumentID,
+			strings.Repeat("=", filled), strings.Repeat(" ", progressWidth-filled), event.Embedded, event.Total))
+	case lilrag.IndexEventStored:
+		p.draw(fmt.Sprintf("%s: stored %d chunks", event.DocumentID, event.Chunks))
+		p.finish()
+	case lilrag.IndexEventError:
+		p.finish()
+	}
+}
+
+// draw replaces the current bar with line, or prints line without a terminal
+func (p *progressBar) draw(line string) {
+	if !p.terminal {
+		fmt.Fprintln(p.out, line)
+		return
+	}
+	fmt.Fprintf(p.out, "\r\033[K%s", line)
+	p.drawn = true
+}
+
+// finish ends the line of the current bar
+func (p *progressBar) finish() {
+	if p.drawn {
+		fmt.Fprintln(p.out)
+		p.drawn = false
+	}
+}
+
+// isURL reports whether an index argument is a web address rather than text or a file
+func isURL(arg string) bool {
+	lower := strings.ToLower(arg)
+	return (strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")) &&
+		!strings.ContainsAny(arg, " \t\n")
+}
+
+func handleCrawl(ctx context.Context, rag *lilrag.LilRag, args []string) error {
+	opts := lilrag.DefaultCrawlOptions()
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--all-hosts":
+			opts.SameHost = false
+			continue
+		case "--ignore-robots":
+			opts.IgnoreRobots = true
+			continue
+		case "--depth", "--max-pages", "--include", "--exclude", "--delay":
+		default:
+			positional = append(positional, args[i])
+			continue
+		}
+
+		flag := args[i]
+		if i+1 >= len(args) {
+			return fmt.Errorf("%s requires a value", flag)
+		}
+		value := args[i+1]
+		i++
+		switch flag {
+		case "--depth", "--max-pages":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid %s: %s", flag, value)
+			}
+			if flag == "--depth" {
+				opts.MaxDepth = n
+			} else {
+				opts.MaxPages = n
+			}
+		case "--include":
+			opts.Include = append(opts.Include, value)
+		case "--exclude":
+			opts.Exclude = append(opts.Exclude, value)
+		case "--delay":
+			delay, err := time.ParseDuration(value)
+			if err != nil || delay < 0 {
+				return fmt.Errorf("invalid --delay: %s", value)
+			}
+			opts.Delay = delay
+		}
+	}
+
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: lil-rag crawl <url> [--depth N] [--max-pages N] [--include regex] " +
+			"[--exclude regex] [--delay 1s] [--all-hosts] [--ignore-robots]")
+	}
+
+	fmt.Printf("Crawling '%s' (depth %d, up to %d pages)...\n", positional[0], opts.MaxDepth, opts.MaxPages)
+	report, err := rag.Crawl(ctx, positional[0], opts)
+	if report != nil {
+		for _, page := range report.Pages {
+			if page.Error != "" {
+				fmt.Printf("  %-9s %s (%s)\n", page.Status, page.URL, page.Error)
+			} else {
+				fmt.Printf("  %-9s %s -> %s\n", page.Status, page.URL, page.ID)
+			}
+		}
+		fmt.Printf("\nIndexed %d, unchanged %d, blocked by robots.txt %d, failed %d\n",
+			report.Count(lilrag.CrawlStatusIndexed), report.Count(lilrag.CrawlStatusUnchanged),
+			report.Count(lilrag.CrawlStatusBlocked), report.Count(lilrag.CrawlStatusFailed))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to crawl: %w", err)
+	}
+	return nil
+}
+
+func handleWatch(ctx context.Context, rag *lilrag.LilRag, args []string) error {
+	args, dirOpts, err := parseDirectoryFlags(args)
+	if err != nil {
+		return err
+	}
+	opts := lilrag.WatchOptions{DirectoryOptions: dirOpts}
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		if args[i] != "--interval" && args[i] != "--debounce" {
+			positional = append(positional, args[i])
+			continue
+		}
+		if i+1 >= len(args) {
+			return fmt.Errorf("%s requires a value", args[i])
+		}
+		duration, err := time.ParseDuration(args[i+1])
+		if err != nil || duration <= 0 {
+			return fmt.Errorf("invalid %s: %s", args[i], args[i+1])
+		}
+		if args[i] == "--interval" {
+			opts.Interval = duration
+		} else {
+			opts.Debounce = duration
+		}
+		i++
+	}
+
+	if len(positional) == 0 || len(positional) > 2 {
+		return fmt.Errorf("usage: lil-rag watch [prefix] <dir> [--include glob] [--exclude glob] " +
+			"[--interval 2s] [--debounce 1s]")
+	}
+	dir := positional[len(positional)-1]
+	if len(positional) == 2 {
+		opts.IDPrefix = positional[0]
+	}
+
+	opts.OnEvent = func(event lilrag.WatchEvent) {
+		switch {
+		case event.Error != "":
+			fmt.Printf("  %-8s %s (%s)\n", event.Type, event.Path, event.Error)
+		case event.OldPath != "":
+			fmt.Printf("  %-8s %s -> %s (%s)\n", event.Type, event.OldPath, event.Path, event.ID)
+		default:
+			fmt.Printf("  %-8s %s -> %s\n", event.Type, event.Path, event.ID)
+		}
+	}
+
+	fmt.Printf("Watching '%s' for changes (press Ctrl+C to stop)...\n", dir)
+	if err := rag.Watch(ctx, dir, opts); err != nil {
+		return fmt.Errorf("failed to watch directory: %w", err)
+	}
+	fmt.Println("Stopped watching")
+	return nil
+}
+
+func handleSync(ctx context.Context, rag *lilrag.LilRag, args []string) error {
+	var opts lilrag.SyncOptions
+	for _, arg := range args {
+		switch arg {
+		case "--reindex":
+			opts.Reindex = true
+		case "--prune":
+			opts.Prune = true
+		case "--dry-run":
+			opts.DryRun = true
+		default:
+			return fmt.Errorf("usage: lil-rag sync [--reindex] [--prune] [--dry-run]")
+		}
+	}
+
+	report, err := rag.Sync(ctx, opts)
+	if report != nil {
+		failed := 0
+		for _, doc := range report.Documents {
+			if doc.Status == lilrag.SourceStatusUnchanged {
+				continue
+			}
+			line := fmt.Sprintf("  %-9s %s (%s)", doc.Status, doc.ID, doc.SourcePath)
+			switch {
+			case doc.Action != "" && doc.Error != "":
+				failed++
+				line += fmt.Sprintf(": %s failed: %s", doc.Action, doc.Error)
+			case doc.Error != "":
+				line += ": " + doc.Error
+			case doc.Action == lilrag.SyncActionReindex && report.DryRun:
+				line += ": would re-index"
+			case doc.Action == lilrag.SyncActionReindex:
+				line += ": re-indexed"
+			case doc.Action == lilrag.SyncActionPrune && report.DryRun:
+				line += ": would prune"
+			case doc.Action == lilrag.SyncActionPrune:
+				line += ": pruned"
+			}
+			fmt.Println(line)
+		}
+		fmt.Printf("\n%d unchanged, %d stale, %d missing", report.Count(lilrag.SourceStatusUnchanged),
+			report.Count(lilrag.SourceStatusStale), report.Count(lilrag.SourceStatusMissing))
+		if unreadable := report.Count(lilrag.SourceStatusError); unreadable > 0 {
+			fmt.Printf(", %d unreadable", unreadable)
+		}
+		fmt.Println()
+		if failed > 0 {
+			return fmt.Errorf("%d documents failed to sync", failed)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to sync: %w", err)
+	}
+	return nil
+}
+
 func handleSearch(ctx context.Context, rag *lilrag.LilRag, args []string) error {
-	if len(args) == 0 {
-		return fmt.Errorf("usage: lil-rag search <query> [limit]")
+	var opts lilrag.SearchOptions
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--language" {
+			if i+1 >= len(args) {
+				return fmt.Errorf("--language requires a value")
+			}
+			opts.Language = args[i+1]
+			i++
+			continue
+		}
+		if args[i] == "--meta" {
+			if i+1 >= len(args) {
+				return fmt.Errorf("--meta requires a key=value filter")
+			}
+			key, value, ok := strings.Cut(args[i+1], "=")
+			if !ok || key == "" {
+				return fmt.Errorf("invalid metadata filter %q, expected key=value", args[i+1])
+			}
+			if opts.Metadata == nil {
+				opts.Metadata = make(map[string]string)
+			}
+			opts.Metadata[key] = value
+			i++
+			continue
+		}
+		positional = append(positional, args[i])
 	}
 
-	query := args[0]
+	if len(positional) == 0 {
+		return fmt.Errorf("usage: lil-rag search <query> [limit] [--language <lang>] [--meta key=value]")
+	}
+
+	query := positional[0]
 	limit := 10
 
-	if len(args) > 1 {
-		if _, err := fmt.Sscanf(args[1], "%d", &limit); err != nil {
-			return fmt.Errorf("invalid limit: %s", args[1])
+	if len(positional) > 1 {
+		if _, err := fmt.Sscanf(positional[1], "%d", &limit); err != nil {
+			return fmt.Errorf("invalid limit: %s", positional[1])
 		}
 	}
 
 	fmt.Printf("Searching for: %s\n", query)
-	results, err := rag.Search(ctx, query, limit)
+	results, err := rag.SearchWithOptions(ctx, query, limit, opts)
 	if err != nil {
 		return fmt.Errorf("failed to search: %w", err)
 	}
@@ -271,8 +692,26 @@ func handleSearch(ctx context.Context, rag *lilrag.LilRag, args []string) error
 
 		if result.Metadata != nil {
 			// Show information about which part matched
-			if pageNum, ok := result.Metadata["page_number"].(int); ok {
+			if symbol, ok := result.Metadata["symbol_name"].(string); ok {
+				matchInfo = fmt.Sprintf(" [Best match: %s", symbol)
+				if startLine, ok := result.Metadata["start_line"].(int); ok {
+					matchInfo += fmt.Sprintf(", lines %d-%v", startLine, result.Metadata["end_line"])
+				}
+				matchInfo += "]"
+			} else if pageNum, ok := result.Metadata["page_number"].(int); ok {
 				matchInfo = fmt.Sprintf(" [Best match: Page %d]", pageNum)
+				if endPage, ok := result.Metadata["end_page_number"].(int); ok {
+					matchInfo = fmt.Sprintf(" [Best match: Pages %d-%d]", pageNum, endPage)
+				}
+				if ocr, ok := result.Metadata["ocr"].(bool); ok && ocr {
+					matchInfo = strings.TrimSuffix(matchInfo, "]") + ", OCR]"
+				}
+			} else if cell, ok := result.Metadata["cell_index"].(string); ok {
+				matchInfo = fmt.Sprintf(" [Best match: Cell %s]", cell)
+			} else if chapter, ok := result.Metadata["chapter_title"].(string); ok {
+				matchInfo = fmt.Sprintf(" [Best match: Chapter %v: %s]", result.Metadata["chapter_index"], chapter)
+			} else if jsonPath, ok := result.Metadata["json_path"].(string); ok {
+				matchInfo = fmt.Sprintf(" [Best match: %s]", jsonPath)
 			} else if chunkType, ok := result.Metadata["chunk_type"].(string); ok && chunkType == "pdf_page" {
 				matchInfo = " [Best match: PDF Page]"
 			} else if isChunk, ok := result.Metadata["is_chunk"].(bool); ok && isChunk {
@@ -325,10 +764,46 @@ func handleConfig(profileConfig *config.ProfileConfig, args []string) error {
 		fmt.Printf("Embedding Model: %s\n", profileConfig.Ollama.EmbeddingModel)
 		fmt.Printf("Chat Model: %s\n", profileConfig.Ollama.ChatModel)
 		fmt.Printf("Vector Size: %d\n", profileConfig.Ollama.VectorSize)
+		fmt.Printf("Embedding Max Tokens: %d\n", profileConfig.Ollama.EmbeddingMaxTokens)
+		fmt.Printf("Chat Context Tokens: %d\n", profileConfig.Ollama.ChatContextTokens)
+		fmt.Printf("Tokenizer: %s\n", profileConfig.Tokenizer.Type)
+		if profileConfig.Tokenizer.Path != "" {
+			fmt.Printf("Tokenizer Path: %s\n", profileConfig.Tokenizer.Path)
+		}
 		fmt.Printf("Chunk Max Tokens: %d\n", profileConfig.Chunking.MaxTokens)
 		fmt.Printf("Chunk Overlap: %d\n", profileConfig.Chunking.Overlap)
+		fmt.Printf("Chunk Strategy: %s\n", profileConfig.Chunking.Strategy)
+		if profileConfig.Chunking.Strategy == lilrag.ChunkStrategySemantic {
+			fmt.Printf("Semantic Percentile: %.1f\n", profileConfig.Chunking.SemanticPercentile)
+			fmt.Printf("Chunk Min Tokens: %d\n", profileConfig.Chunking.MinTokens)
+		}
+		fmt.Printf("Hierarchical Chunking: %t\n", profileConfig.Chunking.Hierarchical)
+		if profileConfig.Chunking.Hierarchical {
+			fmt.Printf("Parent Max Tokens: %d\n", profileConfig.Chunking.ParentMaxTokens)
+			fmt.Printf("Child Max Tokens: %d\n", profileConfig.Chunking.ChildMaxTokens)
+		}
+		fmt.Printf("PDF OCR: %t\n", profileConfig.PDF.OCR)
+		if profileConfig.PDF.OCR {
+			fmt.Printf("PDF OCR Max Pages: %d\n", profileConfig.PDF.OCRMaxPages)
+			fmt.Printf("PDF OCR Concurrency: %d\n", profileConfig.PDF.OCRConcurrency)
+		}
+		fmt.Printf("Email Attachments: %t\n", profileConfig.Email.Attachments)
+		fmt.Printf("DOCX Notes: %t\n", profileConfig.DOCX.Notes)
+		fmt.Printf("HTML Full Page: %t\n", profileConfig.HTML.FullPage)
+		fmt.Printf("Notebook Outputs: %t\n", profileConfig.Notebook.Outputs)
+		fmt.Printf("Archive Combined: %t\n", profileConfig.Archive.Combined)
+		fmt.Printf("Archive Max Entries: %d\n", profileConfig.Archive.MaxEntries)
+		fmt.Printf("Archive Max Size: %d MB\n", profileConfig.Archive.MaxSizeMB)
+		fmt.Printf("Table Query: %t\n", profileConfig.Tables.Query)
+		fmt.Printf("Table Query Max Rows: %d\n", profileConfig.Tables.MaxRows)
 		fmt.Printf("Server Host: %s\n", profileConfig.Server.Host)
 		fmt.Printf("Server Port: %d\n", profileConfig.Server.Port)
+		fmt.Printf("Server Index Root: %s\n", profileConfig.Server.IndexRoot)
+		fmt.Printf("Watch Dirs: %s\n", strings.Join(profileConfig.Watch.Dirs, ", "))
+		if len(profileConfig.Watch.Dirs) > 0 {
+			fmt.Printf("Watch Interval: %ds\n", profileConfig.Watch.IntervalSeconds)
+			fmt.Printf("Watch Debounce: %ds\n", profileConfig.Watch.DebounceSeconds)
+		}
 		return nil
 
 	case "set":
@@ -359,6 +834,22 @@ func handleConfigSet(profileConfig *config.ProfileConfig, args []string) error {
 			return fmt.Errorf("invalid vector size: %s", value)
 		}
 		profileConfig.Ollama.VectorSize = size
+	case "ollama.embedding-max-tokens":
+		var maxTokens int
+		if _, err := fmt.Sscanf(value, "%d", &maxTokens); err != nil {
+			return fmt.Errorf("invalid embedding max tokens: %s", value)
+		}
+		profileConfig.Ollama.EmbeddingMaxTokens = maxTokens
+	case "ollama.chat-context-tokens":
+		var contextTokens int
+		if _, err := fmt.Sscanf(value, "%d", &contextTokens); err != nil {
+			return fmt.Errorf("invalid chat context tokens: %s", value)
+		}
+		profileConfig.Ollama.ChatContextTokens = contextTokens
+	case "tokenizer.type":
+		profileConfig.Tokenizer.Type = value
+	case "tokenizer.path":
+		profileConfig.Tokenizer.Path = value
 	case "storage.path":
 		profileConfig.StoragePath = value
 	case "data.dir":
@@ -371,6 +862,27 @@ func handleConfigSet(profileConfig *config.ProfileConfig, args []string) error {
 			return fmt.Errorf("invalid port: %s", value)
 		}
 		profileConfig.Server.Port = port
+	case "server.index-root":
+		profileConfig.Server.IndexRoot = value
+	case "watch.dirs":
+		profileConfig.Watch.Dirs = nil
+		for _, dir := range strings.Split(value, ",") {
+			if dir = strings.TrimSpace(dir); dir != "" {
+				profileConfig.Watch.Dirs = append(profileConfig.Watch.Dirs, dir)
+			}
+		}
+	case "watch.interval-seconds":
+		var seconds int
+		if _, err := fmt.Sscanf(value, "%d", &seconds); err != nil || seconds <= 0 {
+			return fmt.Errorf("invalid watch interval: %s", value)
+		}
+		profileConfig.Watch.IntervalSeconds = seconds
+	case "watch.debounce-seconds":
+		var seconds int
+		if _, err := fmt.Sscanf(value, "%d", &seconds); err != nil || seconds < 0 {
+			return fmt.Errorf("invalid watch debounce: %s", value)
+		}
+		profileConfig.Watch.DebounceSeconds = seconds
 	case "chunking.max-tokens":
 		var maxTokens int
 		if _, err := fmt.Sscanf(value, "%d", &maxTokens); err != nil {
@@ -383,6 +895,113 @@ func handleConfigSet(profileConfig *config.ProfileConfig, args []string) error {
 			return fmt.Errorf("invalid overlap: %s", value)
 		}
 		profileConfig.Chunking.Overlap = overlap
+	case "chunking.strategy":
+		if value != lilrag.ChunkStrategyAdaptive && value != lilrag.ChunkStrategySemantic {
+			return fmt.Errorf("invalid chunking strategy: %s (expected adaptive or semantic)", value)
+		}
+		profileConfig.Chunking.Strategy = value
+	case "chunking.semantic-percentile":
+		percentile, err := strconv.ParseFloat(value, 64)
+		if err != nil || percentile <= 0 || percentile >= 100 {
+			return fmt.Errorf("invalid semantic percentile: %s", value)
+		}
+		profileConfig.Chunking.SemanticPercentile = percentile
+	case "chunking.min-tokens":
+		var minTokens int
+		if _, err := fmt.Sscanf(value, "%d", &minTokens); err != nil {
+			return fmt.Errorf("invalid min tokens: %s", value)
+		}
+		profileConfig.Chunking.MinTokens = minTokens
+	case "chunking.hierarchical":
+		hierarchical, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid hierarchical value: %s", value)
+		}
+		profileConfig.Chunking.Hierarchical = hierarchical
+	case "chunking.parent-max-tokens":
+		var parentTokens int
+		if _, err := fmt.Sscanf(value, "%d", &parentTokens); err != nil {
+			return fmt.Errorf("invalid parent max tokens: %s", value)
+		}
+		profileConfig.Chunking.ParentMaxTokens = parentTokens
+	case "chunking.child-max-tokens":
+		var childTokens int
+		if _, err := fmt.Sscanf(value, "%d", &childTokens); err != nil {
+			return fmt.Errorf("invalid child max tokens: %s", value)
+		}
+		profileConfig.Chunking.ChildMaxTokens = childTokens
+	case "pdf.ocr":
+		ocr, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid pdf ocr value: %s", value)
+		}
+		profileConfig.PDF.OCR = ocr
+	case "pdf.ocr-max-pages":
+		var maxPages int
+		if _, err := fmt.Sscanf(value, "%d", &maxPages); err != nil {
+			return fmt.Errorf("invalid pdf ocr max pages: %s", value)
+		}
+		profileConfig.PDF.OCRMaxPages = maxPages
+	case "pdf.ocr-concurrency":
+		var concurrency int
+		if _, err := fmt.Sscanf(value, "%d", &concurrency); err != nil {
+			return fmt.Errorf("invalid pdf ocr concurrency: %s", value)
+		}
+		profileConfig.PDF.OCRConcurrency = concurrency
+	case "email.attachments":
+		attachments, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid email attachments value: %s", value)
+		}
+		profileConfig.Email.Attachments = attachments
+	case "docx.notes":
+		notes, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid docx notes value: %s", value)
+		}
+		profileConfig.DOCX.Notes = notes
+	case "html.full-page":
+		fullPage, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid html full page value: %s", value)
+		}
+		profileConfig.HTML.FullPage = fullPage
+	case "notebook.outputs":
+		outputs, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid notebook outputs value: %s", value)
+		}
+		profileConfig.Notebook.Outputs = outputs
+	case "archive.combined":
+		combined, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid archive combined value: %s", value)
+		}
+		profileConfig.Archive.Combined = combined
+	case "archive.max-entries":
+		var maxEntries int
+		if _, err := fmt.Sscanf(value, "%d", &maxEntries); err != nil {
+			return fmt.Errorf("invalid archive max entries: %s", value)
+		}
+		profileConfig.Archive.MaxEntries = maxEntries
+	case "archive.max-size-mb":
+		var maxSize int
+		if _, err := fmt.Sscanf(value, "%d", &maxSize); err != nil {
+			return fmt.Errorf("invalid archive max size: %s", value)
+		}
+		profileConfig.Archive.MaxSizeMB = maxSize
+	case "tables.query":
+		query, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid tables query value: %s", value)
+		}
+		profileConfig.Tables.Query = query
+	case "tables.max-rows":
+		var maxRows int
+		if _, err := fmt.Sscanf(value, "%d", &maxRows); err != nil {
+			return fmt.Errorf("invalid tables max rows: %s", value)
+		}
+		profileConfig.Tables.MaxRows = maxRows
 	default:
 		return fmt.Errorf("unknown config key: %s", key)
 	}
@@ -536,6 +1155,11 @@ func fileExists(path string) bool {
 	return err == nil
 }
 
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
 func handleChat(ctx context.Context, rag *lilrag.LilRag, _ *config.ProfileConfig, args []string) error {
 	if len(args) == 0 {
 		return fmt.Errorf("usage: lil-rag chat <message> [limit]")
@@ -686,7 +1310,30 @@ func printUsage() {
 	fmt.Println("")
 	fmt.Println("Commands:")
 	fmt.Println("  index [id] <text|file|->     Index text, file, or stdin (ID optional, auto-generated if not provided)")
+	fmt.Println("  index [prefix] <dir>         Index the files below a directory (IDs from relative paths)")
+	fmt.Println("        [--include glob]       Only index files matching a glob, e.g. '*.md' (repeatable)")
+	fmt.Println("        [--exclude glob]       Skip files and directories matching a glob (repeatable)")
+	fmt.Println("  index [id] <url>             Fetch and index a web page or remote file (ID derived from URL)")
+	fmt.Println("  watch [prefix] <dir>         Index a directory and keep it in sync until interrupted")
+	fmt.Println("  sync                         Report documents whose source files changed or are missing")
+	fmt.Println("        [--reindex]            Re-index stale documents")
+	fmt.Println("        [--prune]              Delete documents whose source files are missing")
+	fmt.Println("        [--dry-run]            Show what --reindex and --prune would do")
+	fmt.Println("        [--include glob]       Only index files matching a glob (repeatable)")
+	fmt.Println("        [--exclude glob]       Skip files and directories matching a glob (repeatable)")
+	fmt.Println("        [--interval 2s]        Time between scans for changes")
+	fmt.Println("        [--debounce 1s]        Time a changed file must stay unchanged before it is indexed")
+	fmt.Println("  crawl <url>                  Index a site by following links from a page")
+	fmt.Println("        [--depth N]            Link hops to follow (default: 2)")
+	fmt.Println("        [--max-pages N]        Maximum pages to visit (default: 100, 0 = no limit)")
+	fmt.Println("        [--include regex]      Only follow links matching a pattern (repeatable)")
+	fmt.Println("        [--exclude regex]      Skip links matching a pattern (repeatable)")
+	fmt.Println("        [--delay 1s]           Minimum time between requests to a host")
+	fmt.Println("        [--all-hosts]          Follow links to other hosts")
+	fmt.Println("        [--ignore-robots]      Crawl pages disallowed by robots.txt")
 	fmt.Println("  search <query> [limit]       Search for similar text (default limit: 10)")
+	fmt.Println("         [--language <lang>]   Only match source code in a language (e.g. go, python)")
+	fmt.Println("         [--meta key=value]    Only match chunks whose metadata contains a value (e.g. from=alice)")
 	fmt.Println("  chat <message> [limit]       Interactive chat with RAG context (default limit: 5)")
 	fmt.Println("  documents                    List all indexed documents")
 	fmt.Println("  delete <id> [--force]        Delete a document by ID")
@@ -714,12 +1361,38 @@ func printUsage() {
 	fmt.Println("  ollama.model                    Embedding model name")
 	fmt.Println("  ollama.chat-model               Chat model name")
 	fmt.Println("  ollama.vector-size              Vector dimension size")
+	fmt.Println("  ollama.embedding-max-tokens     Embedding model context size in tokens")
+	fmt.Println("  ollama.chat-context-tokens      Token budget for document context in chat")
+	fmt.Println("  tokenizer.type                  Tokenizer: whitespace, wordpiece, bpe (empty = auto)")
+	fmt.Println("  tokenizer.path                  Path to tokenizer.json, vocab.txt or merges.txt")
 	fmt.Println("  storage.path                    Database file path")
 	fmt.Println("  data.dir                        Data directory path")
 	fmt.Println("  server.host                     HTTP server host")
 	fmt.Println("  server.port                     HTTP server port")
+	fmt.Println("  server.index-root               Directory the server may index with /api/index/directory")
+	fmt.Println("  watch.dirs                      Comma-separated directories lil-rag-server keeps indexed")
+	fmt.Println("  watch.interval-seconds          Seconds between scans of watched directories")
+	fmt.Println("  watch.debounce-seconds          Seconds a changed file must stay unchanged before indexing")
 	fmt.Println("  chunking.max-tokens             Maximum tokens per chunk")
 	fmt.Println("  chunking.overlap                Token overlap between chunks")
+	fmt.Println("  chunking.strategy               Chunking strategy: adaptive or semantic")
+	fmt.Println("  chunking.semantic-percentile    Similarity percentile that marks a topic shift")
+	fmt.Println("  chunking.min-tokens             Minimum tokens per semantic chunk")
+	fmt.Println("  chunking.hierarchical           Enable parent/child chunking (true/false)")
+	fmt.Println("  chunking.parent-max-tokens      Maximum tokens per parent section")
+	fmt.Println("  chunking.child-max-tokens       Maximum tokens per embedded child chunk")
+	fmt.Println("  pdf.ocr                         OCR PDF pages without a text layer (true/false)")
+	fmt.Println("  pdf.ocr-max-pages               Maximum pages sent to OCR per PDF")
+	fmt.Println("  pdf.ocr-concurrency             PDF pages sent to the vision model at once")
+	fmt.Println("  email.attachments               Index email attachments in supported formats (true/false)")
+	fmt.Println("  docx.notes                      Index footnotes and comments of Word documents (true/false)")
+	fmt.Println("  html.full-page                  Index whole HTML pages instead of their main content (true/false)")
+	fmt.Println("  notebook.outputs                Index text outputs of notebook code cells (true/false)")
+	fmt.Println("  archive.combined                Index archives as one document instead of one per file (true/false)")
+	fmt.Println("  archive.max-entries             Maximum entries read from an archive")
+	fmt.Println("  archive.max-size-mb             Maximum total uncompressed size of an archive in MB")
+	fmt.Println("  tables.query                    Let chat answer with SQL over XLSX and CSV sheets (true/false)")
+	fmt.Println("  tables.max-rows                 Maximum rows returned by a table query")
 	fmt.Println("")
 	fmt.Println("Examples:")
 	fmt.Println("  lil-rag config init")
@@ -732,6 +1405,11 @@ func printUsage() {
 	fmt.Println("  lil-rag index doc2 document.txt           # Explicit ID")
 	fmt.Println("  echo \"Hello world\" | lil-rag index -    # Auto-generated ID from stdin")
 	fmt.Println("  echo \"Hello world\" | lil-rag index doc3 -  # Explicit ID from stdin")
+	fmt.Println("  lil-rag index wiki ./docs --include '*.md'  # IDs like wiki-setup-guide-md")
+	fmt.Println("  lil-rag index https://wiki.example.com/page  # ID derived from the URL")
+	fmt.Println("  lil-rag crawl https://wiki.example.com/ --depth 1 --exclude '/archive/'")
+	fmt.Println("  lil-rag watch wiki ./docs --include '*.md'  # Re-index edits, drop deleted files")
+	fmt.Println("  lil-rag sync --reindex --prune --dry-run")
 	fmt.Println("  lil-rag search \"hello\" 5")
 	fmt.Println("  lil-rag chat \"What is machine learning?\" 3")
 	fmt.Println("  lil-rag documents               # List all documents")
@@ -9,7 +9,6 @@ require (
 	github.com/dslipak/pdf v0.0.2
 	github.com/gen2brain/go-fitz v1.24.15
 	github.com/mattn/go-sqlite3 v1.14.32
-	github.com/nguyenthenguyen/docx v0.0.0-20230621112118-9c8e795a11db
 	github.com/xuri/excelize/v2 v2.9.1
 	golang.org/x/net v0.43.0
 	gopkg.in/yaml.v3 v3.0.1
@@ -7,6 +7,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -44,6 +45,11 @@ func (h *Handler) Index() http.HandlerFunc {
 			return
 		}
 
+		if req.URL != "" {
+			h.handleURLIndex(w, r, req)
+			return
+		}
+
 		// Generate ID if not provided
 		if req.ID == "" {
 			req.ID = lilrag.GenerateDocumentID()
@@ -84,6 +90,160 @@ func (h *Handler) Index() http.HandlerFunc {
 	}
 }
 
+// IndexDirectory handles recursive directory indexing requests at /api/index/directory.
+// Only directories below the configured index root can be indexed.
+func (h *Handler) IndexDirectory() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			h.writeError(w, http.StatusMethodNotAllowed, "method not allowed", "")
+			return
+		}
+		if h.indexRoot == "" {
+			h.writeError(w, http.StatusForbidden, "directory indexing disabled",
+				"set server.index_root to allow indexing server-side directories")
+			return
+		}
+
+		var req IndexDirectoryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return
+		}
+		if req.Path == "" {
+			h.writeError(w, http.StatusBadRequest, "path is required", "")
+			return
+		}
+
+		dir, err := resolveUnderRoot(h.indexRoot, req.Path)
+		if err != nil {
+			h.writeError(w, http.StatusForbidden, "path not allowed", err.Error())
+			return
+		}
+
+		log.Printf("Indexing directory %s", dir)
+		extendWriteDeadline(w, longRequestTimeout)
+		ctx, cancel := context.WithTimeout(r.Context(), longRequestTimeout)
+		defer cancel()
+
+		report, err := h.rag.IndexDirectory(ctx, dir, lilrag.DirectoryOptions{
+			Include:  req.Include,
+			Exclude:  req.Exclude,
+			IDPrefix: req.IDPrefix,
+		})
+		if err != nil && report == nil {
+			h.writeError(w, http.StatusBadRequest, "failed to index directory", err.Error())
+			return
+		}
+		if err != nil {
+			log.Printf("Indexing of directory %s stopped: %v", dir, err)
+			h.writeError(w, http.StatusInternalServerError, "failed to index directory", err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		response := map[string]interface{}{
+			"status":  "indexed",
+			"indexed": report.Count(lilrag.FileStatusIndexed),
+			"skipped": report.Count(lilrag.FileStatusSkipped),
+			"failed":  report.Count(lilrag.FileStatusFailed),
+			"files":   report.Files,
+		}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Printf("Failed to encode response: %v", err)
+		}
+	}
+}
+
+// resolveUnderRoot resolves a requested path against root, following symlinks, and
+// rejects paths that end up outside of it
+func resolveUnderRoot(root, requested string) (string, error) {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("index root is not accessible: %w", err)
+	}
+	realRoot, err = filepath.Abs(realRoot)
+	if err != nil {
+		return "", err
+	}
+
+	target := requested
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(realRoot, target)
+	}
+	realTarget, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		return "", fmt.Errorf("directory is not accessible: %s", requested)
+	}
+	if realTarget, err = filepath.Abs(realTarget); err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(realRoot, realTarget)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside of the index root", requested)
+	}
+	return realTarget, nil
+}
+
+// handleURLIndex fetches and indexes the page of an index request, or crawls the site
+// from it when the request has crawl options
+func (h *Handler) handleURLIndex(w http.ResponseWriter, r *http.Request, req IndexRequest) {
+	target, err := url.Parse(req.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		h.writeError(w, http.StatusBadRequest, "invalid url", "url must be an http or https URL")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if req.Crawl == nil {
+		log.Printf("Indexing URL %s", req.URL)
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Minute)
+		defer cancel()
+
+		page, err := h.rag.IndexURL(ctx, req.URL, req.ID)
+		if err != nil {
+			log.Printf("Failed to index URL %s: %v", req.URL, err)
+			h.writeError(w, http.StatusInternalServerError, "failed to index", err.Error())
+			return
+		}
+		response := map[string]string{"status": page.Status, "id": page.ID, "url": page.URL}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Printf("Failed to encode response: %v", err)
+		}
+		return
+	}
+
+	opts := lilrag.DefaultCrawlOptions()
+	if req.Crawl.Depth != nil {
+		opts.MaxDepth = *req.Crawl.Depth
+	}
+	if req.Crawl.MaxPages != nil {
+		opts.MaxPages = *req.Crawl.MaxPages
+	}
+	opts.Include = req.Crawl.Include
+	opts.Exclude = req.Crawl.Exclude
+	opts.SameHost = !req.Crawl.AllHosts
+
+	log.Printf("Crawling %s (depth %d, up to %d pages)", req.URL, opts.MaxDepth, opts.MaxPages)
+	extendWriteDeadline(w, longRequestTimeout)
+	ctx, cancel := context.WithTimeout(r.Context(), longRequestTimeout)
+	defer cancel()
+
+	report, err := h.rag.Crawl(ctx, req.URL, opts)
+	if err != nil && report == nil {
+		h.writeError(w, http.StatusBadRequest, "invalid crawl", err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Crawl of %s stopped: %v", req.URL, err)
+		h.writeError(w, http.StatusInternalServerError, "crawl stopped", err.Error())
+		return
+	}
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"status": "crawled", "pages": report.Pages}); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
+
 // Search handles search requests at /api/search (supports both GET and POST)
 func (h *Handler) Search() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -111,8 +271,18 @@ func (h *Handler) handleSearchGET(w http.ResponseWriter, r *http.Request) {
 			limit = l
 		}
 	}
+	opts := lilrag.SearchOptions{Language: r.URL.Query().Get("language")}
+	// Metadata filters are passed as meta.<key>=<value>, e.g. meta.from=alice
+	for key, values := range r.URL.Query() {
+		if name, ok := strings.CutPrefix(key, "meta."); ok && name != "" && len(values) > 0 {
+			if opts.Metadata == nil {
+				opts.Metadata = make(map[string]string)
+			}
+			opts.Metadata[name] = values[0]
+		}
+	}
 	log.Printf("Search GET request - query: '%s', limit: %d", query, limit)
-	h.performSearch(w, r, query, limit)
+	h.performSearch(w, r, query, limit, opts)
 }
 
 func (h *Handler) handleSearchPOST(w http.ResponseWriter, r *http.Request) {
@@ -129,16 +299,18 @@ func (h *Handler) handleSearchPOST(w http.ResponseWriter, r *http.Request) {
 		req.Limit = 10
 	}
 	log.Printf("Search POST request - query: '%s', limit: %d", req.Query, req.Limit)
-	h.performSearch(w, r, req.Query, req.Limit)
+	h.performSearch(w, r, req.Query, req.Limit, lilrag.SearchOptions{Language: req.Language, Metadata: req.Metadata})
 }
 
-func (h *Handler) performSearch(w http.ResponseWriter, r *http.Request, query string, limit int) {
+func (h *Handler) performSearch(
+	w http.ResponseWriter, r *http.Request, query string, limit int, opts lilrag.SearchOptions,
+) {
 	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 	defer cancel()
 
 	log.Printf("Performing search - query: '%s', limit: %d", query, limit)
 	searchStart := time.Now()
-	results, err := h.rag.Search(ctx, query, limit)
+	results, err := h.rag.SearchWithOptions(ctx, query, limit, opts)
 	searchDuration := time.Since(searchStart)
 
 	if err != nil {
@@ -214,11 +386,19 @@ func (h *Handler) Documents() http.HandlerFunc {
 		}
 
 		// Handle list all documents request
-		documents, err := h.rag.ListDocuments(ctx)
+		docs, err := h.rag.ListDocuments(ctx)
 		if err != nil {
 			h.writeError(w, http.StatusInternalServerError, "failed to list documents", err.Error())
 			return
 		}
+
+		// Documents indexed from local files report whether those files changed since
+		documents := make([]documentListItem, len(docs))
+		for i, doc := range docs {
+			documents[i].DocumentInfo = doc
+			documents[i].SourceStatus, _ = lilrag.DocumentSourceStatus(doc)
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(map[string]interface{}{
 			"documents": documents,
@@ -230,6 +410,12 @@ func (h *Handler) Documents() http.HandlerFunc {
 	}
 }
 
+// documentListItem is a document in the /api/documents list
+type documentListItem struct {
+	lilrag.DocumentInfo
+	SourceStatus string `json:"source_status,omitempty"` // Empty without a local source file
+}
+
 // handleFileUpload processes file upload requests
 func (h *Handler) handleFileUpload(w http.ResponseWriter, r *http.Request) {
 	// Parse multipart form with max 50MB
@@ -254,7 +440,11 @@ func (h *Handler) handleFileUpload(w http.ResponseWriter, r *http.Request) {
 
 	// Create temporary file to save uploaded content
 	tempDir := os.TempDir()
-	tempFile, err := os.CreateTemp(tempDir, "lilrag_upload_*"+filepath.Ext(header.Filename))
+	tempExt := filepath.Ext(header.Filename)
+	if strings.HasSuffix(strings.ToLower(header.Filename), ".tar.gz") {
+		tempExt = ".tar.gz" // Keep the double extension so the archive is detected
+	}
+	tempFile, err := os.CreateTemp(tempDir, "lilrag_upload_*"+tempExt)
 	if err != nil {
 		h.writeError(w, http.StatusInternalServerError, "failed to create temp file", err.Error())
 		return
@@ -276,7 +466,13 @@ func (h *Handler) handleFileUpload(w http.ResponseWriter, r *http.Request) {
 
 	// Check if this is an image file and preserve it in the data directory
 	var permanentPath string
-	if h.dataDir != "" && lilrag.IsImageFile(tempFile.Name()) {
+	// The type is sniffed from the content, since the client-supplied extension may be wrong
+	fileType, err := h.rag.DetectFileType(tempFile.Name())
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to detect file type", err.Error())
+		return
+	}
+	if h.dataDir != "" && fileType.DocType == lilrag.DocumentTypeImage {
 		// Create images directory
 		imagesDir := filepath.Join(h.dataDir, "images")
 		if err := os.MkdirAll(imagesDir, 0o755); err != nil {
@@ -296,6 +492,11 @@ func (h *Handler) handleFileUpload(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if h.jobs != nil {
+		h.submitUploadJob(w, r, id, header.Filename, tempFile.Name(), permanentPath)
+		return
+	}
+
 	// Index the file using document handler
 	if err := h.rag.IndexFile(ctx, tempFile.Name(), id); err != nil {
 		log.Printf("Failed to index file %s: %v", header.Filename, err)
@@ -331,6 +532,102 @@ func (h *Handler) handleFileUpload(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// submitUploadJob queues an uploaded file for indexing in the background and responds
+// with 202 and the job, whose progress is reported at /api/jobs/{id}
+func (h *Handler) submitUploadJob(
+	w http.ResponseWriter, r *http.Request, id, fileName, tempPath, sourcePath string,
+) {
+	content, err := os.Open(tempPath)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to queue file", err.Error())
+		return
+	}
+	defer content.Close()
+
+	job, err := h.jobs.Submit(r.Context(), id, fileName, content, sourcePath)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to queue file", err.Error())
+		return
+	}
+
+	log.Printf("Queued file %s as document %s (job %s)", fileName, id, job.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(map[string]string{
+		"status": job.Status,
+		"id":     id,
+		"job_id": job.ID,
+	}); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
+
+// Jobs handles ingestion job requests: GET /api/jobs lists jobs, optionally filtered by
+// ?status=queued,running, GET /api/jobs/{id} reports a job and POST /api/jobs/{id}/cancel
+// cancels it
+func (h *Handler) Jobs() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.jobs == nil {
+			h.writeError(w, http.StatusServiceUnavailable, "background indexing is not enabled", "")
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+
+		path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/jobs"), "/")
+		jobID, action, _ := strings.Cut(path, "/")
+
+		switch {
+		case jobID == "":
+			if r.Method != http.MethodGet {
+				h.writeError(w, http.StatusMethodNotAllowed, "method not allowed", "")
+				return
+			}
+			var statuses []string
+			if status := r.URL.Query().Get("status"); status != "" {
+				statuses = strings.Split(status, ",")
+			}
+			jobs, err := h.jobs.List(ctx, statuses...)
+			if err != nil {
+				h.writeError(w, http.StatusInternalServerError, "failed to list jobs", err.Error())
+				return
+			}
+			if jobs == nil {
+				jobs = []lilrag.Job{}
+			}
+			h.writeJSON(w, map[string]interface{}{"jobs": jobs, "count": len(jobs)})
+		case action == "":
+			if r.Method != http.MethodGet {
+				h.writeError(w, http.StatusMethodNotAllowed, "method not allowed", "")
+				return
+			}
+			job, err := h.jobs.Get(ctx, jobID)
+			if err != nil {
+				h.writeError(w, http.StatusNotFound, "job not found", err.Error())
+				return
+			}
+			h.writeJSON(w, job)
+		case action == "cancel":
+			if r.Method != http.MethodPost {
+				h.writeError(w, http.StatusMethodNotAllowed, "method not allowed", "")
+				return
+			}
+			if _, err := h.jobs.Get(ctx, jobID); err != nil {
+				h.writeError(w, http.StatusNotFound, "job not found", err.Error())
+				return
+			}
+			job, err := h.jobs.Cancel(ctx, jobID)
+			if err != nil {
+				h.writeError(w, http.StatusConflict, "failed to cancel job", err.Error())
+				return
+			}
+			h.writeJSON(w, job)
+		default:
+			h.writeError(w, http.StatusNotFound, "not found", "")
+		}
+	}
+}
+
 // updateDocumentSourcePath updates the source path for a document
 func (h *Handler) updateDocumentSourcePath(ctx context.Context, documentID, sourcePath string) error {
 	// Get the storage instance from rag and update directly
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"time"
@@ -17,17 +18,46 @@ type Handler struct {
 	version  string
 	dataDir  string
 	renderer *theme.Renderer
+	// indexRoot is the server-side directory under which /api/index/directory may index;
+	// directory indexing is disabled when it is empty
+	indexRoot string
+	// jobs indexes file uploads in the background; uploads are indexed synchronously when nil
+	jobs *lilrag.JobQueue
 }
 
 // Request and Response types
 type IndexRequest struct {
 	ID   string `json:"id"`
 	Text string `json:"text"`
+	// URL of a web page or remote file to fetch and index instead of text
+	URL string `json:"url,omitempty"`
+	// Crawl follows the links of the page at URL, indexing each page as its own document
+	Crawl *CrawlRequest `json:"crawl,omitempty"`
+}
+
+// CrawlRequest limits a crawl started by an index request; zero values use the defaults
+type CrawlRequest struct {
+	Depth    *int     `json:"depth,omitempty"`
+	MaxPages *int     `json:"max_pages,omitempty"`
+	Include  []string `json:"include,omitempty"`
+	Exclude  []string `json:"exclude,omitempty"`
+	AllHosts bool     `json:"all_hosts,omitempty"`
+}
+
+// IndexDirectoryRequest indexes a server-side directory under the configured index root
+type IndexDirectoryRequest struct {
+	Path     string   `json:"path"` // Absolute, or relative to the index root
+	Include  []string `json:"include,omitempty"`
+	Exclude  []string `json:"exclude,omitempty"`
+	IDPrefix string   `json:"id_prefix,omitempty"`
 }
 
 type SearchRequest struct {
-	Query string `json:"query"`
-	Limit int    `json:"limit,omitempty"`
+	Query    string `json:"query"`
+	Limit    int    `json:"limit,omitempty"`
+	Language string `json:"language,omitempty"`
+	// Metadata filters on chunk or document metadata such as email headers, e.g. {"from": "alice"}
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 type ChatRequest struct {
@@ -69,6 +99,16 @@ func NewWithVersion(rag *lilrag.LilRag, version, dataDir string) *Handler {
 	return &Handler{rag: rag, version: version, dataDir: dataDir, renderer: renderer}
 }
 
+// SetIndexRoot allows /api/index/directory to index directories below root
+func (h *Handler) SetIndexRoot(root string) {
+	h.indexRoot = root
+}
+
+// SetJobQueue makes file uploads to /api/index return 202 and index in the background
+func (h *Handler) SetJobQueue(jobs *lilrag.JobQueue) {
+	h.jobs = jobs
+}
+
 // LoggingMiddleware logs HTTP requests with details and records Prometheus metrics
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -104,6 +144,24 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// longRequestTimeout bounds requests such as directory indexing and crawls, which run far
+// longer than the server's WriteTimeout
+const longRequestTimeout = 30 * time.Minute
+
+// extendWriteDeadline lets a long-running request write its response after the server's
+// WriteTimeout has passed
+func extendWriteDeadline(w http.ResponseWriter, timeout time.Duration) {
+	err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout))
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Printf("Warning: failed to extend write deadline: %v", err)
+	}
+}
+
 // Utility functions
 func (h *Handler) writeError(w http.ResponseWriter, status int, errType, message string) {
 	w.Header().Set("Content-Type", "application/json")
@@ -116,3 +174,10 @@ func (h *Handler) writeError(w http.ResponseWriter, status int, errType, message
 		log.Printf("Failed to encode error response: %v", err)
 	}
 }
+
+func (h *Handler) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
@@ -669,6 +669,9 @@ func (h *Handler) fallbackChatPage(w http.ResponseWriter, _ *http.Request) {
                     const sourceId = 'source-' + Date.now() + '-' + index;
                     html += '<button class="source-button" onclick="toggleSource(\\'' + sourceId + '\\'))">';
                     html += source.ID + ' (' + (source.Score * 100).toFixed(1) + '%)';
+                    if (source.Metadata && source.Metadata.sheet) {
+                        html += ' · sheet ' + source.Metadata.sheet;
+                    }
                     html += '</button>';
                 });
                 html += '</div>';
@@ -227,24 +227,26 @@ func (h *Handler) ServeFile() http.HandlerFunc {
 			return
 		}
 
-		// Determine content type based on file extension
-		ext := strings.ToLower(filepath.Ext(docInfo.SourcePath))
-		var contentType string
-		switch ext {
-		case ".jpg", ".jpeg":
-			contentType = "image/jpeg"
-		case ".png":
-			contentType = "image/png"
-		case ".gif":
-			contentType = "image/gif"
-		case ".bmp":
-			contentType = "image/bmp"
-		case ".webp":
-			contentType = "image/webp"
-		case ".tiff", ".tif":
-			contentType = "image/tiff"
-		default:
-			contentType = "application/octet-stream"
+		// Prefer the MIME type detected from the content when the image was indexed
+		contentType := docInfo.Metadata["mime_type"]
+		if contentType == "" {
+			// Determine content type based on file extension
+			switch strings.ToLower(filepath.Ext(docInfo.SourcePath)) {
+			case ".jpg", ".jpeg":
+				contentType = "image/jpeg"
+			case ".png":
+				contentType = "image/png"
+			case ".gif":
+				contentType = "image/gif"
+			case ".bmp":
+				contentType = "image/bmp"
+			case ".webp":
+				contentType = "image/webp"
+			case ".tiff", ".tif":
+				contentType = "image/tiff"
+			default:
+				contentType = "application/octet-stream"
+			}
 		}
 
 		// Set headers
@@ -519,6 +521,13 @@ func (h *Handler) fallbackDocumentView(
         .chunk-text {
             line-height: 1.5;
         }
+        .chunk-label {
+            display: inline-block;
+            margin-bottom: 8px;
+            color: #6c757d;
+            font-size: 0.85rem;
+            text-decoration: none;
+        }
         .back-to-chat-button {
             position: absolute;
             top: 30px;
@@ -634,8 +643,24 @@ func (h *Handler) fallbackDocumentView(
                         chunkDiv.className += ' highlighted-chunk';
                     }
                     chunkDiv.innerHTML = '<div class="chunk-text">' + chunk.Text.replace(/\n/g, '<br>') + '</div>';
+
+                    // Notebook chunks link back to their cell, e.g. #cell-3
+                    const cell = chunk.Metadata && chunk.Metadata.cell_index;
+                    if (cell) {
+                        if (!document.getElementById('cell-' + cell)) {
+                            chunkDiv.id = 'cell-' + cell;
+                        }
+                        chunkDiv.insertAdjacentHTML('afterbegin',
+                            '<a class="chunk-label" href="#cell-' + cell + '">Cell ' + cell + '</a>');
+                    }
                     contentDiv.appendChild(chunkDiv);
                 });
+
+                const target = window.location.hash && document.getElementById(window.location.hash.slice(1));
+                if (target) {
+                    target.classList.add('highlighted-chunk');
+                    target.scrollIntoView();
+                }
             })
             .catch(error => {
                 // Fallback to regular content loading
@@ -133,6 +133,13 @@ func TestHandler_Index_JSON(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			expectError:    true,
 		},
+		{
+			name:           "unsupported URL scheme",
+			method:         http.MethodPost,
+			body:           IndexRequest{URL: "file:///etc/passwd"},
+			expectedStatus: http.StatusBadRequest,
+			expectError:    true,
+		},
 		{
 			name:           "invalid JSON",
 			method:         http.MethodPost,
@@ -196,6 +203,124 @@ func TestHandler_Index_JSON(t *testing.T) {
 	}
 }
 
+func TestHandler_IndexDirectory(t *testing.T) {
+	handler := createTestHandler(t)
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "empty"), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	post := func(path string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(IndexDirectoryRequest{Path: path})
+		req := httptest.NewRequest(http.MethodPost, "/api/index/directory", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.IndexDirectory()(w, req)
+		return w
+	}
+
+	if w := post(root); w.Code != http.StatusForbidden {
+		t.Errorf("Expected directory indexing to be disabled without a root, got %d", w.Code)
+	}
+
+	handler.SetIndexRoot(root)
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{"relative path under the root", "empty", http.StatusOK},
+		{"absolute path under the root", filepath.Join(root, "empty"), http.StatusOK},
+		{"parent directory", "..", http.StatusForbidden},
+		{"absolute path outside the root", outside, http.StatusForbidden},
+		{"symlink out of the root", "escape", http.StatusForbidden},
+		{"missing path", "missing", http.StatusForbidden},
+		{"empty path", "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := post(tt.path); w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestExtendWriteDeadline(t *testing.T) {
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		extendWriteDeadline(w, 5*time.Second)
+		time.Sleep(300 * time.Millisecond)
+		_, _ = io.WriteString(w, "done")
+	})
+
+	// The deadline must reach the connection through the logging middleware's writer
+	server := httptest.NewUnstartedServer(LoggingMiddleware(slow))
+	server.Config.WriteTimeout = 100 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected the response to outlive the write timeout, got %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || string(body) != "done" {
+		t.Fatalf("Expected body %q, got %q (%v)", "done", body, err)
+	}
+}
+
+func TestHandler_Documents_SourceStatus(t *testing.T) {
+	handler := createTestHandler(t)
+	sourcePath := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(sourcePath, []byte("Edited since indexing."), 0600); err != nil {
+		t.Fatalf("Failed to write source file: %v", err)
+	}
+
+	storage := handler.rag.GetStorage()
+	ctx := context.Background()
+	sources := map[string]string{
+		"stale":   sourcePath,
+		"missing": filepath.Join(t.TempDir(), "removed.txt"),
+		"text":    "",
+	}
+	for id, source := range sources {
+		chunks := []lilrag.Chunk{{Text: "Indexed text.", TokenCount: 2}}
+		metadata := map[string]string{lilrag.MetadataFileHash: "0000"}
+		if err := storage.IndexChunksWithMetadata(ctx, id, "Indexed text.", chunks, [][]float32{{0.1, 0.2, 0.3}},
+			source, "text", metadata); err != nil {
+			t.Fatalf("Failed to store document %s: %v", id, err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.Documents()(w, httptest.NewRequest(http.MethodGet, "/api/documents", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Documents []struct {
+			ID           string `json:"id"`
+			SourceStatus string `json:"source_status"`
+		} `json:"documents"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	expected := map[string]string{"stale": lilrag.SourceStatusStale, "missing": lilrag.SourceStatusMissing, "text": ""}
+	for _, doc := range response.Documents {
+		if doc.SourceStatus != expected[doc.ID] {
+			t.Errorf("Expected %s to have source status %q, got %q", doc.ID, expected[doc.ID], doc.SourceStatus)
+		}
+	}
+	if len(response.Documents) != len(expected) {
+		t.Errorf("Expected %d documents, got %d", len(expected), len(response.Documents))
+	}
+}
+
 func TestHandler_Search_GET(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -605,6 +730,84 @@ func TestHandler_FileUpload(t *testing.T) {
 	}
 }
 
+func TestHandler_FileUpload_Job(t *testing.T) {
+	handler := createTestHandler(t)
+
+	w := httptest.NewRecorder()
+	handler.Jobs()(w, httptest.NewRequest(http.MethodGet, "/api/jobs", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 without a job queue, got %d", w.Code)
+	}
+
+	// The queue is not run, so the job stays queued
+	jobs, err := lilrag.NewJobQueue(handler.rag, t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create job queue: %v", err)
+	}
+	handler.SetJobQueue(jobs)
+
+	body, contentType, err := createMultipartForm("report", "report.txt", "Quarterly report text.")
+	if err != nil {
+		t.Fatalf("Failed to setup form: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/index", body)
+	req.Header.Set("Content-Type", contentType)
+	w = httptest.NewRecorder()
+	handler.Index()(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d: %s", w.Code, w.Body.String())
+	}
+	var queued map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&queued); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if queued["id"] != "report" || queued["status"] != lilrag.JobStatusQueued || queued["job_id"] == "" {
+		t.Fatalf("Unexpected response %v", queued)
+	}
+
+	getJob := func(method, path string) (int, lilrag.Job) {
+		w := httptest.NewRecorder()
+		handler.Jobs()(w, httptest.NewRequest(method, path, nil))
+		var job lilrag.Job
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&job); err != nil {
+				t.Fatalf("Failed to decode job: %v", err)
+			}
+		}
+		return w.Code, job
+	}
+
+	status, job := getJob(http.MethodGet, "/api/jobs/"+queued["job_id"])
+	if status != http.StatusOK || job.DocumentID != "report" || job.FileName != "report.txt" ||
+		job.Status != lilrag.JobStatusQueued {
+		t.Errorf("Expected the queued job, got %d %+v", status, job)
+	}
+
+	w = httptest.NewRecorder()
+	handler.Jobs()(w, httptest.NewRequest(http.MethodGet, "/api/jobs?status=queued,running", nil))
+	var list struct {
+		Jobs  []lilrag.Job `json:"jobs"`
+		Count int          `json:"count"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode job list: %v", err)
+	}
+	if list.Count != 1 || list.Jobs[0].ID != queued["job_id"] {
+		t.Errorf("Expected the queued job to be listed, got %+v", list)
+	}
+
+	status, job = getJob(http.MethodPost, "/api/jobs/"+queued["job_id"]+"/cancel")
+	if status != http.StatusOK || job.Status != lilrag.JobStatusCanceled {
+		t.Errorf("Expected the job to be canceled, got %d %+v", status, job)
+	}
+	if status, _ := getJob(http.MethodPost, "/api/jobs/"+queued["job_id"]+"/cancel"); status != http.StatusConflict {
+		t.Errorf("Expected status 409 canceling a canceled job, got %d", status)
+	}
+	if status, _ := getJob(http.MethodGet, "/api/jobs/job-unknown"); status != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown job, got %d", status)
+	}
+}
+
 func TestIsPDFFile(t *testing.T) {
 	tests := []struct {
 		filename string
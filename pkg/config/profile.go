@@ -8,11 +8,20 @@ import (
 )
 
 type ProfileConfig struct {
-	Ollama      OllamaConfig `json:"ollama"`
-	StoragePath string       `json:"storage_path"`
-	DataDir     string       `json:"data_dir"`
-	Server      ServerConfig `json:"server"`
-	Chunking    ChunkConfig  `json:"chunking"`
+	Ollama      OllamaConfig    `json:"ollama"`
+	StoragePath string          `json:"storage_path"`
+	DataDir     string          `json:"data_dir"`
+	Server      ServerConfig    `json:"server"`
+	Chunking    ChunkConfig     `json:"chunking"`
+	Tokenizer   TokenizerConfig `json:"tokenizer"`
+	PDF         PDFConfig       `json:"pdf"`
+	Email       EmailConfig     `json:"email"`
+	DOCX        DOCXConfig      `json:"docx"`
+	HTML        HTMLConfig      `json:"html"`
+	Notebook    NotebookConfig  `json:"notebook"`
+	Archive     ArchiveConfig   `json:"archive"`
+	Tables      TablesConfig    `json:"tables"`
+	Watch       WatchConfig     `json:"watch"`
 }
 
 type OllamaConfig struct {
@@ -23,16 +32,89 @@ type OllamaConfig struct {
 	VisionModel    string `json:"vision_model"`
 	TimeoutSeconds int    `json:"timeout_seconds"`
 	ImageMaxSize   int    `json:"image_max_size"`
+
+	EmbeddingMaxTokens int `json:"embedding_max_tokens"` // Context size of the embedding model
+	ChatContextTokens  int `json:"chat_context_tokens"`  // Token budget for document context in chat
+}
+
+// TokenizerConfig selects the tokenizer used to count tokens for chunking, embedding and chat
+type TokenizerConfig struct {
+	Type string `json:"type"` // "whitespace", "wordpiece", "bpe", or empty to auto-detect from Path
+	Path string `json:"path"` // tokenizer.json, vocab.txt or merges.txt matching the embedding model
+}
+
+// PDFConfig controls the OCR fallback for PDF pages without a usable text layer
+type PDFConfig struct {
+	OCR            bool `json:"ocr"`             // Render scanned pages and read them with the vision model
+	OCRMaxPages    int  `json:"ocr_max_pages"`   // Maximum pages sent to OCR per document
+	OCRConcurrency int  `json:"ocr_concurrency"` // Pages read by the vision model at once
+}
+
+// EmailConfig controls how .eml and .mbox files are indexed
+type EmailConfig struct {
+	Attachments bool `json:"attachments"` // Also index attachments in supported formats
+}
+
+// DOCXConfig controls how Word documents are indexed
+type DOCXConfig struct {
+	Notes bool `json:"notes"` // Also index footnotes and comments as separate chunks
+}
+
+// HTMLConfig controls how HTML pages are indexed
+type HTMLConfig struct {
+	FullPage bool `json:"full_page"` // Index the whole page instead of only its main content
+}
+
+// NotebookConfig controls how Jupyter notebooks are indexed
+type NotebookConfig struct {
+	Outputs bool `json:"outputs"` // Also index the text outputs of code cells
+}
+
+// TablesConfig controls SQL queries over the sheets of XLSX and CSV files
+type TablesConfig struct {
+	Query   bool `json:"query"`    // Let the chat model query retrieved sheets with SQL
+	MaxRows int  `json:"max_rows"` // Maximum rows returned by a table query
+}
+
+// ArchiveConfig controls how .zip and .tar.gz archives are indexed
+type ArchiveConfig struct {
+	Combined   bool `json:"combined"`    // Index the whole archive as one document
+	MaxEntries int  `json:"max_entries"` // Maximum entries read from an archive
+	MaxSizeMB  int  `json:"max_size_mb"` // Maximum total uncompressed size of the entries
+}
+
+// WatchConfig lists directories that lil-rag-server keeps indexed while it runs
+type WatchConfig struct {
+	Dirs            []string `json:"dirs"`             // Directories to watch; document IDs are prefixed with their base name
+	Include         []string `json:"include"`          // Only index files matching one of these globs
+	Exclude         []string `json:"exclude"`          // Skip files and directories matching these globs
+	IntervalSeconds int      `json:"interval_seconds"` // Time between scans for changes
+	DebounceSeconds int      `json:"debounce_seconds"` // Time a changed file must stay unchanged before it is indexed
 }
 
 type ServerConfig struct {
 	Host string `json:"host"`
 	Port int    `json:"port"`
+	// IndexRoot is the directory below which /api/index/directory may index server-side
+	// paths; empty disables the endpoint
+	IndexRoot string `json:"index_root"`
 }
 
 type ChunkConfig struct {
 	MaxTokens int `json:"max_tokens"`
 	Overlap   int `json:"overlap"`
+
+	// Hierarchical enables parent/child chunking: small children are embedded for
+	// retrieval and their parent sections are returned as context
+	Hierarchical    bool `json:"hierarchical"`
+	ParentMaxTokens int  `json:"parent_max_tokens"`
+	ChildMaxTokens  int  `json:"child_max_tokens"`
+
+	// Strategy selects "adaptive" or "semantic" chunking. Semantic chunking embeds sentences
+	// and splits where adjacent similarity drops below SemanticPercentile.
+	Strategy           string  `json:"strategy"`
+	SemanticPercentile float64 `json:"semantic_percentile"`
+	MinTokens          int     `json:"min_tokens"`
 }
 
 func DefaultProfile() *ProfileConfig {
@@ -52,6 +134,9 @@ func DefaultProfile() *ProfileConfig {
 			VisionModel:    "llama3.2-vision",
 			TimeoutSeconds: 30,
 			ImageMaxSize:   1120,
+
+			EmbeddingMaxTokens: 2048,
+			ChatContextTokens:  4096,
 		},
 		StoragePath: filepath.Join(dataDir, "lilrag.db"),
 		DataDir:     dataDir,
@@ -62,6 +147,33 @@ func DefaultProfile() *ProfileConfig {
 		Chunking: ChunkConfig{
 			MaxTokens: 256, // Optimized for 2025 RAG best practices (128-512 range)
 			Overlap:   38,  // 15% overlap ratio for optimal context preservation
+
+			Hierarchical:    false,
+			ParentMaxTokens: 1024,
+			ChildMaxTokens:  128,
+
+			Strategy:           "adaptive",
+			SemanticPercentile: 10,
+			MinTokens:          64,
+		},
+		Tokenizer: TokenizerConfig{
+			Type: "", // Auto-detected from Path, whitespace without one
+		},
+		PDF: PDFConfig{
+			OCR:            true,
+			OCRMaxPages:    50,
+			OCRConcurrency: 2,
+		},
+		Archive: ArchiveConfig{
+			MaxEntries: 10000,
+			MaxSizeMB:  1024,
+		},
+		Tables: TablesConfig{
+			MaxRows: 50,
+		},
+		Watch: WatchConfig{
+			IntervalSeconds: 2,
+			DebounceSeconds: 1,
 		},
 	}
 }
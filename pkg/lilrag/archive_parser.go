@@ -0,0 +1,342 @@
+package lilrag
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ArchiveLimits guards archive extraction against zip bombs. The uncompressed size is
+// counted from the bytes actually read, not from the sizes the archive claims.
+type ArchiveLimits struct {
+	MaxEntries   int   // Maximum number of file entries
+	MaxTotalSize int64 // Maximum total uncompressed size of all entries in bytes
+}
+
+// DefaultArchiveLimits returns limits suitable for exports of wikis and shared drives
+func DefaultArchiveLimits() ArchiveLimits {
+	return ArchiveLimits{
+		MaxEntries:   10000,
+		MaxTotalSize: 1 << 30,
+	}
+}
+
+// ArchiveEntry is a parsed file of an archive
+type ArchiveEntry struct {
+	ID       string // Document ID derived from the archive ID and the entry path
+	Path     string // Cleaned path of the entry inside the archive
+	DocType  DocumentType
+	Chunks   []Chunk
+	Metadata map[string]string // Document metadata of the entry, including "archive_entry"
+	Tables   []Table           // Sheets of tabular entries
+}
+
+// ArchiveParser handles .zip, .tar and .tar.gz archives. Every supported entry is routed
+// to its parser through the document handler; entries of unknown types, hidden files and
+// nested archives are skipped.
+type ArchiveParser struct {
+	handler *DocumentHandler
+	limits  ArchiveLimits
+}
+
+// NewArchiveParser creates an archive parser that parses entries with handler
+func NewArchiveParser(handler *DocumentHandler, limits ArchiveLimits) *ArchiveParser {
+	return &ArchiveParser{handler: handler, limits: limits}
+}
+
+// ParseEntries parses every supported entry of the archive. Entries that fail to parse
+// or have no content are skipped; limit violations and unsafe paths fail the archive.
+func (ap *ArchiveParser) ParseEntries(filePath, documentID string) ([]ArchiveEntry, error) {
+	var entries []ArchiveEntry
+	usedIDs := make(map[string]bool)
+
+	err := ap.walk(filePath, func(entryPath, tempPath string) error {
+		id := ArchiveEntryID(documentID, entryPath)
+		for n := 2; usedIDs[id]; n++ {
+			id = ArchiveEntryID(documentID, entryPath) + "-" + strconv.Itoa(n)
+		}
+
+		chunks, err := ap.handler.ParseFileWithChunks(tempPath, id)
+		if err != nil || len(chunks) == 0 {
+			return nil
+		}
+		metadata, err := ap.handler.ParseFileMetadata(tempPath)
+		if err != nil || metadata == nil {
+			metadata = make(map[string]string)
+		}
+		metadata["archive_entry"] = entryPath
+		tables, err := ap.handler.ParseFileTables(tempPath)
+		if err != nil {
+			tables = nil
+		}
+
+		usedIDs[id] = true
+		entries = append(entries, ArchiveEntry{
+			ID:       id,
+			Path:     entryPath,
+			DocType:  ap.handler.DetectDocumentType(tempPath),
+			Chunks:   chunks,
+			Metadata: metadata,
+			Tables:   tables,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no supported files found in archive")
+	}
+	return entries, nil
+}
+
+// Parse extracts the text of every supported entry under a heading with its path
+func (ap *ArchiveParser) Parse(filePath string) (string, error) {
+	var content strings.Builder
+	err := ap.walk(filePath, func(entryPath, tempPath string) error {
+		text, err := ap.handler.ParseFile(tempPath)
+		if err != nil || strings.TrimSpace(text) == "" {
+			return nil
+		}
+		content.WriteString("## " + entryPath + "\n\n")
+		content.WriteString(strings.TrimSpace(text))
+		content.WriteString("\n\n")
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if content.Len() == 0 {
+		return "", fmt.Errorf("no supported files found in archive")
+	}
+	return strings.TrimSpace(content.String()), nil
+}
+
+// ParseWithChunks combines the chunks of every entry into one document. The entry path
+// is prepended to each chunk's heading path and stored as "archive_entry" metadata.
+func (ap *ArchiveParser) ParseWithChunks(filePath, documentID string) ([]Chunk, error) {
+	entries, err := ap.ParseEntries(filePath, documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	var chunks []Chunk
+	for _, entry := range entries {
+		for _, chunk := range entry.Chunks {
+			metadata := make(map[string]string, len(chunk.Metadata)+1)
+			for key, value := range chunk.Metadata {
+				metadata[key] = value
+			}
+			metadata["archive_entry"] = entry.Path
+
+			chunk.Index = len(chunks)
+			chunk.Metadata = metadata
+			if chunk.HeadingPath == "" {
+				chunk.HeadingPath = entry.Path
+			} else {
+				chunk.HeadingPath = entry.Path + " > " + chunk.HeadingPath
+			}
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	return chunks, nil
+}
+
+// SupportedExtensions returns the file extensions this parser supports
+func (ap *ArchiveParser) SupportedExtensions() []string {
+	return []string{".zip", ".tar", ".tar.gz", ".tgz"}
+}
+
+// GetDocumentType returns the type of documents this parser handles
+func (ap *ArchiveParser) GetDocumentType() DocumentType {
+	return DocumentTypeArchive
+}
+
+// IsArchiveFile checks if a file is a .zip, .tar or .tar.gz archive by its name
+func IsArchiveFile(filePath string) bool {
+	name := strings.ToLower(filePath)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+var archiveIDReplacer = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// ArchiveEntryID derives the document ID of an archive entry, e.g. "wiki" and
+// "docs/Setup Guide.md" give "wiki-docs-Setup-Guide-md"
+func ArchiveEntryID(archiveID, entryPath string) string {
+	return archiveID + "-" + strings.Trim(archiveIDReplacer.ReplaceAllString(entryPath, "-"), "-")
+}
+
+// walk extracts the supported entries of an archive one at a time to a temporary file
+// that keeps the entry's file name, and calls fn with it
+func (ap *ArchiveParser) walk(filePath string, fn func(entryPath, tempPath string) error) error {
+	tempDir, err := os.MkdirTemp("", "lilrag_archive_*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	entries := 0
+	var totalSize int64
+	extract := func(name string, r io.Reader) error {
+		entryPath, err := cleanArchivePath(name)
+		if err != nil {
+			return err
+		}
+
+		entries++
+		if ap.limits.MaxEntries > 0 && entries > ap.limits.MaxEntries {
+			return fmt.Errorf("archive has more than %d entries", ap.limits.MaxEntries)
+		}
+		if !ap.indexable(entryPath) {
+			return nil
+		}
+
+		entryDir := filepath.Join(tempDir, strconv.Itoa(entries))
+		if err := os.Mkdir(entryDir, 0o700); err != nil {
+			return fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		defer os.RemoveAll(entryDir)
+
+		tempPath := filepath.Join(entryDir, path.Base(entryPath))
+		out, err := os.Create(tempPath)
+		if err != nil {
+			return fmt.Errorf("failed to create temp file: %w", err)
+		}
+
+		if ap.limits.MaxTotalSize > 0 {
+			r = io.LimitReader(r, ap.limits.MaxTotalSize-totalSize+1)
+		}
+		written, err := io.Copy(out, r)
+		out.Close()
+		if err != nil {
+			return fmt.Errorf("failed to extract %s: %w", entryPath, err)
+		}
+		totalSize += written
+		if ap.limits.MaxTotalSize > 0 && totalSize > ap.limits.MaxTotalSize {
+			return fmt.Errorf("archive exceeds the uncompressed size limit of %d bytes", ap.limits.MaxTotalSize)
+		}
+
+		return fn(entryPath, tempPath)
+	}
+
+	// The format is sniffed from the content, so renamed archives are read correctly
+	switch ap.handler.DetectFileType(filePath).MIMEType {
+	case "application/zip":
+		return walkZip(filePath, ap.limits.MaxEntries, extract)
+	case "application/gzip":
+		return walkTar(filePath, true, extract)
+	default:
+		return walkTar(filePath, false, extract)
+	}
+}
+
+// indexable reports whether an entry should be routed to a parser
+func (ap *ArchiveParser) indexable(entryPath string) bool {
+	for _, segment := range strings.Split(entryPath, "/") {
+		if strings.HasPrefix(segment, ".") || segment == "__MACOSX" {
+			return false
+		}
+	}
+	switch ap.handler.DetectDocumentType(entryPath) {
+	case DocumentTypeUnknown, DocumentTypeArchive:
+		return false
+	}
+	return true
+}
+
+// walkZip calls fn for every regular file of a zip archive. The central directory is
+// checked against maxEntries before anything is extracted.
+func walkZip(filePath string, maxEntries int, fn func(name string, r io.Reader) error) error {
+	r, err := zip.OpenReader(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open zip archive: %w", err)
+	}
+	defer r.Close()
+
+	if maxEntries > 0 && len(r.File) > maxEntries {
+		return fmt.Errorf("archive has more than %d entries", maxEntries)
+	}
+
+	for _, f := range r.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open %s in zip archive: %w", f.Name, err)
+		}
+		err = fn(f.Name, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkTar calls fn for every regular file of a tar archive, which may be gzip compressed
+func walkTar(filePath string, gzipped bool, fn func(name string, r io.Reader) error) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open tar archive: %w", err)
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if gzipped {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := fn(header.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// cleanArchivePath normalizes an entry name and rejects absolute paths and paths that
+// escape the archive root
+func cleanArchivePath(name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(name, "/") || (len(name) > 1 && name[1] == ':') {
+		return "", fmt.Errorf("archive entry %q has an absolute path", name)
+	}
+
+	cleaned := path.Clean(name)
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("archive entry %q escapes the archive root", name)
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,180 @@
+package lilrag
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testArchiveFiles = map[string]string{
+	"docs/Setup Guide.md":   "# Setup\n\nRun make install to build the binaries.",
+	"notes.txt":             "Remember to rotate the API keys every quarter.",
+	"data/blob.bin":         "\x00\x01\x02",
+	".DS_Store":             "junk",
+	"__MACOSX/docs/._x.md":  "junk",
+	"nested/backup.zip":     "not really a zip",
+	"docs/../docs/faq.json": `{"question": "Where are the logs?", "answer": "In /var/log/lilrag"}`,
+}
+
+func archiveEntriesByPath(entries []ArchiveEntry) map[string]ArchiveEntry {
+	byPath := make(map[string]ArchiveEntry, len(entries))
+	for _, entry := range entries {
+		byPath[entry.Path] = entry
+	}
+	return byPath
+}
+
+func TestArchiveParser_Zip(t *testing.T) {
+	filePath := writeTestZip(t, "wiki.zip", testArchiveFiles)
+	parser := NewArchiveParser(NewDocumentHandler(NewTextChunker(256, 38)), DefaultArchiveLimits())
+
+	entries, err := parser.ParseEntries(filePath, "wiki")
+	if err != nil {
+		t.Fatalf("Failed to parse archive: %v", err)
+	}
+
+	// Hidden files, unknown types and nested archives are skipped
+	byPath := archiveEntriesByPath(entries)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d: %v", len(entries), byPath)
+	}
+	setup, ok := byPath["docs/Setup Guide.md"]
+	if !ok || setup.ID != "wiki-docs-Setup-Guide-md" || setup.DocType != DocumentTypeMarkdown ||
+		setup.Metadata["archive_entry"] != "docs/Setup Guide.md" {
+		t.Errorf("Unexpected markdown entry %+v", setup)
+	}
+	if faq, ok := byPath["docs/faq.json"]; !ok || faq.DocType != DocumentTypeJSON || faq.ID != "wiki-docs-faq-json" {
+		t.Errorf("Expected the cleaned JSON entry, got %+v", faq)
+	}
+
+	// Combined mode prefixes heading paths with the entry path
+	chunks, err := parser.ParseWithChunks(filePath, "wiki")
+	if err != nil {
+		t.Fatalf("Failed to parse archive chunks: %v", err)
+	}
+	for i, chunk := range chunks {
+		if chunk.Index != i || !strings.HasPrefix(chunk.HeadingPath, chunk.Metadata["archive_entry"]) {
+			t.Errorf("Unexpected combined chunk %d: %+v", i, chunk)
+		}
+	}
+}
+
+func TestArchiveParser_TarGz(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "drive.tar.gz")
+	f, err := os.Create(filePath)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: "reports/", Typeflag: tar.TypeDir, Mode: 0o755}); err != nil {
+		t.Fatalf("Failed to write directory: %v", err)
+	}
+	content := "Quarterly revenue grew by 12 percent."
+	if err := tw.WriteHeader(&tar.Header{Name: "reports/q3.txt", Mode: 0o644, Size: int64(len(content))}); err != nil {
+		t.Fatalf("Failed to write header: %v", err)
+	}
+	if _, err := tw.Write([]byte(content)); err != nil {
+		t.Fatalf("Failed to write entry: %v", err)
+	}
+	tw.Close()
+	gz.Close()
+	f.Close()
+
+	dh := NewDocumentHandler(NewTextChunker(256, 38))
+	if dh.DetectDocumentType(filePath) != DocumentTypeArchive {
+		t.Fatalf("Expected %s to be detected as an archive", filePath)
+	}
+	entries, err := dh.ParseArchive(filePath, "drive")
+	if err != nil {
+		t.Fatalf("Failed to parse archive: %v", err)
+	}
+	if len(entries) != 1 || entries[0].ID != "drive-reports-q3-txt" || entries[0].Chunks[0].Text != content {
+		t.Errorf("Unexpected entries %+v", entries)
+	}
+}
+
+func TestArchiveParser_Guards(t *testing.T) {
+	dh := NewDocumentHandler(NewTextChunker(256, 38))
+
+	for _, name := range []string{"../escape.txt", "docs/../../escape.txt", "/etc/passwd.txt", `..\escape.txt`} {
+		filePath := writeTestZip(t, "evil.zip", map[string]string{name: "owned"})
+		if _, err := dh.ParseArchive(filePath, "evil"); err == nil {
+			t.Errorf("Expected %q to be rejected", name)
+		}
+	}
+
+	filePath := writeTestZip(t, "wiki.zip", testArchiveFiles)
+	dh.SetArchiveLimits(ArchiveLimits{MaxEntries: 3})
+	if _, err := dh.ParseArchive(filePath, "wiki"); err == nil || !strings.Contains(err.Error(), "entries") {
+		t.Errorf("Expected the entry limit to be enforced, got %v", err)
+	}
+
+	bomb := writeTestZip(t, "bomb.zip", map[string]string{"big.txt": strings.Repeat("a", 1<<20)})
+	dh.SetArchiveLimits(ArchiveLimits{MaxEntries: 10, MaxTotalSize: 1 << 10})
+	if _, err := dh.ParseArchive(bomb, "bomb"); err == nil || !strings.Contains(err.Error(), "size limit") {
+		t.Errorf("Expected the size limit to be enforced, got %v", err)
+	}
+}
+
+func TestLilRag_IndexArchive(t *testing.T) {
+	storage := NewMockStorage()
+	lilRag := &LilRag{
+		storage:         storage,
+		embedder:        NewMockEmbedder(),
+		chunker:         NewTextChunker(256, 38),
+		documentHandler: NewDocumentHandler(NewTextChunker(256, 38)),
+		config:          &Config{},
+	}
+	if err := storage.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize mock storage: %v", err)
+	}
+
+	filePath := writeTestZip(t, "wiki.zip", testArchiveFiles)
+	if err := lilRag.IndexFile(context.Background(), filePath, "wiki"); err != nil {
+		t.Fatalf("Failed to index archive: %v", err)
+	}
+	for _, id := range []string{"wiki-docs-Setup-Guide-md", "wiki-notes-txt", "wiki-docs-faq-json"} {
+		if _, ok := storage.documents[id]; !ok {
+			t.Errorf("Expected entry document %s to be stored", id)
+		}
+	}
+	if _, ok := storage.documents["wiki"]; ok {
+		t.Error("Expected no combined document by default")
+	}
+
+	// Entries dropped from a new version of the archive are deleted, other documents are kept
+	if err := lilRag.Index(context.Background(), "Unrelated note.", "wiki-notes"); err != nil {
+		t.Fatalf("Failed to index unrelated document: %v", err)
+	}
+	smaller := make(map[string]string, len(testArchiveFiles))
+	for name, content := range testArchiveFiles {
+		if name != "notes.txt" {
+			smaller[name] = content
+		}
+	}
+	if err := lilRag.IndexFile(context.Background(), writeTestZip(t, "wiki.zip", smaller), "wiki"); err != nil {
+		t.Fatalf("Failed to re-index archive: %v", err)
+	}
+	if _, ok := storage.documents["wiki-notes-txt"]; ok {
+		t.Error("Expected the removed entry's document to be deleted")
+	}
+	for _, id := range []string{"wiki-docs-Setup-Guide-md", "wiki-docs-faq-json", "wiki-notes"} {
+		if _, ok := storage.documents[id]; !ok {
+			t.Errorf("Expected document %s to be kept", id)
+		}
+	}
+
+	lilRag.config.ArchiveCombined = true
+	if err := lilRag.IndexFile(context.Background(), filePath, "wiki"); err != nil {
+		t.Fatalf("Failed to index combined archive: %v", err)
+	}
+	if text := storage.documents["wiki"]; !strings.Contains(text, "rotate the API keys") ||
+		!strings.Contains(text, "make install") {
+		t.Errorf("Expected the combined document to contain every entry, got %q", text)
+	}
+}
@@ -11,15 +11,21 @@ import (
 	"time"
 
 	"lil-rag/pkg/metrics"
+	"lil-rag/pkg/tokenizer"
 )
 
 // OllamaChatClient handles chat interactions with Ollama
 type OllamaChatClient struct {
-	baseURL string
-	model   string
-	client  *http.Client
+	baseURL       string
+	model         string
+	client        *http.Client
+	tokenizer     tokenizer.Tokenizer
+	contextTokens int
 }
 
+// DefaultChatContextTokens is the token budget for retrieved document context in chat prompts
+const DefaultChatContextTokens = 4096
+
 // NewOllamaChatClient creates a new Ollama chat client
 func NewOllamaChatClient(baseURL, model string) *OllamaChatClient {
 	return NewOllamaChatClientWithTimeout(baseURL, model, 120)
@@ -40,9 +46,16 @@ func NewOllamaChatClientWithTimeout(baseURL, model string, timeoutSeconds int) *
 		client: &http.Client{
 			Timeout: time.Duration(timeoutSeconds) * time.Second,
 		},
+		contextTokens: DefaultChatContextTokens,
 	}
 }
 
+// SetContextBudget configures the tokenizer and token budget for document context in chat prompts
+func (c *OllamaChatClient) SetContextBudget(tok tokenizer.Tokenizer, contextTokens int) {
+	c.tokenizer = tok
+	c.contextTokens = contextTokens
+}
+
 // ChatRequest represents a request to Ollama's chat API
 type ChatRequest struct {
 	Model    string        `json:"model"`
@@ -165,14 +178,36 @@ func (c *OllamaChatClient) createSystemPrompt(searchResults []SearchResult) stri
 
 	prompt.WriteString("RELEVANT DOCUMENTS:\n\n")
 
+	tok := c.tokenizer
+	if tok == nil {
+		tok = tokenizer.Default()
+	}
+	remainingTokens := c.contextTokens
+	hasTableResults := false
+
 	for i, result := range searchResults {
 		prompt.WriteString(fmt.Sprintf("Document %d (ID: %s, Relevance: %.1f%%):\n",
 			i+1, result.ID, result.Score*100))
+		if headingPath, ok := result.Metadata["heading_path"].(string); ok && headingPath != "" {
+			prompt.WriteString(fmt.Sprintf("Section: %s\n", headingPath))
+		}
+		if query, ok := result.Metadata["table_query"].(string); ok {
+			prompt.WriteString(fmt.Sprintf("SQL result from sheet %v: %s\n", result.Metadata["sheet"], query))
+			hasTableResults = true
+		}
 
-		// Use a reasonable excerpt length
+		// Share the remaining token budget among the remaining documents so that
+		// budget unused by short documents carries over to later ones
 		text := result.Text
-		if len(text) > 2000 {
-			text = text[:2000] + "..."
+		if c.contextTokens > 0 {
+			excerptTokens := remainingTokens / (len(searchResults) - i)
+			if excerptTokens < 1 {
+				excerptTokens = 1
+			}
+			if truncated, ok := tokenizer.Truncate(tok, text, excerptTokens); ok {
+				text = truncated + "..."
+			}
+			remainingTokens -= tok.CountTokens(text)
 		}
 
 		prompt.WriteString(text)
@@ -185,10 +220,142 @@ func (c *OllamaChatClient) createSystemPrompt(searchResults []SearchResult) stri
 		"brackets with the document ID: [document-id]. ")
 	prompt.WriteString("For example: \"According to [lilrag-overview]...\" or \"As mentioned in [vector-search]...\". ")
 	prompt.WriteString("Use only the document ID inside the brackets, not \"Document 1\" or similar.")
+	if hasTableResults {
+		prompt.WriteString(" SQL results are computed from the full sheet, so prefer them for totals, counts " +
+			"and filters, and cite them with the document ID followed by the sheet, for example: " +
+			"\"[budget-2024] (sheet Q3)\".")
+	}
+
+	return prompt.String()
+}
+
+// GenerateTableQuery asks the model for a SQLite SELECT statement over the given sheet
+// tables that answers the user's question. samples holds a few rows of each table by
+// name. The query is empty when the model finds the tables cannot answer the question.
+func (c *OllamaChatClient) GenerateTableQuery(ctx context.Context, userMessage string,
+	tables []TableInfo, samples map[string]*TableQueryResult) (string, error) {
+	systemPrompt := createTableQueryPrompt(tables, samples)
+
+	metrics.RecordChatInputTokens(c.model, systemPrompt)
+	metrics.RecordChatInputTokens(c.model, userMessage)
+
+	messages := []ChatMessage{
+		{
+			Role:    "system",
+			Content: systemPrompt,
+		},
+		{
+			Role:    "user",
+			Content: userMessage,
+		},
+	}
+
+	// Use a low temperature so the query sticks to the listed tables and columns
+	reply, err := c.complete(ctx, messages, &ChatOptions{Temperature: 0.1, TopP: 0.9})
+	if err != nil {
+		return "", fmt.Errorf("failed to generate table query: %w", err)
+	}
+
+	metrics.RecordChatOutputTokens(c.model, reply)
+
+	return extractTableQuery(reply), nil
+}
+
+// createTableQueryPrompt describes the sheet tables for GenerateTableQuery
+func createTableQueryPrompt(tables []TableInfo, samples map[string]*TableQueryResult) string {
+	var prompt strings.Builder
+
+	prompt.WriteString("You translate questions about spreadsheets into SQLite queries.\n\nTABLES:\n\n")
+
+	for _, table := range tables {
+		prompt.WriteString(fmt.Sprintf("Table %s (sheet %q of document [%s], %d rows)\nColumns:\n",
+			table.Name, table.Sheet, table.DocumentID, table.RowCount))
+		prompt.WriteString("- _row INTEGER: row number in the sheet\n")
+		for _, column := range table.Columns {
+			prompt.WriteString(fmt.Sprintf("- %s %s: %q\n", column.Name, column.Type, column.Header))
+		}
+		if sample := samples[table.Name]; sample != nil && len(sample.Rows) > 0 {
+			prompt.WriteString("Sample rows:\n")
+			prompt.WriteString(sample.Markdown())
+			prompt.WriteString("\n")
+		}
+		prompt.WriteString("\n")
+	}
+
+	prompt.WriteString("Write a single SQLite SELECT statement that answers the user's question using only " +
+		"these tables and the exact table and column names above. Use SUM, COUNT, AVG, MIN or MAX when the " +
+		"question asks for totals, counts or extremes, and filter text case-insensitively with LIKE.\n")
+	prompt.WriteString("Respond with ONLY the SQL statement, no explanations or code fences. " +
+		"If the tables cannot answer the question, respond with NONE.")
 
 	return prompt.String()
 }
 
+// extractTableQuery takes the SQL statement out of a model reply, which may wrap it in
+// a code fence. NONE gives an empty query.
+func extractTableQuery(reply string) string {
+	reply = strings.TrimSpace(reply)
+	if start := strings.Index(reply, "```"); start >= 0 {
+		block := reply[start+3:]
+		if end := strings.Index(block, "```"); end >= 0 {
+			block = block[:end]
+		}
+		if newline := strings.IndexByte(block, '\n'); newline >= 0 {
+			switch strings.ToLower(strings.TrimSpace(block[:newline])) {
+			case "sql", "sqlite":
+				block = block[newline+1:]
+			}
+		}
+		reply = strings.TrimSpace(block)
+	}
+
+	if strings.EqualFold(strings.TrimSuffix(reply, "."), "NONE") {
+		return ""
+	}
+	return reply
+}
+
+// complete sends a non-streaming chat request and returns the model's reply
+func (c *OllamaChatClient) complete(ctx context.Context, messages []ChatMessage, options *ChatOptions) (string, error) {
+	jsonData, err := json.Marshal(ChatRequest{
+		Model:    c.model,
+		Messages: messages,
+		Stream:   false,
+		Options:  options,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal chat request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/api/chat", c.baseURL)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send chat request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return "", fmt.Errorf("ollama server returned status %d", resp.StatusCode)
+		}
+		return "", fmt.Errorf("chat request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var chatResp ChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return "", fmt.Errorf("failed to decode chat response: %w", err)
+	}
+
+	return chatResp.Message.Content, nil
+}
+
 // TestConnection tests if the Ollama server is reachable and the model is available
 func (c *OllamaChatClient) TestConnection(ctx context.Context) error {
 	// Check if the server is reachable
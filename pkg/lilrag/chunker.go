@@ -1,11 +1,15 @@
 package lilrag
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"math/rand"
 	"regexp"
 	"strings"
 	"time"
+
+	"lil-rag/pkg/tokenizer"
 )
 
 // Content type constants
@@ -16,19 +20,62 @@ const (
 )
 
 type TextChunker struct {
-	MaxTokens  int
-	Overlap    int
-	TokenRegex *regexp.Regexp
+	MaxTokens      int
+	Overlap        int
+	ChildMaxTokens int // When > 0, chunks are split into embedded children linked to their parent
+	TokenRegex     *regexp.Regexp
+	Tokenizer      tokenizer.Tokenizer // Counts tokens; falls back to TokenRegex when nil
+
+	// Semantic chunking (Strategy == ChunkStrategySemantic) splits at topic shifts found by
+	// comparing sentence embeddings; it falls back to adaptive chunking without an Embedder
+	Strategy           string
+	Embedder           Embedder
+	SemanticPercentile float64 // Adjacent-similarity percentile below which a boundary is placed
+	MinTokens          int     // Minimum chunk size before a topic shift may end a chunk
 }
 
 type Chunk struct {
-	Text       string
-	Index      int
-	StartPos   int
-	EndPos     int
-	TokenCount int
-	PageNumber *int   // Optional page number for PDF chunks
-	ChunkType  string // Type of chunk: "text", "pdf_page"
+	Text          string
+	Index         int
+	StartPos      int
+	EndPos        int
+	TokenCount    int
+	PageNumber    *int   // Optional page number for PDF chunks
+	EndPageNumber *int   // Last page for chunks that span several pages
+	ChunkType     string // Type of chunk: "text", "pdf_page"
+	ParentIndex   *int   // Index of the parent chunk for hierarchical (child) chunks
+	IsParent      bool   // Parent chunks are stored for context but not embedded
+	HeadingPath   string // Section breadcrumb for structured documents, e.g. "Install > Linux"
+	Language      string // Programming language for source code chunks
+	SymbolName    string // Declared symbol for source code chunks, e.g. "Client.Upload"
+	SymbolKind    string // Kind of symbol: "function", "method", "type", "class", ...
+	StartLine     int    // First source line (1-based) for source code chunks
+	EndLine       int    // Last source line for source code chunks
+
+	// Metadata holds format-specific attributes, e.g. the chapter title of an EPUB chunk.
+	// It is stored with the chunk and returned in search result metadata.
+	Metadata map[string]string
+}
+
+// PageLabel returns the page or page range of the chunk, e.g. "3" or "3-5", or ""
+// for chunks without page information
+func (c Chunk) PageLabel() string {
+	if c.PageNumber == nil {
+		return ""
+	}
+	if c.EndPageNumber != nil && *c.EndPageNumber != *c.PageNumber {
+		return fmt.Sprintf("%d-%d", *c.PageNumber, *c.EndPageNumber)
+	}
+	return fmt.Sprintf("%d", *c.PageNumber)
+}
+
+// EmbeddingText returns the text used to create the chunk's embedding. The heading
+// path is prepended so sections keep their context when embedded on their own.
+func (c Chunk) EmbeddingText() string {
+	if c.HeadingPath == "" {
+		return c.Text
+	}
+	return c.HeadingPath + "\n\n" + c.Text
 }
 
 func NewTextChunker(maxTokens, overlap int) *TextChunker {
@@ -39,14 +86,38 @@ func NewTextChunker(maxTokens, overlap int) *TextChunker {
 		MaxTokens:  maxTokens,
 		Overlap:    overlap,
 		TokenRegex: tokenRegex,
+		Tokenizer:  tokenizer.Default(),
 	}
 }
 
+// NewHierarchicalTextChunker creates a chunker that produces parent chunks of up to
+// parentTokens, each split into child chunks of up to childTokens for embedding
+func NewHierarchicalTextChunker(parentTokens, childTokens, overlap int) *TextChunker {
+	tc := NewTextChunker(parentTokens, overlap)
+	tc.ChildMaxTokens = childTokens
+	return tc
+}
+
+// IsHierarchical reports whether the chunker links small child chunks to larger parents
+func (tc *TextChunker) IsHierarchical() bool {
+	return tc.ChildMaxTokens > 0 && tc.ChildMaxTokens < tc.MaxTokens
+}
+
+// EstimateTokenCount counts tokens with the chunker's tokenizer
 func (tc *TextChunker) EstimateTokenCount(text string) int {
+	if tc.Tokenizer != nil {
+		return tc.Tokenizer.CountTokens(text)
+	}
 	return len(tc.TokenRegex.FindAllString(text, -1))
 }
 
+// ChunkText chunks text without a deadline; see ChunkTextContext
 func (tc *TextChunker) ChunkText(text string) []Chunk {
+	return tc.ChunkTextContext(context.Background(), text)
+}
+
+// ChunkTextContext chunks text, passing ctx to the embedder used by semantic chunking
+func (tc *TextChunker) ChunkTextContext(ctx context.Context, text string) []Chunk {
 	text = strings.TrimSpace(text)
 	if text == "" {
 		return nil
@@ -69,6 +140,15 @@ func (tc *TextChunker) ChunkText(text string) []Chunk {
 		}
 	}
 
+	// Use embedding-driven topic boundaries when semantic chunking is selected
+	if tc.Strategy == ChunkStrategySemantic && tc.Embedder != nil {
+		chunks, err := tc.ChunkTextSemantic(ctx, text)
+		if err == nil {
+			return chunks
+		}
+		log.Printf("Warning: semantic chunking failed, using adaptive chunking: %v", err)
+	}
+
 	// Apply semantic chunking for ALL documents to get optimal boundaries
 	// Even small documents benefit from content-type aware processing
 	semanticChunks := tc.adaptiveChunk(text, contentType)
@@ -516,16 +596,25 @@ func (tc *TextChunker) findStartPosition(text, sentence string) int {
 
 func (tc *TextChunker) splitLongChunkByWords(chunk Chunk) []Chunk {
 	words := strings.Fields(chunk.Text)
-	if len(words) <= tc.MaxTokens {
+	wordTokens := make([]int, len(words))
+	totalTokens := 0
+	for i, word := range words {
+		wordTokens[i] = tc.EstimateTokenCount(word)
+		totalTokens += wordTokens[i]
+	}
+	if totalTokens <= tc.MaxTokens {
 		return []Chunk{chunk}
 	}
 
 	var chunks []Chunk
 
-	for i := 0; i < len(words); i += tc.MaxTokens - tc.Overlap {
-		end := i + tc.MaxTokens
-		if end > len(words) {
-			end = len(words)
+	for i := 0; i < len(words); {
+		// Take as many words as fit within MaxTokens, always at least one
+		end := i
+		tokens := 0
+		for end < len(words) && (end == i || tokens+wordTokens[end] <= tc.MaxTokens) {
+			tokens += wordTokens[end]
+			end++
 		}
 
 		chunkWords := words[i:end]
@@ -536,23 +625,97 @@ func (tc *TextChunker) splitLongChunkByWords(chunk Chunk) []Chunk {
 			Index:      0,                  // Will be re-indexed by the caller
 			StartPos:   chunk.StartPos + i, // Approximate start position
 			EndPos:     chunk.StartPos + i + len(chunkText),
-			TokenCount: len(chunkWords),
+			TokenCount: tokens,
 		})
 
 		// Break if we've covered all words
 		if end >= len(words) {
 			break
 		}
+
+		// Step back over up to Overlap tokens of trailing words for the next chunk
+		next := end
+		overlapTokens := 0
+		for next > i+1 && overlapTokens+wordTokens[next-1] <= tc.Overlap {
+			overlapTokens += wordTokens[next-1]
+			next--
+		}
+		i = next
 	}
 
 	return chunks
 }
 
+// ChunkTextHierarchical chunks text into parent sections and links child chunks to them
+func (tc *TextChunker) ChunkTextHierarchical(text string) []Chunk {
+	return tc.BuildHierarchy(tc.ChunkText(text))
+}
+
+// BuildHierarchy treats the given chunks as parents and splits each one into child chunks.
+// Parents come first, followed by their children; parents that already fit within the
+// child size are kept as regular embedded chunks. Returns the chunks unchanged if the
+// chunker is not hierarchical.
+func (tc *TextChunker) BuildHierarchy(parents []Chunk) []Chunk {
+	if !tc.IsHierarchical() {
+		return parents
+	}
+
+	childOverlap := tc.ChildMaxTokens / 8
+	childChunker := NewTextChunker(tc.ChildMaxTokens, childOverlap)
+	childChunker.Tokenizer = tc.Tokenizer
+
+	var result []Chunk
+	for _, parent := range parents {
+		if parent.TokenCount <= tc.ChildMaxTokens {
+			parent.Index = len(result)
+			result = append(result, parent)
+			continue
+		}
+
+		children := childChunker.ChunkText(parent.Text)
+		if len(children) <= 1 {
+			parent.Index = len(result)
+			result = append(result, parent)
+			continue
+		}
+
+		parentIndex := len(result)
+		parent.Index = parentIndex
+		parent.IsParent = true
+		result = append(result, parent)
+
+		for _, child := range children {
+			child.Index = len(result)
+			child.ParentIndex = &parentIndex
+			child.StartPos += parent.StartPos
+			child.EndPos += parent.StartPos
+			child.PageNumber = parent.PageNumber
+			child.EndPageNumber = parent.EndPageNumber
+			child.HeadingPath = parent.HeadingPath
+			child.Language = parent.Language
+			child.SymbolName = parent.SymbolName
+			child.SymbolKind = parent.SymbolKind
+			child.StartLine = parent.StartLine
+			child.EndLine = parent.EndLine
+			child.Metadata = parent.Metadata
+			child.ChunkType = parent.ChunkType
+			result = append(result, child)
+		}
+	}
+
+	return result
+}
+
 // IsLongText checks if text needs chunking
 func (tc *TextChunker) IsLongText(text string) bool {
 	return tc.EstimateTokenCount(text) > tc.MaxTokens
 }
 
+// NeedsChildChunks reports whether a hierarchical chunker would split text into children
+func (tc *TextChunker) NeedsChildChunks(text string) bool {
+	return tc.IsHierarchical() && tc.EstimateTokenCount(text) > tc.ChildMaxTokens
+}
+
 // GetChunkID generates a unique ID for a chunk
 func GetChunkID(documentID string, chunkIndex int) string {
 	if chunkIndex == 0 {
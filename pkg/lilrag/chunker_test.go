@@ -611,3 +611,79 @@ func TestTextChunker_RealWorldExample(t *testing.T) {
 		t.Errorf("Lost words during chunking: original %d, chunks %d", len(originalWords), len(chunkWords))
 	}
 }
+
+func TestTextChunker_BuildHierarchy(t *testing.T) {
+	chunker := NewHierarchicalTextChunker(40, 10, 4)
+	if !chunker.IsHierarchical() {
+		t.Fatal("Expected chunker to be hierarchical")
+	}
+
+	text := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 12)
+	chunks := chunker.ChunkTextHierarchical(text)
+	if len(chunks) == 0 {
+		t.Fatal("Expected chunks, got 0")
+	}
+
+	parents := 0
+	for i, chunk := range chunks {
+		if chunk.Index != i {
+			t.Errorf("Expected chunk %d to have index %d, got %d", i, i, chunk.Index)
+		}
+		if chunk.IsParent {
+			parents++
+			if chunk.ParentIndex != nil {
+				t.Errorf("Parent chunk %d should not have a parent", i)
+			}
+			continue
+		}
+		if chunk.ParentIndex == nil {
+			continue
+		}
+		parent := chunks[*chunk.ParentIndex]
+		if !parent.IsParent {
+			t.Errorf("Chunk %d points at non-parent chunk %d", i, *chunk.ParentIndex)
+		}
+		if *chunk.ParentIndex >= i {
+			t.Errorf("Child chunk %d should come after its parent %d", i, *chunk.ParentIndex)
+		}
+		if !strings.Contains(parent.Text, strings.TrimSpace(chunk.Text)) {
+			t.Errorf("Child chunk %d text is not contained in its parent", i)
+		}
+	}
+
+	if parents == 0 {
+		t.Error("Expected at least one parent chunk")
+	}
+}
+
+func TestTextChunker_BuildHierarchy_SmallParent(t *testing.T) {
+	chunker := NewHierarchicalTextChunker(40, 10, 4)
+
+	chunks := chunker.BuildHierarchy([]Chunk{{Text: "Short text.", TokenCount: 2, Index: 0}})
+	if len(chunks) != 1 {
+		t.Fatalf("Expected 1 chunk, got %d", len(chunks))
+	}
+	if chunks[0].IsParent || chunks[0].ParentIndex != nil {
+		t.Error("Expected a small chunk to be kept as a regular chunk")
+	}
+
+	typed := chunker.BuildHierarchy([]Chunk{{
+		Text:       strings.Repeat("The quick brown fox jumps over the lazy dog. ", 12),
+		TokenCount: 108,
+		ChunkType:  "pdf_ocr",
+	}})
+	if len(typed) < 2 {
+		t.Fatalf("Expected a parent and children, got %d chunks", len(typed))
+	}
+	for i, chunk := range typed {
+		if chunk.ChunkType != "pdf_ocr" {
+			t.Errorf("Expected chunk %d to keep chunk type pdf_ocr, got %q", i, chunk.ChunkType)
+		}
+	}
+
+	flat := NewTextChunker(40, 4)
+	input := []Chunk{{Text: strings.Repeat("word ", 30), TokenCount: 30}}
+	if got := flat.BuildHierarchy(input); !reflect.DeepEqual(got, input) {
+		t.Error("Expected non-hierarchical chunker to return chunks unchanged")
+	}
+}
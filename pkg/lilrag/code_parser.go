@@ -0,0 +1,834 @@
+package lilrag
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Symbol kinds recorded on source code chunks
+const (
+	SymbolKindPackage  = "package"
+	SymbolKindFunction = "function"
+	SymbolKindMethod   = "method"
+	SymbolKindType     = "type"
+	SymbolKindClass    = "class"
+	SymbolKindConst    = "const"
+	SymbolKindVar      = "var"
+)
+
+// codeLanguages maps source file extensions to language names
+var codeLanguages = map[string]string{
+	".go":    "go",
+	".py":    "python",
+	".pyw":   "python",
+	".rb":    "ruby",
+	".js":    "javascript",
+	".jsx":   "javascript",
+	".mjs":   "javascript",
+	".cjs":   "javascript",
+	".ts":    "typescript",
+	".tsx":   "typescript",
+	".mts":   "typescript",
+	".cts":   "typescript",
+	".java":  "java",
+	".kt":    "kotlin",
+	".kts":   "kotlin",
+	".scala": "scala",
+	".swift": "swift",
+	".c":     "c",
+	".h":     "c",
+	".cc":    "cpp",
+	".cpp":   "cpp",
+	".cxx":   "cpp",
+	".hh":    "cpp",
+	".hpp":   "cpp",
+	".hxx":   "cpp",
+	".cs":    "csharp",
+	".rs":    "rust",
+	".php":   "php",
+}
+
+// containerKinds are symbol kinds whose bodies are split into member chunks when too large
+var containerKinds = map[string]bool{
+	"class": true, "interface": true, "struct": true, "enum": true, "trait": true, "object": true,
+	"record": true, "impl": true, "protocol": true, "extension": true, "namespace": true,
+	"module": true, "union": true,
+}
+
+// Symbol patterns for brace-delimited languages, tried in order against a declaration signature
+var (
+	containerPattern = regexp.MustCompile(`^(?:(?:export|default|public|private|protected|internal|abstract|static|` +
+		`final|sealed|partial|data|open|inner|declare|unsafe|pub(?:\([^)]*\))?)\s+)*` +
+		`(class|interface|struct|enum|trait|object|record|impl|protocol|extension|namespace|module|union)\b` +
+		`\s*(?:<[^>]*>\s*)?([A-Za-z_][\w:.]*)`)
+	goMethodPattern    = regexp.MustCompile(`^func\s+\([^)]*?\*?\s*([A-Za-z_]\w*)(?:\[[^\]]*\])?\)\s*([A-Za-z_]\w*)`)
+	goTypePattern      = regexp.MustCompile(`^type\s+([A-Za-z_]\w*)`)
+	keywordFuncPattern = regexp.MustCompile(`^(?:(?:export|default|public|private|protected|internal|static|final|` +
+		`abstract|override|open|async|suspend|inline|operator|infix|tailrec|unsafe|const|mutating|class|` +
+		`extern(?:\s+"[^"]*")?|pub(?:\([^)]*\))?)\s+)*(?:function\s*\*?|fn|fun|func|def)\s+` +
+		`(?:<[^>]*>\s*)?(?:[\w.]+\.)?([A-Za-z_$][\w$]*)`)
+	arrowFuncPattern = regexp.MustCompile(`^(?:export\s+)?(?:const|let|var)\s+([A-Za-z_$][\w$]*)\s*(?::[^=]+)?=\s*` +
+		`(?:async\s+)?(?:function\b|\([^)]*\)\s*(?::[^=]+)?=>|[A-Za-z_$][\w$]*\s*=>)`)
+	memberFuncPattern = regexp.MustCompile(`^(?:(?:public|private|protected|internal|static|async|readonly|abstract|` +
+		`override|virtual|final|get|set)\s+)*\*?([A-Za-z_$][\w$]*)\s*(?:<[^>]*>)?\s*\(`)
+	typedFuncPattern = regexp.MustCompile(`^(?:[\w$<>\[\],.*&:~?]+\s+)+[*&]*([A-Za-z_~$][\w$]*)\s*\(`)
+)
+
+// Symbol patterns for indentation-delimited languages
+var (
+	pythonFuncPattern  = regexp.MustCompile(`^(?:async\s+)?def\s+([A-Za-z_]\w*)`)
+	pythonClassPattern = regexp.MustCompile(`^class\s+([A-Za-z_]\w*)`)
+	rubyFuncPattern    = regexp.MustCompile(`^def\s+(?:self\.)?([A-Za-z_]\w*[?!=]?)`)
+	rubyClassPattern   = regexp.MustCompile(`^(class|module)\s+([A-Z][\w:]*)`)
+)
+
+// controlKeywords are never symbol names, e.g. "if (x) {" is not a function
+var controlKeywords = map[string]bool{
+	"if": true, "for": true, "foreach": true, "while": true, "switch": true, "catch": true, "return": true,
+	"else": true, "do": true, "new": true, "function": true, "sizeof": true, "typeof": true, "using": true,
+	"lock": true, "synchronized": true, "try": true, "match": true, "when": true, "guard": true,
+}
+
+// CodeParser handles source code files, chunking them along declarations such as
+// functions, methods and types. Go files are parsed with go/parser; other languages
+// are split with brace- or indentation-aware rules.
+type CodeParser struct {
+	chunker *TextChunker
+}
+
+// codeSegment is a contiguous range of source lines holding one declaration
+type codeSegment struct {
+	start   int // First line (0-based)
+	end     int // Line after the last line
+	body    int // First line of a container's body, or -1 if it cannot be split into members
+	bodyEnd int // Line after the last body line
+	name    string
+	kind    string
+}
+
+// NewCodeParser creates a new source code parser
+func NewCodeParser() *CodeParser {
+	return &CodeParser{}
+}
+
+// NewCodeParserWithChunker creates a source code parser that sizes chunks with the given chunker
+func NewCodeParserWithChunker(chunker *TextChunker) *CodeParser {
+	return &CodeParser{chunker: chunker}
+}
+
+// CodeLanguage returns the programming language of a source file, or "" if the
+// extension is not a recognized source code extension
+func CodeLanguage(filePath string) string {
+	return codeLanguages[strings.ToLower(filepath.Ext(filePath))]
+}
+
+// Parse extracts text content from a source code file
+func (cp *CodeParser) Parse(filePath string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read source file: %w", err)
+	}
+	return string(content), nil
+}
+
+// ParseWithChunks splits a source file into one chunk per declaration. Each chunk
+// records its language, symbol name and kind, and line range.
+func (cp *CodeParser) ParseWithChunks(filePath, _ string) ([]Chunk, error) {
+	content, err := cp.Parse(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return cp.ChunkCode(content, filepath.Base(filePath), CodeLanguage(filePath)), nil
+}
+
+// ChunkCode chunks source code along its declarations. fileName is prepended to each
+// chunk's heading path so it becomes part of the embedded text.
+func (cp *CodeParser) ChunkCode(content, fileName, language string) []Chunk {
+	// Use a default chunker if none provided
+	if cp.chunker == nil {
+		cp.chunker = NewTextChunker(256, 38) // Use optimized defaults
+	}
+
+	if strings.TrimSpace(content) == "" {
+		return nil
+	}
+
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	lines := strings.Split(content, "\n")
+
+	var segments []codeSegment
+	for _, segment := range cp.topLevelSegments(content, lines, language) {
+		segments = append(segments, cp.expand(lines, segment, language)...)
+	}
+	segments = cp.mergeSegments(lines, segments)
+
+	offsets := make([]int, len(lines))
+	for i := 1; i < len(lines); i++ {
+		offsets[i] = offsets[i-1] + len(lines[i-1]) + 1
+	}
+
+	var chunks []Chunk
+	for _, segment := range segments {
+		text := strings.Join(lines[segment.start:segment.end], "\n")
+		heading := fileName
+		if segment.name != "" {
+			heading = strings.TrimPrefix(fileName+" > "+segment.name, " > ")
+		}
+
+		chunk := Chunk{
+			Text:        text,
+			StartPos:    offsets[segment.start],
+			EndPos:      offsets[segment.end-1] + len(lines[segment.end-1]),
+			TokenCount:  cp.chunker.EstimateTokenCount(text),
+			ChunkType:   ContentTypeCode,
+			HeadingPath: heading,
+			Language:    language,
+			SymbolName:  segment.name,
+			SymbolKind:  segment.kind,
+			StartLine:   segment.start + 1,
+			EndLine:     segment.end,
+		}
+
+		// Lines longer than the chunk size (e.g. minified code) are split as plain text
+		if chunk.TokenCount > cp.chunker.MaxTokens {
+			for _, part := range cp.chunker.ChunkText(text) {
+				sub := chunk
+				sub.Text = part.Text
+				sub.StartPos = chunk.StartPos + part.StartPos
+				sub.EndPos = chunk.StartPos + part.EndPos
+				sub.TokenCount = part.TokenCount
+				sub.Index = len(chunks)
+				chunks = append(chunks, sub)
+			}
+			continue
+		}
+
+		chunk.Index = len(chunks)
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks
+}
+
+// topLevelSegments splits a whole file into declaration segments
+func (cp *CodeParser) topLevelSegments(content string, lines []string, language string) []codeSegment {
+	if language == "go" {
+		if segments, err := goSegments(content, len(lines)); err == nil {
+			return trimSegments(lines, segments)
+		}
+		// Files that don't parse (e.g. templates or syntax errors) fall back to brace rules
+	}
+	return cp.segments(lines, 0, len(lines), codeSegment{}, language)
+}
+
+// segments splits a line range into declaration segments. Segments without a symbol
+// of their own inherit the name and kind of scope.
+func (cp *CodeParser) segments(lines []string, from, to int, scope codeSegment, language string) []codeSegment {
+	if language == "python" || language == "ruby" {
+		return trimSegments(lines, indentSegments(lines, from, to, scope, language))
+	}
+	return trimSegments(lines, braceSegments(lines, from, to, scope, language))
+}
+
+// expand splits segments larger than the chunk size, into members for containers
+// such as classes and into line windows otherwise
+func (cp *CodeParser) expand(lines []string, segment codeSegment, language string) []codeSegment {
+	if cp.lineTokens(lines, segment.start, segment.end) <= cp.chunker.MaxTokens {
+		return []codeSegment{segment}
+	}
+
+	if containerKinds[segment.kind] && segment.body > segment.start && segment.body < segment.bodyEnd {
+		members := cp.segments(lines, segment.body, segment.bodyEnd, segment, language)
+		if len(members) > 0 {
+			result := []codeSegment{{start: segment.start, end: segment.body, body: -1,
+				name: segment.name, kind: segment.kind}}
+			for _, member := range members {
+				result = append(result, cp.expand(lines, member, language)...)
+			}
+			// The closing line of the container stays with its last member
+			result[len(result)-1].end = segment.end
+			return result
+		}
+	}
+
+	var windows []codeSegment
+	start, tokens := segment.start, 0
+	for i := segment.start; i < segment.end; i++ {
+		count := cp.chunker.EstimateTokenCount(lines[i])
+		if i > start && tokens+count > cp.chunker.MaxTokens {
+			windows = append(windows, codeSegment{start: start, end: i, body: -1, name: segment.name, kind: segment.kind})
+			start, tokens = i, 0
+		}
+		tokens += count
+	}
+	windows = append(windows, codeSegment{start: start, end: segment.end, body: -1,
+		name: segment.name, kind: segment.kind})
+
+	return trimSegments(lines, windows)
+}
+
+// mergeSegments joins adjacent segments of the same symbol, such as consecutive import
+// statements or the fields of a class, while they fit in a chunk
+func (cp *CodeParser) mergeSegments(lines []string, segments []codeSegment) []codeSegment {
+	var merged []codeSegment
+	for _, segment := range segments {
+		if n := len(merged); n > 0 {
+			last := &merged[n-1]
+			if last.name == segment.name && last.kind == segment.kind &&
+				cp.lineTokens(lines, last.start, segment.end) <= cp.chunker.MaxTokens {
+				last.end = segment.end
+				continue
+			}
+		}
+		merged = append(merged, segment)
+	}
+	return merged
+}
+
+// lineTokens estimates the token count of a line range
+func (cp *CodeParser) lineTokens(lines []string, start, end int) int {
+	return cp.chunker.EstimateTokenCount(strings.Join(lines[start:end], "\n"))
+}
+
+// goSegments splits Go source into declarations using go/parser. Doc comments and
+// floating comments are attached to the declaration that follows them.
+func goSegments(content string, lineCount int) ([]codeSegment, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Go source: %w", err)
+	}
+
+	line := func(pos token.Pos) int {
+		return fset.Position(pos).Line - 1
+	}
+
+	// Package clause, file comments and imports form the header
+	headerEnd := line(file.Name.End()) + 1
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			headerEnd = line(gen.End()) + 1
+		}
+	}
+
+	segments := []codeSegment{{start: 0, end: headerEnd, body: -1, name: file.Name.Name, kind: SymbolKindPackage}}
+	next := headerEnd
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			continue
+		}
+
+		end := line(decl.End()) + 1
+		if end <= next {
+			// Several declarations on one line stay in a single segment
+			continue
+		}
+
+		segment := codeSegment{start: next, end: end, body: -1}
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			segment.name, segment.kind = d.Name.Name, SymbolKindFunction
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				segment.name = goReceiverName(d.Recv.List[0].Type) + "." + d.Name.Name
+				segment.kind = SymbolKindMethod
+			}
+		case *ast.GenDecl:
+			segment.name, segment.kind = goGenDeclSymbol(d)
+		}
+
+		segments = append(segments, segment)
+		next = end
+	}
+
+	// Trailing comments after the last declaration
+	if next < lineCount {
+		segments = append(segments, codeSegment{start: next, end: lineCount, body: -1})
+	}
+
+	return segments, nil
+}
+
+// goReceiverName returns the type name of a method receiver, e.g. "Client" for (c *Client)
+func goReceiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return goReceiverName(t.X)
+	case *ast.IndexExpr:
+		return goReceiverName(t.X)
+	case *ast.IndexListExpr:
+		return goReceiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	default:
+		return ""
+	}
+}
+
+// goGenDeclSymbol returns the declared names and kind of a type, const or var declaration
+func goGenDeclSymbol(decl *ast.GenDecl) (string, string) {
+	var names []string
+	for _, spec := range decl.Specs {
+		switch s := spec.(type) {
+		case *ast.TypeSpec:
+			names = append(names, s.Name.Name)
+		case *ast.ValueSpec:
+			for _, name := range s.Names {
+				names = append(names, name.Name)
+			}
+		}
+	}
+
+	kind := SymbolKindVar
+	switch decl.Tok {
+	case token.TYPE:
+		kind = SymbolKindType
+	case token.CONST:
+		kind = SymbolKindConst
+	}
+
+	return strings.Join(names, ", "), kind
+}
+
+// braceScanner counts braces and brackets outside of comments and string literals,
+// carrying block comment and raw string state across lines
+type braceScanner struct {
+	singleQuoteStrings bool // ' delimits strings rather than character literals
+	rawStrings         bool // ` delimits multi-line strings
+	inBlockComment     bool
+	inRawString        bool
+}
+
+func newBraceScanner(language string) *braceScanner {
+	return &braceScanner{
+		singleQuoteStrings: language == "javascript" || language == "typescript" || language == "php",
+		rawStrings:         language == "javascript" || language == "typescript" || language == "go",
+	}
+}
+
+// scan returns the change in brace and bracket depth over a line, and whether it opens a brace
+func (s *braceScanner) scan(line string) (braceDelta, bracketDelta int, opened bool) {
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		next := byte(0)
+		if i+1 < len(line) {
+			next = line[i+1]
+		}
+
+		switch {
+		case s.inBlockComment:
+			if c == '*' && next == '/' {
+				s.inBlockComment = false
+				i++
+			}
+		case s.inRawString:
+			if c == '`' {
+				s.inRawString = false
+			}
+		case c == '/' && next == '/':
+			return braceDelta, bracketDelta, opened
+		case c == '/' && next == '*':
+			s.inBlockComment = true
+			i++
+		case c == '`' && s.rawStrings:
+			s.inRawString = true
+		case c == '"' || (c == '\'' && s.singleQuoteStrings):
+			i = skipQuoted(line, i)
+		case c == '\'':
+			i = skipCharLiteral(line, i)
+		case c == '{':
+			braceDelta++
+			opened = true
+		case c == '}':
+			braceDelta--
+		case c == '(' || c == '[':
+			bracketDelta++
+		case c == ')' || c == ']':
+			bracketDelta--
+		}
+	}
+	return braceDelta, bracketDelta, opened
+}
+
+// skipQuoted returns the index of the quote closing the string that starts at i
+func skipQuoted(line string, i int) int {
+	quote := line[i]
+	for j := i + 1; j < len(line); j++ {
+		switch line[j] {
+		case '\\':
+			j++
+		case quote:
+			return j
+		}
+	}
+	return len(line) - 1
+}
+
+// skipCharLiteral skips a character literal such as 'a' or '\n'; anything else, like a
+// Rust lifetime, is left alone
+func skipCharLiteral(line string, i int) int {
+	if i+2 < len(line) && line[i+1] != '\\' && line[i+2] == '\'' {
+		return i + 2
+	}
+	if i+1 < len(line) && line[i+1] == '\\' {
+		if end := strings.IndexByte(line[i+2:], '\''); end >= 0 && end < 8 {
+			return i + 2 + end
+		}
+	}
+	return i
+}
+
+// braceSegments splits a line range of a brace-delimited language into top-level
+// statements and declarations. Comments and annotations attach to the next declaration.
+func braceSegments(lines []string, from, to int, scope codeSegment, language string) []codeSegment {
+	scanner := newBraceScanner(language)
+
+	var segments []codeSegment
+	start, header, openLine, pending := -1, -1, -1, -1
+	depth, brackets := 0, 0
+
+	for i := from; i < to; i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		inComment := scanner.inBlockComment
+		braceDelta, bracketDelta, opened := scanner.scan(lines[i])
+
+		if header < 0 {
+			if trimmed == "" {
+				continue
+			}
+			if inComment || isBraceComment(trimmed) || (isAnnotation(trimmed) && braceDelta == 0 && bracketDelta == 0) {
+				if pending < 0 {
+					pending = i
+				}
+				continue
+			}
+			start, header = i, i
+			if pending >= 0 {
+				start, pending = pending, -1
+			}
+		}
+
+		depth += braceDelta
+		brackets += bracketDelta
+		if opened && openLine < 0 {
+			openLine = i
+		}
+		if depth < 0 {
+			depth = 0
+		}
+		if brackets < 0 {
+			brackets = 0
+		}
+		if depth > 0 || brackets > 0 {
+			continue
+		}
+		if openLine < 0 && (continuesStatement(trimmed) || nextLineOpensBlock(lines, i+1, to)) {
+			continue
+		}
+
+		segments = append(segments, braceSegment(lines, start, header, openLine, i+1, scope, language))
+		start, header, openLine = -1, -1, -1
+	}
+
+	switch {
+	case header >= 0:
+		segments = append(segments, braceSegment(lines, start, header, openLine, to, scope, language))
+	case pending >= 0:
+		segments = append(segments, codeSegment{start: pending, end: to, body: -1, name: scope.name, kind: scope.kind})
+	}
+
+	return segments
+}
+
+// braceSegment builds a segment, detecting the declared symbol from its signature
+func braceSegment(lines []string, start, header, openLine, end int, scope codeSegment, language string) codeSegment {
+	sigEnd := header + 1
+	if openLine >= header {
+		sigEnd = openLine + 1
+	}
+	if sigEnd > header+5 {
+		sigEnd = header + 5
+	}
+	if sigEnd > end {
+		sigEnd = end
+	}
+
+	parts := make([]string, 0, sigEnd-header)
+	for _, line := range lines[header:sigEnd] {
+		parts = append(parts, strings.TrimSpace(line))
+	}
+	signature := strings.Join(parts, " ")
+	if i := strings.Index(signature, "{"); i >= 0 {
+		signature = signature[:i]
+	}
+
+	segment := codeSegment{start: start, end: end, body: -1}
+	name, kind := braceSymbol(signature, language, openLine >= 0, scope.name != "")
+	if name == "" {
+		segment.name, segment.kind = scope.name, scope.kind
+		return segment
+	}
+
+	segment.name, segment.kind = qualifySymbol(scope, name, kind)
+	if containerKinds[kind] && openLine >= 0 && end-1 > openLine {
+		segment.body, segment.bodyEnd = openLine+1, end-1
+	}
+	return segment
+}
+
+// braceSymbol detects the symbol declared by a signature in a brace-delimited language
+func braceSymbol(signature, language string, hasBlock, inScope bool) (string, string) {
+	if language == "go" {
+		if m := goMethodPattern.FindStringSubmatch(signature); m != nil {
+			return m[1] + "." + m[2], SymbolKindMethod
+		}
+		if m := goTypePattern.FindStringSubmatch(signature); m != nil {
+			return m[1], SymbolKindType
+		}
+	}
+
+	if m := containerPattern.FindStringSubmatch(signature); m != nil {
+		// "struct point *make_point(void)" is a C function returning a struct
+		cStyleReturn := (language == "c" || language == "cpp") && strings.Contains(signature, "(")
+		if !cStyleReturn {
+			return m[2], m[1]
+		}
+	}
+	if m := keywordFuncPattern.FindStringSubmatch(signature); m != nil && !controlKeywords[m[1]] {
+		return m[1], SymbolKindFunction
+	}
+	if m := arrowFuncPattern.FindStringSubmatch(signature); m != nil {
+		return m[1], SymbolKindFunction
+	}
+	if language == "typescript" {
+		if m := goTypePattern.FindStringSubmatch(signature); m != nil {
+			return m[1], SymbolKindType
+		}
+	}
+	if !hasBlock || (strings.Contains(signature, "=") && !strings.Contains(signature, "=>")) {
+		return "", ""
+	}
+	if inScope {
+		if m := memberFuncPattern.FindStringSubmatch(signature); m != nil && !controlKeywords[m[1]] {
+			return m[1], SymbolKindFunction
+		}
+	}
+	if m := typedFuncPattern.FindStringSubmatch(signature); m != nil && !controlKeywords[m[1]] {
+		return m[1], SymbolKindFunction
+	}
+	return "", ""
+}
+
+// indentSegments splits a line range of an indentation-delimited language into
+// statements and declarations at the range's base indentation. Comments and
+// decorators attach to the next declaration.
+func indentSegments(lines []string, from, to int, scope codeSegment, language string) []codeSegment {
+	var segments []codeSegment
+	start, header, pending, base := -1, -1, -1, -1
+	inString := false
+
+	for i := from; i < to; i++ {
+		trimmed := strings.TrimSpace(lines[i])
+
+		// Skip the inside of Python triple-quoted strings, which may start at column 0
+		wasInString := inString
+		if language == "python" && (strings.Count(lines[i], `"""`)+strings.Count(lines[i], `'''`))%2 == 1 {
+			inString = !inString
+		}
+		if trimmed == "" || wasInString {
+			continue
+		}
+
+		indent := indentation(lines[i])
+		if base < 0 {
+			base = indent
+		}
+		if indent != base || isBlockContinuation(trimmed, language) {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "#") || (language == "python" && strings.HasPrefix(trimmed, "@")) {
+			if pending < 0 {
+				pending = i
+			}
+			continue
+		}
+
+		if header >= 0 {
+			end := i
+			if pending >= 0 {
+				end = pending
+			}
+			segments = append(segments, indentSegment(lines, start, header, end, scope, language))
+		}
+		start, header = i, i
+		if pending >= 0 {
+			start, pending = pending, -1
+		}
+	}
+
+	switch {
+	case header >= 0:
+		segments = append(segments, indentSegment(lines, start, header, to, scope, language))
+	case pending >= 0:
+		segments = append(segments, codeSegment{start: pending, end: to, body: -1, name: scope.name, kind: scope.kind})
+	}
+
+	return segments
+}
+
+// indentSegment builds a segment, detecting the declared symbol from its first line
+func indentSegment(lines []string, start, header, end int, scope codeSegment, language string) codeSegment {
+	signature := strings.TrimSpace(lines[header])
+	segment := codeSegment{start: start, end: end, body: -1}
+
+	var name, kind string
+	switch language {
+	case "python":
+		if m := pythonFuncPattern.FindStringSubmatch(signature); m != nil {
+			name, kind = m[1], SymbolKindFunction
+		} else if m := pythonClassPattern.FindStringSubmatch(signature); m != nil {
+			name, kind = m[1], SymbolKindClass
+		}
+	case "ruby":
+		if m := rubyFuncPattern.FindStringSubmatch(signature); m != nil {
+			name, kind = m[1], SymbolKindFunction
+		} else if m := rubyClassPattern.FindStringSubmatch(signature); m != nil {
+			name, kind = m[2], m[1]
+		}
+	}
+
+	if name == "" {
+		segment.name, segment.kind = scope.name, scope.kind
+		return segment
+	}
+
+	segment.name, segment.kind = qualifySymbol(scope, name, kind)
+	if containerKinds[kind] && header+1 < end {
+		segment.body, segment.bodyEnd = header+1, end
+	}
+	return segment
+}
+
+// qualifySymbol prefixes a member's name with its enclosing symbol; functions inside
+// a container are methods
+func qualifySymbol(scope codeSegment, name, kind string) (string, string) {
+	if scope.name == "" {
+		return name, kind
+	}
+	if kind == SymbolKindFunction {
+		kind = SymbolKindMethod
+	}
+	return scope.name + "." + name, kind
+}
+
+// trimSegments drops leading and trailing blank lines from segments, and blank segments
+func trimSegments(lines []string, segments []codeSegment) []codeSegment {
+	trimmed := segments[:0]
+	for _, segment := range segments {
+		for segment.start < segment.end && strings.TrimSpace(lines[segment.start]) == "" {
+			segment.start++
+		}
+		for segment.end > segment.start && strings.TrimSpace(lines[segment.end-1]) == "" {
+			segment.end--
+		}
+		if segment.start < segment.end {
+			trimmed = append(trimmed, segment)
+		}
+	}
+	return trimmed
+}
+
+// isBraceComment reports whether a trimmed line is a comment in a brace-delimited language
+func isBraceComment(trimmed string) bool {
+	return strings.HasPrefix(trimmed, "//") || strings.HasPrefix(trimmed, "/*") ||
+		strings.HasPrefix(trimmed, "*")
+}
+
+// isAnnotation reports whether a trimmed line is an annotation, decorator or attribute
+func isAnnotation(trimmed string) bool {
+	return (strings.HasPrefix(trimmed, "@") && !strings.HasPrefix(trimmed, "@interface")) ||
+		strings.HasPrefix(trimmed, "#[") || (strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]"))
+}
+
+// continuesStatement reports whether a line ends in the middle of a statement
+func continuesStatement(trimmed string) bool {
+	suffixes := []string{",", "=", "(", "+", "&&", "||", ".", "=>", "->", ":", "?", "\\", "|", "extends", "implements"}
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(trimmed, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextLineOpensBlock reports whether the next non-blank line starts with "{",
+// as with Allman-style braces
+func nextLineOpensBlock(lines []string, from, to int) bool {
+	for i := from; i < to; i++ {
+		if trimmed := strings.TrimSpace(lines[i]); trimmed != "" {
+			return strings.HasPrefix(trimmed, "{")
+		}
+	}
+	return false
+}
+
+// isBlockContinuation reports whether a line at base indentation continues the
+// previous block rather than starting a new statement
+func isBlockContinuation(trimmed, language string) bool {
+	if strings.HasPrefix(trimmed, ")") || strings.HasPrefix(trimmed, "]") || strings.HasPrefix(trimmed, "}") {
+		return true
+	}
+
+	word := trimmed
+	if i := strings.IndexAny(word, " :("); i >= 0 {
+		word = word[:i]
+	}
+
+	switch language {
+	case "python":
+		return word == "else" || word == "elif" || word == "except" || word == "finally"
+	case "ruby":
+		return word == "end" || word == "else" || word == "elsif" || word == "when" ||
+			word == "in" || word == "rescue" || word == "ensure"
+	}
+	return false
+}
+
+// indentation returns the width of a line's leading whitespace, counting tabs as four spaces
+func indentation(line string) int {
+	width := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += 4
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+// SupportedExtensions returns the file extensions this parser supports
+func (cp *CodeParser) SupportedExtensions() []string {
+	extensions := make([]string, 0, len(codeLanguages))
+	for ext := range codeLanguages {
+		extensions = append(extensions, ext)
+	}
+	sort.Strings(extensions)
+	return extensions
+}
+
+// GetDocumentType returns the type of documents this parser handles
+func (cp *CodeParser) GetDocumentType() DocumentType {
+	return DocumentTypeCode
+}
@@ -0,0 +1,220 @@
+package lilrag
+
+import (
+	"strings"
+	"testing"
+)
+
+// symbolsOf returns "kind name" for each chunk, for compact assertions
+func symbolsOf(chunks []Chunk) []string {
+	symbols := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		symbols[i] = chunk.SymbolKind + " " + chunk.SymbolName
+	}
+	return symbols
+}
+
+func assertSymbols(t *testing.T, chunks []Chunk, expected []string) {
+	t.Helper()
+	got := symbolsOf(chunks)
+	if strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected symbols %q, got %q", expected, got)
+	}
+}
+
+func TestCodeParser_ChunkCode_Go(t *testing.T) {
+	source := `// Package storage uploads files.
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// maxRetries bounds upload attempts
+const maxRetries = 3
+
+// Uploader sends files to S3
+type Uploader struct {
+	bucket string
+}
+
+// Upload retries S3 uploads with exponential backoff
+func (u *Uploader) Upload(ctx context.Context, key string) error {
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		time.Sleep(time.Second << attempt)
+	}
+	return nil
+}
+
+func NewUploader(bucket string) *Uploader {
+	return &Uploader{bucket: bucket}
+}
+`
+	chunks := NewCodeParser().ChunkCode(source, "upload.go", "go")
+
+	assertSymbols(t, chunks, []string{
+		"package storage", "const maxRetries", "type Uploader", "method Uploader.Upload", "function NewUploader",
+	})
+
+	upload := chunks[3]
+	if upload.StartLine != 17 || upload.EndLine != 23 {
+		t.Errorf("Expected Upload to span lines 17-23, got %d-%d", upload.StartLine, upload.EndLine)
+	}
+	if !strings.HasPrefix(upload.Text, "// Upload retries S3 uploads") {
+		t.Errorf("Expected doc comment to be included, got %q", upload.Text)
+	}
+	if upload.Text != source[upload.StartPos:upload.EndPos] {
+		t.Errorf("Chunk text does not match its source offsets")
+	}
+	if upload.Language != "go" || upload.ChunkType != ContentTypeCode {
+		t.Errorf("Expected go code chunk, got %q/%q", upload.Language, upload.ChunkType)
+	}
+	if upload.HeadingPath != "upload.go > Uploader.Upload" {
+		t.Errorf("Unexpected heading path %q", upload.HeadingPath)
+	}
+	for i, chunk := range chunks {
+		if chunk.Index != i {
+			t.Errorf("Expected chunk %d to have index %d, got %d", i, i, chunk.Index)
+		}
+	}
+}
+
+func TestCodeParser_ChunkCode_GoSyntaxErrorFallback(t *testing.T) {
+	source := "package main\n\nfunc (s *Server) Start() {\n\tif broken {\n}\n\nfunc main() {\n}\n"
+	chunks := NewCodeParser().ChunkCode(source, "main.go", "go")
+
+	symbols := strings.Join(symbolsOf(chunks), "|")
+	if !strings.Contains(symbols, "method Server.Start") {
+		t.Errorf("Expected brace rules to find Server.Start, got %q", symbols)
+	}
+}
+
+func TestCodeParser_ChunkCode_Python(t *testing.T) {
+	source := `"""Upload helpers.
+
+Retries live here.
+"""
+import boto3
+
+MAX_RETRIES = 3
+
+
+@retry(times=MAX_RETRIES)
+def upload(path):
+    client = boto3.client("s3")
+    if path:
+        client.upload_file(path)
+    else:
+        return None
+
+
+class Uploader:
+    def __init__(self, bucket):
+        self.bucket = bucket
+
+    def upload(self, path):
+        return upload(path)
+`
+	chunks := NewCodeParser().ChunkCode(source, "upload.py", "python")
+
+	assertSymbols(t, chunks, []string{" ", "function upload", "class Uploader"})
+	if !strings.HasPrefix(chunks[1].Text, "@retry") {
+		t.Errorf("Expected decorator to be attached to upload, got %q", chunks[1].Text)
+	}
+	if !strings.Contains(chunks[1].Text, "return None") {
+		t.Errorf("Expected else branch to stay in upload, got %q", chunks[1].Text)
+	}
+	if chunks[2].StartLine != 19 || chunks[2].EndLine != 24 {
+		t.Errorf("Expected class to span lines 19-24, got %d-%d", chunks[2].StartLine, chunks[2].EndLine)
+	}
+}
+
+func TestCodeParser_ChunkCode_SplitsLargeClasses(t *testing.T) {
+	var body strings.Builder
+	for _, name := range []string{"upload", "download", "delete"} {
+		body.WriteString("    def " + name + "(self, key):\n")
+		for i := 0; i < 8; i++ {
+			body.WriteString("        self.client.call(\"" + name + "\", key, attempt, timeout, retries)\n")
+		}
+		body.WriteString("\n")
+	}
+	source := "class S3Client:\n    retries = 3\n\n" + body.String()
+
+	parser := NewCodeParserWithChunker(NewTextChunker(80, 0))
+	chunks := parser.ChunkCode(source, "s3.py", "python")
+
+	assertSymbols(t, chunks, []string{
+		"class S3Client", "method S3Client.upload", "method S3Client.download", "method S3Client.delete",
+	})
+	for _, chunk := range chunks {
+		if chunk.TokenCount > 80 {
+			t.Errorf("Chunk %s has %d tokens, exceeds max 80", chunk.SymbolName, chunk.TokenCount)
+		}
+	}
+}
+
+func TestCodeParser_ChunkCode_BraceLanguages(t *testing.T) {
+	typescript := `import { S3 } from "aws-sdk";
+import { sleep } from "./util";
+
+/** Retries S3 uploads. */
+export async function uploadWithRetry(key: string): Promise<void> {
+  for (let i = 0; i < 3; i++) {
+    await sleep(i);
+  }
+}
+
+export const backoff = (attempt: number) => {
+  return 2 ** attempt;
+};
+
+export class Uploader {
+  private client = new S3();
+}
+`
+	chunks := NewCodeParser().ChunkCode(typescript, "upload.ts", "typescript")
+	assertSymbols(t, chunks, []string{" ", "function uploadWithRetry", "function backoff", "class Uploader"})
+	if !strings.HasPrefix(chunks[1].Text, "/** Retries S3 uploads. */") {
+		t.Errorf("Expected comment to be attached, got %q", chunks[1].Text)
+	}
+
+	java := `package com.example;
+
+public class Uploader
+{
+    @Override
+    public void upload(String key) throws IOException
+    {
+        if (key == null) {
+            return;
+        }
+    }
+}
+`
+	chunks = NewCodeParser().ChunkCode(java, "Uploader.java", "java")
+	assertSymbols(t, chunks, []string{" ", "class Uploader"})
+
+	rust := "pub fn retry<'a>(s: &'a str) -> &'a str {\n    s\n}\n\nimpl Client {\n    fn new() -> Self { Client {} }\n}\n"
+	chunks = NewCodeParser().ChunkCode(rust, "lib.rs", "rust")
+	assertSymbols(t, chunks, []string{"function retry", "impl Client"})
+}
+
+func TestDetectDocumentType_Code(t *testing.T) {
+	dh := NewDocumentHandler(NewTextChunker(256, 38))
+
+	for path, language := range map[string]string{
+		"main.go": "go", "app.py": "python", "index.ts": "typescript", "lib.rs": "rust", "Main.java": "java",
+	} {
+		if got := dh.DetectDocumentType(path); got != DocumentTypeCode {
+			t.Errorf("Expected %s to be detected as code, got %s", path, got)
+		}
+		if got := CodeLanguage(path); got != language {
+			t.Errorf("Expected %s to be %s, got %s", path, language, got)
+		}
+	}
+
+	if got := dh.DetectDocumentType("notes.txt"); got != DocumentTypeTXT {
+		t.Errorf("Expected notes.txt to stay text, got %s", got)
+	}
+}
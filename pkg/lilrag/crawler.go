@@ -0,0 +1,650 @@
+package lilrag
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	// DefaultCrawlUserAgent identifies the crawler to web servers and robots.txt
+	DefaultCrawlUserAgent = "lil-rag/1.0 (+https://github.com/streed/lil-rag)"
+	// DefaultMaxFetchSize caps the size of a fetched page, like file uploads
+	DefaultMaxFetchSize = 50 << 20
+	// maxPageLinks caps the links kept per page for revalidated crawls
+	maxPageLinks = 500
+)
+
+// Statuses of a crawled page
+const (
+	CrawlStatusIndexed   = "indexed"   // Fetched and indexed
+	CrawlStatusUnchanged = "unchanged" // The server confirmed the indexed copy is current
+	CrawlStatusBlocked   = "blocked"   // Disallowed by robots.txt
+	CrawlStatusSkipped   = "skipped"   // Redirected outside the crawl
+	CrawlStatusFailed    = "failed"    // Could not be fetched or indexed
+)
+
+// Redirects that a crawl does not follow
+var (
+	errRedirectOffHost = errors.New("redirected outside the crawled host")
+	errRedirectBlocked = errors.New("redirect target disallowed by robots.txt")
+)
+
+// genericContentTypes say nothing about the format of a download, so the extension of
+// the URL path is used instead
+var genericContentTypes = map[string]bool{
+	"application/octet-stream":   true,
+	"binary/octet-stream":        true,
+	"application/x-download":     true,
+	"application/force-download": true,
+	"text/plain":                 true,
+}
+
+// contentTypeExtensions are the extensions of common download types, which the system's
+// MIME tables may list under rarer extensions such as .ehtml first
+var contentTypeExtensions = map[string]string{
+	"text/html":             ".html",
+	"application/xhtml+xml": ".html",
+	"application/pdf":       ".pdf",
+	"text/csv":              ".csv",
+	"text/markdown":         ".md",
+	"application/json":      ".json",
+	"application/xml":       ".xml",
+	"text/xml":              ".xml",
+	"application/zip":       ".zip",
+	"application/epub+zip":  ".epub",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   ".docx",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         ".xlsx",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": ".pptx",
+}
+
+// CrawlOptions controls which links a crawl follows and how politely it fetches them
+type CrawlOptions struct {
+	MaxDepth     int           // Link hops followed from the start page; 0 fetches only the start page
+	MaxPages     int           // Maximum number of pages visited, 0 for no limit
+	SameHost     bool          // Only follow links to the host of the start page
+	Include      []string      // Regular expressions, one of which discovered URLs must match
+	Exclude      []string      // Regular expressions of discovered URLs that are not followed
+	Delay        time.Duration // Minimum time between requests to the same host
+	IgnoreRobots bool          // Crawl pages disallowed by robots.txt
+	UserAgent    string        // User-Agent header and robots.txt agent
+}
+
+// DefaultCrawlOptions returns options for a polite crawl of a single site
+func DefaultCrawlOptions() CrawlOptions {
+	return CrawlOptions{
+		MaxDepth:  2,
+		MaxPages:  100,
+		SameHost:  true,
+		Delay:     time.Second,
+		UserAgent: DefaultCrawlUserAgent,
+	}
+}
+
+// CrawlPage is the outcome of fetching one URL
+type CrawlPage struct {
+	URL    string `json:"url"`
+	ID     string `json:"id"`
+	Depth  int    `json:"depth"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// CrawlReport lists the pages visited by a crawl in order
+type CrawlReport struct {
+	Pages []CrawlPage `json:"pages"`
+}
+
+// Count returns the number of pages with a status
+func (r *CrawlReport) Count(status string) int {
+	count := 0
+	for _, page := range r.Pages {
+		if page.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// URLDocumentID derives the document ID of a web page, e.g.
+// "https://wiki.example.com/docs/Setup Guide" gives "wiki-example-com-docs-Setup-Guide".
+// Long IDs are shortened with a hash of the URL.
+func URLDocumentID(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return GenerateDocumentID()
+	}
+	name := u.Host + u.Path
+	if u.RawQuery != "" {
+		name += "-" + u.RawQuery
+	}
+	id := strings.Trim(archiveIDReplacer.ReplaceAllString(name, "-"), "-")
+	if len(id) > 100 {
+		sum := sha1.Sum([]byte(rawURL))
+		id = strings.TrimRight(id[:90], "-") + "-" + hex.EncodeToString(sum[:4])
+	}
+	return id
+}
+
+// IndexURL fetches a web page or file and indexes it through the same content detection
+// and parsers as local files. The URL is stored as the document's source path, and the
+// ETag and Last-Modified headers are kept so that indexing it again sends a conditional
+// request and skips pages that have not changed. An empty id is derived from the URL.
+func (m *LilRag) IndexURL(ctx context.Context, rawURL, id string) (*CrawlPage, error) {
+	u, err := parseCrawlURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if id == "" {
+		id = URLDocumentID(u.String())
+	}
+
+	opts := DefaultCrawlOptions()
+	opts.IgnoreRobots = true // An explicitly requested page is not crawled
+	opts.SameHost = false
+	fetcher := m.newWebFetcher(opts)
+
+	page, _ := m.indexWebPage(ctx, fetcher, u, id, 0)
+	if page.Status == CrawlStatusFailed {
+		return &page, fmt.Errorf("failed to index %s: %s", page.URL, page.Error)
+	}
+	return &page, nil
+}
+
+// Crawl indexes startURL and the pages it links to, breadth first, up to opts.MaxDepth
+// links away. Each page is indexed as its own document with an ID derived from its URL;
+// robots.txt, the request delay and conditional requests for unchanged pages make
+// repeated crawls cheap for the server.
+func (m *LilRag) Crawl(ctx context.Context, startURL string, opts CrawlOptions) (*CrawlReport, error) {
+	start, err := parseCrawlURL(startURL)
+	if err != nil {
+		return nil, err
+	}
+	include, err := compilePatterns(opts.Include)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include pattern: %w", err)
+	}
+	exclude, err := compilePatterns(opts.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exclude pattern: %w", err)
+	}
+
+	follow := func(link *url.URL) bool {
+		if opts.SameHost && !strings.EqualFold(link.Host, start.Host) {
+			return false
+		}
+		target := link.String()
+		if len(include) > 0 && !matchesAny(include, target) {
+			return false
+		}
+		return !matchesAny(exclude, target)
+	}
+
+	type queued struct {
+		url   *url.URL
+		depth int
+	}
+	queue := []queued{{url: start}}
+	seen := map[string]bool{start.String(): true}
+	fetcher := m.newWebFetcher(opts)
+	if opts.SameHost {
+		fetcher.host = start.Host
+	}
+	report := &CrawlReport{}
+
+	for len(queue) > 0 && (opts.MaxPages <= 0 || len(report.Pages) < opts.MaxPages) {
+		item := queue[0]
+		queue = queue[1:]
+
+		page, links := m.indexWebPage(ctx, fetcher, item.url, URLDocumentID(item.url.String()), item.depth)
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		report.Pages = append(report.Pages, page)
+		if page.Status != CrawlStatusIndexed && page.Status != CrawlStatusUnchanged {
+			continue
+		}
+
+		if item.depth >= opts.MaxDepth {
+			continue
+		}
+		for _, rawLink := range links {
+			link, err := parseCrawlURL(rawLink)
+			if err != nil || seen[link.String()] || !follow(link) {
+				continue
+			}
+			seen[link.String()] = true
+			queue = append(queue, queued{url: link, depth: item.depth + 1})
+		}
+	}
+
+	return report, nil
+}
+
+// indexWebPage fetches and indexes one page and returns the links found on it
+func (m *LilRag) indexWebPage(
+	ctx context.Context, fetcher *webFetcher, u *url.URL, id string, depth int,
+) (CrawlPage, []string) {
+	page := CrawlPage{URL: u.String(), ID: id, Depth: depth}
+	fail := func(err error) (CrawlPage, []string) {
+		page.Status = CrawlStatusFailed
+		page.Error = err.Error()
+		return page, nil
+	}
+
+	allowed, err := fetcher.allowed(ctx, u)
+	if err != nil {
+		return fail(err)
+	}
+	if !allowed {
+		page.Status = CrawlStatusBlocked
+		return page, nil
+	}
+
+	// Revalidate the indexed copy of this URL, if there is one. A redirected page is
+	// stored under its final URL, so it is matched by the URL that was requested.
+	requested := page.URL
+	var previous map[string]string
+	if m.storage != nil {
+		doc, err := m.storage.GetDocumentByID(ctx, id)
+		if err == nil && (doc.SourcePath == requested || doc.Metadata["requested_url"] == requested) {
+			previous = doc.Metadata
+		}
+	}
+
+	fetched, err := fetcher.fetch(ctx, u, previous)
+	switch {
+	case errors.Is(err, errRedirectOffHost):
+		page.Status = CrawlStatusSkipped
+		return page, nil
+	case errors.Is(err, errRedirectBlocked):
+		page.Status = CrawlStatusBlocked
+		return page, nil
+	case err != nil:
+		return fail(err)
+	}
+	if fetched.notModified {
+		page.Status = CrawlStatusUnchanged
+		return page, splitLinks(previous["links"])
+	}
+	defer os.Remove(fetched.path)
+	page.URL = fetched.url.String()
+
+	metadata := map[string]string{"source_url": page.URL}
+	if page.URL != requested {
+		metadata["requested_url"] = requested
+	}
+	if fetched.etag != "" {
+		metadata["etag"] = fetched.etag
+	}
+	if fetched.lastModified != "" {
+		metadata["last_modified"] = fetched.lastModified
+	}
+
+	var links []string
+	if m.documentHandler != nil && m.documentHandler.DetectDocumentType(fetched.path) == DocumentTypeHTML {
+		links = htmlFileLinks(fetched.path, fetched.url)
+		if len(links) > 0 {
+			metadata["links"] = strings.Join(links, "\n")
+		}
+	}
+
+	if err := m.indexFileFrom(ctx, fetched.path, page.URL, id, metadata); err != nil {
+		return fail(err)
+	}
+	page.Status = CrawlStatusIndexed
+	return page, links
+}
+
+// webFetcher downloads pages while honoring robots.txt and a delay between requests to
+// the same host
+type webFetcher struct {
+	client      *http.Client
+	opts        CrawlOptions
+	host        string // Host that redirects must stay on, if any
+	maxSize     int64
+	lastRequest map[string]time.Time
+	robots      map[string]*robotsRules
+}
+
+func (m *LilRag) newWebFetcher(opts CrawlOptions) *webFetcher {
+	timeout := 30 * time.Second
+	if m.config != nil && m.config.TimeoutSeconds > 0 {
+		timeout = time.Duration(m.config.TimeoutSeconds) * time.Second
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = DefaultCrawlUserAgent
+	}
+	f := &webFetcher{
+		client:      &http.Client{Timeout: timeout},
+		opts:        opts,
+		maxSize:     DefaultMaxFetchSize,
+		lastRequest: make(map[string]time.Time),
+		robots:      make(map[string]*robotsRules),
+	}
+	f.client.CheckRedirect = f.checkRedirect
+	return f
+}
+
+// checkRedirect applies the same-host rule and robots.txt to every redirect hop. The
+// redirects of robots.txt itself are followed like the default client does.
+func (f *webFetcher) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if via[0].URL.Path == "/robots.txt" {
+		return nil
+	}
+
+	if f.host != "" && !strings.EqualFold(req.URL.Host, f.host) {
+		return errRedirectOffHost
+	}
+	allowed, err := f.allowed(req.Context(), req.URL)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return errRedirectBlocked
+	}
+	return nil
+}
+
+// fetchedPage is a downloaded page saved to a temporary file
+type fetchedPage struct {
+	url          *url.URL // URL after redirects
+	path         string
+	notModified  bool
+	etag         string
+	lastModified string
+}
+
+// allowed reports whether robots.txt of the URL's host allows fetching it
+func (f *webFetcher) allowed(ctx context.Context, u *url.URL) (bool, error) {
+	if f.opts.IgnoreRobots {
+		return true, nil
+	}
+
+	origin := u.Scheme + "://" + u.Host
+	rules, ok := f.robots[origin]
+	if !ok {
+		var err error
+		if rules, err = f.fetchRobots(ctx, origin); err != nil {
+			return false, err
+		}
+		f.robots[origin] = rules
+	}
+
+	target := u.EscapedPath()
+	if target == "" {
+		target = "/"
+	}
+	if u.RawQuery != "" {
+		target += "?" + u.RawQuery
+	}
+	return rules.Allowed(target), nil
+}
+
+// fetchRobots reads the robots.txt of a site. A missing file allows everything, and a
+// server error disallows everything until the next crawl.
+func (f *webFetcher) fetchRobots(ctx context.Context, origin string) (*robotsRules, error) {
+	resp, err := f.get(ctx, origin+"/robots.txt", nil)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return &robotsRules{disallowed: true}, nil
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return parseRobots(resp.Body, f.opts.UserAgent), nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return &robotsRules{}, nil
+	default:
+		return &robotsRules{disallowed: true}, nil
+	}
+}
+
+// fetch downloads a page, sending If-None-Match and If-Modified-Since when previous
+// metadata has the validators of an earlier fetch
+func (f *webFetcher) fetch(ctx context.Context, u *url.URL, previous map[string]string) (*fetchedPage, error) {
+	headers := make(http.Header)
+	if etag := previous["etag"]; etag != "" {
+		headers.Set("If-None-Match", etag)
+	}
+	if lastModified := previous["last_modified"]; lastModified != "" {
+		headers.Set("If-Modified-Since", lastModified)
+	}
+
+	resp, err := f.get(ctx, u.String(), headers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && len(headers) > 0 {
+		return &fetchedPage{url: u, notModified: true}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", u, resp.Status)
+	}
+
+	temp, err := os.CreateTemp("", "lilrag_url_*"+fetchExtension(resp.Request.URL, resp.Header.Get("Content-Type")))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	written, err := io.Copy(temp, io.LimitReader(resp.Body, f.maxSize+1))
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && written > f.maxSize {
+		err = fmt.Errorf("response exceeds %d bytes", f.maxSize)
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+		return nil, fmt.Errorf("failed to download %s: %w", u, err)
+	}
+
+	return &fetchedPage{
+		url:          resp.Request.URL,
+		path:         temp.Name(),
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}, nil
+}
+
+// get sends a GET request once the delay since the last request to the host has passed
+func (f *webFetcher) get(ctx context.Context, rawURL string, headers http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range headers {
+		req.Header[key] = values
+	}
+	req.Header.Set("User-Agent", f.opts.UserAgent)
+
+	host := req.URL.Host
+	delay := f.opts.Delay
+	if rules := f.robots[req.URL.Scheme+"://"+host]; rules != nil && rules.crawlDelay > delay {
+		delay = rules.crawlDelay
+	}
+	if last, ok := f.lastRequest[host]; ok {
+		if wait := time.Until(last.Add(delay)); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+	f.lastRequest[host] = time.Now()
+
+	return f.client.Do(req)
+}
+
+// fetchExtension picks the temporary file extension of a download from the Content-Type
+// header, or from the URL path when the header is missing, generic or unknown, so a
+// page such as index.php served as text/html is parsed as HTML. The extension is only
+// a hint; the type is detected from the content.
+func fetchExtension(u *url.URL, contentType string) string {
+	name := strings.ToLower(u.Path)
+	pathExt := ""
+	if strings.HasSuffix(name, ".tar.gz") {
+		pathExt = ".tar.gz"
+	} else if ext := path.Ext(name); ext != "" && len(ext) <= 8 && isFileExtension(ext[1:]) {
+		pathExt = ext
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && !genericContentTypes[mediaType] {
+		if ext, ok := contentTypeExtensions[mediaType]; ok {
+			return ext
+		}
+		if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+			// Keep the path's extension when it matches the type, e.g. .tar.gz rather than .gz
+			for _, ext := range exts {
+				if ext == pathExt || (pathExt == ".tar.gz" && ext == ".gz") {
+					return pathExt
+				}
+			}
+			return exts[0]
+		}
+	}
+	return pathExt
+}
+
+// isFileExtension reports whether s looks like a file extension rather than part of a
+// path such as "/v1.2"
+func isFileExtension(s string) bool {
+	for _, c := range s {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return s != "" && (s[0] < '0' || s[0] > '9')
+}
+
+// parseCrawlURL parses an http or https URL and drops its fragment
+func parseCrawlURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported URL scheme %q, expected http or https", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q: missing host", rawURL)
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.Host = strings.ToLower(u.Host)
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u, nil
+}
+
+// htmlFileLinks returns the http and https links of an HTML file, resolved against its
+// URL or <base href>, in document order. Links marked rel="nofollow" are left out.
+func htmlFileLinks(filePath string, pageURL *url.URL) []string {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	doc, err := html.Parse(file)
+	if err != nil {
+		return nil
+	}
+
+	base := pageURL
+	var links []string
+	seen := make(map[string]bool)
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "base":
+				if href, err := pageURL.Parse(htmlAttr(n, "href")); err == nil && hasHTMLAttr(n, "href") {
+					base = href
+				}
+			case "a", "area":
+				rel := strings.Fields(strings.ToLower(htmlAttr(n, "rel")))
+				href := strings.TrimSpace(htmlAttr(n, "href"))
+				if href == "" || containsString(rel, "nofollow") || len(links) >= maxPageLinks {
+					break
+				}
+				if link, err := base.Parse(href); err == nil {
+					if link, err := parseCrawlURL(link.String()); err == nil && !seen[link.String()] {
+						seen[link.String()] = true
+						links = append(links, link.String())
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return links
+}
+
+// splitLinks reads the links stored in a page's metadata
+func splitLinks(links string) []string {
+	if links == "" {
+		return nil
+	}
+	return strings.Split(links, "\n")
+}
+
+// compilePatterns compiles regular expressions
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// matchesAny reports whether s matches one of the regular expressions
+func matchesAny(patterns []*regexp.Regexp, s string) bool {
+	for _, re := range patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString reports whether values contains s
+func containsString(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
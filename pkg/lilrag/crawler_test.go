@@ -0,0 +1,376 @@
+package lilrag
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testSite is a small website that records the requests it serves
+type testSite struct {
+	mu       sync.Mutex
+	requests []string
+	times    []time.Time
+	pages    map[string]string
+}
+
+func newTestSite(t *testing.T) (*testSite, *httptest.Server) {
+	t.Helper()
+	site := &testSite{pages: map[string]string{
+		"/robots.txt": "User-agent: *\nDisallow: /private/\n",
+		"/": `<html><head><title>Home</title></head><body>
+<p>Welcome to the team wiki, which documents how we deploy and operate our services.</p>
+<a href="/docs/setup">Setup</a> <a href="/docs/faq#top">FAQ</a> <a href="/private/keys">Keys</a>
+<a href="/blog/news">News</a> <a href="https://elsewhere.example.com/">Elsewhere</a>
+<a href="/docs/ignored" rel="nofollow">Ignored</a></body></html>`,
+		"/docs/setup": `<html><body><p>Run make install to set up the deployment tooling on a new laptop.</p>
+<a href="/docs/setup/deep">Deep</a></body></html>`,
+		"/docs/faq":        `<html><body><p>Rotate the API keys every ninety days, as required by the audit.</p></body></html>`,
+		"/docs/setup/deep": `<html><body><p>This page is three links away from the home page.</p></body></html>`,
+		"/private/keys":    `<html><body><p>Secret keys.</p></body></html>`,
+		"/blog/news":       `<html><body><p>News about the wiki and its many contributors.</p></body></html>`,
+	}}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		site.mu.Lock()
+		site.requests = append(site.requests, r.URL.Path)
+		site.times = append(site.times, time.Now())
+		site.mu.Unlock()
+
+		page, ok := site.pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		etag := fmt.Sprintf(`"%x"`, len(page))
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		if r.URL.Path == "/robots.txt" {
+			w.Header().Set("Content-Type", "text/plain")
+		} else {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		}
+		fmt.Fprint(w, page)
+	}))
+	t.Cleanup(server.Close)
+	return site, server
+}
+
+func (s *testSite) fetched(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, request := range s.requests {
+		if request == path {
+			count++
+		}
+	}
+	return count
+}
+
+func setupCrawlRag(t *testing.T) *LilRag {
+	t.Helper()
+	return &LilRag{
+		storage:         setupTableStorage(t),
+		embedder:        NewMockEmbedder(),
+		chunker:         NewTextChunker(256, 38),
+		documentHandler: NewDocumentHandler(NewTextChunker(256, 38)),
+		config:          &Config{},
+	}
+}
+
+func TestLilRag_Crawl(t *testing.T) {
+	site, server := newTestSite(t)
+	lilRag := setupCrawlRag(t)
+	ctx := context.Background()
+
+	opts := DefaultCrawlOptions()
+	opts.Delay = 0
+	opts.MaxDepth = 1
+	opts.Exclude = []string{"/blog/"}
+	report, err := lilRag.Crawl(ctx, server.URL, opts)
+	if err != nil {
+		t.Fatalf("Failed to crawl: %v", err)
+	}
+
+	statuses := make(map[string]string)
+	for _, page := range report.Pages {
+		statuses[strings.TrimPrefix(page.URL, server.URL)] = page.Status
+	}
+	expected := map[string]string{
+		"/":             CrawlStatusIndexed,
+		"/docs/setup":   CrawlStatusIndexed,
+		"/docs/faq":     CrawlStatusIndexed,
+		"/private/keys": CrawlStatusBlocked,
+	}
+	for path, status := range expected {
+		if statuses[path] != status {
+			t.Errorf("Expected %s to be %s, got %q", path, status, statuses[path])
+		}
+	}
+	if len(report.Pages) != len(expected) {
+		t.Errorf("Expected %d pages, got %+v", len(expected), report.Pages)
+	}
+	for _, path := range []string{"/private/keys", "/blog/news", "/docs/setup/deep", "/docs/ignored"} {
+		if site.fetched(path) != 0 {
+			t.Errorf("Expected %s not to be fetched", path)
+		}
+	}
+
+	doc, err := lilRag.GetDocumentByID(ctx, URLDocumentID(server.URL+"/docs/faq"))
+	if err != nil {
+		t.Fatalf("Failed to get crawled document: %v", err)
+	}
+	if doc.SourcePath != server.URL+"/docs/faq" || doc.DocType != string(DocumentTypeHTML) {
+		t.Errorf("Expected the URL as source path of an HTML document, got %q (%s)", doc.SourcePath, doc.DocType)
+	}
+	if doc.Metadata["etag"] == "" || doc.Metadata["source_url"] != doc.SourcePath {
+		t.Errorf("Expected the ETag and source URL in the metadata, got %v", doc.Metadata)
+	}
+	chunks, err := lilRag.GetDocumentChunks(ctx, doc.ID)
+	if err != nil || len(chunks) == 0 || !strings.Contains(chunks[0].Text, "Rotate the API keys") {
+		t.Errorf("Expected the page text in the chunks, got %+v (%v)", chunks, err)
+	}
+
+	// A second crawl revalidates the pages and still follows the links of unchanged pages
+	report, err = lilRag.Crawl(ctx, server.URL, opts)
+	if err != nil {
+		t.Fatalf("Failed to crawl again: %v", err)
+	}
+	if report.Count(CrawlStatusUnchanged) != 3 || report.Count(CrawlStatusIndexed) != 0 {
+		t.Errorf("Expected the pages to be unchanged, got %+v", report.Pages)
+	}
+}
+
+func TestLilRag_CrawlIncludeAndDepth(t *testing.T) {
+	_, server := newTestSite(t)
+	lilRag := setupCrawlRag(t)
+
+	opts := DefaultCrawlOptions()
+	opts.Delay = 0
+	opts.MaxDepth = 3
+	opts.Include = []string{"/docs/setup"}
+	report, err := lilRag.Crawl(context.Background(), server.URL, opts)
+	if err != nil {
+		t.Fatalf("Failed to crawl: %v", err)
+	}
+
+	var paths []string
+	for _, page := range report.Pages {
+		paths = append(paths, strings.TrimPrefix(page.URL, server.URL))
+	}
+	if strings.Join(paths, " ") != "/ /docs/setup /docs/setup/deep" {
+		t.Errorf("Expected the included pages in breadth-first order, got %v", paths)
+	}
+
+	opts.MaxPages = 2
+	report, err = lilRag.Crawl(context.Background(), server.URL, opts)
+	if err != nil {
+		t.Fatalf("Failed to crawl: %v", err)
+	}
+	if len(report.Pages) != 2 {
+		t.Errorf("Expected the crawl to stop after 2 pages, got %+v", report.Pages)
+	}
+}
+
+func TestLilRag_CrawlDelay(t *testing.T) {
+	site, server := newTestSite(t)
+	lilRag := setupCrawlRag(t)
+
+	opts := DefaultCrawlOptions()
+	opts.Delay = 50 * time.Millisecond
+	opts.MaxDepth = 1
+	opts.Include = []string{"/docs/"}
+	if _, err := lilRag.Crawl(context.Background(), server.URL, opts); err != nil {
+		t.Fatalf("Failed to crawl: %v", err)
+	}
+
+	site.mu.Lock()
+	defer site.mu.Unlock()
+	if len(site.times) != 4 {
+		t.Fatalf("Expected robots.txt and 3 pages to be fetched, got %v", site.requests)
+	}
+	// Requests are timed when the server receives them, so allow for a little jitter
+	for i := 1; i < len(site.times); i++ {
+		if gap := site.times[i].Sub(site.times[i-1]); gap < opts.Delay-5*time.Millisecond {
+			t.Errorf("Expected at least %v between requests, got %v", opts.Delay, gap)
+		}
+	}
+}
+
+func TestLilRag_IndexURL(t *testing.T) {
+	site, server := newTestSite(t)
+	lilRag := setupCrawlRag(t)
+	ctx := context.Background()
+
+	// An explicitly requested page is fetched even when robots.txt disallows crawling it
+	page, err := lilRag.IndexURL(ctx, server.URL+"/private/keys", "keys")
+	if err != nil {
+		t.Fatalf("Failed to index URL: %v", err)
+	}
+	if page.Status != CrawlStatusIndexed || page.ID != "keys" || site.fetched("/robots.txt") != 0 {
+		t.Errorf("Expected the page to be indexed without robots.txt, got %+v", page)
+	}
+
+	page, err = lilRag.IndexURL(ctx, server.URL+"/private/keys", "keys")
+	if err != nil || page.Status != CrawlStatusUnchanged {
+		t.Errorf("Expected the page to be unchanged, got %+v (%v)", page, err)
+	}
+
+	// A redirected page is revalidated by the URL that was requested
+	redirecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest" {
+			http.Redirect(w, r, "/docs/faq", http.StatusFound)
+			return
+		}
+		server.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(redirecting.Close)
+	for _, expected := range []string{CrawlStatusIndexed, CrawlStatusUnchanged} {
+		page, err := lilRag.IndexURL(ctx, redirecting.URL+"/latest", "latest")
+		if err != nil || page.Status != expected {
+			t.Errorf("Expected the redirected page to be %s, got %+v (%v)", expected, page, err)
+		}
+	}
+	if site.fetched("/docs/faq") != 2 {
+		t.Errorf("Expected the redirect target to be revalidated, got %d requests", site.fetched("/docs/faq"))
+	}
+
+	if _, err := lilRag.IndexURL(ctx, server.URL+"/missing", ""); err == nil {
+		t.Error("Expected an error for a missing page")
+	}
+	if _, err := lilRag.IndexURL(ctx, "ftp://example.com/file.txt", ""); err == nil {
+		t.Error("Expected an error for an unsupported scheme")
+	}
+}
+
+func TestLilRag_CrawlRedirects(t *testing.T) {
+	var elsewhereHits int
+	elsewhere := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		elsewhereHits++
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body><p>A page on another host.</p></body></html>")
+	}))
+	t.Cleanup(elsewhere.Close)
+
+	site, server := newTestSite(t)
+	site.pages["/"] = `<html><body><p>Links that redirect somewhere the crawl does not go.</p>
+<a href="/moved">Moved</a> <a href="/old-keys">Old keys</a></body></html>`
+	redirects := map[string]string{"/moved": elsewhere.URL + "/page", "/old-keys": "/private/keys"}
+	redirecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if target, ok := redirects[r.URL.Path]; ok {
+			http.Redirect(w, r, target, http.StatusFound)
+			return
+		}
+		server.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(redirecting.Close)
+
+	opts := DefaultCrawlOptions()
+	opts.Delay = 0
+	report, err := setupCrawlRag(t).Crawl(context.Background(), redirecting.URL, opts)
+	if err != nil {
+		t.Fatalf("Failed to crawl: %v", err)
+	}
+
+	statuses := make(map[string]string)
+	for _, page := range report.Pages {
+		statuses[strings.TrimPrefix(page.URL, redirecting.URL)] = page.Status
+	}
+	if statuses["/moved"] != CrawlStatusSkipped || statuses["/old-keys"] != CrawlStatusBlocked {
+		t.Errorf("Expected the off-host redirect to be skipped and the disallowed one blocked, got %v", statuses)
+	}
+	if elsewhereHits != 0 || site.fetched("/private/keys") != 0 {
+		t.Errorf("Expected the redirect targets not to be fetched, got %d and %d requests",
+			elsewhereHits, site.fetched("/private/keys"))
+	}
+}
+
+func TestFetchExtension(t *testing.T) {
+	tests := []struct {
+		rawURL, contentType, expected string
+	}{
+		{"https://example.com/index.php", "text/html; charset=utf-8", ".html"},
+		{"https://example.com/report.pdf", "application/pdf", ".pdf"},
+		{"https://example.com/download?id=7", "application/pdf", ".pdf"},
+		{"https://example.com/page.html", "text/html", ".html"},
+		{"https://example.com/README.md", "text/plain; charset=utf-8", ".md"},
+		{"https://example.com/data.csv", "application/octet-stream", ".csv"},
+		{"https://example.com/backup.tar.gz", "application/gzip", ".tar.gz"},
+		{"https://example.com/notes.txt", "", ".txt"},
+		{"https://example.com/v1.2", "", ""},
+	}
+
+	for _, tt := range tests {
+		u, err := url.Parse(tt.rawURL)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", tt.rawURL, err)
+		}
+		if got := fetchExtension(u, tt.contentType); got != tt.expected {
+			t.Errorf("fetchExtension(%s, %q) = %q, expected %q", tt.rawURL, tt.contentType, got, tt.expected)
+		}
+	}
+}
+
+func TestParseRobots(t *testing.T) {
+	robots := `# Example
+User-agent: *
+Disallow: /
+
+User-agent: lil-rag
+User-agent: other-bot
+Disallow: /private/
+Allow: /private/public-*.html$
+Disallow: /*.pdf$
+Crawl-delay: 2.5
+`
+	rules := parseRobots(strings.NewReader(robots), DefaultCrawlUserAgent)
+	cases := map[string]bool{
+		"/":                         true,
+		"/docs/setup":               true,
+		"/private/keys":             false,
+		"/private/public-faq.html":  true,
+		"/private/public-faq.htmlx": false,
+		"/files/report.pdf":         false,
+		"/files/report.pdf?x=1":     true,
+		"/robots.txt":               true,
+	}
+	for path, allowed := range cases {
+		if rules.Allowed(path) != allowed {
+			t.Errorf("Expected Allowed(%q) to be %v", path, allowed)
+		}
+	}
+	if rules.crawlDelay != 2500*time.Millisecond {
+		t.Errorf("Expected a crawl delay of 2.5s, got %v", rules.crawlDelay)
+	}
+
+	// Other agents fall back to the "*" group
+	if parseRobots(strings.NewReader(robots), "somebot/2.0").Allowed("/docs") {
+		t.Error("Expected other agents to be disallowed")
+	}
+}
+
+func TestURLDocumentID(t *testing.T) {
+	cases := map[string]string{
+		"https://wiki.example.com/docs/Setup Guide": "wiki-example-com-docs-Setup-Guide",
+		"http://localhost:8080/":                    "localhost-8080",
+		"https://example.com/search?q=go":           "example-com-search-q-go",
+	}
+	for rawURL, expected := range cases {
+		if id := URLDocumentID(rawURL); id != expected {
+			t.Errorf("Expected ID %q for %s, got %q", expected, rawURL, id)
+		}
+	}
+	if id := URLDocumentID("https://example.com/" + strings.Repeat("a", 200)); len(id) > 100 {
+		t.Errorf("Expected long IDs to be shortened, got %d characters", len(id))
+	}
+}
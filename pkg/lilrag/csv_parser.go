@@ -2,12 +2,15 @@ package lilrag
 
 import (
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 )
 
-// CSVParser handles CSV files
+// CSVParser handles CSV files. Records are read one at a time, so very large files are
+// parsed with bounded memory.
 type CSVParser struct {
 	chunker *TextChunker
 }
@@ -17,155 +20,143 @@ func NewCSVParser() *CSVParser {
 	return &CSVParser{}
 }
 
-// Parse extracts text content from a CSV file
-func (cp *CSVParser) Parse(filePath string) (string, error) {
+// eachRecord opens a CSV file and calls fn with its header and then with every data row
+// and its 1-based row number. The record passed to fn is reused by the next call.
+func (cp *CSVParser) eachRecord(filePath string, header func([]string) error, row func(int, []string) error) error {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to open CSV file: %w", err)
+		return fmt.Errorf("failed to open CSV file: %w", err)
 	}
 	defer file.Close()
 
 	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		return "", fmt.Errorf("failed to read CSV file: %w", err)
+	reader.ReuseRecord = true
+
+	for rowNum := 0; ; rowNum++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read CSV file: %w", err)
+		}
+
+		if rowNum == 0 {
+			err = header(append([]string(nil), record...))
+		} else {
+			err = row(rowNum, record)
+		}
+		if err != nil {
+			return err
+		}
 	}
+}
 
+// formatRow renders a record as "Row N: header: value | ..." using Column%d for cells
+// beyond the header
+func (cp *CSVParser) formatRow(rowNum int, header, record []string) string {
+	var rowText strings.Builder
+	rowText.WriteString(fmt.Sprintf("Row %d: ", rowNum))
+
+	// Create meaningful row representation
+	for j, cell := range record {
+		if j < len(header) {
+			rowText.WriteString(fmt.Sprintf("%s: %s", header[j], cell))
+		} else {
+			rowText.WriteString(fmt.Sprintf("Column%d: %s", j+1, cell))
+		}
+		if j < len(record)-1 {
+			rowText.WriteString(" | ")
+		}
+	}
+	return rowText.String()
+}
+
+// Parse extracts text content from a CSV file
+func (cp *CSVParser) Parse(filePath string) (string, error) {
 	var content strings.Builder
+	var header []string
 
-	// Process header row if exists
-	if len(records) > 0 {
-		header := records[0]
+	err := cp.eachRecord(filePath, func(record []string) error {
+		header = record
 		content.WriteString("CSV Headers: ")
 		content.WriteString(strings.Join(header, " | "))
 		content.WriteString("\n\n")
-
-		// Process data rows
-		for i, record := range records[1:] {
-			rowNum := i + 1
-			content.WriteString(fmt.Sprintf("Row %d: ", rowNum))
-
-			// Create meaningful row representation
-			for j, cell := range record {
-				if j < len(header) {
-					content.WriteString(fmt.Sprintf("%s: %s", header[j], cell))
-				} else {
-					content.WriteString(fmt.Sprintf("Column%d: %s", j+1, cell))
-				}
-				if j < len(record)-1 {
-					content.WriteString(" | ")
-				}
-			}
-			content.WriteString("\n")
-		}
+		return nil
+	}, func(rowNum int, record []string) error {
+		content.WriteString(cp.formatRow(rowNum, header, record))
+		content.WriteString("\n")
+		return nil
+	})
+	if err != nil {
+		return "", err
 	}
 
 	return content.String(), nil
 }
 
 // ParseWithChunks extracts and chunks content from a CSV file
-func (cp *CSVParser) ParseWithChunks(filePath, _ string) ([]Chunk, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open CSV file: %w", err)
-	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
+func (cp *CSVParser) ParseWithChunks(filePath, documentID string) ([]Chunk, error) {
+	chunks := []Chunk{}
+	err := cp.StreamChunks(filePath, documentID, func(chunk Chunk) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to read CSV file: %w", err)
+		return nil, err
 	}
 
-	if len(records) == 0 {
-		return []Chunk{}, nil
-	}
+	return chunks, nil
+}
 
+// StreamChunks reads a CSV file one record at a time and passes each chunk to emit as soon
+// as it is complete: a header chunk followed by batches of labelled rows
+func (cp *CSVParser) StreamChunks(filePath, _ string, emit func(Chunk) error) error {
 	// Use a default chunker if none provided
 	if cp.chunker == nil {
 		cp.chunker = NewTextChunker(256, 38) // Use optimized defaults
 	}
 
-	var chunks []Chunk
-	chunkIndex := 0
-	header := records[0]
-
-	// Create header chunk
-	headerText := fmt.Sprintf("CSV Document Headers: %s", strings.Join(header, " | "))
-	headerChunk := Chunk{
-		Text:       headerText,
-		Index:      chunkIndex,
-		StartPos:   0,
-		EndPos:     len(headerText),
-		TokenCount: cp.chunker.EstimateTokenCount(headerText),
-		ChunkType:  "csv_header",
+	out := &chunkEmitter{emit: emit}
+	batch := &rowBatcher{
+		out:       out,
+		chunker:   cp.chunker,
+		maxTokens: 200, // Smaller chunks for tabular data
+		chunkType: "csv_rows",
 	}
-	chunks = append(chunks, headerChunk)
-	chunkIndex++
-
-	// Group rows into optimally sized chunks
-	var currentChunk strings.Builder
-	var rowsInChunk []int
-	estimatedTokens := 0
-
-	for i, record := range records[1:] {
-		rowNum := i + 1
-		rowText := fmt.Sprintf("Row %d: ", rowNum)
-
-		// Create meaningful row representation
-		for j, cell := range record {
-			if j < len(header) {
-				rowText += fmt.Sprintf("%s: %s", header[j], cell)
-			} else {
-				rowText += fmt.Sprintf("Column%d: %s", j+1, cell)
-			}
-			if j < len(record)-1 {
-				rowText += " | "
-			}
-		}
-		rowText += "\n"
-
-		rowTokens := cp.chunker.EstimateTokenCount(rowText)
-
-		// If adding this row would exceed chunk size, create a chunk
-		if estimatedTokens+rowTokens > 200 && len(rowsInChunk) > 0 { // Smaller chunks for tabular data
-			chunk := Chunk{
-				Text:       currentChunk.String(),
-				Index:      chunkIndex,
-				StartPos:   0,
-				EndPos:     currentChunk.Len(),
-				TokenCount: estimatedTokens,
-				ChunkType:  "csv_rows",
-			}
-			chunks = append(chunks, chunk)
-			chunkIndex++
-
-			// Reset for next chunk
-			currentChunk.Reset()
-			rowsInChunk = []int{}
-			estimatedTokens = 0
-		}
+	var header []string
 
-		// Add row to current chunk
-		currentChunk.WriteString(rowText)
-		rowsInChunk = append(rowsInChunk, rowNum)
-		estimatedTokens += rowTokens
-	}
+	err := cp.eachRecord(filePath, func(record []string) error {
+		header = record
 
-	// Add final chunk if there's content
-	if currentChunk.Len() > 0 {
-		chunk := Chunk{
-			Text:       currentChunk.String(),
-			Index:      chunkIndex,
+		// Create header chunk
+		headerText := fmt.Sprintf("CSV Document Headers: %s", strings.Join(header, " | "))
+		return out.add(Chunk{
+			Text:       headerText,
 			StartPos:   0,
-			EndPos:     currentChunk.Len(),
-			TokenCount: estimatedTokens,
-			ChunkType:  "csv_rows",
-		}
-		chunks = append(chunks, chunk)
+			EndPos:     len(headerText),
+			TokenCount: cp.chunker.EstimateTokenCount(headerText),
+			ChunkType:  "csv_header",
+		})
+	}, func(rowNum int, record []string) error {
+		return batch.add(cp.formatRow(rowNum, header, record) + "\n")
+	})
+	if err != nil {
+		return err
 	}
 
-	return chunks, nil
+	// Add final chunk if there's content
+	return batch.flush()
+}
+
+// StreamTables writes the CSV file to w as a single table named "CSV" whose rows are
+// numbered as in its chunks
+func (cp *CSVParser) StreamTables(filePath string, w TableWriter) error {
+	return cp.eachRecord(filePath, func(record []string) error {
+		return w.BeginTable("CSV", record)
+	}, func(rowNum int, record []string) error {
+		return w.WriteRow(TableRow{Number: rowNum, Cells: record})
+	})
 }
 
 // SupportedExtensions returns the file extensions this parser supports
@@ -0,0 +1,122 @@
+package lilrag
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// maxStreamingHeap bounds the live heap growth while a large file is streamed
+const maxStreamingHeap = 16 << 20
+
+// heapPeak tracks the largest growth of the live heap while a parser streams chunks
+type heapPeak struct {
+	baseline uint64
+	peak     uint64
+	calls    int
+}
+
+func newHeapPeak() *heapPeak {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return &heapPeak{baseline: stats.HeapAlloc}
+}
+
+// sample records the live heap size on the first call and every 500 calls after it
+func (hp *heapPeak) sample() {
+	hp.calls++
+	if hp.calls%500 != 1 {
+		return
+	}
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	if stats.HeapAlloc > hp.baseline && stats.HeapAlloc-hp.baseline > hp.peak {
+		hp.peak = stats.HeapAlloc - hp.baseline
+	}
+}
+
+func TestCSVParser_StreamChunks(t *testing.T) {
+	filePath := writeTestFile(t, "spend.csv", testSpendCSV)
+
+	var streamed []Chunk
+	err := NewCSVParser().StreamChunks(filePath, "spend", func(chunk Chunk) error {
+		streamed = append(streamed, chunk)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to stream chunks: %v", err)
+	}
+	if len(streamed) != 2 || streamed[0].ChunkType != "csv_header" || streamed[1].Index != 1 ||
+		!strings.Contains(streamed[1].Text, "Row 3: Vendor: Acme | Quarter: Q2 | Amount: 99 | ZIP: 02139") {
+		t.Errorf("Unexpected chunks %+v", streamed)
+	}
+
+	// Errors from emit stop the parser
+	stop := errors.New("stop")
+	err = NewCSVParser().StreamChunks(filePath, "spend", func(Chunk) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Errorf("Expected the emit error, got %v", err)
+	}
+}
+
+// writeLargeCSV writes a CSV file with a header row and the given number of data rows
+func writeLargeCSV(t *testing.T, rows int) string {
+	t.Helper()
+
+	filePath := filepath.Join(t.TempDir(), "large.csv")
+	file, err := os.Create(filePath)
+	if err != nil {
+		t.Fatalf("Failed to create CSV: %v", err)
+	}
+	defer file.Close()
+
+	out := bufio.NewWriter(file)
+	fmt.Fprintln(out, "id,vendor,region,amount,notes")
+	for i := 1; i <= rows; i++ {
+		fmt.Fprintf(out, "%d,Vendor %d,Region %d,%d.%02d,\"Invoice %d for services, paid on time\"\n",
+			i, i%977, i%13, i*7, i%100, i)
+	}
+	if err := out.Flush(); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+	return filePath
+}
+
+func TestCSVParser_StreamChunksLargeFile(t *testing.T) {
+	const rows = 400000
+	filePath := writeLargeCSV(t, rows)
+	info, err := os.Stat(filePath)
+	if err != nil {
+		t.Fatalf("Failed to stat CSV: %v", err)
+	}
+
+	heap := newHeapPeak()
+	var chunks int
+	var last string
+	err = NewCSVParser().StreamChunks(filePath, "large", func(chunk Chunk) error {
+		if chunk.Index != chunks {
+			t.Fatalf("Expected chunk %d, got %d", chunks, chunk.Index)
+		}
+		chunks++
+		last = chunk.Text
+		heap.sample()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to stream chunks: %v", err)
+	}
+
+	if !strings.Contains(last, fmt.Sprintf("Row %d: id: %d", rows, rows)) {
+		t.Errorf("Expected the last chunk to end with the last row, got %q", last)
+	}
+	if heap.peak > maxStreamingHeap || int64(heap.peak) > info.Size()/2 {
+		t.Errorf("Heap grew by %d bytes while streaming a %d byte file in %d chunks", heap.peak, info.Size(), chunks)
+	}
+}
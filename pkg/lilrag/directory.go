@@ -0,0 +1,221 @@
+package lilrag
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Statuses of a file found while indexing a directory
+const (
+	FileStatusIndexed = "indexed" // Parsed and indexed
+	FileStatusSkipped = "skipped" // Binary, unsupported or not a regular file
+	FileStatusFailed  = "failed"  // Could not be parsed or indexed
+)
+
+// DirectoryOptions selects the files of a directory to index. Globs use the
+// .lilragignore syntax and are matched against paths relative to the directory.
+type DirectoryOptions struct {
+	Include  []string // Only index files matching one of these globs, e.g. "*.md" or "docs/**"
+	Exclude  []string // Skip files and directories matching these globs
+	IDPrefix string   // Prefix of the document IDs derived from relative paths
+}
+
+// DirectoryFile is the outcome of indexing one file of a directory
+type DirectoryFile struct {
+	Path   string `json:"path"` // Slash-separated path relative to the directory
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// DirectoryReport lists the files of a directory that were considered for indexing
+type DirectoryReport struct {
+	Root  string          `json:"root"`
+	Files []DirectoryFile `json:"files"`
+}
+
+// Count returns the number of files with a status
+func (r *DirectoryReport) Count(status string) int {
+	count := 0
+	for _, file := range r.Files {
+		if file.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// DirectoryFileID derives the document ID of a file from its path relative to the indexed
+// directory, e.g. "docs/Setup Guide.md" gives "docs-Setup-Guide-md", or
+// "wiki-docs-Setup-Guide-md" with the prefix "wiki"
+func DirectoryFileID(prefix, relPath string) string {
+	if prefix != "" {
+		return ArchiveEntryID(prefix, relPath)
+	}
+	return strings.Trim(archiveIDReplacer.ReplaceAllString(relPath, "-"), "-")
+}
+
+// IndexDirectory indexes the files below root, each as its own document with an ID
+// derived from its relative path. Hidden files and directories, paths excluded by
+// .lilragignore files or opts.Exclude, and binary files are skipped; a file that fails
+// to index is reported without stopping the walk.
+func (m *LilRag) IndexDirectory(ctx context.Context, root string, opts DirectoryOptions) (*DirectoryReport, error) {
+	if m.documentHandler == nil {
+		return nil, fmt.Errorf("document handler not initialized")
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("not a directory: %s", root)
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve directory: %w", err)
+	}
+
+	report := &DirectoryReport{Root: root}
+	usedIDs := make(map[string]bool)
+
+	err = walkDirectory(ctx, absRoot, opts, func(rel, filePath string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			report.Files = append(report.Files, DirectoryFile{Path: rel, Status: FileStatusFailed, Reason: walkErr.Error()})
+			return nil
+		}
+
+		file := DirectoryFile{Path: rel}
+		switch {
+		case !d.Type().IsRegular():
+			file.Status, file.Reason = FileStatusSkipped, "not a regular file"
+		case m.documentHandler.DetectDocumentType(filePath) == DocumentTypeUnknown:
+			file.Status, file.Reason = FileStatusSkipped, "binary or unsupported file"
+		default:
+			file.ID = uniqueDirectoryFileID(opts.IDPrefix, rel, usedIDs)
+			usedIDs[file.ID] = true
+
+			if err := m.IndexFile(ctx, filePath, file.ID); err != nil {
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					return ctxErr
+				}
+				file.Status, file.Reason = FileStatusFailed, err.Error()
+			} else {
+				file.Status = FileStatusIndexed
+			}
+		}
+		report.Files = append(report.Files, file)
+		return nil
+	})
+	if err != nil {
+		return report, fmt.Errorf("failed to index directory: %w", err)
+	}
+	return report, nil
+}
+
+// uniqueDirectoryFileID derives the document ID of a file like DirectoryFileID, adding a
+// numeric suffix when the ID is already used by another file
+func uniqueDirectoryFileID(prefix, relPath string, usedIDs map[string]bool) string {
+	id := DirectoryFileID(prefix, relPath)
+	for n := 2; usedIDs[id]; n++ {
+		id = DirectoryFileID(prefix, relPath) + "-" + strconv.Itoa(n)
+	}
+	return id
+}
+
+// walkDirectory calls visit for every file below root that is not hidden, ignored by a
+// .lilragignore file or opts.Exclude, and that matches opts.Include. Paths below root that
+// cannot be read are passed to visit with their error; directories among them are skipped.
+func walkDirectory(
+	ctx context.Context, root string, opts DirectoryOptions,
+	visit func(rel, filePath string, d fs.DirEntry, err error) error,
+) error {
+	include, err := parseGlobs(opts.Include)
+	if err != nil {
+		return err
+	}
+	exclude, err := parseGlobs(opts.Exclude)
+	if err != nil {
+		return err
+	}
+	ignoreFiles := make(map[string]*ignoreRules)
+
+	return filepath.WalkDir(root, func(filePath string, d fs.DirEntry, walkErr error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			rel = ""
+		}
+
+		if walkErr != nil {
+			if rel == "" {
+				return walkErr
+			}
+			if err := visit(rel, filePath, d, walkErr); err != nil {
+				return err
+			}
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if rel != "" {
+			if strings.HasPrefix(d.Name(), ".") ||
+				ignored(ancestorIgnoreRules(ignoreFiles, rel), rel, d.IsDir()) ||
+				matchRules(exclude, rel, d.IsDir(), false) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+
+		if d.IsDir() {
+			rules, err := loadIgnoreFile(filepath.Join(filePath, IgnoreFileName), rel)
+			if err != nil {
+				return err
+			}
+			if rules != nil {
+				ignoreFiles[rel] = rules
+			}
+			return nil
+		}
+		if len(include) > 0 && !matchRules(include, rel, false, false) {
+			return nil
+		}
+		return visit(rel, filePath, d, nil)
+	})
+}
+
+// ancestorIgnoreRules returns the rules of the ignore files in the directories enclosing
+// a path, from the walk root down
+func ancestorIgnoreRules(ignoreFiles map[string]*ignoreRules, relPath string) []*ignoreRules {
+	if len(ignoreFiles) == 0 {
+		return nil
+	}
+	var dirs []string
+	for dir := path.Dir(relPath); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		dirs = append(dirs, dir)
+	}
+	dirs = append(dirs, "")
+
+	var stack []*ignoreRules
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if rules := ignoreFiles[dirs[i]]; rules != nil {
+			stack = append(stack, rules)
+		}
+	}
+	return stack
+}
@@ -0,0 +1,152 @@
+package lilrag
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestTree writes files below a temporary directory, creating parent directories
+func writeTestTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		filePath := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return root
+}
+
+func TestLilRag_IndexDirectory(t *testing.T) {
+	root := writeTestTree(t, map[string]string{
+		"README.md":              "# Wiki\n\nHow the team deploys and operates its services.",
+		"docs/Setup Guide.md":    "# Setup\n\nRun make install on a new laptop.",
+		"docs/faq.txt":           "Rotate the API keys every ninety days.",
+		"docs/drafts/todo.txt":   "Unfinished notes.",
+		"docs/keep.log":          "Deploy log that is explicitly kept.",
+		"build/output.txt":       "Generated output.",
+		"logs/server.log":        "Server log.",
+		"assets/logo.bin":        "\x00\x01\x02\x03\x00\x00",
+		"empty.txt":              "",
+		".git/config":            "[core]",
+		".lilragignore":          "# Generated files\nbuild/\n*.log\n",
+		"docs/.lilragignore":     "drafts\n!keep.log\n",
+		"notes/a-b.txt":          "First note with a colliding ID.",
+		"notes/a/b.txt":          "Second note with a colliding ID.",
+		"vendor/module/file.txt": "Vendored file.",
+	})
+
+	storage := NewMockStorage()
+	if err := storage.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize mock storage: %v", err)
+	}
+	lilRag := &LilRag{
+		storage:         storage,
+		embedder:        NewMockEmbedder(),
+		chunker:         NewTextChunker(256, 38),
+		documentHandler: NewDocumentHandler(NewTextChunker(256, 38)),
+		config:          &Config{},
+	}
+
+	report, err := lilRag.IndexDirectory(context.Background(), root, DirectoryOptions{Exclude: []string{"vendor/"}})
+	if err != nil {
+		t.Fatalf("Failed to index directory: %v", err)
+	}
+
+	statuses := make(map[string]DirectoryFile)
+	for _, file := range report.Files {
+		statuses[file.Path] = file
+	}
+	expected := map[string]string{
+		"README.md":           FileStatusIndexed,
+		"docs/Setup Guide.md": FileStatusIndexed,
+		"docs/faq.txt":        FileStatusIndexed,
+		"docs/keep.log":       FileStatusIndexed,
+		"notes/a-b.txt":       FileStatusIndexed,
+		"notes/a/b.txt":       FileStatusIndexed,
+		"assets/logo.bin":     FileStatusSkipped,
+		"empty.txt":           FileStatusFailed,
+	}
+	for path, status := range expected {
+		if statuses[path].Status != status {
+			t.Errorf("Expected %s to be %s, got %+v", path, status, statuses[path])
+		}
+	}
+	if len(report.Files) != len(expected) {
+		t.Errorf("Expected %d files in the report, got %+v", len(expected), report.Files)
+	}
+
+	if id := statuses["docs/Setup Guide.md"].ID; id != "docs-Setup-Guide-md" {
+		t.Errorf("Expected an ID derived from the relative path, got %q", id)
+	}
+	if statuses["notes/a-b.txt"].ID == statuses["notes/a/b.txt"].ID {
+		t.Errorf("Expected colliding IDs to be made unique, got %q", statuses["notes/a/b.txt"].ID)
+	}
+	if text := storage.documents["docs-faq-txt"]; !strings.Contains(text, "Rotate the API keys") {
+		t.Errorf("Expected docs/faq.txt to be stored, got %q", text)
+	}
+
+	// Include globs and an ID prefix
+	report, err = lilRag.IndexDirectory(context.Background(), root, DirectoryOptions{
+		Include:  []string{"*.md"},
+		IDPrefix: "wiki",
+	})
+	if err != nil {
+		t.Fatalf("Failed to index directory: %v", err)
+	}
+	if report.Count(FileStatusIndexed) != 2 || len(report.Files) != 2 {
+		t.Errorf("Expected only the Markdown files, got %+v", report.Files)
+	}
+	if _, ok := storage.documents["wiki-README-md"]; !ok {
+		t.Error("Expected the prefixed ID wiki-README-md to be stored")
+	}
+
+	if _, err := lilRag.IndexDirectory(context.Background(), filepath.Join(root, "README.md"), DirectoryOptions{}); err == nil {
+		t.Error("Expected an error for a file instead of a directory")
+	}
+}
+
+func TestParseIgnorePattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		matched bool
+	}{
+		{"*.log", "server.log", false, true},
+		{"*.log", "logs/deep/server.log", false, true},
+		{"*.log", "server.log.txt", false, false},
+		{"build/", "build", true, true},
+		{"build/", "build", false, false},
+		{"/docs/*.md", "docs/setup.md", false, true},
+		{"/docs/*.md", "docs/guides/setup.md", false, false},
+		{"docs/**/*.md", "docs/setup.md", false, true},
+		{"docs/**/*.md", "docs/guides/deep/setup.md", false, true},
+		{"**/testdata", "pkg/lilrag/testdata", true, true},
+		{"report-[0-9].csv", "report-7.csv", false, true},
+		{"report-[!0-9].csv", "report-7.csv", false, false},
+		{"file?.txt", "file1.txt", false, true},
+		{`\#notes`, "#notes", false, true},
+	}
+	for _, tt := range cases {
+		rule, err := parseIgnorePattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", tt.pattern, err)
+		}
+		if matched := matchRules([]ignoreRule{rule}, tt.path, tt.isDir, false); matched != tt.matched {
+			t.Errorf("Expected %q matching %s to be %v", tt.pattern, tt.path, tt.matched)
+		}
+	}
+
+	negated, _ := parseGlobs([]string{"*.log", "!keep.log"})
+	if matchRules(negated, "keep.log", false, false) || !matchRules(negated, "other.log", false, false) {
+		t.Error("Expected a negated pattern to re-include a path")
+	}
+}
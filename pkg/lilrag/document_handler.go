@@ -1,25 +1,37 @@
 package lilrag
 
 import (
+	"context"
 	"fmt"
-	"path/filepath"
-	"strings"
 )
 
 // DocumentType represents the type of document being processed
 type DocumentType string
 
 const (
-	DocumentTypePDF     DocumentType = "pdf"
-	DocumentTypeDOCX    DocumentType = "docx"
-	DocumentTypeXLSX    DocumentType = "xlsx"
-	DocumentTypePPTX    DocumentType = "pptx"
-	DocumentTypeHTML    DocumentType = "html"
-	DocumentTypeCSV     DocumentType = "csv"
-	DocumentTypeTXT     DocumentType = "txt"
-	DocumentTypeODT     DocumentType = "odt"
-	DocumentTypeImage   DocumentType = "image"
-	DocumentTypeUnknown DocumentType = "unknown"
+	DocumentTypePDF      DocumentType = "pdf"
+	DocumentTypeDOCX     DocumentType = "docx"
+	DocumentTypeXLSX     DocumentType = "xlsx"
+	DocumentTypePPTX     DocumentType = "pptx"
+	DocumentTypeHTML     DocumentType = "html"
+	DocumentTypeCSV      DocumentType = "csv"
+	DocumentTypeTXT      DocumentType = "txt"
+	DocumentTypeMarkdown DocumentType = "markdown"
+	DocumentTypeCode     DocumentType = "code"
+	DocumentTypeODT      DocumentType = "odt"
+	DocumentTypeODS      DocumentType = "ods"
+	DocumentTypeODP      DocumentType = "odp"
+	DocumentTypeEPUB     DocumentType = "epub"
+	DocumentTypeEmail    DocumentType = "email"
+	DocumentTypeMBOX     DocumentType = "mbox"
+	DocumentTypeJSON     DocumentType = "json"
+	DocumentTypeJSONL    DocumentType = "jsonl"
+	DocumentTypeYAML     DocumentType = "yaml"
+	DocumentTypeXML      DocumentType = "xml"
+	DocumentTypeNotebook DocumentType = "notebook"
+	DocumentTypeArchive  DocumentType = "archive"
+	DocumentTypeImage    DocumentType = "image"
+	DocumentTypeUnknown  DocumentType = "unknown"
 )
 
 // DocumentParser is the interface that all document parsers must implement
@@ -37,6 +49,31 @@ type DocumentParser interface {
 	GetDocumentType() DocumentType
 }
 
+// MetadataParser is implemented by parsers that extract document-level metadata, such as
+// email headers, which is stored with the document and can be used to filter searches
+type MetadataParser interface {
+	ParseMetadata(filePath string) (map[string]string, error)
+}
+
+// TableParser is implemented by parsers of tabular documents, whose sheets are also
+// loaded into SQL tables so they can be queried during chat. Sheets are written to w
+// row by row as they are read.
+type TableParser interface {
+	StreamTables(filePath string, w TableWriter) error
+}
+
+// ContextParser is implemented by parsers whose chunking can call out to an embedding
+// model, so indexing can cancel it
+type ContextParser interface {
+	ParseWithChunksContext(ctx context.Context, filePath, documentID string) ([]Chunk, error)
+}
+
+// ChunkStreamer is implemented by parsers that can pass chunks on while the file is
+// still being read, so memory stays bounded for very large files
+type ChunkStreamer interface {
+	StreamChunks(filePath, documentID string, emit func(Chunk) error) error
+}
+
 // DocumentHandler manages all document parsers and routes files to appropriate handlers
 type DocumentHandler struct {
 	parsers        map[DocumentType]DocumentParser
@@ -84,16 +121,42 @@ func (dh *DocumentHandler) registerDefaultParsers() {
 	dh.RegisterParser(DocumentTypePDF, NewPDFParser())
 
 	// Text parser (already exists as fallback)
-	dh.RegisterParser(DocumentTypeTXT, NewTextParser())
+	dh.RegisterParser(DocumentTypeTXT, NewTextParserWithChunker(dh.chunker))
+	dh.RegisterParser(DocumentTypeMarkdown, NewMarkdownParserWithChunker(dh.chunker))
+
+	// Source code parser (Go, Python, JavaScript/TypeScript, Java, C/C++, Rust, ...)
+	dh.RegisterParser(DocumentTypeCode, NewCodeParserWithChunker(dh.chunker))
 
 	// Microsoft Office document parsers
-	dh.RegisterParser(DocumentTypeDOCX, NewDOCXParser())
+	dh.RegisterParser(DocumentTypeDOCX, NewDOCXParserWithChunker(dh.chunker))
 	dh.RegisterParser(DocumentTypeXLSX, NewXLSXParser())
-	// dh.RegisterParser(DocumentTypePPTX, NewPPTXParser()) // TODO: Implement PPTX parser
+	dh.RegisterParser(DocumentTypePPTX, NewPPTXParserWithChunker(dh.chunker))
+
+	// OpenDocument (LibreOffice) parsers
+	dh.RegisterParser(DocumentTypeODT, NewODTParser())
+	dh.RegisterParser(DocumentTypeODS, NewODSParser())
+	dh.RegisterParser(DocumentTypeODP, NewODPParserWithChunker(dh.chunker))
 
 	// Web and data format parsers
 	dh.RegisterParser(DocumentTypeHTML, NewHTMLParser())
 	dh.RegisterParser(DocumentTypeCSV, NewCSVParser())
+	dh.RegisterParser(DocumentTypeJSON, NewJSONParser())
+	dh.RegisterParser(DocumentTypeJSONL, NewJSONLParser())
+	dh.RegisterParser(DocumentTypeYAML, NewYAMLParser())
+	dh.RegisterParser(DocumentTypeXML, NewXMLParser())
+
+	// E-book parsers
+	dh.RegisterParser(DocumentTypeEPUB, NewEPUBParserWithChunker(dh.chunker))
+
+	// Email parsers
+	dh.RegisterParser(DocumentTypeEmail, NewEMLParserWithChunker(dh.chunker))
+	dh.RegisterParser(DocumentTypeMBOX, NewMBOXParserWithChunker(dh.chunker))
+
+	// Jupyter notebook parser
+	dh.RegisterParser(DocumentTypeNotebook, NewNotebookParserWithChunker(dh.chunker))
+
+	// Archive parser routing entries back through this handler
+	dh.RegisterParser(DocumentTypeArchive, NewArchiveParser(dh, DefaultArchiveLimits()))
 
 	// Image parser with OCR capabilities
 	dh.RegisterParser(DocumentTypeImage, NewImageParserWithTimeout(
@@ -103,9 +166,6 @@ func (dh *DocumentHandler) registerDefaultParsers() {
 		dh.timeoutSeconds*10,
 		dh.imageMaxSize,
 	))
-
-	// Future parsers will be added here
-	// dh.RegisterParser(DocumentTypeODT, NewODTParser())
 }
 
 // RegisterParser registers a new document parser
@@ -113,32 +173,10 @@ func (dh *DocumentHandler) RegisterParser(docType DocumentType, parser DocumentP
 	dh.parsers[docType] = parser
 }
 
-// DetectDocumentType determines the document type from file extension
+// DetectDocumentType determines the document type from the file content, using the
+// extension as a hint. Paths that cannot be read are detected by extension alone.
 func (dh *DocumentHandler) DetectDocumentType(filePath string) DocumentType {
-	ext := strings.ToLower(filepath.Ext(filePath))
-
-	switch ext {
-	case ".pdf":
-		return DocumentTypePDF
-	case ".docx":
-		return DocumentTypeDOCX
-	case ".xlsx":
-		return DocumentTypeXLSX
-	case ".pptx":
-		return DocumentTypePPTX
-	case ".html", ".htm":
-		return DocumentTypeHTML
-	case ".csv":
-		return DocumentTypeCSV
-	case ".txt", ".md":
-		return DocumentTypeTXT
-	case ".odt":
-		return DocumentTypeODT
-	case ".jpg", ".jpeg", ".png", ".gif", ".bmp", ".webp", ".tiff", ".tif":
-		return DocumentTypeImage
-	default:
-		return DocumentTypeUnknown
-	}
+	return dh.DetectFileType(filePath).DocType
 }
 
 // ParseFile parses any supported document file
@@ -159,6 +197,12 @@ func (dh *DocumentHandler) ParseFile(filePath string) (string, error) {
 
 // ParseFileWithChunks parses and chunks any supported document file
 func (dh *DocumentHandler) ParseFileWithChunks(filePath, documentID string) ([]Chunk, error) {
+	return dh.ParseFileWithChunksContext(context.Background(), filePath, documentID)
+}
+
+// ParseFileWithChunksContext parses and chunks any supported document file, passing ctx
+// to parsers that implement ContextParser
+func (dh *DocumentHandler) ParseFileWithChunksContext(ctx context.Context, filePath, documentID string) ([]Chunk, error) {
 	docType := dh.DetectDocumentType(filePath)
 
 	parser, exists := dh.parsers[docType]
@@ -170,9 +214,117 @@ func (dh *DocumentHandler) ParseFileWithChunks(filePath, documentID string) ([]C
 		}
 	}
 
+	if contextParser, ok := parser.(ContextParser); ok {
+		return contextParser.ParseWithChunksContext(ctx, filePath, documentID)
+	}
 	return parser.ParseWithChunks(filePath, documentID)
 }
 
+// CanStreamChunks reports whether the parser of a file implements ChunkStreamer
+func (dh *DocumentHandler) CanStreamChunks(filePath string) bool {
+	_, ok := dh.parsers[dh.DetectDocumentType(filePath)].(ChunkStreamer)
+	return ok
+}
+
+// StreamFileChunks parses and chunks any supported document file, passing each chunk to
+// emit in order. Parsers that cannot stream are parsed in full first.
+func (dh *DocumentHandler) StreamFileChunks(
+	ctx context.Context, filePath, documentID string, emit func(Chunk) error,
+) error {
+	if streamer, ok := dh.parsers[dh.DetectDocumentType(filePath)].(ChunkStreamer); ok {
+		return streamer.StreamChunks(filePath, documentID, emit)
+	}
+
+	chunks, err := dh.ParseFileWithChunksContext(ctx, filePath, documentID)
+	if err != nil {
+		return err
+	}
+	for _, chunk := range chunks {
+		if err := emit(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseFileMetadata returns the document-level metadata of a file: the detected
+// "mime_type" plus whatever its parser extracts
+func (dh *DocumentHandler) ParseFileMetadata(filePath string) (map[string]string, error) {
+	fileType := dh.DetectFileType(filePath)
+
+	metadata := make(map[string]string)
+	if parser, ok := dh.parsers[fileType.DocType].(MetadataParser); ok {
+		parsed, err := parser.ParseMetadata(filePath)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range parsed {
+			metadata[key] = value
+		}
+	}
+	if fileType.MIMEType != "" {
+		metadata["mime_type"] = fileType.MIMEType
+	}
+	return metadata, nil
+}
+
+// StreamFileTables writes the sheets of a tabular document to w; other documents write
+// nothing
+func (dh *DocumentHandler) StreamFileTables(filePath string, w TableWriter) error {
+	parser, ok := dh.parsers[dh.DetectDocumentType(filePath)].(TableParser)
+	if !ok {
+		return nil
+	}
+	return parser.StreamTables(filePath, w)
+}
+
+// ParseFileTables returns the sheets of a tabular document, or nil for other documents
+func (dh *DocumentHandler) ParseFileTables(filePath string) ([]Table, error) {
+	collector := &tableCollector{}
+	if err := dh.StreamFileTables(filePath, collector); err != nil {
+		return nil, err
+	}
+	return collector.tables, nil
+}
+
+// EnableEmailAttachments makes the email parsers index attachments in supported formats
+// as part of the message
+func (dh *DocumentHandler) EnableEmailAttachments() {
+	dh.RegisterParser(DocumentTypeEmail, NewEMLParserWithAttachments(dh.chunker, dh))
+	dh.RegisterParser(DocumentTypeMBOX, NewMBOXParserWithAttachments(dh.chunker, dh))
+}
+
+// EnableDOCXNotes makes the DOCX parser index footnotes and comments as separate chunks
+func (dh *DocumentHandler) EnableDOCXNotes() {
+	dh.RegisterParser(DocumentTypeDOCX, NewDOCXParserWithNotes(dh.chunker))
+}
+
+// EnableHTMLFullPage makes the HTML parser read every element of a page instead of only
+// its main content
+func (dh *DocumentHandler) EnableHTMLFullPage() {
+	dh.RegisterParser(DocumentTypeHTML, NewHTMLParserWithFullPage())
+}
+
+// EnableNotebookOutputs makes the notebook parser index the text outputs of code cells
+func (dh *DocumentHandler) EnableNotebookOutputs() {
+	dh.RegisterParser(DocumentTypeNotebook, NewNotebookParserWithOutputs(dh.chunker))
+}
+
+// SetArchiveLimits replaces the zip-bomb limits used when parsing archives
+func (dh *DocumentHandler) SetArchiveLimits(limits ArchiveLimits) {
+	dh.RegisterParser(DocumentTypeArchive, NewArchiveParser(dh, limits))
+}
+
+// ParseArchive parses every supported entry of an archive as its own document with an
+// ID derived from documentID and the entry path
+func (dh *DocumentHandler) ParseArchive(filePath, documentID string) ([]ArchiveEntry, error) {
+	parser, ok := dh.parsers[DocumentTypeArchive].(*ArchiveParser)
+	if !ok {
+		return nil, fmt.Errorf("no archive parser registered")
+	}
+	return parser.ParseEntries(filePath, documentID)
+}
+
 // GetSupportedFormats returns all supported document formats
 func (dh *DocumentHandler) GetSupportedFormats() map[DocumentType][]string {
 	formats := make(map[DocumentType][]string)
@@ -1,15 +1,38 @@
 package lilrag
 
 import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
 	"fmt"
+	"io"
+	"regexp"
+	"strconv"
 	"strings"
-
-	"github.com/nguyenthenguyen/docx"
 )
 
-// DOCXParser handles Microsoft Word .docx files
+// DOCXParser handles Microsoft Word .docx files. The body is read from word/document.xml:
+// heading styles become Markdown headings, list paragraphs keep their numbering and tables
+// become Markdown tables, so the document is chunked along its section hierarchy.
 type DOCXParser struct {
 	chunker *TextChunker
+	notes   bool // Also emit footnotes and comments as chunks
+}
+
+// DOCXDocument is the body of a Word document rendered as Markdown, with its notes
+type DOCXDocument struct {
+	Markdown  string
+	Footnotes []DOCXNote
+	Comments  []DOCXNote
+}
+
+// DOCXNote is a footnote or comment with the heading path of the paragraph that
+// references it
+type DOCXNote struct {
+	ID          string
+	Author      string // Comment author; empty for footnotes
+	Text        string
+	HeadingPath string
 }
 
 // NewDOCXParser creates a new DOCX parser
@@ -17,21 +40,93 @@ func NewDOCXParser() *DOCXParser {
 	return &DOCXParser{}
 }
 
-// Parse extracts text content from a DOCX file
-func (dp *DOCXParser) Parse(filePath string) (string, error) {
-	r, err := docx.ReadDocxFile(filePath)
+// NewDOCXParserWithChunker creates a DOCX parser that chunks sections with chunker
+func NewDOCXParserWithChunker(chunker *TextChunker) *DOCXParser {
+	return &DOCXParser{chunker: chunker}
+}
+
+// NewDOCXParserWithNotes creates a DOCX parser that also emits footnotes and comments
+// as "docx_footnote" and "docx_comment" chunks
+func NewDOCXParserWithNotes(chunker *TextChunker) *DOCXParser {
+	return &DOCXParser{chunker: chunker, notes: true}
+}
+
+// ReadDocument reads the body, footnotes and comments of a DOCX file
+func (dp *DOCXParser) ReadDocument(filePath string) (*DOCXDocument, error) {
+	r, err := zip.OpenReader(filePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to open DOCX file: %w", err)
+		return nil, fmt.Errorf("failed to open DOCX file: %w", err)
 	}
 	defer r.Close()
 
-	docData := r.Editable()
-	return docData.GetContent(), nil
+	files := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		files[f.Name] = f
+	}
+
+	body, ok := files["word/document.xml"]
+	if !ok {
+		return nil, fmt.Errorf("DOCX file has no word/document.xml")
+	}
+	rc, err := body.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DOCX body: %w", err)
+	}
+	defer rc.Close()
+
+	reader := &docxBodyReader{
+		headingStyles: readDOCXHeadingStyles(files),
+		numbering:     readDOCXNumbering(files),
+		notePaths:     make(map[string]string),
+	}
+	if err := reader.read(xml.NewDecoder(rc)); err != nil {
+		return nil, fmt.Errorf("failed to read DOCX body: %w", err)
+	}
+
+	doc := &DOCXDocument{Markdown: reader.markdown()}
+	for _, note := range readDOCXNotes(files, "word/footnotes.xml") {
+		note.HeadingPath = reader.notePaths["footnote:"+note.ID]
+		doc.Footnotes = append(doc.Footnotes, note)
+	}
+	for _, note := range readDOCXNotes(files, "word/comments.xml") {
+		note.HeadingPath = reader.notePaths["comment:"+note.ID]
+		doc.Comments = append(doc.Comments, note)
+	}
+	return doc, nil
 }
 
-// ParseWithChunks extracts and chunks content from a DOCX file
-func (dp *DOCXParser) ParseWithChunks(filePath, _ string) ([]Chunk, error) {
-	content, err := dp.Parse(filePath)
+// Parse extracts the text of a DOCX file as Markdown. Footnote and comment text is
+// appended when the parser includes notes.
+func (dp *DOCXParser) Parse(filePath string) (string, error) {
+	doc, err := dp.ReadDocument(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	var content strings.Builder
+	content.WriteString(doc.Markdown)
+	if dp.notes {
+		for _, note := range doc.Footnotes {
+			content.WriteString("\n\n[^" + note.ID + "]: " + note.Text)
+		}
+		for _, note := range doc.Comments {
+			content.WriteString("\n\n" + docxCommentText(note))
+		}
+	}
+
+	return strings.TrimSpace(content.String()), nil
+}
+
+// ParseWithChunks chunks the document along its heading hierarchy as "docx_section"
+// chunks. With notes enabled, every footnote and comment follows as its own chunk with
+// the heading path of the paragraph that references it.
+func (dp *DOCXParser) ParseWithChunks(filePath, documentID string) ([]Chunk, error) {
+	return dp.ParseWithChunksContext(context.Background(), filePath, documentID)
+}
+
+// ParseWithChunksContext is ParseWithChunks with ctx passed to the chunker
+func (dp *DOCXParser) ParseWithChunksContext(ctx context.Context, filePath, _ string) ([]Chunk, error) {
+	doc, err := dp.ReadDocument(filePath)
 	if err != nil {
 		return nil, err
 	}
@@ -41,20 +136,36 @@ func (dp *DOCXParser) ParseWithChunks(filePath, _ string) ([]Chunk, error) {
 		dp.chunker = NewTextChunker(320, 48) // Slightly larger chunks for prose content
 	}
 
-	// Clean up the content
-	content = dp.cleanContent(content)
-
-	// Detect content type for better chunking
-	contentType := dp.detectContentType(content)
+	chunks := (&MarkdownParser{chunker: dp.chunker}).ChunkMarkdownContext(ctx, doc.Markdown)
+	for i := range chunks {
+		chunks[i].ChunkType = "docx_section"
+	}
 
-	// Chunk the content based on type
-	chunks := dp.chunkByContentType(content, contentType)
+	if dp.notes {
+		addNote := func(text, chunkType string, note DOCXNote, metadata map[string]string) {
+			for _, chunk := range dp.chunker.ChunkTextContext(ctx, text) {
+				chunk.Index = len(chunks)
+				chunk.ChunkType = chunkType
+				chunk.HeadingPath = note.HeadingPath
+				chunk.Metadata = metadata
+				chunks = append(chunks, chunk)
+			}
+		}
+		for _, note := range doc.Footnotes {
+			addNote("Footnote "+note.ID+": "+note.Text, "docx_footnote", note,
+				map[string]string{"footnote_id": note.ID})
+		}
+		for _, note := range doc.Comments {
+			metadata := map[string]string{"comment_id": note.ID}
+			if note.Author != "" {
+				metadata["comment_author"] = note.Author
+			}
+			addNote(docxCommentText(note), "docx_comment", note, metadata)
+		}
+	}
 
-	// Update chunk metadata
-	for i, chunk := range chunks {
-		chunk.Index = i
-		chunk.ChunkType = fmt.Sprintf("docx_%s", contentType)
-		chunks[i] = chunk
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("no readable content found in DOCX")
 	}
 
 	return chunks, nil
@@ -276,3 +387,504 @@ func (dp *DOCXParser) SupportedExtensions() []string {
 func (dp *DOCXParser) GetDocumentType() DocumentType {
 	return DocumentTypeDOCX
 }
+
+// docxCommentText renders a comment with its author
+func docxCommentText(note DOCXNote) string {
+	if note.Author == "" {
+		return "Comment: " + note.Text
+	}
+	return "Comment by " + note.Author + ": " + note.Text
+}
+
+// docxSkippedElements hold text that is not part of the current body: the previous
+// properties of tracked changes and the fallback copy of alternate content
+var docxSkippedElements = map[string]bool{
+	"pPrChange": true,
+	"rPrChange": true,
+	"Fallback":  true,
+}
+
+// docxParagraph accumulates a w:p element
+type docxParagraph struct {
+	style      string
+	outlineLvl string
+	numID      string
+	ilvl       string
+	text       strings.Builder
+	noteRefs   []string // "footnote:<id>" and "comment:<id>" references
+}
+
+// docxTable collects the rows of a w:tbl element
+type docxTable struct {
+	rows [][]string
+	row  []string
+	cell []string
+}
+
+// docxBlock is a rendered paragraph, list item or table
+type docxBlock struct {
+	text string
+	list bool
+}
+
+// docxBodyReader renders word/document.xml as Markdown blocks
+type docxBodyReader struct {
+	headingStyles map[string]int
+	numbering     *docxNumbering
+	blocks        []docxBlock
+	headings      []docxHeading
+	notePaths     map[string]string // Heading path of each referenced note
+}
+
+type docxHeading struct {
+	level int
+	title string
+}
+
+func (br *docxBodyReader) read(decoder *xml.Decoder) error {
+	var (
+		paragraphs []*docxParagraph // Text boxes nest paragraphs inside paragraphs
+		tables     []*docxTable
+		inText     bool
+		skip       int
+	)
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if docxSkippedElements[t.Name.Local] {
+				skip++
+			}
+			if skip > 0 {
+				continue
+			}
+
+			var p *docxParagraph
+			if len(paragraphs) > 0 {
+				p = paragraphs[len(paragraphs)-1]
+			}
+			switch t.Name.Local {
+			case "p":
+				paragraphs = append(paragraphs, &docxParagraph{})
+			case "pStyle":
+				if p != nil {
+					p.style = odfAttr(t, "val")
+				}
+			case "outlineLvl":
+				if p != nil {
+					p.outlineLvl = odfAttr(t, "val")
+				}
+			case "numId":
+				if p != nil {
+					p.numID = odfAttr(t, "val")
+				}
+			case "ilvl":
+				if p != nil {
+					p.ilvl = odfAttr(t, "val")
+				}
+			case "t":
+				inText = p != nil
+			case "tab", "br", "cr":
+				if p != nil {
+					p.text.WriteString(" ")
+				}
+			case "noBreakHyphen":
+				if p != nil {
+					p.text.WriteString("-")
+				}
+			case "footnoteReference":
+				if p != nil {
+					id := odfAttr(t, "id")
+					p.text.WriteString("[^" + id + "]")
+					p.noteRefs = append(p.noteRefs, "footnote:"+id)
+				}
+			case "commentReference":
+				if p != nil {
+					p.noteRefs = append(p.noteRefs, "comment:"+odfAttr(t, "id"))
+				}
+			case "tbl":
+				tables = append(tables, &docxTable{})
+			case "tr":
+				if len(tables) > 0 {
+					tables[len(tables)-1].row = nil
+				}
+			case "tc":
+				if len(tables) > 0 {
+					tables[len(tables)-1].cell = nil
+				}
+			}
+		case xml.CharData:
+			if inText && skip == 0 {
+				paragraphs[len(paragraphs)-1].text.Write(t)
+			}
+		case xml.EndElement:
+			if docxSkippedElements[t.Name.Local] {
+				skip--
+				continue
+			}
+			if skip > 0 {
+				continue
+			}
+
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				if len(paragraphs) == 0 {
+					continue
+				}
+				p := paragraphs[len(paragraphs)-1]
+				paragraphs = paragraphs[:len(paragraphs)-1]
+				if len(tables) > 0 {
+					table := tables[len(tables)-1]
+					if text := strings.Join(strings.Fields(p.text.String()), " "); text != "" {
+						table.cell = append(table.cell, text)
+					}
+					br.recordNotes(p)
+					continue
+				}
+				br.addParagraph(p)
+			case "tc":
+				if len(tables) > 0 {
+					table := tables[len(tables)-1]
+					table.row = append(table.row, strings.Join(table.cell, " "))
+				}
+			case "tr":
+				if len(tables) > 0 {
+					table := tables[len(tables)-1]
+					table.rows = append(table.rows, table.row)
+				}
+			case "tbl":
+				if len(tables) == 0 {
+					continue
+				}
+				table := tables[len(tables)-1]
+				tables = tables[:len(tables)-1]
+				if len(table.rows) == 0 {
+					continue
+				}
+				if len(tables) > 0 {
+					// A nested table is flattened into the text of its parent cell
+					parent := tables[len(tables)-1]
+					for _, row := range table.rows {
+						parent.cell = append(parent.cell, strings.Join(row, " "))
+					}
+					continue
+				}
+				br.blocks = append(br.blocks, docxBlock{text: markdownTable(table.rows)})
+			}
+		}
+	}
+}
+
+// addParagraph renders a body paragraph as a heading, list item or plain paragraph
+func (br *docxBodyReader) addParagraph(p *docxParagraph) {
+	text := strings.Join(strings.Fields(p.text.String()), " ")
+	if text == "" {
+		return
+	}
+
+	level := br.headingStyles[p.style]
+	if lvl, err := strconv.Atoi(p.outlineLvl); err == nil && lvl < 9 {
+		level = lvl + 1
+	}
+
+	switch {
+	case level > 0:
+		for len(br.headings) > 0 && br.headings[len(br.headings)-1].level >= level {
+			br.headings = br.headings[:len(br.headings)-1]
+		}
+		br.headings = append(br.headings, docxHeading{level: level, title: text})
+		br.blocks = append(br.blocks, docxBlock{text: strings.Repeat("#", min(level, 6)) + " " + text})
+	case p.numID != "" && p.numID != "0":
+		ilvl, _ := strconv.Atoi(p.ilvl)
+		label := br.numbering.label(p.numID, ilvl)
+		br.blocks = append(br.blocks, docxBlock{text: strings.Repeat("  ", ilvl) + label + " " + text, list: true})
+	default:
+		br.blocks = append(br.blocks, docxBlock{text: text})
+	}
+	br.recordNotes(p)
+}
+
+// recordNotes remembers the current heading path for the notes a paragraph references
+func (br *docxBodyReader) recordNotes(p *docxParagraph) {
+	titles := make([]string, len(br.headings))
+	for i, h := range br.headings {
+		titles[i] = h.title
+	}
+	for _, ref := range p.noteRefs {
+		if _, ok := br.notePaths[ref]; !ok {
+			br.notePaths[ref] = strings.Join(titles, " > ")
+		}
+	}
+}
+
+// markdown joins the blocks, keeping consecutive list items together
+func (br *docxBodyReader) markdown() string {
+	var b strings.Builder
+	for i, block := range br.blocks {
+		if i > 0 {
+			if block.list && br.blocks[i-1].list {
+				b.WriteString("\n")
+			} else {
+				b.WriteString("\n\n")
+			}
+		}
+		b.WriteString(block.text)
+	}
+	return b.String()
+}
+
+var docxHeadingStyleRegex = regexp.MustCompile(`(?i)^heading ?([1-9])$`)
+
+// readDOCXHeadingStyles maps paragraph style IDs to heading levels. Localized documents
+// use their own style IDs, so the style name and outline level are checked as well.
+func readDOCXHeadingStyles(files map[string]*zip.File) map[string]int {
+	levels := make(map[string]int)
+	for i := 1; i <= 9; i++ {
+		levels["Heading"+strconv.Itoa(i)] = i
+	}
+
+	var styles struct {
+		Styles []struct {
+			ID   string `xml:"styleId,attr"`
+			Name struct {
+				Val string `xml:"val,attr"`
+			} `xml:"name"`
+			OutlineLvl *struct {
+				Val int `xml:"val,attr"`
+			} `xml:"pPr>outlineLvl"`
+		} `xml:"style"`
+	}
+	if err := readZipXML(files, "word/styles.xml", &styles); err != nil {
+		return levels
+	}
+
+	for _, style := range styles.Styles {
+		if match := docxHeadingStyleRegex.FindStringSubmatch(style.Name.Val); match != nil {
+			levels[style.ID], _ = strconv.Atoi(match[1])
+		} else if style.OutlineLvl != nil && style.OutlineLvl.Val < 9 {
+			levels[style.ID] = style.OutlineLvl.Val + 1
+		}
+	}
+	return levels
+}
+
+// docxLevel is the numbering format of one list level
+type docxLevel struct {
+	start   int
+	format  string // w:numFmt, e.g. "decimal", "lowerLetter" or "bullet"
+	lvlText string // Label template, e.g. "%1." or "%1.%2."
+}
+
+// docxNumbering renders list labels from word/numbering.xml, counting items per list
+type docxNumbering struct {
+	levels   map[string]map[int]docxLevel // numId -> ilvl -> level
+	counters map[string][]int
+}
+
+// readDOCXNumbering reads the list definitions of a document
+func readDOCXNumbering(files map[string]*zip.File) *docxNumbering {
+	numbering := &docxNumbering{
+		levels:   make(map[string]map[int]docxLevel),
+		counters: make(map[string][]int),
+	}
+
+	var doc struct {
+		AbstractNums []struct {
+			ID     string `xml:"abstractNumId,attr"`
+			Levels []struct {
+				ILvl  int `xml:"ilvl,attr"`
+				Start *struct {
+					Val int `xml:"val,attr"`
+				} `xml:"start"`
+				NumFmt struct {
+					Val string `xml:"val,attr"`
+				} `xml:"numFmt"`
+				LvlText struct {
+					Val string `xml:"val,attr"`
+				} `xml:"lvlText"`
+			} `xml:"lvl"`
+		} `xml:"abstractNum"`
+		Nums []struct {
+			ID       string `xml:"numId,attr"`
+			Abstract struct {
+				Val string `xml:"val,attr"`
+			} `xml:"abstractNumId"`
+		} `xml:"num"`
+	}
+	if err := readZipXML(files, "word/numbering.xml", &doc); err != nil {
+		return numbering
+	}
+
+	abstract := make(map[string]map[int]docxLevel)
+	for _, a := range doc.AbstractNums {
+		levels := make(map[int]docxLevel)
+		for _, l := range a.Levels {
+			level := docxLevel{start: 1, format: l.NumFmt.Val, lvlText: l.LvlText.Val}
+			if l.Start != nil {
+				level.start = l.Start.Val
+			}
+			levels[l.ILvl] = level
+		}
+		abstract[a.ID] = levels
+	}
+	for _, num := range doc.Nums {
+		numbering.levels[num.ID] = abstract[num.Abstract.Val]
+	}
+	return numbering
+}
+
+// label advances the counter of a list level and returns the item's label, e.g. "3.",
+// "1.2." or "-" for bullets. Deeper levels restart after an item.
+func (dn *docxNumbering) label(numID string, ilvl int) string {
+	if ilvl < 0 || ilvl > 8 {
+		ilvl = 0
+	}
+	levels := dn.levels[numID]
+	level, ok := levels[ilvl]
+	if !ok || level.format == "bullet" || level.format == "none" {
+		return "-"
+	}
+
+	counters := dn.counters[numID]
+	if counters == nil {
+		counters = make([]int, 9)
+		dn.counters[numID] = counters
+	}
+	if counters[ilvl] == 0 {
+		counters[ilvl] = level.start
+	} else {
+		counters[ilvl]++
+	}
+	for deeper := ilvl + 1; deeper < len(counters); deeper++ {
+		counters[deeper] = 0
+	}
+
+	lvlText := level.lvlText
+	if lvlText == "" {
+		lvlText = "%" + strconv.Itoa(ilvl+1) + "."
+	}
+	for i := ilvl; i >= 0; i-- {
+		placeholder := "%" + strconv.Itoa(i+1)
+		if !strings.Contains(lvlText, placeholder) {
+			continue
+		}
+		value := counters[i]
+		if value == 0 {
+			value = levels[i].start
+		}
+		lvlText = strings.ReplaceAll(lvlText, placeholder, formatDOCXNumber(value, levels[i].format))
+	}
+	return lvlText
+}
+
+// formatDOCXNumber renders a list counter in a w:numFmt format
+func formatDOCXNumber(n int, format string) string {
+	switch format {
+	case "lowerLetter", "upperLetter":
+		var letters []byte
+		for ; n > 0; n = (n - 1) / 26 {
+			letters = append([]byte{byte('a' + (n-1)%26)}, letters...)
+		}
+		if format == "upperLetter" {
+			return strings.ToUpper(string(letters))
+		}
+		return string(letters)
+	case "lowerRoman", "upperRoman":
+		roman := toRoman(n)
+		if format == "lowerRoman" {
+			return strings.ToLower(roman)
+		}
+		return roman
+	default:
+		return strconv.Itoa(n)
+	}
+}
+
+// toRoman renders a positive number as an upper case Roman numeral
+func toRoman(n int) string {
+	values := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	symbols := []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+
+	var b strings.Builder
+	for i, value := range values {
+		for n >= value {
+			b.WriteString(symbols[i])
+			n -= value
+		}
+	}
+	return b.String()
+}
+
+// readDOCXNotes reads the footnotes or comments part of a document in order. The
+// separator footnotes Word adds to every document are skipped.
+func readDOCXNotes(files map[string]*zip.File, name string) []DOCXNote {
+	f, ok := files[name]
+	if !ok {
+		return nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil
+	}
+	defer rc.Close()
+
+	var (
+		notes  []DOCXNote
+		note   *DOCXNote
+		text   strings.Builder
+		depth  int
+		inText bool
+	)
+	decoder := xml.NewDecoder(rc)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return notes
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case depth == 2:
+				note = nil
+				if odfAttr(t, "type") == "" || odfAttr(t, "type") == "normal" {
+					note = &DOCXNote{ID: odfAttr(t, "id"), Author: odfAttr(t, "author")}
+					text.Reset()
+				}
+			case t.Name.Local == "t":
+				inText = true
+			case t.Name.Local == "tab" || t.Name.Local == "br":
+				text.WriteString(" ")
+			}
+		case xml.CharData:
+			if inText && note != nil {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			depth--
+			switch {
+			case t.Name.Local == "t":
+				inText = false
+			case t.Name.Local == "p":
+				text.WriteString(" ")
+			case depth == 1 && note != nil:
+				note.Text = strings.Join(strings.Fields(text.String()), " ")
+				if note.Text != "" {
+					notes = append(notes, *note)
+				}
+				note = nil
+			}
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package lilrag
+
+import (
+	"strings"
+	"testing"
+)
+
+const testDOCXNamespace = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"`
+
+func testDOCXParagraph(style, text string) string {
+	props := ""
+	if style != "" {
+		props = `<w:pPr><w:pStyle w:val="` + style + `"/></w:pPr>`
+	}
+	return `<w:p>` + props + `<w:r><w:t>` + text + `</w:t></w:r></w:p>`
+}
+
+func testDOCXListItem(numID, ilvl, text string) string {
+	return `<w:p><w:pPr><w:numPr><w:ilvl w:val="` + ilvl + `"/><w:numId w:val="` + numID + `"/></w:numPr></w:pPr>` +
+		`<w:r><w:t>` + text + `</w:t></w:r></w:p>`
+}
+
+func testDOCXCell(text string) string {
+	return `<w:tc>` + testDOCXParagraph("", text) + `</w:tc>`
+}
+
+func writeTestDOCX(t *testing.T) string {
+	t.Helper()
+
+	body := testDOCXParagraph("Title1", "Runbook") +
+		testDOCXParagraph("Heading1", "Deployment") +
+		`<w:p><w:r><w:t xml:space="preserve">Deploy with the </w:t></w:r><w:r><w:rPr><w:b/></w:rPr>` +
+		`<w:t>release</w:t></w:r><w:r><w:t xml:space="preserve"> script.</w:t></w:r>` +
+		`<w:r><w:footnoteReference w:id="1"/></w:r></w:p>` +
+		testDOCXListItem("1", "0", "Build the image") +
+		testDOCXListItem("1", "1", "Tag it") +
+		testDOCXListItem("1", "0", "Push the image") +
+		testDOCXListItem("2", "0", "Check the logs") +
+		testDOCXParagraph("Heading2", "Rollback") +
+		`<w:p><w:commentRangeStart w:id="0"/><w:r><w:t>Revert the release tag.</w:t></w:r>` +
+		`<w:r><w:commentReference w:id="0"/></w:r></w:p>` +
+		`<w:tbl><w:tr>` + testDOCXCell("Service") + testDOCXCell("Owner") + `</w:tr>` +
+		`<w:tr>` + testDOCXCell("billing") + testDOCXCell("payments team") + `</w:tr></w:tbl>` +
+		`<w:p><w:pPr><w:pStyle w:val="Heading1"/><w:pPrChange><w:pPr><w:pStyle w:val="Heading3"/></w:pPr></w:pPrChange>` +
+		`</w:pPr><w:r><w:t>Monitoring</w:t></w:r><w:del><w:r><w:delText>Old text</w:delText></w:r></w:del></w:p>` +
+		testDOCXParagraph("", "Dashboards live in Grafana.")
+
+	return writeTestZip(t, "runbook.docx", map[string]string{
+		"word/document.xml": `<w:document ` + testDOCXNamespace + `><w:body>` + body + `</w:body></w:document>`,
+		"word/styles.xml": `<w:styles ` + testDOCXNamespace + `>` +
+			`<w:style w:type="paragraph" w:styleId="Title1"><w:name w:val="heading 1"/></w:style>` +
+			`<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 2"/></w:style>` +
+			`<w:style w:type="paragraph" w:styleId="Heading2"><w:name w:val="heading 3"/></w:style>` +
+			`</w:styles>`,
+		"word/numbering.xml": `<w:numbering ` + testDOCXNamespace + `>` +
+			`<w:abstractNum w:abstractNumId="10">` +
+			`<w:lvl w:ilvl="0"><w:start w:val="1"/><w:numFmt w:val="decimal"/><w:lvlText w:val="%1."/></w:lvl>` +
+			`<w:lvl w:ilvl="1"><w:start w:val="1"/><w:numFmt w:val="lowerLetter"/><w:lvlText w:val="%1.%2)"/></w:lvl>` +
+			`</w:abstractNum>` +
+			`<w:abstractNum w:abstractNumId="20"><w:lvl w:ilvl="0"><w:numFmt w:val="bullet"/>` +
+			`<w:lvlText w:val="•"/></w:lvl></w:abstractNum>` +
+			`<w:num w:numId="1"><w:abstractNumId w:val="10"/></w:num>` +
+			`<w:num w:numId="2"><w:abstractNumId w:val="20"/></w:num></w:numbering>`,
+		"word/footnotes.xml": `<w:footnotes ` + testDOCXNamespace + `>` +
+			`<w:footnote w:type="separator" w:id="-1"><w:p><w:r><w:separator/></w:r></w:p></w:footnote>` +
+			`<w:footnote w:id="1"><w:p><w:r><w:t>The script lives in ops/release.sh.</w:t></w:r></w:p></w:footnote>` +
+			`</w:footnotes>`,
+		"word/comments.xml": `<w:comments ` + testDOCXNamespace + `>` +
+			`<w:comment w:id="0" w:author="Dana"><w:p><w:r><w:t>Also clear the CDN cache.</w:t></w:r></w:p></w:comment>` +
+			`</w:comments>`,
+	})
+}
+
+func TestDOCXParser_ReadDocument(t *testing.T) {
+	doc, err := NewDOCXParser().ReadDocument(writeTestDOCX(t))
+	if err != nil {
+		t.Fatalf("Failed to read DOCX: %v", err)
+	}
+
+	expected := "# Runbook\n\n" +
+		"## Deployment\n\n" +
+		"Deploy with the release script.[^1]\n\n" +
+		"1. Build the image\n  1.a) Tag it\n2. Push the image\n- Check the logs\n\n" +
+		"### Rollback\n\n" +
+		"Revert the release tag.\n\n" +
+		"| Service | Owner |\n| --- | --- |\n| billing | payments team |\n\n" +
+		"## Monitoring\n\n" +
+		"Dashboards live in Grafana."
+	if doc.Markdown != expected {
+		t.Errorf("Unexpected markdown:\n%s\nexpected:\n%s", doc.Markdown, expected)
+	}
+
+	if len(doc.Footnotes) != 1 || doc.Footnotes[0].ID != "1" ||
+		doc.Footnotes[0].HeadingPath != "Runbook > Deployment" {
+		t.Errorf("Unexpected footnotes %+v", doc.Footnotes)
+	}
+	if len(doc.Comments) != 1 || doc.Comments[0].Author != "Dana" ||
+		doc.Comments[0].HeadingPath != "Runbook > Deployment > Rollback" {
+		t.Errorf("Unexpected comments %+v", doc.Comments)
+	}
+}
+
+func TestDOCXParser_ParseWithChunks(t *testing.T) {
+	filePath := writeTestDOCX(t)
+
+	chunks, err := NewDOCXParser().ParseWithChunks(filePath, "runbook")
+	if err != nil {
+		t.Fatalf("Failed to parse DOCX: %v", err)
+	}
+	for _, chunk := range chunks {
+		if chunk.ChunkType != "docx_section" {
+			t.Errorf("Expected only section chunks without notes, got %+v", chunk)
+		}
+		if strings.Contains(chunk.Text, "Grafana") && chunk.HeadingPath != "Runbook > Monitoring" {
+			t.Errorf("Expected the monitoring heading path, got %q", chunk.HeadingPath)
+		}
+	}
+
+	dh := NewDocumentHandler(NewTextChunker(256, 38))
+	dh.EnableDOCXNotes()
+	chunks, err = dh.ParseFileWithChunks(filePath, "runbook")
+	if err != nil {
+		t.Fatalf("Failed to parse DOCX with notes: %v", err)
+	}
+
+	byType := make(map[string]Chunk)
+	for i, chunk := range chunks {
+		if chunk.Index != i {
+			t.Errorf("Expected chunk %d to have index %d, got %d", i, i, chunk.Index)
+		}
+		byType[chunk.ChunkType] = chunk
+	}
+	footnote := byType["docx_footnote"]
+	if footnote.Text != "Footnote 1: The script lives in ops/release.sh." || footnote.Metadata["footnote_id"] != "1" ||
+		footnote.HeadingPath != "Runbook > Deployment" {
+		t.Errorf("Unexpected footnote chunk %+v", footnote)
+	}
+	comment := byType["docx_comment"]
+	if comment.Text != "Comment by Dana: Also clear the CDN cache." || comment.Metadata["comment_author"] != "Dana" ||
+		comment.HeadingPath != "Runbook > Deployment > Rollback" {
+		t.Errorf("Unexpected comment chunk %+v", comment)
+	}
+}
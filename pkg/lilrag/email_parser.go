@@ -0,0 +1,638 @@
+package lilrag
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// maxEmailAttachmentSize caps the attachments read from a message; larger ones are skipped
+const maxEmailAttachmentSize = 25 << 20
+
+// EmailMessage is a decoded email with its headers, readable body and attachments
+type EmailMessage struct {
+	// Headers holds from, to, cc, subject, date (RFC 3339), message_id, in_reply_to and
+	// thread_id, the ID of the first message in the thread
+	Headers     map[string]string
+	Body        string
+	Attachments []EmailAttachment
+}
+
+// EmailAttachment is a file attached to an email
+type EmailAttachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// emailParser holds what the EML and MBOX parsers share
+type emailParser struct {
+	chunker     *TextChunker
+	attachments *DocumentHandler // Parses supported attachments when set
+}
+
+// EMLParser handles single RFC 5322 messages saved as .eml files
+type EMLParser struct {
+	emailParser
+}
+
+// MBOXParser handles mailbox files holding many messages, such as mailing list archives
+type MBOXParser struct {
+	emailParser
+}
+
+// NewEMLParser creates a new EML parser
+func NewEMLParser() *EMLParser {
+	return &EMLParser{}
+}
+
+// NewEMLParserWithChunker creates an EML parser that chunks message bodies with chunker
+func NewEMLParserWithChunker(chunker *TextChunker) *EMLParser {
+	return &EMLParser{emailParser{chunker: chunker}}
+}
+
+// NewEMLParserWithAttachments creates an EML parser that also indexes attachments in
+// formats the document handler supports
+func NewEMLParserWithAttachments(chunker *TextChunker, attachments *DocumentHandler) *EMLParser {
+	return &EMLParser{emailParser{chunker: chunker, attachments: attachments}}
+}
+
+// NewMBOXParser creates a new mbox parser
+func NewMBOXParser() *MBOXParser {
+	return &MBOXParser{}
+}
+
+// NewMBOXParserWithChunker creates an mbox parser that chunks message bodies with chunker
+func NewMBOXParserWithChunker(chunker *TextChunker) *MBOXParser {
+	return &MBOXParser{emailParser{chunker: chunker}}
+}
+
+// NewMBOXParserWithAttachments creates an mbox parser that also indexes attachments in
+// formats the document handler supports
+func NewMBOXParserWithAttachments(chunker *TextChunker, attachments *DocumentHandler) *MBOXParser {
+	return &MBOXParser{emailParser{chunker: chunker, attachments: attachments}}
+}
+
+// ReadEmail decodes a message, preferring the plain text alternative of its body and
+// reading HTML parts through the HTMLParser
+func ReadEmail(r io.Reader) (*EmailMessage, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read email: %w", err)
+	}
+
+	email := &EmailMessage{Headers: emailHeaders(msg.Header)}
+	texts, err := email.readPart(textproto.MIMEHeader(msg.Header), msg.Body)
+	if err != nil {
+		return nil, err
+	}
+	email.Body = strings.Join(texts, "\n\n")
+
+	return email, nil
+}
+
+// Text renders the message as indexed: a header block followed by the body
+func (e *EmailMessage) Text() string {
+	var text strings.Builder
+	for _, field := range []struct{ label, key string }{
+		{"Subject", "subject"}, {"From", "from"}, {"To", "to"}, {"Cc", "cc"}, {"Date", "date"},
+	} {
+		if value := e.Headers[field.key]; value != "" {
+			text.WriteString(field.label + ": " + value + "\n")
+		}
+	}
+	if len(e.Attachments) > 0 {
+		names := make([]string, len(e.Attachments))
+		for i, attachment := range e.Attachments {
+			names[i] = attachment.Filename
+		}
+		text.WriteString("Attachments: " + strings.Join(names, ", ") + "\n")
+	}
+	if e.Body != "" {
+		text.WriteString("\n" + e.Body)
+	}
+	return strings.TrimSpace(text.String())
+}
+
+// readPart returns the readable text of a MIME part, collecting attachments on the way
+func (e *EmailMessage) readPart(header textproto.MIMEHeader, body io.Reader) ([]string, error) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+	body = decodeTransferEncoding(header.Get("Content-Transfer-Encoding"), body)
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := dispositionParams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+	if decoded, err := emailWordDecoder.DecodeHeader(filename); err == nil {
+		filename = decoded
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		return e.readMultipart(mediaType, params["boundary"], body)
+
+	case disposition == "attachment" || (filename != "" && !strings.HasPrefix(mediaType, "text/")):
+		data, err := io.ReadAll(io.LimitReader(body, maxEmailAttachmentSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read attachment %s: %w", filename, err)
+		}
+		if filename != "" && len(data) <= maxEmailAttachmentSize {
+			e.Attachments = append(e.Attachments, EmailAttachment{
+				Filename:    filepath.Base(filename),
+				ContentType: mediaType,
+				Data:        data,
+			})
+		}
+		return nil, nil
+
+	case mediaType == "message/rfc822":
+		// Forwarded messages are kept inline with their own header block
+		forwarded, err := ReadEmail(body)
+		if err != nil {
+			return nil, err
+		}
+		e.Attachments = append(e.Attachments, forwarded.Attachments...)
+		forwarded.Attachments = nil
+		return []string{"---------- Forwarded message ----------\n" + forwarded.Text()}, nil
+
+	case mediaType == "text/plain", mediaType == "text/html":
+		reader, err := charset.NewReaderLabel(params["charset"], body)
+		if err != nil {
+			reader = body // Unknown charsets are read as UTF-8
+		}
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read email body: %w", err)
+		}
+
+		text := strings.TrimSpace(string(data))
+		if mediaType == "text/html" {
+			doc, err := html.Parse(strings.NewReader(text))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse HTML body: %w", err)
+			}
+			text = strings.Join((&HTMLParser{}).extractCleanSections(doc), "\n\n")
+		}
+		if text == "" {
+			return nil, nil
+		}
+		return []string{text}, nil
+	}
+
+	return nil, nil
+}
+
+// readMultipart reads every part of a multipart body. Of the alternatives in
+// multipart/alternative only the plain text one is kept, or the last one when there is none.
+func (e *EmailMessage) readMultipart(mediaType, boundary string, body io.Reader) ([]string, error) {
+	if boundary == "" {
+		return nil, fmt.Errorf("multipart email part without boundary")
+	}
+
+	var texts, plain, last []string
+	reader := multipart.NewReader(body, boundary)
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read multipart email: %w", err)
+		}
+
+		partTexts, err := e.readPart(part.Header, part)
+		if err != nil {
+			return nil, err
+		}
+		if mediaType != "multipart/alternative" {
+			texts = append(texts, partTexts...)
+			continue
+		}
+		if len(partTexts) > 0 {
+			last = partTexts
+			if partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type")); partType == "text/plain" {
+				plain = partTexts
+			}
+		}
+	}
+
+	if mediaType == "multipart/alternative" {
+		if plain != nil {
+			return plain, nil
+		}
+		return last, nil
+	}
+	return texts, nil
+}
+
+// decodeTransferEncoding undoes base64 and quoted-printable transfer encodings
+func decodeTransferEncoding(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body) // Line breaks are ignored
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	default:
+		return body
+	}
+}
+
+var (
+	emailWordDecoder = &mime.WordDecoder{CharsetReader: charset.NewReaderLabel}
+	messageIDRegex   = regexp.MustCompile(`<([^<>\s]+)>`)
+)
+
+// emailHeaders extracts the indexed headers, decoding RFC 2047 words in names and subjects
+func emailHeaders(header mail.Header) map[string]string {
+	headers := make(map[string]string)
+	set := func(key, value string) {
+		if value = strings.Join(strings.Fields(value), " "); value != "" {
+			headers[key] = value
+		}
+	}
+
+	addressParser := &mail.AddressParser{WordDecoder: emailWordDecoder}
+	for _, key := range []string{"From", "To", "Cc"} {
+		value := header.Get(key)
+		if value == "" {
+			continue
+		}
+		addresses, err := addressParser.ParseList(value)
+		if err != nil {
+			decoded, _ := emailWordDecoder.DecodeHeader(value)
+			set(strings.ToLower(key), decoded)
+			continue
+		}
+		formatted := make([]string, len(addresses))
+		for i, address := range addresses {
+			formatted[i] = address.Address
+			if address.Name != "" {
+				formatted[i] = address.Name + " <" + address.Address + ">"
+			}
+		}
+		set(strings.ToLower(key), strings.Join(formatted, ", "))
+	}
+
+	subject, err := emailWordDecoder.DecodeHeader(header.Get("Subject"))
+	if err != nil {
+		subject = header.Get("Subject")
+	}
+	set("subject", subject)
+
+	if date, err := header.Date(); err == nil {
+		set("date", date.UTC().Format(time.RFC3339))
+	} else {
+		set("date", header.Get("Date"))
+	}
+
+	messageIDs := func(key string) []string {
+		var ids []string
+		for _, match := range messageIDRegex.FindAllStringSubmatch(header.Get(key), -1) {
+			ids = append(ids, match[1])
+		}
+		return ids
+	}
+	var messageID, inReplyTo string
+	if ids := messageIDs("Message-Id"); len(ids) > 0 {
+		messageID = ids[0]
+	}
+	if ids := messageIDs("In-Reply-To"); len(ids) > 0 {
+		inReplyTo = ids[0]
+	}
+	set("message_id", messageID)
+	set("in_reply_to", inReplyTo)
+
+	// The first reference is the thread root; replies without references point at their parent
+	threadID := messageID
+	if references := messageIDs("References"); len(references) > 0 {
+		threadID = references[0]
+	} else if inReplyTo != "" {
+		threadID = inReplyTo
+	}
+	set("thread_id", threadID)
+
+	return headers
+}
+
+// chunkMessage chunks one message, tagging every chunk with its headers as metadata. With
+// attachment parsing enabled, supported attachments follow as chunks of their own.
+func (ep *emailParser) chunkMessage(ctx context.Context, email *EmailMessage, documentID string,
+	extra map[string]string, chunks []Chunk) ([]Chunk, error) {
+	// Use a default chunker if none provided
+	if ep.chunker == nil {
+		ep.chunker = NewTextChunker(256, 38) // Use optimized defaults for structured content
+	}
+
+	metadata := make(map[string]string, len(email.Headers)+len(extra))
+	for key, value := range email.Headers {
+		metadata[key] = value
+	}
+	for key, value := range extra {
+		metadata[key] = value
+	}
+
+	subject := email.Headers["subject"]
+	for _, chunk := range ep.chunker.ChunkTextContext(ctx, email.Text()) {
+		chunk.Index = len(chunks)
+		chunk.ChunkType = "email_message"
+		chunk.HeadingPath = subject
+		chunk.Metadata = metadata
+		chunks = append(chunks, chunk)
+	}
+
+	for _, attachment := range email.Attachments {
+		attachmentChunks, err := ep.parseAttachment(attachment, documentID)
+		if err != nil {
+			return nil, err
+		}
+		for _, chunk := range attachmentChunks {
+			chunk.Index = len(chunks)
+			chunk.HeadingPath = joinHeadingPath(subject, attachment.Filename, chunk.HeadingPath)
+
+			chunkMetadata := make(map[string]string, len(metadata)+len(chunk.Metadata)+1)
+			for key, value := range metadata {
+				chunkMetadata[key] = value
+			}
+			for key, value := range chunk.Metadata {
+				chunkMetadata[key] = value
+			}
+			chunkMetadata["attachment"] = attachment.Filename
+			chunk.Metadata = chunkMetadata
+
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	return chunks, nil
+}
+
+// parseAttachment parses an attachment through the document handler. Formats without a
+// dedicated parser and images, which are mostly logos and signatures, are skipped.
+func (ep *emailParser) parseAttachment(attachment EmailAttachment, documentID string) ([]Chunk, error) {
+	if ep.attachments == nil {
+		return nil, nil
+	}
+	name := filepath.Base(attachment.Filename)
+	if name == "." || name == ".." || name == string(filepath.Separator) {
+		return nil, nil
+	}
+	docType := ep.attachments.DetectDocumentType(name)
+	if _, ok := ep.attachments.parsers[docType]; !ok || docType == DocumentTypeImage {
+		return nil, nil
+	}
+
+	dir, err := os.MkdirTemp("", "lilrag-attachment-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attachment directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	filePath := filepath.Join(dir, name)
+	if err := os.WriteFile(filePath, attachment.Data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write attachment %s: %w", name, err)
+	}
+
+	chunks, err := ep.attachments.ParseFileWithChunks(filePath, documentID)
+	if err != nil {
+		// An unreadable attachment should not prevent indexing the message itself
+		return nil, nil
+	}
+	return chunks, nil
+}
+
+// joinHeadingPath joins non-empty heading path segments
+func joinHeadingPath(segments ...string) string {
+	var parts []string
+	for _, segment := range segments {
+		if segment != "" {
+			parts = append(parts, segment)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
+
+// Parse extracts the headers and body of an .eml file
+func (p *EMLParser) Parse(filePath string) (string, error) {
+	email, err := readEmailFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	return email.Text(), nil
+}
+
+// ParseWithChunks chunks the message and, when enabled, its attachments
+func (p *EMLParser) ParseWithChunks(filePath, documentID string) ([]Chunk, error) {
+	return p.ParseWithChunksContext(context.Background(), filePath, documentID)
+}
+
+// ParseWithChunksContext is ParseWithChunks with ctx passed to the chunker
+func (p *EMLParser) ParseWithChunksContext(ctx context.Context, filePath, documentID string) ([]Chunk, error) {
+	email, err := readEmailFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks, err := p.chunkMessage(ctx, email, documentID, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("no readable content found in email")
+	}
+	return chunks, nil
+}
+
+// ParseMetadata returns the message headers as document metadata
+func (p *EMLParser) ParseMetadata(filePath string) (map[string]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open email file: %w", err)
+	}
+	defer file.Close()
+
+	msg, err := mail.ReadMessage(bufio.NewReader(file))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read email: %w", err)
+	}
+	return emailHeaders(msg.Header), nil
+}
+
+// SupportedExtensions returns the file extensions this parser supports
+func (p *EMLParser) SupportedExtensions() []string {
+	return []string{".eml"}
+}
+
+// GetDocumentType returns the type of documents this parser handles
+func (p *EMLParser) GetDocumentType() DocumentType {
+	return DocumentTypeEmail
+}
+
+// Parse extracts every message of a mailbox in order
+func (p *MBOXParser) Parse(filePath string) (string, error) {
+	var messages []string
+	err := readMboxFile(filePath, func(_ int, email *EmailMessage) error {
+		messages = append(messages, email.Text())
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(messages, "\n\n"), nil
+}
+
+// ParseWithChunks chunks every message separately. Each chunk carries the headers of its
+// message and its 1-based message_index, so searches can be filtered per message.
+func (p *MBOXParser) ParseWithChunks(filePath, documentID string) ([]Chunk, error) {
+	return p.ParseWithChunksContext(context.Background(), filePath, documentID)
+}
+
+// ParseWithChunksContext is ParseWithChunks with ctx passed to the chunker
+func (p *MBOXParser) ParseWithChunksContext(ctx context.Context, filePath, documentID string) ([]Chunk, error) {
+	var chunks []Chunk
+	err := readMboxFile(filePath, func(messageIndex int, email *EmailMessage) error {
+		var err error
+		chunks, err = p.chunkMessage(ctx, email, documentID,
+			map[string]string{"message_index": strconv.Itoa(messageIndex)}, chunks)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("no readable content found in mailbox")
+	}
+	return chunks, nil
+}
+
+// ParseMetadata returns the number of messages in the mailbox as document metadata
+func (p *MBOXParser) ParseMetadata(filePath string) (map[string]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mailbox: %w", err)
+	}
+	defer file.Close()
+
+	count := 0
+	if err := splitMbox(file, func([]byte) error { count++; return nil }); err != nil {
+		return nil, err
+	}
+	return map[string]string{"message_count": strconv.Itoa(count)}, nil
+}
+
+// SupportedExtensions returns the file extensions this parser supports
+func (p *MBOXParser) SupportedExtensions() []string {
+	return []string{".mbox"}
+}
+
+// GetDocumentType returns the type of documents this parser handles
+func (p *MBOXParser) GetDocumentType() DocumentType {
+	return DocumentTypeMBOX
+}
+
+func readEmailFile(filePath string) (*EmailMessage, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open email file: %w", err)
+	}
+	defer file.Close()
+
+	return ReadEmail(bufio.NewReader(file))
+}
+
+// readMboxFile decodes the messages of a mailbox one at a time, passing fn the 1-based
+// position of each message. Messages that cannot be decoded are logged and skipped; the
+// mailbox only fails when none of its messages can be read.
+func readMboxFile(filePath string, fn func(int, *EmailMessage) error) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open mailbox: %w", err)
+	}
+	defer file.Close()
+
+	position, parsed := 0, 0
+	var firstErr error
+	err = splitMbox(file, func(raw []byte) error {
+		position++
+		email, err := ReadEmail(bytes.NewReader(raw))
+		if err != nil {
+			log.Printf("Warning: skipping unreadable message %d in %s: %v", position, filePath, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			return nil
+		}
+		parsed++
+		return fn(position, email)
+	})
+	if err != nil {
+		return err
+	}
+	if parsed == 0 && firstErr != nil {
+		return fmt.Errorf("no readable messages in mailbox: %w", firstErr)
+	}
+	return nil
+}
+
+// splitMbox calls fn with each raw message of a mailbox. Messages start with a "From "
+// line after a blank line; ">From " lines quoted by mboxrd writers are unquoted.
+func splitMbox(r io.Reader, fn func([]byte) error) error {
+	reader := bufio.NewReader(r)
+	var message bytes.Buffer
+	previousBlank := true
+
+	flush := func() error {
+		if len(bytes.TrimSpace(message.Bytes())) == 0 {
+			return nil
+		}
+		err := fn(bytes.Clone(message.Bytes()))
+		message.Reset()
+		return err
+	}
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			switch {
+			case previousBlank && bytes.HasPrefix(line, []byte("From ")):
+				if flushErr := flush(); flushErr != nil {
+					return flushErr
+				}
+			case bytes.HasPrefix(bytes.TrimLeft(line, ">"), []byte("From ")) && line[0] == '>':
+				message.Write(line[1:])
+			default:
+				message.Write(line)
+			}
+			previousBlank = len(bytes.TrimSpace(line)) == 0
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read mailbox: %w", err)
+		}
+	}
+
+	return flush()
+}
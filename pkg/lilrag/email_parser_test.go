@@ -0,0 +1,217 @@
+package lilrag
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testIncidentEmail = "From: =?UTF-8?Q?J=C3=BCrgen_Ops?= <jurgen@example.com>\r\n" +
+	"To: oncall@example.com, Alice <alice@example.com>\r\n" +
+	"Subject: Re: [INC-42] Checkout latency\r\n" +
+	"Date: Tue, 3 Sep 2024 10:15:00 +0200\r\n" +
+	"Message-ID: <reply-2@example.com>\r\n" +
+	"In-Reply-To: <reply-1@example.com>\r\n" +
+	"References: <root@example.com> <reply-1@example.com>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"outer\"\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"inner\"\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"The cache node was restarted at 10:02 and latency is back to norm=\r\n" +
+	"al.\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p>The cache node was <b>restarted</b>.</p>\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: text/markdown\r\n" +
+	"Content-Disposition: attachment; filename=\"timeline.md\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"%s\r\n" +
+	"--outer--\r\n"
+
+// writeTestFile writes a text fixture such as an email or JSON document to a temp file
+func writeTestFile(t *testing.T, fileName, content string) string {
+	t.Helper()
+	filePath := filepath.Join(t.TempDir(), fileName)
+	if err := os.WriteFile(filePath, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", fileName, err)
+	}
+	return filePath
+}
+
+func testIncidentEmailContent() string {
+	attachment := base64.StdEncoding.EncodeToString([]byte("# Timeline\n\n10:02 cache node restarted"))
+	return strings.Replace(testIncidentEmail, "%s", attachment, 1)
+}
+
+func TestReadEmail(t *testing.T) {
+	email, err := ReadEmail(strings.NewReader(testIncidentEmailContent()))
+	if err != nil {
+		t.Fatalf("Failed to read email: %v", err)
+	}
+
+	expected := map[string]string{
+		"from":        "Jürgen Ops <jurgen@example.com>",
+		"to":          "oncall@example.com, Alice <alice@example.com>",
+		"subject":     "Re: [INC-42] Checkout latency",
+		"date":        "2024-09-03T08:15:00Z",
+		"message_id":  "reply-2@example.com",
+		"in_reply_to": "reply-1@example.com",
+		"thread_id":   "root@example.com",
+	}
+	for key, value := range expected {
+		if email.Headers[key] != value {
+			t.Errorf("Expected %s %q, got %q", key, value, email.Headers[key])
+		}
+	}
+
+	// The plain text alternative wins over HTML
+	if email.Body != "The cache node was restarted at 10:02 and latency is back to normal." {
+		t.Errorf("Unexpected body %q", email.Body)
+	}
+	if len(email.Attachments) != 1 || email.Attachments[0].Filename != "timeline.md" ||
+		!strings.Contains(string(email.Attachments[0].Data), "cache node restarted") {
+		t.Errorf("Unexpected attachments %+v", email.Attachments)
+	}
+}
+
+func TestReadEmail_HTMLOnly(t *testing.T) {
+	content := "From: alice@example.com\r\nSubject: Weekly report\r\n" +
+		"Content-Type: text/html; charset=iso-8859-1\r\n\r\n" +
+		"<html><head><title>ignored</title></head><body><h1>Status</h1><p>All systems \xe9tabl\xe9s</p></body></html>"
+
+	email, err := ReadEmail(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Failed to read email: %v", err)
+	}
+	if email.Body != "## Status\n\nAll systems établés" {
+		t.Errorf("Unexpected body %q", email.Body)
+	}
+	if email.Headers["thread_id"] != "" {
+		t.Errorf("Expected no thread ID without message IDs, got %q", email.Headers["thread_id"])
+	}
+}
+
+func TestEMLParser_ParseWithChunks(t *testing.T) {
+	filePath := writeTestFile(t, "incident.eml", testIncidentEmailContent())
+
+	chunks, err := NewEMLParser().ParseWithChunks(filePath, "incident")
+	if err != nil {
+		t.Fatalf("Failed to parse email: %v", err)
+	}
+	if len(chunks) != 1 || chunks[0].ChunkType != "email_message" {
+		t.Fatalf("Expected one message chunk without attachment parsing, got %+v", chunks)
+	}
+	if !strings.HasPrefix(chunks[0].Text, "Subject: Re: [INC-42] Checkout latency\nFrom: Jürgen Ops") ||
+		!strings.Contains(chunks[0].Text, "Attachments: timeline.md") {
+		t.Errorf("Unexpected message text %q", chunks[0].Text)
+	}
+	if chunks[0].Metadata["thread_id"] != "root@example.com" || chunks[0].HeadingPath != "Re: [INC-42] Checkout latency" {
+		t.Errorf("Unexpected chunk metadata %v / %q", chunks[0].Metadata, chunks[0].HeadingPath)
+	}
+
+	metadata, err := NewEMLParser().ParseMetadata(filePath)
+	if err != nil {
+		t.Fatalf("Failed to parse metadata: %v", err)
+	}
+	if metadata["from"] != "Jürgen Ops <jurgen@example.com>" {
+		t.Errorf("Unexpected document metadata %v", metadata)
+	}
+
+	// With attachment parsing, the Markdown attachment is chunked by its own parser
+	dh := NewDocumentHandler(NewTextChunker(256, 38))
+	dh.EnableEmailAttachments()
+	chunks, err = dh.ParseFileWithChunks(filePath, "incident")
+	if err != nil {
+		t.Fatalf("Failed to parse email with attachments: %v", err)
+	}
+	if len(chunks) < 2 {
+		t.Fatalf("Expected attachment chunks, got %+v", chunks)
+	}
+	attachment := chunks[len(chunks)-1]
+	if attachment.Index != len(chunks)-1 || attachment.Metadata["attachment"] != "timeline.md" ||
+		attachment.Metadata["subject"] != "Re: [INC-42] Checkout latency" ||
+		!strings.HasPrefix(attachment.HeadingPath, "Re: [INC-42] Checkout latency > timeline.md") {
+		t.Errorf("Unexpected attachment chunk %+v", attachment)
+	}
+}
+
+func TestMBOXParser_ParseWithChunks(t *testing.T) {
+	mbox := "From alice@example.com Mon Sep  2 09:00:00 2024\n" +
+		"From: alice@example.com\nSubject: Deploy freeze\nMessage-ID: <root@example.com>\n\n" +
+		"No deploys on Friday.\n>From the release calendar.\n\n" +
+		"From bob@example.com Mon Sep  2 10:00:00 2024\n" +
+		"From: bob@example.com\nSubject: Re: Deploy freeze\nMessage-ID: <r1@example.com>\n" +
+		"In-Reply-To: <root@example.com>\n\nAcknowledged.\n"
+	filePath := writeTestFile(t, "list.mbox", mbox)
+
+	chunks, err := NewMBOXParser().ParseWithChunks(filePath, "list")
+	if err != nil {
+		t.Fatalf("Failed to parse mbox: %v", err)
+	}
+	if len(chunks) != 2 {
+		t.Fatalf("Expected one chunk per message, got %d", len(chunks))
+	}
+	if !strings.HasSuffix(chunks[0].Text, "No deploys on Friday.\nFrom the release calendar.") {
+		t.Errorf("Unexpected first message %q", chunks[0].Text)
+	}
+	for i, chunk := range chunks {
+		if chunk.Index != i || chunk.Metadata["thread_id"] != "root@example.com" {
+			t.Errorf("Chunk %d: unexpected index or thread %d/%v", i, chunk.Index, chunk.Metadata)
+		}
+	}
+	if chunks[1].Metadata["from"] != "bob@example.com" || chunks[1].Metadata["message_index"] != "2" {
+		t.Errorf("Unexpected second message metadata %v", chunks[1].Metadata)
+	}
+
+	metadata, err := NewMBOXParser().ParseMetadata(filePath)
+	if err != nil || metadata["message_count"] != "2" {
+		t.Errorf("Expected 2 messages, got %v (%v)", metadata, err)
+	}
+}
+
+func TestMBOXParser_SkipsUnreadableMessages(t *testing.T) {
+	broken := "From mailer@example.com Mon Sep  2 08:00:00 2024\n" +
+		"this header line has no colon\n\nLost body.\n\n"
+	good := "From carol@example.com Mon Sep  2 11:00:00 2024\n" +
+		"From: carol@example.com\nSubject: Rollback plan\n\nRoll back with the previous tag.\n"
+
+	chunks, err := NewMBOXParser().ParseWithChunks(writeTestFile(t, "mixed.mbox", broken+good), "mixed")
+	if err != nil {
+		t.Fatalf("Expected the readable message to be parsed, got %v", err)
+	}
+	if len(chunks) != 1 || chunks[0].Metadata["from"] != "carol@example.com" {
+		t.Fatalf("Expected only the readable message, got %+v", chunks)
+	}
+	if chunks[0].Metadata["message_index"] != "2" {
+		t.Errorf("Expected the message to keep its position, got %v", chunks[0].Metadata["message_index"])
+	}
+
+	if _, err := NewMBOXParser().ParseWithChunks(writeTestFile(t, "broken.mbox", broken), "broken"); err == nil {
+		t.Error("Expected a mailbox without readable messages to fail")
+	}
+}
+
+func TestDetectDocumentType_Email(t *testing.T) {
+	dh := NewDocumentHandler(NewTextChunker(256, 38))
+
+	for path, docType := range map[string]DocumentType{"a.eml": DocumentTypeEmail, "b.mbox": DocumentTypeMBOX} {
+		if got := dh.DetectDocumentType(path); got != docType {
+			t.Errorf("Expected %s to be %s, got %s", path, docType, got)
+		}
+		if _, ok := dh.GetSupportedFormats()[docType]; !ok {
+			t.Errorf("Expected a parser for %s", docType)
+		}
+	}
+}
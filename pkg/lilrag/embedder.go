@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"regexp"
 	"strings"
@@ -13,6 +14,7 @@ import (
 	"time"
 
 	"lil-rag/pkg/metrics"
+	"lil-rag/pkg/tokenizer"
 )
 
 type OllamaEmbedder struct {
@@ -30,9 +32,14 @@ type OllamaEmbedder struct {
 type TextPreprocessor struct {
 	normalizeWhitespace bool
 	removeExtraSpaces   bool
-	maxLength           int
+	maxLength           int                 // Character limit, 0 to disable
+	maxTokens           int                 // Token limit of the embedding model, 0 to disable
+	tokenizer           tokenizer.Tokenizer // Tokenizer used for maxTokens, defaults to tokenizer.Default()
 }
 
+// DefaultEmbeddingMaxTokens is the context size assumed for embedding models
+const DefaultEmbeddingMaxTokens = 2048
+
 type EmbedderMetrics struct {
 	TotalRequests  int64
 	CacheHits      int64
@@ -65,11 +72,17 @@ func NewOllamaEmbedderWithTimeout(baseURL, model string, timeoutSeconds int) (*O
 		preprocessor: &TextPreprocessor{
 			normalizeWhitespace: true,
 			removeExtraSpaces:   true,
-			maxLength:           8192,
+			maxTokens:           DefaultEmbeddingMaxTokens,
 		},
 	}, nil
 }
 
+// SetTokenizer configures the tokenizer and token limit used to fit text into the embedding model context
+func (o *OllamaEmbedder) SetTokenizer(tok tokenizer.Tokenizer, maxTokens int) {
+	o.preprocessor.tokenizer = tok
+	o.preprocessor.maxTokens = maxTokens
+}
+
 func (o *OllamaEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
 	if text == "" {
 		return nil, fmt.Errorf("text cannot be empty")
@@ -85,7 +98,7 @@ func (o *OllamaEmbedder) Embed(ctx context.Context, text string) ([]float32, err
 	if embedding, found := o.getFromCache(processedText); found {
 		// Record cache hit
 		o.cacheHits++
-		metrics.RecordEmbeddingTokens(o.model, processedText, true)
+		o.recordTokens(processedText, true)
 		o.updateCacheHitRate()
 		return embedding, nil
 	}
@@ -97,7 +110,7 @@ func (o *OllamaEmbedder) Embed(ctx context.Context, text string) ([]float32, err
 	}
 
 	// Record tokens for cache miss
-	metrics.RecordEmbeddingTokens(o.model, processedText, false)
+	o.recordTokens(processedText, false)
 	o.updateCacheHitRate()
 
 	// Cache the result
@@ -149,9 +162,32 @@ func (tp *TextPreprocessor) preprocess(text string) string {
 		}
 	}
 
+	// Limit to the embedding model's context so the model does not silently drop the tail
+	if tp.maxTokens > 0 {
+		tok := tp.textTokenizer()
+		if truncated, ok := tokenizer.Truncate(tok, text, tp.maxTokens); ok {
+			log.Printf("Warning: text truncated to %d tokens for embedding (%s tokenizer)", tp.maxTokens, tok.Name())
+			text = truncated
+		}
+	}
+
 	return text
 }
 
+// textTokenizer returns the configured tokenizer, or the whitespace fallback
+func (tp *TextPreprocessor) textTokenizer() tokenizer.Tokenizer {
+	if tp.tokenizer == nil {
+		return tokenizer.Default()
+	}
+	return tp.tokenizer
+}
+
+// recordTokens records the embedding tokens of text as counted by the embedder's tokenizer
+func (o *OllamaEmbedder) recordTokens(text string, cacheHit bool) {
+	tokens := o.preprocessor.textTokenizer().CountTokens(text)
+	metrics.RecordEmbeddingTokenCount(o.model, tokens, cacheHit)
+}
+
 // enhanceImageContent improves semantic searchability of image-derived text
 func (tp *TextPreprocessor) enhanceImageContent(text string) string {
 	// Check if this looks like image-derived content (has markdown formatting and structured data)
@@ -335,7 +371,7 @@ func (o *OllamaEmbedder) EmbedQuery(ctx context.Context, query string) ([]float3
 	if embedding, found := o.getFromCache(processedQuery); found {
 		// Record cache hit
 		o.cacheHits++
-		metrics.RecordEmbeddingTokens(o.model, processedQuery, true)
+		o.recordTokens(processedQuery, true)
 		o.updateCacheHitRate()
 		return embedding, nil
 	}
@@ -347,7 +383,7 @@ func (o *OllamaEmbedder) EmbedQuery(ctx context.Context, query string) ([]float3
 	}
 
 	// Record tokens for cache miss
-	metrics.RecordEmbeddingTokens(o.model, processedQuery, false)
+	o.recordTokens(processedQuery, false)
 	o.updateCacheHitRate()
 
 	// Cache the result
@@ -9,6 +9,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"lil-rag/pkg/tokenizer"
 )
 
 func TestNewOllamaEmbedder(t *testing.T) {
@@ -187,6 +189,27 @@ func TestTextPreprocessor_preprocess_ConfigOptions(t *testing.T) {
 	}
 }
 
+func TestTextPreprocessor_preprocess_MaxTokens(t *testing.T) {
+	preprocessor := &TextPreprocessor{
+		normalizeWhitespace: true,
+		removeExtraSpaces:   true,
+		maxTokens:           5,
+		tokenizer:           tokenizer.NewWhitespace(),
+	}
+
+	result := preprocessor.preprocess("one two three four five six seven")
+	if result != "one two three four five" {
+		t.Errorf("Expected text truncated to 5 tokens, got %q", result)
+	}
+
+	// Long single-token-per-word text is no longer cut at a fixed character count
+	preprocessor.maxTokens = 2048
+	input := strings.Repeat("word ", 2000)
+	if result := preprocessor.preprocess(input); result != strings.TrimSpace(input) {
+		t.Errorf("Expected text within the token limit to be unchanged, got %d characters", len(result))
+	}
+}
+
 // Mock HTTP server for testing Ollama API
 func createMockOllamaServer(t *testing.T, responses map[string][]float32, errorResponse bool, statusCode int) *httptest.Server {
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,376 @@
+package lilrag
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// EPUBParser handles EPUB e-books. Chapters are read in OPF spine order and each
+// XHTML chapter goes through the HTMLParser section extraction.
+type EPUBParser struct {
+	chunker *TextChunker
+}
+
+// EPUBChapter is one spine item of a book with its extracted sections
+type EPUBChapter struct {
+	Order    int // 1-based position among chapters with text
+	Title    string
+	Path     string // Path of the XHTML file inside the package
+	Sections []string
+}
+
+// EPUBBook is the metadata and chapters of an EPUB file
+type EPUBBook struct {
+	Title    string
+	Author   string
+	Chapters []EPUBChapter
+}
+
+// NewEPUBParser creates a new EPUB parser
+func NewEPUBParser() *EPUBParser {
+	return &EPUBParser{}
+}
+
+// NewEPUBParserWithChunker creates an EPUB parser that chunks chapters with chunker
+func NewEPUBParserWithChunker(chunker *TextChunker) *EPUBParser {
+	return &EPUBParser{chunker: chunker}
+}
+
+// ParseBook reads the book metadata and every chapter in reading order
+func (ep *EPUBParser) ParseBook(filePath string) (*EPUBBook, error) {
+	r, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open EPUB file: %w", err)
+	}
+	defer r.Close()
+
+	files := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		files[f.Name] = f
+	}
+
+	var container struct {
+		Rootfiles []struct {
+			FullPath string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	if err := readZipXML(files, "META-INF/container.xml", &container); err != nil {
+		return nil, fmt.Errorf("failed to read EPUB container: %w", err)
+	}
+	if len(container.Rootfiles) == 0 {
+		return nil, fmt.Errorf("EPUB container has no package document")
+	}
+	opfPath := container.Rootfiles[0].FullPath
+
+	var pkg epubPackage
+	if err := readZipXML(files, opfPath, &pkg); err != nil {
+		return nil, fmt.Errorf("failed to read EPUB package: %w", err)
+	}
+
+	book := &EPUBBook{
+		Title:  strings.TrimSpace(pkg.Title),
+		Author: strings.TrimSpace(pkg.Creator),
+	}
+
+	manifest := make(map[string]epubItem, len(pkg.Items))
+	for _, item := range pkg.Items {
+		item.Href = resolveEPUBHref(opfPath, item.Href)
+		manifest[item.ID] = item
+	}
+
+	titles := epubTOCTitles(files, pkg, manifest)
+	hp := &HTMLParser{}
+
+	for _, ref := range pkg.Spine.ItemRefs {
+		item, ok := manifest[ref.IDRef]
+		if !ok || !strings.Contains(item.MediaType, "html") {
+			continue
+		}
+
+		doc, err := readZipHTML(files, item.Href)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read chapter %s: %w", item.Href, err)
+		}
+
+		sections := hp.extractCleanSections(doc)
+		if len(sections) == 0 {
+			continue // Cover pages and images have no text
+		}
+
+		title := titles[item.Href]
+		if title == "" {
+			title = epubHeading(hp, doc)
+		}
+		order := len(book.Chapters) + 1
+		if title == "" {
+			title = fmt.Sprintf("Chapter %d", order)
+		}
+
+		book.Chapters = append(book.Chapters, EPUBChapter{
+			Order:    order,
+			Title:    title,
+			Path:     item.Href,
+			Sections: sections,
+		})
+	}
+
+	return book, nil
+}
+
+// Parse extracts the text of every chapter in reading order
+func (ep *EPUBParser) Parse(filePath string) (string, error) {
+	book, err := ep.ParseBook(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	var content strings.Builder
+	if book.Title != "" {
+		content.WriteString("Title: " + book.Title + "\n\n")
+	}
+	for _, chapter := range book.Chapters {
+		content.WriteString("# " + chapter.Title + "\n\n")
+		content.WriteString(strings.Join(chapter.Sections, "\n\n"))
+		content.WriteString("\n\n")
+	}
+
+	return strings.TrimSpace(content.String()), nil
+}
+
+// ParseWithChunks chunks each chapter's sections separately. Chunks carry the chapter
+// title as their heading path and the chapter title and order as metadata.
+func (ep *EPUBParser) ParseWithChunks(filePath, documentID string) ([]Chunk, error) {
+	return ep.ParseWithChunksContext(context.Background(), filePath, documentID)
+}
+
+// ParseWithChunksContext is ParseWithChunks with ctx passed to the chunker
+func (ep *EPUBParser) ParseWithChunksContext(ctx context.Context, filePath, _ string) ([]Chunk, error) {
+	book, err := ep.ParseBook(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use a default chunker if none provided
+	if ep.chunker == nil {
+		ep.chunker = NewTextChunker(256, 38) // Use optimized defaults for structured content
+	}
+
+	var chunks []Chunk
+	if book.Title != "" {
+		titleText := "Book Title: " + book.Title
+		if book.Author != "" {
+			titleText += " by " + book.Author
+		}
+		chunks = append(chunks, Chunk{
+			Text:       titleText,
+			Index:      0,
+			StartPos:   0,
+			EndPos:     len(titleText),
+			TokenCount: ep.chunker.EstimateTokenCount(titleText),
+			ChunkType:  "epub_title",
+		})
+	}
+
+	for _, chapter := range book.Chapters {
+		metadata := map[string]string{
+			"chapter_title": chapter.Title,
+			"chapter_index": strconv.Itoa(chapter.Order),
+		}
+
+		for _, section := range chapter.Sections {
+			for _, chunk := range ep.chunker.ChunkTextContext(ctx, section) {
+				chunk.Index = len(chunks)
+				chunk.ChunkType = "epub_section"
+				chunk.HeadingPath = chapter.Title
+				chunk.Metadata = metadata
+				chunks = append(chunks, chunk)
+			}
+		}
+	}
+
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("no readable content found in EPUB")
+	}
+
+	return chunks, nil
+}
+
+// SupportedExtensions returns the file extensions this parser supports
+func (ep *EPUBParser) SupportedExtensions() []string {
+	return []string{".epub"}
+}
+
+// GetDocumentType returns the type of documents this parser handles
+func (ep *EPUBParser) GetDocumentType() DocumentType {
+	return DocumentTypeEPUB
+}
+
+// epubPackage is the part of the OPF package document needed to read a book
+type epubPackage struct {
+	Title   string     `xml:"metadata>title"`
+	Creator string     `xml:"metadata>creator"`
+	Items   []epubItem `xml:"manifest>item"`
+	Spine   struct {
+		TOC      string `xml:"toc,attr"` // Manifest ID of the EPUB 2 NCX table of contents
+		ItemRefs []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"itemref"`
+	} `xml:"spine"`
+}
+
+type epubItem struct {
+	ID         string `xml:"id,attr"`
+	Href       string `xml:"href,attr"`
+	MediaType  string `xml:"media-type,attr"`
+	Properties string `xml:"properties,attr"`
+}
+
+// epubTOCTitles maps chapter paths to their titles from the EPUB 3 navigation document,
+// or from the EPUB 2 NCX when there is none
+func epubTOCTitles(files map[string]*zip.File, pkg epubPackage, manifest map[string]epubItem) map[string]string {
+	titles := make(map[string]string)
+	add := func(basePath, href, title string) {
+		title = strings.Join(strings.Fields(title), " ")
+		target := resolveEPUBHref(basePath, href)
+		if title != "" && titles[target] == "" {
+			titles[target] = title
+		}
+	}
+
+	for _, item := range manifest {
+		if !strings.Contains(" "+item.Properties+" ", " nav ") {
+			continue
+		}
+		doc, err := readZipHTML(files, item.Href)
+		if err != nil {
+			break
+		}
+		var walk func(n *html.Node)
+		walk = func(n *html.Node) {
+			if n.Type == html.ElementNode && n.Data == "a" {
+				for _, attr := range n.Attr {
+					if attr.Key == "href" {
+						add(item.Href, attr.Val, (&HTMLParser{}).extractNodeText(n))
+					}
+				}
+				return
+			}
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				walk(c)
+			}
+		}
+		walk(doc)
+		if len(titles) > 0 {
+			return titles
+		}
+	}
+
+	ncx, ok := manifest[pkg.Spine.TOC]
+	if !ok {
+		return titles
+	}
+	f, ok := files[ncx.Href]
+	if !ok {
+		return titles
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return titles
+	}
+	defer rc.Close()
+
+	// navPoints hold a navLabel followed by the content they point to
+	decoder := xml.NewDecoder(rc)
+	var label strings.Builder
+	inText := false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return titles
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "navLabel":
+				label.Reset()
+			case "text":
+				inText = true
+			case "content":
+				add(ncx.Href, odfAttr(t, "src"), label.String())
+			}
+		case xml.CharData:
+			if inText {
+				label.Write(t)
+			}
+		case xml.EndElement:
+			if t.Name.Local == "text" {
+				inText = false
+			}
+		}
+	}
+}
+
+// epubHeading returns the first heading of a chapter, or its <title>
+func epubHeading(hp *HTMLParser, doc *html.Node) string {
+	var heading string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if heading != "" {
+			return
+		}
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "h1", "h2", "h3":
+				heading = strings.Join(strings.Fields(hp.extractNodeText(n)), " ")
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	if heading == "" {
+		heading = strings.Join(strings.Fields(hp.extractTitle(doc)), " ")
+	}
+	return heading
+}
+
+// readZipHTML parses an XHTML part of a package
+func readZipHTML(files map[string]*zip.File, name string) (*html.Node, error) {
+	f, ok := files[name]
+	if !ok {
+		return nil, fmt.Errorf("missing part %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer rc.Close()
+
+	doc, err := html.Parse(rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return doc, nil
+}
+
+// resolveEPUBHref resolves a percent-encoded href relative to the document that
+// contains it, dropping any fragment
+func resolveEPUBHref(basePath, href string) string {
+	if i := strings.Index(href, "#"); i >= 0 {
+		href = href[:i]
+	}
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+	return resolvePartPath(basePath, href)
+}
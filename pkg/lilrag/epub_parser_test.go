@@ -0,0 +1,114 @@
+package lilrag
+
+import (
+	"strings"
+	"testing"
+)
+
+const epubContainer = `<?xml version="1.0"?>` +
+	`<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">` +
+	`<rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>` +
+	`</rootfiles></container>`
+
+func epubChapterXHTML(title, body string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?><html xmlns="http://www.w3.org/1999/xhtml">` +
+		`<head><title>` + title + `</title></head><body>` + body + `</body></html>`
+}
+
+func TestEPUBParser_ParseWithChunks(t *testing.T) {
+	filePath := writeTestZip(t, "book.epub", map[string]string{
+		"mimetype":               "application/epub+zip",
+		"META-INF/container.xml": epubContainer,
+		// The spine order differs from the manifest order
+		"OEBPS/content.opf": `<package xmlns="http://www.idpf.org/2007/opf" version="3.0">` +
+			`<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">` +
+			`<dc:title>Go in Practice</dc:title><dc:creator>Jane Doe</dc:creator></metadata>` +
+			`<manifest>` +
+			`<item id="ch2" href="text/chapter%202.xhtml" media-type="application/xhtml+xml"/>` +
+			`<item id="ch1" href="text/ch1.xhtml" media-type="application/xhtml+xml"/>` +
+			`<item id="cover" href="cover.xhtml" media-type="application/xhtml+xml"/>` +
+			`<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>` +
+			`<item id="img" href="images/cover.png" media-type="image/png"/>` +
+			`</manifest>` +
+			`<spine><itemref idref="cover"/><itemref idref="ch1"/><itemref idref="ch2"/></spine></package>`,
+		"OEBPS/nav.xhtml": epubChapterXHTML("Contents", `<nav><ol>`+
+			`<li><a href="text/ch1.xhtml">Getting  Started</a></li>`+
+			`<li><a href="text/chapter%202.xhtml#top">Concurrency</a></li></ol></nav>`),
+		"OEBPS/cover.xhtml": epubChapterXHTML("Cover", `<img src="images/cover.png"/>`),
+		"OEBPS/text/ch1.xhtml": epubChapterXHTML("ch1",
+			`<h1>Getting Started</h1>
+			<p>Install the
+			toolchain first.</p>`),
+		"OEBPS/text/chapter 2.xhtml": epubChapterXHTML("ch2",
+			`<h1>Concurrency</h1><p>Goroutines are cheap.</p><h2>Channels</h2><ul><li>Buffered</li></ul>`),
+	})
+
+	chunks, err := NewEPUBParser().ParseWithChunks(filePath, "book")
+	if err != nil {
+		t.Fatalf("Failed to parse EPUB: %v", err)
+	}
+
+	expected := []struct {
+		text, chunkType, chapter, order string
+	}{
+		{"Book Title: Go in Practice by Jane Doe", "epub_title", "", ""},
+		{"## Getting Started\n\nInstall the\ntoolchain first.", "epub_section", "Getting Started", "1"},
+		{"## Concurrency\n\nGoroutines are cheap.", "epub_section", "Concurrency", "2"},
+		{"## Channels\n\n• Buffered", "epub_section", "Concurrency", "2"},
+	}
+	if len(chunks) != len(expected) {
+		t.Fatalf("Expected %d chunks, got %d: %+v", len(expected), len(chunks), chunks)
+	}
+	for i, want := range expected {
+		chunk := chunks[i]
+		if chunk.Index != i || chunk.ChunkType != want.chunkType {
+			t.Errorf("Chunk %d: unexpected index/type %d/%s", i, chunk.Index, chunk.ChunkType)
+		}
+		if chunk.Text != want.text {
+			t.Errorf("Chunk %d: expected %q, got %q", i, want.text, chunk.Text)
+		}
+		if chunk.Metadata["chapter_title"] != want.chapter || chunk.Metadata["chapter_index"] != want.order {
+			t.Errorf("Chunk %d: unexpected metadata %v", i, chunk.Metadata)
+		}
+		if chunk.HeadingPath != want.chapter {
+			t.Errorf("Chunk %d: expected heading path %q, got %q", i, want.chapter, chunk.HeadingPath)
+		}
+	}
+}
+
+func TestEPUBParser_NCXTitles(t *testing.T) {
+	filePath := writeTestZip(t, "old.epub", map[string]string{
+		"META-INF/container.xml": strings.Replace(epubContainer, "OEBPS/content.opf", "content.opf", 1),
+		"content.opf": `<package xmlns="http://www.idpf.org/2007/opf" version="2.0"><metadata/>` +
+			`<manifest><item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>` +
+			`<item id="a" href="a.html" media-type="application/xhtml+xml"/>` +
+			`<item id="b" href="b.html" media-type="application/xhtml+xml"/></manifest>` +
+			`<spine toc="ncx"><itemref idref="a"/><itemref idref="b"/></spine></package>`,
+		"toc.ncx": `<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/"><navMap>` +
+			`<navPoint id="p1"><navLabel><text>Preface</text></navLabel><content src="a.html"/></navPoint>` +
+			`</navMap></ncx>`,
+		"a.html": epubChapterXHTML("a", `<p>Why this book exists.</p>`),
+		"b.html": epubChapterXHTML("", `<p>No heading here.</p>`),
+	})
+
+	content, err := NewEPUBParser().Parse(filePath)
+	if err != nil {
+		t.Fatalf("Failed to parse EPUB: %v", err)
+	}
+
+	expected := "# Preface\n\nWhy this book exists.\n\n# Chapter 2\n\nNo heading here."
+	if content != expected {
+		t.Errorf("Expected %q, got %q", expected, content)
+	}
+}
+
+func TestDetectDocumentType_EPUB(t *testing.T) {
+	dh := NewDocumentHandler(NewTextChunker(256, 38))
+
+	if got := dh.DetectDocumentType("manual.epub"); got != DocumentTypeEPUB {
+		t.Errorf("Expected epub, got %s", got)
+	}
+	if _, ok := dh.GetSupportedFormats()[DocumentTypeEPUB]; !ok {
+		t.Error("Expected EPUB parser to be registered")
+	}
+}
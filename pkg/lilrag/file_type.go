@@ -0,0 +1,324 @@
+package lilrag
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sniffLen is the number of leading bytes inspected to detect a file's type, the same
+// amount http.DetectContentType considers
+const sniffLen = 512
+
+// FileType is the document type and MIME type detected for a file
+type FileType struct {
+	DocType  DocumentType
+	MIMEType string
+}
+
+// documentMIMETypes are the MIME types recorded for document types whose content does
+// not determine a more specific one
+var documentMIMETypes = map[DocumentType]string{
+	DocumentTypePDF:      "application/pdf",
+	DocumentTypeDOCX:     "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	DocumentTypeXLSX:     "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	DocumentTypePPTX:     "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	DocumentTypeHTML:     "text/html",
+	DocumentTypeCSV:      "text/csv",
+	DocumentTypeTXT:      "text/plain",
+	DocumentTypeMarkdown: "text/markdown",
+	DocumentTypeCode:     "text/plain",
+	DocumentTypeODT:      "application/vnd.oasis.opendocument.text",
+	DocumentTypeODS:      "application/vnd.oasis.opendocument.spreadsheet",
+	DocumentTypeODP:      "application/vnd.oasis.opendocument.presentation",
+	DocumentTypeEPUB:     "application/epub+zip",
+	DocumentTypeEmail:    "message/rfc822",
+	DocumentTypeMBOX:     "application/mbox",
+	DocumentTypeJSON:     "application/json",
+	DocumentTypeJSONL:    "application/x-ndjson",
+	DocumentTypeYAML:     "application/yaml",
+	DocumentTypeXML:      "application/xml",
+	DocumentTypeNotebook: "application/x-ipynb+json",
+}
+
+// DetectFileType detects the document and MIME type of a file from its content. Magic
+// bytes identify PDFs, images and archives, the package manifest tells OOXML, ODF and
+// EPUB files apart from plain zip archives, and http.DetectContentType is the fallback.
+// The extension is only a hint: it picks the format of text files, which cannot be told
+// apart by content, and is used alone when the file cannot be read.
+func (dh *DocumentHandler) DetectFileType(filePath string) FileType {
+	hint := documentTypeFromExtension(filePath)
+
+	header, err := readFileHeader(filePath)
+	if err != nil || len(header) == 0 {
+		return FileType{DocType: hint, MIMEType: mimeTypeForExtension(hint, filePath)}
+	}
+
+	switch {
+	case bytes.HasPrefix(header, []byte("%PDF-")):
+		return FileType{DocType: DocumentTypePDF, MIMEType: documentMIMETypes[DocumentTypePDF]}
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")) || bytes.HasPrefix(header, []byte("PK\x05\x06")):
+		fileType := detectZipType(filePath)
+		if fileType.DocType == DocumentTypeArchive && isPackageDocumentType(hint) {
+			// A package without a recognizable manifest is still more likely the named format
+			return FileType{DocType: hint, MIMEType: documentMIMETypes[hint]}
+		}
+		return fileType
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		if isGzippedTar(filePath) {
+			return FileType{DocType: DocumentTypeArchive, MIMEType: "application/gzip"}
+		}
+		return FileType{DocType: DocumentTypeUnknown, MIMEType: "application/gzip"}
+	case isTarHeader(header):
+		return FileType{DocType: DocumentTypeArchive, MIMEType: "application/x-tar"}
+	case bytes.HasPrefix(header, []byte("II*\x00")) || bytes.HasPrefix(header, []byte("MM\x00*")):
+		return FileType{DocType: DocumentTypeImage, MIMEType: "image/tiff"}
+	}
+
+	detected, _, _ := mime.ParseMediaType(http.DetectContentType(header))
+	switch {
+	case strings.HasPrefix(detected, "image/"):
+		return FileType{DocType: DocumentTypeImage, MIMEType: detected}
+	case strings.HasPrefix(detected, "text/"):
+		if isTextDocumentType(hint) {
+			return FileType{DocType: hint, MIMEType: mimeTypeForExtension(hint, filePath)}
+		}
+		return detectTextType(detected, header)
+	}
+
+	return FileType{DocType: DocumentTypeUnknown, MIMEType: detected}
+}
+
+// detectZipType tells OOXML, ODF and EPUB packages apart from plain zip archives by
+// their manifests
+func detectZipType(filePath string) FileType {
+	archive := FileType{DocType: DocumentTypeArchive, MIMEType: "application/zip"}
+
+	r, err := zip.OpenReader(filePath)
+	if err != nil {
+		return archive
+	}
+	defer r.Close()
+
+	files := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		files[f.Name] = f
+	}
+
+	// ODF and EPUB store their media type uncompressed in a "mimetype" file
+	if f, ok := files["mimetype"]; ok {
+		if rc, err := f.Open(); err == nil {
+			data, _ := io.ReadAll(io.LimitReader(rc, 128))
+			rc.Close()
+			mimeType := strings.TrimSpace(string(data))
+			for _, docType := range []DocumentType{DocumentTypeODT, DocumentTypeODS, DocumentTypeODP, DocumentTypeEPUB} {
+				if documentMIMETypes[docType] == mimeType {
+					return FileType{DocType: docType, MIMEType: mimeType}
+				}
+			}
+		}
+	}
+
+	// OOXML declares the content type of its main part in [Content_Types].xml
+	var contentTypes struct {
+		Overrides []struct {
+			ContentType string `xml:"ContentType,attr"`
+		} `xml:"Override"`
+	}
+	if err := readZipXML(files, "[Content_Types].xml", &contentTypes); err == nil {
+		for _, override := range contentTypes.Overrides {
+			switch {
+			case strings.Contains(override.ContentType, "wordprocessingml.document.main"):
+				return FileType{DocType: DocumentTypeDOCX, MIMEType: documentMIMETypes[DocumentTypeDOCX]}
+			case strings.Contains(override.ContentType, "spreadsheetml.sheet.main"):
+				return FileType{DocType: DocumentTypeXLSX, MIMEType: documentMIMETypes[DocumentTypeXLSX]}
+			case strings.Contains(override.ContentType, "presentationml.presentation.main"):
+				return FileType{DocType: DocumentTypePPTX, MIMEType: documentMIMETypes[DocumentTypePPTX]}
+			}
+		}
+	}
+
+	// Fall back to the main part names for packages written without overrides
+	for part, docType := range map[string]DocumentType{
+		"word/document.xml":    DocumentTypeDOCX,
+		"xl/workbook.xml":      DocumentTypeXLSX,
+		"ppt/presentation.xml": DocumentTypePPTX,
+	} {
+		if _, ok := files[part]; ok {
+			return FileType{DocType: docType, MIMEType: documentMIMETypes[docType]}
+		}
+	}
+
+	return archive
+}
+
+// detectTextType picks a document type for text whose extension gives no hint
+func detectTextType(detected string, header []byte) FileType {
+	switch detected {
+	case "text/html":
+		return FileType{DocType: DocumentTypeHTML, MIMEType: detected}
+	case "text/xml":
+		return FileType{DocType: DocumentTypeXML, MIMEType: documentMIMETypes[DocumentTypeXML]}
+	}
+
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(header, []byte("\xef\xbb\xbf")))
+	// Jupyter writes "cells" first, so the first cell type is usually within the header
+	if bytes.HasPrefix(trimmed, []byte("{")) &&
+		(bytes.Contains(trimmed, []byte(`"nbformat"`)) || bytes.Contains(trimmed, []byte(`"cell_type"`))) {
+		return FileType{DocType: DocumentTypeNotebook, MIMEType: documentMIMETypes[DocumentTypeNotebook]}
+	}
+	if bytes.HasPrefix(trimmed, []byte("{")) || bytes.HasPrefix(trimmed, []byte("[")) {
+		return FileType{DocType: DocumentTypeJSON, MIMEType: documentMIMETypes[DocumentTypeJSON]}
+	}
+	return FileType{DocType: DocumentTypeTXT, MIMEType: "text/plain"}
+}
+
+// isTextDocumentType reports whether documents of a type are plain text, so their
+// format can only be told by the extension
+func isTextDocumentType(docType DocumentType) bool {
+	switch docType {
+	case DocumentTypeTXT, DocumentTypeMarkdown, DocumentTypeCode, DocumentTypeHTML, DocumentTypeCSV,
+		DocumentTypeJSON, DocumentTypeJSONL, DocumentTypeYAML, DocumentTypeXML, DocumentTypeNotebook,
+		DocumentTypeEmail, DocumentTypeMBOX:
+		return true
+	}
+	return false
+}
+
+// isPackageDocumentType reports whether documents of a type are zip packages
+func isPackageDocumentType(docType DocumentType) bool {
+	switch docType {
+	case DocumentTypeDOCX, DocumentTypeXLSX, DocumentTypePPTX, DocumentTypeODT, DocumentTypeODS,
+		DocumentTypeODP, DocumentTypeEPUB:
+		return true
+	}
+	return false
+}
+
+// mimeTypeForExtension returns the MIME type of a document type detected from the file
+// name, or "" when there is none
+func mimeTypeForExtension(docType DocumentType, filePath string) string {
+	if mimeType, ok := documentMIMETypes[docType]; ok {
+		return mimeType
+	}
+	switch docType {
+	case DocumentTypeImage:
+		mimeType, _, _ := mime.ParseMediaType(mime.TypeByExtension(strings.ToLower(filepath.Ext(filePath))))
+		return mimeType
+	case DocumentTypeArchive:
+		name := strings.ToLower(filePath)
+		switch {
+		case strings.HasSuffix(name, ".zip"):
+			return "application/zip"
+		case strings.HasSuffix(name, ".tar"):
+			return "application/x-tar"
+		default:
+			return "application/gzip"
+		}
+	}
+	return ""
+}
+
+// readFileHeader reads the first sniffLen bytes of a file
+func readFileHeader(filePath string) ([]byte, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	header := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	return header[:n], nil
+}
+
+// isTarHeader reports whether a block starts with a POSIX or GNU tar header
+func isTarHeader(header []byte) bool {
+	return len(header) >= 262 && string(header[257:262]) == "ustar"
+}
+
+// isGzippedTar reports whether a gzip file holds a tar archive
+func isGzippedTar(filePath string) bool {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return false
+	}
+	defer gz.Close()
+
+	header := make([]byte, sniffLen)
+	n, _ := io.ReadFull(gz, header)
+	return isTarHeader(header[:n])
+}
+
+// documentTypeFromExtension determines the document type from the file name alone
+func documentTypeFromExtension(filePath string) DocumentType {
+	// .tar.gz has a double extension, so archives are matched by suffix
+	if IsArchiveFile(filePath) {
+		return DocumentTypeArchive
+	}
+
+	ext := strings.ToLower(filepath.Ext(filePath))
+
+	switch ext {
+	case ".pdf":
+		return DocumentTypePDF
+	case ".docx":
+		return DocumentTypeDOCX
+	case ".xlsx":
+		return DocumentTypeXLSX
+	case ".pptx":
+		return DocumentTypePPTX
+	case ".html", ".htm":
+		return DocumentTypeHTML
+	case ".csv":
+		return DocumentTypeCSV
+	case ".json":
+		return DocumentTypeJSON
+	case ".jsonl", ".ndjson":
+		return DocumentTypeJSONL
+	case ".yaml", ".yml":
+		return DocumentTypeYAML
+	case ".xml":
+		return DocumentTypeXML
+	case ".ipynb":
+		return DocumentTypeNotebook
+	case ".txt":
+		return DocumentTypeTXT
+	case ".md", ".markdown":
+		return DocumentTypeMarkdown
+	case ".odt":
+		return DocumentTypeODT
+	case ".ods":
+		return DocumentTypeODS
+	case ".odp":
+		return DocumentTypeODP
+	case ".epub":
+		return DocumentTypeEPUB
+	case ".eml":
+		return DocumentTypeEmail
+	case ".mbox":
+		return DocumentTypeMBOX
+	case ".jpg", ".jpeg", ".png", ".gif", ".bmp", ".webp", ".tiff", ".tif":
+		return DocumentTypeImage
+	default:
+		if CodeLanguage(filePath) != "" {
+			return DocumentTypeCode
+		}
+		return DocumentTypeUnknown
+	}
+}
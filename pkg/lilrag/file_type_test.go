@@ -0,0 +1,96 @@
+package lilrag
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+func TestDetectFileType(t *testing.T) {
+	var tarGz bytes.Buffer
+	gz := gzip.NewWriter(&tarGz)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: "notes.txt", Mode: 0o644, Size: 2}); err != nil {
+		t.Fatalf("Failed to write tar header: %v", err)
+	}
+	if _, err := tw.Write([]byte("hi")); err != nil {
+		t.Fatalf("Failed to write tar entry: %v", err)
+	}
+	tw.Close()
+	gz.Close()
+
+	docx := writeTestZip(t, "contract.zip", map[string]string{
+		"[Content_Types].xml": `<?xml version="1.0"?><Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Override PartName="/word/document.xml" ContentType="application/` +
+			`vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/></Types>`,
+		"word/document.xml": "<w:document/>",
+	})
+	odt := writeTestZip(t, "minutes.zip", map[string]string{
+		"mimetype":    "application/vnd.oasis.opendocument.text",
+		"content.xml": "<office:document-content/>",
+	})
+	plainZip := writeTestZip(t, "export.bin", map[string]string{"a.txt": "hello"})
+
+	tests := []struct {
+		name     string
+		filePath string
+		docType  DocumentType
+		mimeType string
+	}{
+		{"PDF without extension", writeTestFile(t, "report", "%PDF-1.7\n%binary"), DocumentTypePDF, "application/pdf"},
+		{"DOCX renamed to .zip", docx, DocumentTypeDOCX,
+			"application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+		{"ODT renamed to .zip", odt, DocumentTypeODT, "application/vnd.oasis.opendocument.text"},
+		{"zip with an unknown extension", plainZip, DocumentTypeArchive, "application/zip"},
+		{"tar.gz without extension", writeTestFile(t, "backup", tarGz.String()), DocumentTypeArchive, "application/gzip"},
+		{"PNG named .txt", writeTestFile(t, "scan.txt", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), DocumentTypeImage, "image/png"},
+		{"HTML without extension", writeTestFile(t, "page", "<!DOCTYPE html><html><body>Hi</body></html>"),
+			DocumentTypeHTML, "text/html"},
+		{"JSON without extension", writeTestFile(t, "dump", `{"service": "billing"}`), DocumentTypeJSON, "application/json"},
+		{"text without extension", writeTestFile(t, "README", "Plain notes"), DocumentTypeTXT, "text/plain"},
+		{"Markdown with leading HTML keeps its extension", writeTestFile(t, "doc.md", "<p>Intro</p>\n\n# Title"),
+			DocumentTypeMarkdown, "text/markdown"},
+		{"binary with a text extension", writeTestFile(t, "data.csv", "\x00\x01\x02\x03"), DocumentTypeUnknown,
+			"application/octet-stream"},
+		{"missing file falls back to the extension", "missing.csv", DocumentTypeCSV, "text/csv"},
+	}
+
+	dh := NewDocumentHandler(NewTextChunker(256, 38))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileType := dh.DetectFileType(tt.filePath)
+			if fileType.DocType != tt.docType || fileType.MIMEType != tt.mimeType {
+				t.Errorf("Expected %s (%s), got %s (%s)", tt.docType, tt.mimeType, fileType.DocType, fileType.MIMEType)
+			}
+		})
+	}
+}
+
+func TestDocumentHandler_ContentSniffing(t *testing.T) {
+	dh := NewDocumentHandler(NewTextChunker(256, 38))
+
+	// A notebook uploaded without its extension is still parsed as a notebook
+	filePath := writeTestFile(t, "lilrag_upload_123", testNotebook)
+	chunks, err := dh.ParseFileWithChunks(filePath, "upload")
+	if err != nil {
+		t.Fatalf("Failed to parse notebook: %v", err)
+	}
+	if chunks[0].ChunkType != "nb_markdown" {
+		t.Errorf("Expected notebook chunks, got %s", chunks[0].ChunkType)
+	}
+
+	metadata, err := dh.ParseFileMetadata(filePath)
+	if err != nil {
+		t.Fatalf("Failed to parse metadata: %v", err)
+	}
+	if metadata["mime_type"] != "application/x-ipynb+json" {
+		t.Errorf("Expected the detected MIME type in metadata, got %v", metadata)
+	}
+
+	// Parser metadata is kept alongside the MIME type
+	metadata, err = dh.ParseFileMetadata(writeTestFile(t, "incident.eml", testIncidentEmailContent()))
+	if err != nil || metadata["mime_type"] != "message/rfc822" || metadata["thread_id"] != "root@example.com" {
+		t.Errorf("Unexpected email metadata %v (%v)", metadata, err)
+	}
+}
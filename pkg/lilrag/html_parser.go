@@ -1,6 +1,7 @@
 package lilrag
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"regexp"
@@ -9,16 +10,26 @@ import (
 	"golang.org/x/net/html"
 )
 
-// HTMLParser handles HTML files
+// HTMLParser handles HTML files. By default only the main content of a page is read:
+// navigation, footers, sidebars and cookie banners are dropped and the remaining nodes
+// are scored by text and link density. The page title, meta description and canonical
+// URL are kept as document metadata.
 type HTMLParser struct {
-	chunker *TextChunker
+	chunker  *TextChunker
+	fullPage bool // Read every element of the page instead of the main content
 }
 
-// NewHTMLParser creates a new HTML parser
+// NewHTMLParser creates a new HTML parser that reads the main content of pages
 func NewHTMLParser() *HTMLParser {
 	return &HTMLParser{}
 }
 
+// NewHTMLParserWithFullPage creates an HTML parser that reads every element of a page,
+// including navigation and footers
+func NewHTMLParserWithFullPage() *HTMLParser {
+	return &HTMLParser{fullPage: true}
+}
+
 // Parse extracts text content from an HTML file
 func (hp *HTMLParser) Parse(filePath string) (string, error) {
 	file, err := os.Open(filePath)
@@ -43,8 +54,10 @@ func (hp *HTMLParser) Parse(filePath string) (string, error) {
 	}
 
 	// Extract body text
-	bodyText := hp.extractText(doc)
-	content.WriteString(bodyText)
+	for _, node := range hp.pageContent(doc) {
+		content.WriteString(hp.extractText(node))
+		content.WriteString("\n\n")
+	}
 
 	// Clean up excessive whitespace
 	cleanText := hp.cleanWhitespace(content.String())
@@ -52,7 +65,12 @@ func (hp *HTMLParser) Parse(filePath string) (string, error) {
 }
 
 // ParseWithChunks extracts and chunks content from an HTML file
-func (hp *HTMLParser) ParseWithChunks(filePath, _ string) ([]Chunk, error) {
+func (hp *HTMLParser) ParseWithChunks(filePath, documentID string) ([]Chunk, error) {
+	return hp.ParseWithChunksContext(context.Background(), filePath, documentID)
+}
+
+// ParseWithChunksContext is ParseWithChunks with ctx passed to the chunker
+func (hp *HTMLParser) ParseWithChunksContext(ctx context.Context, filePath, _ string) ([]Chunk, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open HTML file: %w", err)
@@ -89,7 +107,13 @@ func (hp *HTMLParser) ParseWithChunks(filePath, _ string) ([]Chunk, error) {
 	}
 
 	// Extract structured content by sections
-	sections := hp.extractSections(doc)
+	content := hp.pageContent(doc)
+	var sections []string
+	if hp.fullPage {
+		sections = hp.extractSections(doc)
+	} else {
+		sections = hp.extractContentSections(content)
+	}
 
 	if len(sections) > 0 {
 		// Chunk each section separately to preserve HTML structure
@@ -98,7 +122,7 @@ func (hp *HTMLParser) ParseWithChunks(filePath, _ string) ([]Chunk, error) {
 				continue
 			}
 
-			sectionChunks := hp.chunker.ChunkText(section)
+			sectionChunks := hp.chunker.ChunkTextContext(ctx, section)
 			for _, chunk := range sectionChunks {
 				chunk.Index = chunkIndex
 				chunk.ChunkType = "html_section"
@@ -108,10 +132,13 @@ func (hp *HTMLParser) ParseWithChunks(filePath, _ string) ([]Chunk, error) {
 		}
 	} else {
 		// Fallback to regular text chunking
-		bodyText := hp.extractText(doc)
+		var bodyText string
+		for _, node := range content {
+			bodyText += hp.extractText(node) + " "
+		}
 		if strings.TrimSpace(bodyText) != "" {
 			bodyText = hp.cleanWhitespace(bodyText)
-			bodyChunks := hp.chunker.ChunkText(bodyText)
+			bodyChunks := hp.chunker.ChunkTextContext(ctx, bodyText)
 
 			for _, chunk := range bodyChunks {
 				chunk.Index = chunkIndex
@@ -125,6 +152,30 @@ func (hp *HTMLParser) ParseWithChunks(filePath, _ string) ([]Chunk, error) {
 	return chunks, nil
 }
 
+// ParseMetadata returns the page's "title", "description" and "canonical_url"
+func (hp *HTMLParser) ParseMetadata(filePath string) (map[string]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open HTML file: %w", err)
+	}
+	defer file.Close()
+
+	doc, err := html.Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML file: %w", err)
+	}
+	return htmlPageMetadata(doc), nil
+}
+
+// pageContent returns the nodes whose text is indexed: the whole document in full-page
+// mode, otherwise the main content of the page
+func (hp *HTMLParser) pageContent(doc *html.Node) []*html.Node {
+	if hp.fullPage {
+		return []*html.Node{doc}
+	}
+	return htmlMainContent(doc)
+}
+
 // extractTitle finds and extracts the HTML title
 func (hp *HTMLParser) extractTitle(n *html.Node) string {
 	if n.Type == html.ElementNode && n.Data == "title" {
@@ -195,6 +246,139 @@ func (hp *HTMLParser) walkSections(n *html.Node, sections *[]string, currentSect
 	}
 }
 
+// extractContentSections extracts sections from the main content nodes of a page.
+// Unlike walkSections it descends into containers, so headings nested in wrapper
+// elements still start sections, and tables are kept as Markdown tables.
+func (hp *HTMLParser) extractContentSections(nodes []*html.Node) []string {
+	var sections []string
+	var currentSection strings.Builder
+
+	for _, n := range nodes {
+		hp.walkContent(n, &sections, &currentSection)
+	}
+
+	// Add final section if there's content
+	if currentSection.Len() > 0 {
+		sections = append(sections, currentSection.String())
+	}
+
+	return sections
+}
+
+// walkContent traverses main content nodes. Elements without block children are read
+// as paragraphs, and runs of text and inline elements between blocks are joined.
+func (hp *HTMLParser) walkContent(n *html.Node, sections *[]string, currentSection *strings.Builder) {
+	if n.Type != html.ElementNode {
+		return
+	}
+	writeParagraph := func(text string) {
+		if text = strings.Join(strings.Fields(text), " "); text != "" {
+			currentSection.WriteString(text)
+			currentSection.WriteString("\n\n")
+		}
+	}
+
+	switch {
+	case n.Data == "table":
+		if table := hp.extractTableText(n); table != "" {
+			currentSection.WriteString(table)
+			currentSection.WriteString("\n\n")
+		}
+		return
+	case !hasHTMLBlockChildren(n):
+		switch n.Data {
+		case "h1", "h2", "h3", "h4", "h5", "h6", "ul", "ol":
+			hp.walkSections(n, sections, currentSection)
+		default:
+			writeParagraph(hp.extractNodeText(n))
+		}
+		return
+	}
+
+	var inline strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && htmlBlockTags[c.Data] {
+			writeParagraph(inline.String())
+			inline.Reset()
+			hp.walkContent(c, sections, currentSection)
+			continue
+		}
+		inline.WriteString(hp.extractNodeText(c))
+	}
+	writeParagraph(inline.String())
+}
+
+// extractTableText renders an HTML table as a Markdown table
+func (hp *HTMLParser) extractTableText(table *html.Node) string {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "table" && n != table {
+			return // Nested tables are read as cell text
+		}
+		if n.Type == html.ElementNode && n.Data == "tr" {
+			var row []string
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.ElementNode && (c.Data == "td" || c.Data == "th") {
+					row = append(row, collapseHTMLText(c))
+				}
+			}
+			if len(row) > 0 {
+				rows = append(rows, row)
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(table)
+
+	if len(rows) == 0 {
+		return ""
+	}
+	return markdownTable(rows)
+}
+
+// extractCleanSections extracts sections like extractSections, falling back to the body
+// text. Markup indentation is collapsed and whitespace-only lines separate paragraphs.
+func (hp *HTMLParser) extractCleanSections(doc *html.Node) []string {
+	sections := hp.extractSections(doc)
+	if body := findBody(doc); len(sections) == 0 && body != nil {
+		sections = []string{hp.extractText(body)}
+	}
+
+	var tidy []string
+	for _, section := range sections {
+		var paragraphs, lines []string
+		for _, line := range strings.Split(section+"\n", "\n") {
+			if line = strings.Join(strings.Fields(line), " "); line != "" {
+				lines = append(lines, line)
+			} else if len(lines) > 0 {
+				paragraphs = append(paragraphs, strings.Join(lines, "\n"))
+				lines = nil
+			}
+		}
+		if len(paragraphs) > 0 {
+			tidy = append(tidy, strings.Join(paragraphs, "\n\n"))
+		}
+	}
+	return tidy
+}
+
+// findBody returns the <body> element so the document <title> is not read as text
+func findBody(n *html.Node) *html.Node {
+	if n.Type == html.ElementNode && n.Data == "body" {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if body := findBody(c); body != nil {
+			return body
+		}
+	}
+	return nil
+}
+
 // extractText extracts all readable text from HTML
 func (hp *HTMLParser) extractText(n *html.Node) string {
 	if n.Type == html.TextNode {
@@ -0,0 +1,138 @@
+package lilrag
+
+import (
+	"strings"
+	"testing"
+)
+
+const testArticleHTML = `<!DOCTYPE html>
+<html>
+<head>
+  <title>  Tuning SQLite for Search </title>
+  <meta name="description" content="How we made vector search fast.">
+  <meta property="og:url" content="https://example.com/og">
+  <link rel="canonical" href="https://example.com/blog/sqlite-search">
+  <style>body { color: red; }</style>
+</head>
+<body>
+  <div class="cookie-banner">We use cookies. <a href="/accept">Accept all cookies</a></div>
+  <header class="site-header">
+    <nav><a href="/">Home</a> <a href="/blog">Blog</a> <a href="/about">About us</a></nav>
+  </header>
+  <div id="page">
+    <div class="layout">
+      <div class="post-body">
+        <h1>Tuning SQLite for Search</h1>
+        <p>Vector search in SQLite is fast enough for most personal knowledge bases, provided the
+        index fits in memory and the page cache is sized generously.</p>
+        <div class="section">
+          <h2>Page cache</h2>
+          <p>We raised the page cache to 64 MB, which removed most of the disk reads during queries,
+          and kept the WAL journal so that writers do not block readers.</p>
+          Results are <b>measured</b> on a laptop, with a warm cache.
+          <table>
+            <tr><th>Setting</th><th>Latency</th></tr>
+            <tr><td>Default</td><td>41 ms</td></tr>
+            <tr><td>64 MB cache</td><td>9 ms</td></tr>
+          </table>
+        </div>
+      </div>
+      <div class="more">
+        <ul>
+          <li><a href="/a">Ten tips for Postgres</a></li>
+          <li><a href="/b">Why we love Go</a></li>
+          <li><a href="/c">Another article about databases, caching, and indexes</a></li>
+        </ul>
+      </div>
+    </div>
+  </div>
+  <aside><p>Subscribe to our newsletter for weekly posts about databases and search.</p></aside>
+  <footer><p>Copyright 2024 Example Inc. All rights reserved, everywhere, forever.</p></footer>
+  <script>trackPageView();</script>
+</body>
+</html>`
+
+func TestHTMLParser_MainContent(t *testing.T) {
+	filePath := writeTestFile(t, "post.html", testArticleHTML)
+
+	chunks, err := NewHTMLParser().ParseWithChunks(filePath, "post")
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+	var text strings.Builder
+	for _, chunk := range chunks {
+		text.WriteString(chunk.Text + "\n")
+	}
+	content := text.String()
+
+	for _, expected := range []string{
+		"Document Title:",
+		"## Tuning SQLite for Search",
+		"## Page cache",
+		"page cache is sized generously",
+		"Results are measured on a laptop, with a warm cache.",
+		"| 64 MB cache | 9 ms |",
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("Expected %q in the chunks, got:\n%s", expected, content)
+		}
+	}
+	for _, boilerplate := range []string{"cookies", "About us", "Ten tips", "newsletter", "Copyright", "trackPageView"} {
+		if strings.Contains(content, boilerplate) {
+			t.Errorf("Expected %q to be removed, got:\n%s", boilerplate, content)
+		}
+	}
+
+	parsed, err := NewHTMLParser().Parse(filePath)
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+	if !strings.Contains(parsed, "WAL journal") || strings.Contains(parsed, "About us") {
+		t.Errorf("Expected only the main content in the text, got:\n%s", parsed)
+	}
+
+	// The full-page parser keeps everything but scripts and styles
+	text.Reset()
+	chunks, err = NewHTMLParserWithFullPage().ParseWithChunks(filePath, "post")
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+	for _, chunk := range chunks {
+		text.WriteString(chunk.Text + "\n")
+	}
+	if !strings.Contains(text.String(), "Copyright 2024") || !strings.Contains(text.String(), "Accept all cookies") {
+		t.Errorf("Expected the full page in the chunks, got:\n%s", text.String())
+	}
+}
+
+func TestHTMLParser_ParseMetadata(t *testing.T) {
+	filePath := writeTestFile(t, "post.html", testArticleHTML)
+
+	metadata, err := NewDocumentHandler(NewTextChunker(256, 38)).ParseFileMetadata(filePath)
+	if err != nil {
+		t.Fatalf("Failed to parse metadata: %v", err)
+	}
+	expected := map[string]string{
+		"title":         "Tuning SQLite for Search",
+		"description":   "How we made vector search fast.",
+		"canonical_url": "https://example.com/blog/sqlite-search",
+	}
+	for key, value := range expected {
+		if metadata[key] != value {
+			t.Errorf("Expected %s %q, got %q", key, value, metadata[key])
+		}
+	}
+}
+
+func TestHTMLParser_MainContentWithoutCandidates(t *testing.T) {
+	filePath := writeTestFile(t, "short.html", `<html><body><nav><a href="/">Home</a></nav>
+<span>Short note.</span></body></html>`)
+
+	chunks, err := NewHTMLParser().ParseWithChunks(filePath, "short")
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+	if len(chunks) != 1 || !strings.Contains(chunks[0].Text, "Short note.") || strings.Contains(chunks[0].Text, "Home") {
+		t.Errorf("Expected the body without navigation, got %+v", chunks)
+	}
+}
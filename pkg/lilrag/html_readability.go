@@ -0,0 +1,300 @@
+package lilrag
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// htmlBoilerplateTags are removed from a page before its main content is chosen
+var htmlBoilerplateTags = map[string]bool{
+	"nav": true, "footer": true, "aside": true, "script": true, "style": true, "noscript": true,
+	"template": true, "iframe": true, "svg": true, "button": true, "select": true,
+}
+
+// htmlBoilerplateRoles are ARIA landmark roles of navigation, banners and sidebars
+var htmlBoilerplateRoles = map[string]bool{
+	"navigation": true, "banner": true, "contentinfo": true, "complementary": true,
+	"dialog": true, "alertdialog": true, "search": true,
+}
+
+// htmlBlockTags start a new block of text; elements without block children are read as
+// one paragraph
+var htmlBlockTags = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "main": true, "header": true,
+	"table": true, "ul": true, "ol": true, "dl": true, "pre": true, "blockquote": true, "figure": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+var (
+	// htmlUnlikelyPattern matches class names and IDs of cookie banners, menus, sidebars
+	// and similar page furniture
+	htmlUnlikelyPattern = regexp.MustCompile(`(?i)cookie|consent|banner|sidebar|side-bar|menu|breadcrumb|` +
+		`comment|share|social|promo|advert|sponsor|popup|modal|newsletter|subscribe|related|footer|masthead|skip`)
+	// htmlLikelyPattern matches class names and IDs of article content
+	htmlLikelyPattern = regexp.MustCompile(`(?i)article|content|main|post|entry|story|body|text|blog`)
+)
+
+// htmlMinParagraph is the shortest text, in bytes, that counts towards a node's score
+const htmlMinParagraph = 25
+
+// htmlMainContent returns the nodes holding the main content of a page, in document
+// order. Boilerplate elements are removed from doc first. Paragraphs are scored by
+// their length and commas, the scores are added to their ancestors, and each candidate's
+// score is reduced by its link density, as in Readability. The best candidate is
+// returned with siblings that score nearly as well; pages without a candidate return
+// their body.
+func htmlMainContent(doc *html.Node) []*html.Node {
+	removeHTMLBoilerplate(doc)
+
+	body := findBody(doc)
+	if body == nil {
+		body = doc
+	}
+
+	scores := make(map[*html.Node]float64)
+	var candidates []*html.Node
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode || n.Data == "html" {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = htmlTagScore(n) + htmlClassScore(n)
+			candidates = append(candidates, n)
+		}
+		scores[n] += score
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if n.Type != html.ElementNode || !isHTMLParagraph(n) {
+			return
+		}
+
+		text := collapseHTMLText(n)
+		if len(text) < htmlMinParagraph {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + float64(min(len(text)/100, 3))
+
+		// Parents get the full score, grandparents half and great-grandparents a sixth
+		addScore(n.Parent, score)
+		if n.Parent != nil {
+			addScore(n.Parent.Parent, score/2)
+			if n.Parent.Parent != nil {
+				addScore(n.Parent.Parent.Parent, score/6)
+			}
+		}
+	}
+	walk(body)
+
+	var top *html.Node
+	for _, candidate := range candidates {
+		scores[candidate] *= 1 - htmlLinkDensity(candidate)
+		if top == nil || scores[candidate] > scores[top] {
+			top = candidate
+		}
+	}
+	if top == nil || top == body || top.Parent == nil {
+		return []*html.Node{body}
+	}
+
+	// Keep siblings that score nearly as well, such as the other columns of an article,
+	// and plain paragraphs without many links
+	threshold := max(10, scores[top]*0.2)
+	var content []*html.Node
+	for sibling := top.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+		if sibling.Type != html.ElementNode {
+			continue
+		}
+		keep := sibling == top
+		if score, ok := scores[sibling]; ok && score >= threshold {
+			keep = true
+		}
+		if sibling.Data == "p" && len(collapseHTMLText(sibling)) > 80 && htmlLinkDensity(sibling) < 0.25 {
+			keep = true
+		}
+		if keep {
+			content = append(content, sibling)
+		}
+	}
+	return content
+}
+
+// removeHTMLBoilerplate removes navigation, footers, sidebars, scripts and elements
+// whose class or ID marks them as page furniture
+func removeHTMLBoilerplate(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode || (c.Type == html.ElementNode && isHTMLBoilerplate(c)) {
+			n.RemoveChild(c)
+		} else {
+			removeHTMLBoilerplate(c)
+		}
+		c = next
+	}
+}
+
+// isHTMLBoilerplate reports whether an element is navigation or page furniture
+func isHTMLBoilerplate(n *html.Node) bool {
+	if htmlBoilerplateTags[n.Data] || htmlBoilerplateRoles[htmlAttr(n, "role")] {
+		return true
+	}
+	switch n.Data {
+	case "html", "body", "main", "article":
+		return false
+	}
+	if hasHTMLAttr(n, "hidden") || htmlAttr(n, "aria-hidden") == "true" {
+		return true
+	}
+	names := htmlAttr(n, "class") + " " + htmlAttr(n, "id")
+	return htmlUnlikelyPattern.MatchString(names) && !htmlLikelyPattern.MatchString(names)
+}
+
+// isHTMLParagraph reports whether an element is read as a paragraph when scoring
+func isHTMLParagraph(n *html.Node) bool {
+	switch n.Data {
+	case "p", "pre", "td", "blockquote":
+		return true
+	case "div":
+		return !hasHTMLBlockChildren(n)
+	}
+	return false
+}
+
+// hasHTMLBlockChildren reports whether an element contains block-level children
+func hasHTMLBlockChildren(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && htmlBlockTags[c.Data] {
+			return true
+		}
+	}
+	return false
+}
+
+// htmlTagScore is the initial score of a candidate, favouring containers over lists
+// and headings
+func htmlTagScore(n *html.Node) float64 {
+	switch n.Data {
+	case "article", "main":
+		return 10
+	case "div":
+		return 5
+	case "pre", "td", "blockquote":
+		return 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		return -3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		return -5
+	}
+	return 0
+}
+
+// htmlClassScore rewards class names and IDs that look like content and penalises ones
+// that look like page furniture
+func htmlClassScore(n *html.Node) float64 {
+	var score float64
+	for _, name := range []string{htmlAttr(n, "class"), htmlAttr(n, "id")} {
+		if name == "" {
+			continue
+		}
+		if htmlUnlikelyPattern.MatchString(name) {
+			score -= 25
+		}
+		if htmlLikelyPattern.MatchString(name) {
+			score += 25
+		}
+	}
+	return score
+}
+
+// htmlLinkDensity is the share of an element's text that is inside links
+func htmlLinkDensity(n *html.Node) float64 {
+	textLength := len(collapseHTMLText(n))
+	if textLength == 0 {
+		return 0
+	}
+
+	linkLength := 0
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
+			linkLength += len(collapseHTMLText(n))
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return float64(linkLength) / float64(textLength)
+}
+
+// collapseHTMLText returns the text of a node with whitespace collapsed
+func collapseHTMLText(n *html.Node) string {
+	return strings.Join(strings.Fields((&HTMLParser{}).extractNodeText(n)), " ")
+}
+
+// htmlAttr returns the value of an attribute of an element
+func htmlAttr(n *html.Node, name string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == name {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// hasHTMLAttr reports whether an element has an attribute, such as a boolean "hidden"
+func hasHTMLAttr(n *html.Node, name string) bool {
+	for _, attr := range n.Attr {
+		if attr.Key == name {
+			return true
+		}
+	}
+	return false
+}
+
+// htmlPageMetadata reads the title, meta description and canonical URL of a page
+func htmlPageMetadata(doc *html.Node) map[string]string {
+	metadata := make(map[string]string)
+	if title := strings.Join(strings.Fields((&HTMLParser{}).extractTitle(doc)), " "); title != "" {
+		metadata["title"] = title
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "meta":
+				name := strings.ToLower(htmlAttr(n, "name") + htmlAttr(n, "property"))
+				content := strings.TrimSpace(htmlAttr(n, "content"))
+				switch {
+				case content == "":
+				case name == "description":
+					metadata["description"] = content
+				case name == "og:description" && metadata["description"] == "":
+					metadata["description"] = content
+				case name == "og:url" && metadata["canonical_url"] == "":
+					metadata["canonical_url"] = content
+				}
+			case "link":
+				href := strings.TrimSpace(htmlAttr(n, "href"))
+				if href != "" && strings.EqualFold(htmlAttr(n, "rel"), "canonical") {
+					metadata["canonical_url"] = href
+				}
+			case "body":
+				return // Metadata is in the head
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return metadata
+}
@@ -0,0 +1,163 @@
+package lilrag
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFileName is the name of the .gitignore-style files that exclude paths from
+// directory indexing. Patterns apply to the directory of the file and everything below it.
+const IgnoreFileName = ".lilragignore"
+
+// ignoreRule is one pattern of an ignore file or an include/exclude glob
+type ignoreRule struct {
+	negate  bool // "!pattern" re-includes a path excluded by an earlier rule
+	dirOnly bool // "pattern/" only matches directories
+	pattern *regexp.Regexp
+}
+
+// ignoreRules are the rules of one ignore file, matched against paths relative to base
+type ignoreRules struct {
+	base  string // Slash-separated directory of the ignore file, relative to the walk root
+	rules []ignoreRule
+}
+
+// loadIgnoreFile reads the ignore file of a directory; a missing file has no rules
+func loadIgnoreFile(filePath, base string) (*ignoreRules, error) {
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	rules := &ignoreRules{base: base}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := parseIgnorePattern(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q in %s: %w", line, filePath, err)
+		}
+		rules.rules = append(rules.rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+	return rules, nil
+}
+
+// parseIgnorePattern compiles a .gitignore pattern. Patterns without a slash match a
+// name at any depth, patterns with one are anchored to the ignore file's directory, "**"
+// matches any number of directories and a trailing slash matches only directories.
+func parseIgnorePattern(pattern string) (ignoreRule, error) {
+	var rule ignoreRule
+	if strings.HasPrefix(pattern, "!") {
+		rule.negate = true
+		pattern = pattern[1:]
+	} else if strings.HasPrefix(pattern, `\`) {
+		pattern = pattern[1:] // "\#" and "\!" match a literal first character
+	}
+	if strings.HasSuffix(pattern, "/") {
+		rule.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+
+	prefix := "^(?:.*/)?"
+	if strings.Contains(pattern, "/") {
+		prefix = "^"
+		pattern = strings.TrimPrefix(pattern, "/")
+	}
+	expr, err := globExpr(pattern)
+	if err != nil {
+		return rule, err
+	}
+	rule.pattern, err = regexp.Compile(prefix + expr + "$")
+	return rule, err
+}
+
+// globExpr translates a glob to a regular expression over slash-separated paths
+func globExpr(glob string) (string, error) {
+	var expr strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case c == '*':
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated character class")
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			expr.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return expr.String(), nil
+}
+
+// parseGlobs compiles include or exclude globs, which use the ignore file syntax
+func parseGlobs(globs []string) ([]ignoreRule, error) {
+	rules := make([]ignoreRule, 0, len(globs))
+	for _, glob := range globs {
+		rule, err := parseIgnorePattern(glob)
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", glob, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// matchRules applies rules in order to a slash-separated path; the last matching rule decides
+// whether the path is matched, and matched is unchanged when no rule applies
+func matchRules(rules []ignoreRule, relPath string, isDir, matched bool) bool {
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.pattern.MatchString(relPath) {
+			matched = !rule.negate
+		}
+	}
+	return matched
+}
+
+// ignored reports whether the ignore files of a path's ancestors exclude it. stack holds
+// the rules of the enclosing directories from the walk root down; deeper files win.
+func ignored(stack []*ignoreRules, relPath string, isDir bool) bool {
+	excluded := false
+	for _, rules := range stack {
+		rel := relPath
+		if rules.base != "" {
+			rel = strings.TrimPrefix(relPath, rules.base+"/")
+		}
+		excluded = matchRules(rules.rules, rel, isDir, excluded)
+	}
+	return excluded
+}
@@ -7,9 +7,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"image"
-	"image/gif"
+	_ "image/gif" // Register decoders for image.Decode
 	"image/jpeg"
-	"image/png"
+	_ "image/png"
 	"io"
 	"net/http"
 	"os"
@@ -18,8 +18,8 @@ import (
 	"time"
 
 	"golang.org/x/image/draw"
-	"golang.org/x/image/tiff"
-	"golang.org/x/image/webp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
 
 	"lil-rag/pkg/metrics"
 )
@@ -89,6 +89,42 @@ type VisionResponse struct {
 	Done      bool          `json:"done"`
 }
 
+// ocrPrompt asks the vision model to transcribe an image as markdown
+const ocrPrompt = `Analyze this image and extract all visible text content using proper markdown formatting. Please:
+
+**CONTENT TO EXTRACT:**
+1. Read all text in the image, including:
+   - Headers, titles, and subtitles
+   - Body text and paragraphs
+   - Lists, bullet points, and numbered items
+   - Tables, captions, and labels
+   - Any handwritten text (if legible)
+   - Text in charts, diagrams, or figures
+
+**MARKDOWN FORMATTING REQUIREMENTS:**
+- Use proper markdown headers: # for main titles, ## for sections, ### for subsections
+- Use **bold text** for emphasized or important text
+- Use *italic text* for subtle emphasis
+- Use standard markdown lists:
+  - For unordered lists: use "- " (dash + space) consistently
+  - For ordered lists: use "1. ", "2. ", etc.
+  - For nested lists: indent with 2 spaces per level
+- Use proper code blocks with triple backticks for code sections
+- Use > for blockquotes if applicable
+- Use | tables | format | for tabular data
+- Use [link text](url) format for any URLs or references
+
+**STRUCTURE REQUIREMENTS:**
+- Maintain the original reading order (left to right, top to bottom)
+- Preserve document hierarchy and organization
+- Use consistent formatting throughout
+- Separate sections with blank lines
+- If text is unclear or partially obscured, indicate with *[unclear text]*
+
+**OUTPUT FORMAT:**
+Provide ONLY the extracted text content in proper markdown format, without any additional ` +
+	`commentary, explanations, or meta-text.`
+
 // ResizeImage resizes an image to fit within maxSize while preserving aspect ratio
 func (p *ImageParser) ResizeImage(imagePath string, maxSize int) ([]byte, error) {
 	// Open and decode the image
@@ -98,23 +134,8 @@ func (p *ImageParser) ResizeImage(imagePath string, maxSize int) ([]byte, error)
 	}
 	defer file.Close()
 
-	// Decode image based on file extension
-	ext := strings.ToLower(filepath.Ext(imagePath))
-	var img image.Image
-	switch ext {
-	case ".jpg", ".jpeg":
-		img, err = jpeg.Decode(file)
-	case ".png":
-		img, err = png.Decode(file)
-	case ".gif":
-		img, err = gif.Decode(file)
-	case ".webp":
-		img, err = webp.Decode(file)
-	case ".tiff", ".tif":
-		img, err = tiff.Decode(file)
-	default:
-		return nil, fmt.Errorf("unsupported image format: %s", ext)
-	}
+	// Decode image based on its content; the format packages register their decoders
+	img, _, err := image.Decode(file)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode image: %w", err)
 	}
@@ -135,9 +156,20 @@ func (p *ImageParser) ResizeImage(imagePath string, maxSize int) ([]byte, error)
 		return io.ReadAll(file)
 	}
 
-	// Create resized image
-	resized := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
-	draw.CatmullRom.Scale(resized, resized.Bounds(), img, bounds, draw.Over, nil)
+	return encodeJPEG(img, newWidth, newHeight)
+}
+
+// encodeResizedJPEG scales an image to fit within maxSize and encodes it as JPEG
+func encodeResizedJPEG(img image.Image, maxSize int) ([]byte, error) {
+	bounds := img.Bounds()
+	newWidth, newHeight := calculateResizeDimensions(bounds.Dx(), bounds.Dy(), maxSize)
+	return encodeJPEG(img, newWidth, newHeight)
+}
+
+// encodeJPEG scales an image to the given dimensions and encodes it as JPEG
+func encodeJPEG(img image.Image, width, height int) ([]byte, error) {
+	resized := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(resized, resized.Bounds(), img, img.Bounds(), draw.Over, nil)
 
 	// Encode to bytes (always use JPEG for efficiency)
 	var buf bytes.Buffer
@@ -176,43 +208,23 @@ func (p *ImageParser) Parse(filePath string) (string, error) {
 		return "", fmt.Errorf("failed to resize image for OCR: %w", err)
 	}
 
-	base64Image := base64.StdEncoding.EncodeToString(imageData)
-
-	// Create OCR prompt for proper markdown output
-	ocrPrompt := `Analyze this image and extract all visible text content using proper markdown formatting. Please:
-
-**CONTENT TO EXTRACT:**
-1. Read all text in the image, including:
-   - Headers, titles, and subtitles
-   - Body text and paragraphs
-   - Lists, bullet points, and numbered items
-   - Tables, captions, and labels
-   - Any handwritten text (if legible)
-   - Text in charts, diagrams, or figures
+	return p.extractText(context.Background(), imageData)
+}
 
-**MARKDOWN FORMATTING REQUIREMENTS:**
-- Use proper markdown headers: # for main titles, ## for sections, ### for subsections
-- Use **bold text** for emphasized or important text
-- Use *italic text* for subtle emphasis
-- Use standard markdown lists:
-  - For unordered lists: use "- " (dash + space) consistently
-  - For ordered lists: use "1. ", "2. ", etc.
-  - For nested lists: indent with 2 spaces per level
-- Use proper code blocks with triple backticks for code sections
-- Use > for blockquotes if applicable
-- Use | tables | format | for tabular data
-- Use [link text](url) format for any URLs or references
+// OCRImage extracts text from a decoded image, such as a rendered PDF page, using the
+// same prompt as image files
+func (p *ImageParser) OCRImage(ctx context.Context, img image.Image) (string, error) {
+	imageData, err := encodeResizedJPEG(img, p.imageMaxSize)
+	if err != nil {
+		return "", fmt.Errorf("failed to resize image for OCR: %w", err)
+	}
 
-**STRUCTURE REQUIREMENTS:**
-- Maintain the original reading order (left to right, top to bottom)
-- Preserve document hierarchy and organization
-- Use consistent formatting throughout
-- Separate sections with blank lines
-- If text is unclear or partially obscured, indicate with *[unclear text]*
+	return p.extractText(ctx, imageData)
+}
 
-**OUTPUT FORMAT:**
-Provide ONLY the extracted text content in proper markdown format, without any additional ` +
-		`commentary, explanations, or meta-text.`
+// extractText sends encoded image data to the vision model with the OCR prompt
+func (p *ImageParser) extractText(ctx context.Context, imageData []byte) (string, error) {
+	base64Image := base64.StdEncoding.EncodeToString(imageData)
 
 	// Create vision request
 	messages := []VisionMessage{
@@ -240,7 +252,7 @@ Provide ONLY the extracted text content in proper markdown format, without any a
 
 	// Send request to Ollama
 	url := fmt.Sprintf("%s/api/chat", p.ollamaURL)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", fmt.Errorf("failed to create OCR request: %w", err)
 	}
@@ -279,7 +291,12 @@ Provide ONLY the extracted text content in proper markdown format, without any a
 }
 
 // ParseWithChunks extracts text from image and creates chunks optimized for the content
-func (p *ImageParser) ParseWithChunks(filePath, _ string) ([]Chunk, error) {
+func (p *ImageParser) ParseWithChunks(filePath, documentID string) ([]Chunk, error) {
+	return p.ParseWithChunksContext(context.Background(), filePath, documentID)
+}
+
+// ParseWithChunksContext is ParseWithChunks with ctx passed to the chunker
+func (p *ImageParser) ParseWithChunksContext(ctx context.Context, filePath, _ string) ([]Chunk, error) {
 	// Extract text using OCR
 	text, err := p.Parse(filePath)
 	if err != nil {
@@ -302,7 +319,7 @@ func (p *ImageParser) ParseWithChunks(filePath, _ string) ([]Chunk, error) {
 	}
 
 	// Use chunker to split the extracted text
-	chunks := p.chunker.ChunkText(text)
+	chunks := p.chunker.ChunkTextContext(ctx, text)
 
 	// Update chunk metadata to indicate these are from image OCR
 	for i := range chunks {
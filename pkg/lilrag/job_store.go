@@ -0,0 +1,156 @@
+package lilrag
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Statuses of an ingestion job
+const (
+	JobStatusQueued    = "queued"    // Waiting for the worker
+	JobStatusRunning   = "running"   // Being indexed
+	JobStatusCompleted = "completed" // Indexed
+	JobStatusFailed    = "failed"    // Could not be indexed, see Error
+	JobStatusCanceled  = "canceled"  // Canceled before it completed
+)
+
+// Stages of a running job
+const (
+	StageParsing   = "parsing"   // The file is parsed into chunks
+	StageEmbedding = "embedding" // The chunks are embedded
+	StageStoring   = "storing"   // The chunks and embeddings are stored
+)
+
+// Job is a file queued for indexing in the background
+type Job struct {
+	ID         string `json:"id"`
+	DocumentID string `json:"document_id"`
+	FileName   string `json:"file_name"` // Name of the uploaded file
+	// FilePath is the staged copy of the file that is indexed and removed afterwards
+	FilePath string `json:"-"`
+	// SourcePath is recorded as the document's source path after indexing, if set
+	SourcePath     string    `json:"source_path,omitempty"`
+	Status         string    `json:"status"`
+	Stage          string    `json:"stage"` // Indexing stage of a running job, see StageParsing
+	ChunksEmbedded int       `json:"chunks_embedded"`
+	ChunksTotal    int       `json:"chunks_total"` // 0 while a streamed file is being read
+	Error          string    `json:"error,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Done reports whether a job has finished, successfully or not
+func (j *Job) Done() bool {
+	return j.Status == JobStatusCompleted || j.Status == JobStatusFailed || j.Status == JobStatusCanceled
+}
+
+// JobStore is implemented by storage backends that persist ingestion jobs, so queued jobs
+// survive restarts
+type JobStore interface {
+	// CreateJob stores a new job
+	CreateJob(ctx context.Context, job *Job) error
+	// UpdateJob stores the status, stage, progress and error of a job
+	UpdateJob(ctx context.Context, job *Job) error
+	// GetJob returns a job by ID
+	GetJob(ctx context.Context, id string) (*Job, error)
+	// ListJobs returns the jobs with one of the given statuses, or all jobs, oldest first
+	ListJobs(ctx context.Context, statuses ...string) ([]Job, error)
+}
+
+const jobColumns = `id, document_id, file_name, file_path, source_path, status, stage, chunks_embedded,
+	chunks_total, error, created_at, updated_at`
+
+// CreateJob stores a new job
+func (s *SQLiteStorage) CreateJob(ctx context.Context, job *Job) error {
+	if s.db == nil {
+		return fmt.Errorf("storage not initialized")
+	}
+	now := time.Now().UTC()
+	job.CreatedAt, job.UpdatedAt = now, now
+	_, err := s.db.ExecContext(ctx, `INSERT INTO jobs (`+jobColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		job.ID, job.DocumentID, job.FileName, job.FilePath, job.SourcePath, job.Status, job.Stage,
+		job.ChunksEmbedded, job.ChunksTotal, job.Error, job.CreatedAt, job.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create job: %w", err)
+	}
+	return nil
+}
+
+// UpdateJob stores the status, stage, progress and error of a job
+func (s *SQLiteStorage) UpdateJob(ctx context.Context, job *Job) error {
+	if s.db == nil {
+		return fmt.Errorf("storage not initialized")
+	}
+	job.UpdatedAt = time.Now().UTC()
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE jobs SET status = ?, stage = ?, chunks_embedded = ?, chunks_total = ?, error = ?, updated_at = ?
+		WHERE id = ?
+	`, job.Status, job.Stage, job.ChunksEmbedded, job.ChunksTotal, job.Error, job.UpdatedAt, job.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update job: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("job not found: %s", job.ID)
+	}
+	return nil
+}
+
+// GetJob returns a job by ID
+func (s *SQLiteStorage) GetJob(ctx context.Context, id string) (*Job, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("storage not initialized")
+	}
+	var job Job
+	err := s.db.QueryRowContext(ctx, `SELECT `+jobColumns+` FROM jobs WHERE id = ?`, id).Scan(jobDest(&job)...)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("job not found: %s", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	return &job, nil
+}
+
+// ListJobs returns the jobs with one of the given statuses, or all jobs, oldest first
+func (s *SQLiteStorage) ListJobs(ctx context.Context, statuses ...string) ([]Job, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("storage not initialized")
+	}
+	query := `SELECT ` + jobColumns + ` FROM jobs`
+	args := make([]interface{}, len(statuses))
+	if len(statuses) > 0 {
+		query += ` WHERE status IN (?` + strings.Repeat(", ?", len(statuses)-1) + `)`
+		for i, status := range statuses {
+			args[i] = status
+		}
+	}
+	query += ` ORDER BY created_at, rowid`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []Job
+	for rows.Next() {
+		var job Job
+		if err := rows.Scan(jobDest(&job)...); err != nil {
+			return nil, fmt.Errorf("failed to scan job row: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during job iteration: %w", err)
+	}
+	return jobs, nil
+}
+
+// jobDest returns the scan destinations of the job columns
+func jobDest(job *Job) []interface{} {
+	return []interface{}{&job.ID, &job.DocumentID, &job.FileName, &job.FilePath, &job.SourcePath, &job.Status,
+		&job.Stage, &job.ChunksEmbedded, &job.ChunksTotal, &job.Error, &job.CreatedAt, &job.UpdatedAt}
+}
@@ -0,0 +1,252 @@
+package lilrag
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// JobQueue indexes uploaded files in the background, one at a time. Jobs and the staged
+// copies of their files are persisted, so queued jobs survive restarts.
+type JobQueue struct {
+	rag      *LilRag
+	store    JobStore
+	stageDir string
+	wake     chan struct{}
+
+	mu      sync.Mutex
+	running map[string]context.CancelFunc // Cancels the running job by ID
+}
+
+// NewJobQueue returns a queue that stages uploaded files in stageDir. The storage of rag
+// must persist jobs.
+func NewJobQueue(rag *LilRag, stageDir string) (*JobQueue, error) {
+	store, ok := rag.storage.(JobStore)
+	if !ok {
+		return nil, fmt.Errorf("storage does not support ingestion jobs")
+	}
+	if err := os.MkdirAll(stageDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+	return &JobQueue{
+		rag:      rag,
+		store:    store,
+		stageDir: stageDir,
+		wake:     make(chan struct{}, 1),
+		running:  make(map[string]context.CancelFunc),
+	}, nil
+}
+
+// Submit stages the content of an uploaded file and queues it for indexing as documentID.
+// A non-empty sourcePath is recorded as the document's source path once it is indexed.
+func (q *JobQueue) Submit(
+	ctx context.Context, documentID, fileName string, content io.Reader, sourcePath string,
+) (*Job, error) {
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+
+	ext := filepath.Ext(fileName)
+	if strings.HasSuffix(strings.ToLower(fileName), ".tar.gz") {
+		ext = ".tar.gz" // Keep the double extension so the archive is detected
+	}
+	// Staged files are named like upload temp files, so sync does not track them
+	stagedPath := filepath.Join(q.stageDir, uploadTempPrefix+id+ext)
+	if err := writeStagedFile(stagedPath, content); err != nil {
+		return nil, err
+	}
+
+	job := &Job{
+		ID:         id,
+		DocumentID: documentID,
+		FileName:   fileName,
+		FilePath:   stagedPath,
+		SourcePath: sourcePath,
+		Status:     JobStatusQueued,
+	}
+	if err := q.store.CreateJob(ctx, job); err != nil {
+		os.Remove(stagedPath)
+		return nil, err
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// Get returns a job by ID
+func (q *JobQueue) Get(ctx context.Context, id string) (*Job, error) {
+	return q.store.GetJob(ctx, id)
+}
+
+// List returns the jobs with one of the given statuses, or all jobs, oldest first
+func (q *JobQueue) List(ctx context.Context, statuses ...string) ([]Job, error) {
+	return q.store.ListJobs(ctx, statuses...)
+}
+
+// Cancel cancels a queued or running job. A running job stops at its next embedding and
+// is marked canceled by the worker; its document keeps its previous version, if any.
+func (q *JobQueue) Cancel(ctx context.Context, id string) (*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, err := q.store.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	switch job.Status {
+	case JobStatusQueued:
+		job.Status = JobStatusCanceled
+		if err := q.store.UpdateJob(ctx, job); err != nil {
+			return nil, err
+		}
+		os.Remove(job.FilePath)
+	case JobStatusRunning:
+		if cancel := q.running[id]; cancel != nil {
+			cancel()
+		}
+	default:
+		return job, fmt.Errorf("job already %s: %s", job.Status, id)
+	}
+	return job, nil
+}
+
+// Run processes queued jobs until ctx is done. Jobs that were running when a previous
+// process stopped are queued again first.
+func (q *JobQueue) Run(ctx context.Context) error {
+	interrupted, err := q.store.ListJobs(ctx, JobStatusRunning)
+	if err != nil {
+		return err
+	}
+	for i := range interrupted {
+		interrupted[i].Status = JobStatusQueued
+		if err := q.store.UpdateJob(ctx, &interrupted[i]); err != nil {
+			return err
+		}
+	}
+
+	for {
+		job, jobCtx, err := q.next(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-q.wake:
+			}
+			continue
+		}
+		q.process(ctx, jobCtx, job)
+	}
+}
+
+// next marks the oldest queued job as running and returns it with a context that Cancel
+// cancels, or nil if no job is queued
+func (q *JobQueue) next(ctx context.Context) (*Job, context.Context, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queued, err := q.store.ListJobs(ctx, JobStatusQueued)
+	if err != nil || len(queued) == 0 {
+		return nil, nil, err
+	}
+	job := &queued[0]
+	job.Status = JobStatusRunning
+	job.Stage = StageParsing
+	if err := q.store.UpdateJob(ctx, job); err != nil {
+		return nil, nil, err
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	q.running[job.ID] = cancel
+	return job, jobCtx, nil
+}
+
+// process indexes the staged file of a running job and records the outcome
+func (q *JobQueue) process(ctx, jobCtx context.Context, job *Job) {
+	onProgress := func(event IndexEvent) {
+		switch event.Type {
+		case IndexEventChunked, IndexEventEmbedded:
+			job.Stage = StageEmbedding
+			if event.Embedded == event.Total {
+				job.Stage = StageStoring
+			}
+		default:
+			return
+		}
+		job.ChunksEmbedded, job.ChunksTotal = event.Embedded, event.Total
+		if err := q.store.UpdateJob(ctx, job); err != nil {
+			log.Printf("Warning: failed to record progress of job %s: %v", job.ID, err)
+		}
+	}
+	err := q.rag.IndexFileWithOptions(jobCtx, job.FilePath, job.DocumentID, IndexOptions{OnProgress: onProgress})
+	if err == nil && job.SourcePath != "" {
+		if updater, ok := q.rag.storage.(sourcePathUpdater); ok {
+			err = updater.UpdateDocumentSourcePath(ctx, job.DocumentID, job.SourcePath)
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	canceled := err != nil && jobCtx.Err() != nil // Not if it completed before Cancel
+	q.running[job.ID]()
+	delete(q.running, job.ID)
+
+	if ctx.Err() != nil {
+		return // Stopped; the job is queued again on the next run
+	}
+	switch {
+	case canceled:
+		job.Status = JobStatusCanceled
+	case err != nil:
+		job.Status, job.Error = JobStatusFailed, err.Error()
+	default:
+		job.Status = JobStatusCompleted
+	}
+	if err := q.store.UpdateJob(ctx, job); err != nil {
+		log.Printf("Warning: failed to record outcome of job %s: %v", job.ID, err)
+	}
+	os.Remove(job.FilePath)
+}
+
+// writeStagedFile writes the content of an uploaded file to path
+func writeStagedFile(path string, content io.Reader) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to stage file: %w", err)
+	}
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		os.Remove(path)
+		return fmt.Errorf("failed to stage file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("failed to stage file: %w", err)
+	}
+	return nil
+}
+
+// newJobID returns a random job ID
+func newJobID() (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate job ID: %w", err)
+	}
+	return "job-" + hex.EncodeToString(b[:]), nil
+}
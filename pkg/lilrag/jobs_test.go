@@ -0,0 +1,188 @@
+package lilrag
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// blockingEmbedder blocks every embedding until its context is canceled
+type blockingEmbedder struct {
+	started chan struct{}
+}
+
+func (e *blockingEmbedder) Embed(ctx context.Context, _ string) ([]float32, error) {
+	select {
+	case e.started <- struct{}{}:
+	default:
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func setupJobQueue(t *testing.T, embedder Embedder) (*JobQueue, *SQLiteStorage) {
+	t.Helper()
+	storage := setupTableStorage(t)
+	lilRag := &LilRag{
+		storage:         storage,
+		embedder:        embedder,
+		chunker:         NewTextChunker(256, 38),
+		documentHandler: NewDocumentHandler(NewTextChunker(256, 38)),
+		config:          &Config{},
+	}
+	jobs, err := NewJobQueue(lilRag, t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create job queue: %v", err)
+	}
+	return jobs, storage
+}
+
+// runJobQueue runs the queue in the background until the test ends
+func runJobQueue(t *testing.T, jobs *JobQueue) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- jobs.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run returned an error: %v", err)
+		}
+	})
+}
+
+// waitForJob waits until a job is done and returns it
+func waitForJob(t *testing.T, jobs *JobQueue, id string) *Job {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := jobs.Get(context.Background(), id)
+		if err != nil {
+			t.Fatalf("Failed to get job: %v", err)
+		}
+		if job.Done() {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for job %s", id)
+	return nil
+}
+
+func TestJobQueue_Run(t *testing.T) {
+	jobs, storage := setupJobQueue(t, NewMockEmbedder())
+	runJobQueue(t, jobs)
+	ctx := context.Background()
+
+	text := strings.Repeat("Rotate the API keys every ninety days and test the backup restore. ", 40)
+	job, err := jobs.Submit(ctx, "keys", "keys.txt", strings.NewReader(text), "/srv/docs/keys.txt")
+	if err != nil {
+		t.Fatalf("Failed to submit job: %v", err)
+	}
+	if job.Status != JobStatusQueued || !strings.HasPrefix(job.ID, "job-") {
+		t.Errorf("Expected a queued job, got %+v", job)
+	}
+
+	job = waitForJob(t, jobs, job.ID)
+	if job.Status != JobStatusCompleted || job.Error != "" {
+		t.Fatalf("Expected the job to complete, got %+v", job)
+	}
+	if job.Stage != StageStoring || job.ChunksTotal < 2 || job.ChunksEmbedded != job.ChunksTotal {
+		t.Errorf("Expected every chunk to be embedded, got %+v", job)
+	}
+	if _, err := os.Stat(job.FilePath); !os.IsNotExist(err) {
+		t.Errorf("Expected the staged file to be removed, got %v", err)
+	}
+
+	doc, err := storage.GetDocumentByID(ctx, "keys")
+	if err != nil {
+		t.Fatalf("Expected the document to be indexed: %v", err)
+	}
+	if doc.SourcePath != "/srv/docs/keys.txt" {
+		t.Errorf("Expected the source path of the job, got %q", doc.SourcePath)
+	}
+
+	failed, err := jobs.Submit(ctx, "empty", "empty.txt", strings.NewReader(""), "")
+	if err != nil {
+		t.Fatalf("Failed to submit job: %v", err)
+	}
+	failed = waitForJob(t, jobs, failed.ID)
+	if failed.Status != JobStatusFailed || failed.Error == "" {
+		t.Errorf("Expected an empty file to fail with an error, got %+v", failed)
+	}
+}
+
+func TestJobQueue_Cancel(t *testing.T) {
+	embedder := &blockingEmbedder{started: make(chan struct{}, 1)}
+	jobs, _ := setupJobQueue(t, embedder)
+	ctx := context.Background()
+
+	queued, err := jobs.Submit(ctx, "queued", "queued.txt", strings.NewReader("Queued text."), "")
+	if err != nil {
+		t.Fatalf("Failed to submit job: %v", err)
+	}
+	queued, err = jobs.Cancel(ctx, queued.ID)
+	if err != nil || queued.Status != JobStatusCanceled {
+		t.Fatalf("Expected the queued job to be canceled, got %+v (%v)", queued, err)
+	}
+	if _, err := os.Stat(queued.FilePath); !os.IsNotExist(err) {
+		t.Errorf("Expected the staged file to be removed, got %v", err)
+	}
+	if _, err := jobs.Cancel(ctx, queued.ID); err == nil {
+		t.Error("Expected canceling a canceled job to fail")
+	}
+
+	running, err := jobs.Submit(ctx, "running", "running.txt", strings.NewReader("Running text."), "")
+	if err != nil {
+		t.Fatalf("Failed to submit job: %v", err)
+	}
+	runJobQueue(t, jobs)
+	select {
+	case <-embedder.started:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for the job to start embedding")
+	}
+	if job, err := jobs.Get(ctx, running.ID); err != nil || job.Status != JobStatusRunning ||
+		job.Stage != StageEmbedding {
+		t.Errorf("Expected the job to be embedding, got %+v (%v)", job, err)
+	}
+	if _, err := jobs.Cancel(ctx, running.ID); err != nil {
+		t.Fatalf("Failed to cancel running job: %v", err)
+	}
+	if job := waitForJob(t, jobs, running.ID); job.Status != JobStatusCanceled {
+		t.Errorf("Expected the running job to be canceled, got %+v", job)
+	}
+}
+
+func TestJobQueue_RequeuesInterruptedJobs(t *testing.T) {
+	jobs, storage := setupJobQueue(t, NewMockEmbedder())
+	ctx := context.Background()
+
+	// A job left running by a previous process that stopped
+	stagedPath := filepath.Join(jobs.stageDir, uploadTempPrefix+"interrupted.txt")
+	if err := os.WriteFile(stagedPath, []byte("Text of an interrupted upload."), 0o600); err != nil {
+		t.Fatalf("Failed to stage file: %v", err)
+	}
+	interrupted := &Job{
+		ID:         "job-interrupted",
+		DocumentID: "interrupted",
+		FileName:   "interrupted.txt",
+		FilePath:   stagedPath,
+		Status:     JobStatusRunning,
+		Stage:      StageEmbedding,
+	}
+	if err := storage.CreateJob(ctx, interrupted); err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+
+	runJobQueue(t, jobs)
+	if job := waitForJob(t, jobs, interrupted.ID); job.Status != JobStatusCompleted {
+		t.Errorf("Expected the interrupted job to complete, got %+v", job)
+	}
+	if _, err := storage.GetDocumentByID(ctx, "interrupted"); err != nil {
+		t.Errorf("Expected the interrupted upload to be indexed: %v", err)
+	}
+}
@@ -3,12 +3,14 @@ package lilrag
 import (
 	"context"
 	"fmt"
+	"log"
 	"os"
 	"regexp"
 	"strings"
 	"time"
 
 	"lil-rag/pkg/metrics"
+	"lil-rag/pkg/tokenizer"
 )
 
 const (
@@ -39,6 +41,63 @@ type Config struct {
 	MaxTokens      int
 	Overlap        int
 	ImageMaxSize   int
+
+	// HierarchicalChunking embeds small child chunks for retrieval and returns
+	// their larger parent sections as context
+	HierarchicalChunking bool
+	ParentMaxTokens      int
+	ChildMaxTokens       int
+
+	// ChunkStrategy selects "adaptive" (default) or "semantic" chunking
+	ChunkStrategy      string
+	SemanticPercentile float64
+	MinTokens          int
+
+	// TokenizerType selects "whitespace", "wordpiece" or "bpe"; empty auto-detects from TokenizerPath
+	TokenizerType string
+	// TokenizerPath points at a tokenizer.json, vocab.txt or merges.txt matching the embedding model
+	TokenizerPath string
+	// EmbeddingMaxTokens is the embedding model's context size; longer text is truncated
+	EmbeddingMaxTokens int
+	// ChatContextTokens is the token budget for retrieved document context in chat prompts
+	ChatContextTokens int
+
+	// PDFOCR renders PDF pages with little or no extractable text and reads them with
+	// the vision model, so scanned PDFs can be indexed
+	PDFOCR bool
+	// PDFOCRMaxPages caps the pages sent to OCR per document
+	PDFOCRMaxPages int
+	// PDFOCRConcurrency is the number of pages read by the vision model at once
+	PDFOCRConcurrency int
+
+	// EmailAttachments indexes attachments of .eml and .mbox messages in supported formats
+	// along with the message
+	EmailAttachments bool
+
+	// DOCXNotes indexes the footnotes and comments of Word documents as separate chunks
+	// under the heading of the paragraph that references them
+	DOCXNotes bool
+
+	// HTMLFullPage indexes every element of HTML pages, including navigation, footers and
+	// sidebars, instead of only the main content
+	HTMLFullPage bool
+
+	// NotebookOutputs indexes the text outputs of Jupyter notebook code cells with the code
+	NotebookOutputs bool
+
+	// TableQuery lets the chat model answer questions with read-only SQL over the sheets
+	// of retrieved XLSX and CSV files
+	TableQuery bool
+	// TableQueryMaxRows caps the rows returned by a table query
+	TableQueryMaxRows int
+
+	// ArchiveCombined indexes a .zip or .tar.gz archive as one document instead of one
+	// document per entry
+	ArchiveCombined bool
+	// ArchiveMaxEntries caps the number of entries read from an archive
+	ArchiveMaxEntries int
+	// ArchiveMaxSizeMB caps the total uncompressed size of an archive's entries
+	ArchiveMaxSizeMB int
 }
 
 type Storage interface {
@@ -47,9 +106,10 @@ type Storage interface {
 	IndexChunks(ctx context.Context, documentID string, text string, chunks []Chunk, embeddings [][]float32) error
 	IndexChunksWithMetadata(
 		ctx context.Context, documentID, text string, chunks []Chunk, embeddings [][]float32,
-		originalFilePath, docType string,
+		originalFilePath, docType string, metadata map[string]string,
 	) error
 	Search(ctx context.Context, embedding []float32, limit int) ([]SearchResult, error)
+	SearchWithOptions(ctx context.Context, embedding []float32, limit int, opts SearchOptions) ([]SearchResult, error)
 	ListDocuments(ctx context.Context) ([]DocumentInfo, error)
 	GetDocumentByID(ctx context.Context, documentID string) (*DocumentInfo, error)
 	GetDocumentChunks(ctx context.Context, documentID string) ([]Chunk, error)
@@ -80,19 +140,45 @@ type DocumentInfo struct {
 	IsImage    bool      `json:"is_image"`
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
+
+	// Metadata holds document-level fields such as email headers
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 // ChunkInfo represents a chunk with database metadata for API responses
 type ChunkInfo struct {
-	ID         string `json:"id"`
-	DocumentID string `json:"document_id"`
-	Text       string `json:"text"`
-	Index      int    `json:"index"`
-	StartPos   int    `json:"start_pos"`
-	EndPos     int    `json:"end_pos"`
-	TokenCount int    `json:"token_count"`
-	ChunkType  string `json:"chunk_type"`
-	PageNumber *int   `json:"page_number,omitempty"`
+	ID            string `json:"id"`
+	DocumentID    string `json:"document_id"`
+	Text          string `json:"text"`
+	Index         int    `json:"index"`
+	StartPos      int    `json:"start_pos"`
+	EndPos        int    `json:"end_pos"`
+	TokenCount    int    `json:"token_count"`
+	ChunkType     string `json:"chunk_type"`
+	PageNumber    *int   `json:"page_number,omitempty"`
+	EndPageNumber *int   `json:"end_page_number,omitempty"`
+	ParentChunkID string `json:"parent_chunk_id,omitempty"`
+	HeadingPath   string `json:"heading_path,omitempty"`
+	Language      string `json:"language,omitempty"`
+	SymbolName    string `json:"symbol_name,omitempty"`
+	SymbolKind    string `json:"symbol_kind,omitempty"`
+	StartLine     int    `json:"start_line,omitempty"`
+	EndLine       int    `json:"end_line,omitempty"`
+
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// SearchOptions restricts a search to matching chunks
+type SearchOptions struct {
+	Language string // Only match source code chunks in this language, e.g. "go" or "python"
+	// Metadata only matches chunks whose chunk or document metadata contains each value,
+	// ignoring case, e.g. {"from": "alice@example.com"}
+	Metadata map[string]string
+}
+
+// IsEmpty reports whether no filters are set
+func (o SearchOptions) IsEmpty() bool {
+	return o.Language == "" && len(o.Metadata) == 0
 }
 
 func New(config *Config) (*LilRag, error) {
@@ -121,6 +207,33 @@ func New(config *Config) (*LilRag, error) {
 	if config.Overlap == 0 {
 		config.Overlap = 200
 	}
+	if config.ParentMaxTokens == 0 {
+		config.ParentMaxTokens = 1024
+	}
+	if config.ChildMaxTokens == 0 {
+		config.ChildMaxTokens = 128
+	}
+	if config.EmbeddingMaxTokens == 0 {
+		config.EmbeddingMaxTokens = DefaultEmbeddingMaxTokens
+	}
+	if config.ChatContextTokens == 0 {
+		config.ChatContextTokens = DefaultChatContextTokens
+	}
+	if config.PDFOCRMaxPages == 0 {
+		config.PDFOCRMaxPages = 50
+	}
+	if config.PDFOCRConcurrency == 0 {
+		config.PDFOCRConcurrency = 2
+	}
+	if config.TableQueryMaxRows == 0 {
+		config.TableQueryMaxRows = DefaultTableQueryRows
+	}
+	if config.ArchiveMaxEntries == 0 {
+		config.ArchiveMaxEntries = DefaultArchiveLimits().MaxEntries
+	}
+	if config.ArchiveMaxSizeMB == 0 {
+		config.ArchiveMaxSizeMB = int(DefaultArchiveLimits().MaxTotalSize >> 20)
+	}
 
 	return &LilRag{
 		config: config,
@@ -138,14 +251,42 @@ func (m *LilRag) Initialize() error {
 	}
 	m.storage = storage
 
+	// Load the tokenizer first so chunking, embedding limits and metrics all count the same way;
+	// it is kept per instance on the chunker, embedder and chat client
+	tok, err := tokenizer.New(m.config.TokenizerType, m.config.TokenizerPath)
+	if err != nil {
+		return fmt.Errorf("failed to initialize tokenizer: %w", err)
+	}
+
 	embedder, err := NewOllamaEmbedderWithTimeout(m.config.OllamaURL, m.config.Model, m.config.TimeoutSeconds)
 	if err != nil {
 		return fmt.Errorf("failed to initialize embedder: %w", err)
 	}
+	embedder.SetTokenizer(tok, m.config.EmbeddingMaxTokens)
 	m.embedder = embedder
 
 	// Initialize text chunker
-	m.chunker = NewTextChunker(m.config.MaxTokens, m.config.Overlap)
+	if m.config.HierarchicalChunking {
+		m.chunker = NewHierarchicalTextChunker(m.config.ParentMaxTokens, m.config.ChildMaxTokens, m.config.Overlap)
+	} else {
+		m.chunker = NewTextChunker(m.config.MaxTokens, m.config.Overlap)
+	}
+	m.chunker.Tokenizer = tok
+	if m.config.ChunkStrategy == ChunkStrategySemantic {
+		m.chunker.Strategy = ChunkStrategySemantic
+		m.chunker.Embedder = m.embedder
+		m.chunker.SemanticPercentile = m.config.SemanticPercentile
+		m.chunker.MinTokens = m.config.MinTokens
+	}
+
+	// Embedded chunks must fit the embedding model's context
+	if m.chunker.IsHierarchical() {
+		if m.chunker.ChildMaxTokens > m.config.EmbeddingMaxTokens {
+			m.chunker.ChildMaxTokens = m.config.EmbeddingMaxTokens
+		}
+	} else if m.chunker.MaxTokens > m.config.EmbeddingMaxTokens {
+		m.chunker.MaxTokens = m.config.EmbeddingMaxTokens
+	}
 
 	// Initialize PDF parser (keep for backward compatibility)
 	m.pdfParser = NewPDFParser()
@@ -159,13 +300,60 @@ func (m *LilRag) Initialize() error {
 		m.config.ImageMaxSize,
 	)
 
+	// Scanned PDF pages are rendered and read by the same vision model as images
+	if m.config.PDFOCR {
+		ocr := NewImageParserWithTimeout(
+			m.config.OllamaURL,
+			m.config.VisionModel,
+			m.chunker,
+			m.config.TimeoutSeconds*10,
+			m.config.ImageMaxSize,
+		)
+		m.pdfParser = NewPDFParserWithOCR(ocr, m.config.PDFOCRMaxPages, m.config.PDFOCRConcurrency)
+		m.documentHandler.RegisterParser(DocumentTypePDF, m.pdfParser)
+	}
+
+	if m.config.EmailAttachments {
+		m.documentHandler.EnableEmailAttachments()
+	}
+
+	if m.config.DOCXNotes {
+		m.documentHandler.EnableDOCXNotes()
+	}
+
+	if m.config.HTMLFullPage {
+		m.documentHandler.EnableHTMLFullPage()
+	}
+
+	if m.config.NotebookOutputs {
+		m.documentHandler.EnableNotebookOutputs()
+	}
+
+	m.documentHandler.SetArchiveLimits(ArchiveLimits{
+		MaxEntries:   m.config.ArchiveMaxEntries,
+		MaxTotalSize: int64(m.config.ArchiveMaxSizeMB) << 20,
+	})
+
 	// Initialize chat client
 	m.chatClient = NewOllamaChatClientWithTimeout(m.config.OllamaURL, m.config.ChatModel, m.config.TimeoutSeconds*4)
+	m.chatClient.SetContextBudget(tok, m.config.ChatContextTokens)
 
 	return m.storage.Initialize()
 }
 
+// Index splits text into chunks, embeds them and stores them as document id
 func (m *LilRag) Index(ctx context.Context, text, id string) error {
+	return m.IndexWithOptions(ctx, text, id, IndexOptions{})
+}
+
+// IndexWithOptions indexes text like Index, reporting its progress to opts.OnProgress
+func (m *LilRag) IndexWithOptions(ctx context.Context, text, id string, opts IndexOptions) error {
+	ctx = withIndexProgress(ctx, opts.OnProgress)
+	return reportIndexError(ctx, id, m.indexText(ctx, text, id))
+}
+
+// indexText indexes text as document id
+func (m *LilRag) indexText(ctx context.Context, text, id string) error {
 	if text == "" {
 		return fmt.Errorf("text cannot be empty")
 	}
@@ -177,47 +365,84 @@ func (m *LilRag) Index(ctx context.Context, text, id string) error {
 	}
 
 	// Check if text needs chunking
-	if !m.chunker.IsLongText(text) {
+	if !m.chunker.IsLongText(text) && !m.chunker.NeedsChildChunks(text) {
 		// Simple case: text fits in one chunk
+		event := IndexEvent{DocumentID: id, Chunks: 1, Total: 1}
+		event.Type = IndexEventChunked
+		reportIndexEvent(ctx, event)
 		embedding, err := m.embedder.Embed(ctx, text)
 		if err != nil {
 			return fmt.Errorf("failed to create embedding: %w", err)
 		}
-		return m.storage.Index(ctx, id, text, embedding)
+		event.Type, event.Embedded = IndexEventEmbedded, 1
+		reportIndexEvent(ctx, event)
+		if err := m.storage.Index(ctx, id, text, embedding); err != nil {
+			return err
+		}
+		event.Type = IndexEventStored
+		reportIndexEvent(ctx, event)
+		return nil
 	}
 
 	// Complex case: text needs to be chunked
-	chunks := m.chunker.ChunkText(text)
+	chunks := m.chunker.ChunkTextContext(ctx, text)
+	if m.chunker.IsHierarchical() {
+		chunks = m.chunker.BuildHierarchy(chunks)
+	}
 	if len(chunks) == 0 {
 		return fmt.Errorf("failed to create chunks from text")
 	}
 
-	fmt.Printf("Splitting text into %d chunks for document '%s'\n", len(chunks), id)
-
 	// Record document tokens processed
 	totalTokens := 0
 	for _, chunk := range chunks {
-		totalTokens += chunk.TokenCount
+		if chunk.ParentIndex == nil {
+			totalTokens += chunk.TokenCount
+		}
 	}
 	metrics.RecordDocumentTokens("text", totalTokens)
 
 	// Create embeddings for each chunk
 	embeddings := make([][]float32, len(chunks))
+	event := IndexEvent{Type: IndexEventChunked, DocumentID: id, Chunks: len(chunks), Total: embeddedChunkCount(chunks)}
+	reportIndexEvent(ctx, event)
 	for i, chunk := range chunks {
-		fmt.Printf("Creating embedding for chunk %d/%d (tokens: %d)\n", i+1, len(chunks), chunk.TokenCount)
-		embedding, err := m.embedder.Embed(ctx, chunk.Text)
+		if chunk.IsParent {
+			continue
+		}
+		embedding, err := m.embedder.Embed(ctx, chunk.EmbeddingText())
 		if err != nil {
 			return fmt.Errorf("failed to create embedding for chunk %d: %w", i, err)
 		}
 		embeddings[i] = embedding
+		event.Type = IndexEventEmbedded
+		event.Embedded++
+		reportIndexEvent(ctx, event)
 	}
 
 	// Store document with chunks
-	return m.storage.IndexChunks(ctx, id, text, chunks, embeddings)
+	if err := m.storage.IndexChunks(ctx, id, text, chunks, embeddings); err != nil {
+		return err
+	}
+	event.Type = IndexEventStored
+	reportIndexEvent(ctx, event)
+	return nil
 }
 
 // IndexPDF indexes a PDF file with page-based chunking
 func (m *LilRag) IndexPDF(ctx context.Context, filePath, id string) error {
+	return m.IndexPDFWithOptions(ctx, filePath, id, IndexOptions{})
+}
+
+// IndexPDFWithOptions indexes a PDF file like IndexPDF, reporting its progress to
+// opts.OnProgress
+func (m *LilRag) IndexPDFWithOptions(ctx context.Context, filePath, id string, opts IndexOptions) error {
+	ctx = withIndexProgress(ctx, opts.OnProgress)
+	return reportIndexError(ctx, id, m.indexPDF(ctx, filePath, id))
+}
+
+// indexPDF indexes a PDF file as document id
+func (m *LilRag) indexPDF(ctx context.Context, filePath, id string) error {
 	if filePath == "" {
 		return fmt.Errorf("file path cannot be empty")
 	}
@@ -231,112 +456,435 @@ func (m *LilRag) IndexPDF(ctx context.Context, filePath, id string) error {
 	}
 
 	// Parse PDF into page-based chunks
-	chunks, err := m.pdfParser.ParsePDFWithPageChunks(filePath, id)
+	chunks, err := m.pdfParser.ParseWithChunksContext(ctx, filePath, id)
 	if err != nil {
 		return fmt.Errorf("failed to parse PDF: %w", err)
 	}
+	reportIndexEvent(ctx, IndexEvent{Type: IndexEventParsed, DocumentID: id})
 
 	if len(chunks) == 0 {
 		return fmt.Errorf("no readable content found in PDF")
 	}
 
-	fmt.Printf("Parsing PDF into %d page chunks for document '%s'\n", len(chunks), id)
+	if m.chunker != nil && m.chunker.IsHierarchical() {
+		chunks = m.chunker.BuildHierarchy(chunks)
+	}
 
 	// Create embeddings for each page chunk
 	embeddings := make([][]float32, len(chunks))
+	event := IndexEvent{Type: IndexEventChunked, DocumentID: id, Chunks: len(chunks), Total: embeddedChunkCount(chunks)}
+	reportIndexEvent(ctx, event)
 	for i, chunk := range chunks {
-		pageInfo := ""
-		if chunk.PageNumber != nil {
-			pageInfo = fmt.Sprintf(" (page %d)", *chunk.PageNumber)
+		if chunk.IsParent {
+			continue
 		}
-		fmt.Printf("Creating embedding for chunk %d/%d%s (tokens: %d)\n",
-			i+1, len(chunks), pageInfo, chunk.TokenCount)
-
-		embedding, err := m.embedder.Embed(ctx, chunk.Text)
+		embedding, err := m.embedder.Embed(ctx, chunk.EmbeddingText())
 		if err != nil {
 			return fmt.Errorf("failed to create embedding for page chunk %d: %w", i, err)
 		}
 		embeddings[i] = embedding
+		event.Type = IndexEventEmbedded
+		event.Embedded++
+		reportIndexEvent(ctx, event)
 	}
 
 	// Create a combined text for the document record (first 1000 chars from each page)
 	var combinedText strings.Builder
 	for _, chunk := range chunks {
+		if chunk.ParentIndex != nil {
+			continue // Child text is already covered by its parent
+		}
 		text := chunk.Text
 		if len(text) > 1000 {
 			text = text[:1000] + "..."
 		}
-		if chunk.PageNumber != nil {
-			combinedText.WriteString(fmt.Sprintf("[Page %d] ", *chunk.PageNumber))
+		if label := chunk.PageLabel(); label != "" {
+			combinedText.WriteString(fmt.Sprintf("[Page %s] ", label))
 		}
 		combinedText.WriteString(text)
 		combinedText.WriteString("\n\n")
 	}
 
 	// Store document with page chunks
-	return m.storage.IndexChunks(ctx, id, combinedText.String(), chunks, embeddings)
+	if err := m.storage.IndexChunks(ctx, id, combinedText.String(), chunks, embeddings); err != nil {
+		return err
+	}
+	event.Type = IndexEventStored
+	reportIndexEvent(ctx, event)
+	return nil
 }
 
 // IndexFile indexes a file, automatically detecting the format and using appropriate parser
 func (m *LilRag) IndexFile(ctx context.Context, filePath, id string) error {
+	return m.IndexFileWithOptions(ctx, filePath, id, IndexOptions{})
+}
+
+// IndexFileWithOptions indexes a file like IndexFile, reporting its progress to
+// opts.OnProgress
+func (m *LilRag) IndexFileWithOptions(ctx context.Context, filePath, id string, opts IndexOptions) error {
+	ctx = withIndexProgress(ctx, opts.OnProgress)
+	return reportIndexError(ctx, id, m.indexFile(ctx, filePath, id))
+}
+
+// indexFile indexes a file as document id
+func (m *LilRag) indexFile(ctx context.Context, filePath, id string) error {
 	if m.documentHandler == nil {
 		// Fallback to legacy behavior if document handler not initialized
 		if IsPDFFile(filePath) {
-			return m.IndexPDF(ctx, filePath, id)
+			return m.indexPDF(ctx, filePath, id)
 		}
 		content, err := os.ReadFile(filePath)
 		if err != nil {
 			return fmt.Errorf("failed to read file: %w", err)
 		}
-		return m.Index(ctx, string(content), id)
+		return m.indexText(ctx, string(content), id)
+	}
+
+	return m.indexFileFrom(ctx, filePath, filePath, id, nil)
+}
+
+// indexFileFrom indexes a local file whose content came from sourcePath, such as a
+// downloaded web page, adding extraMetadata to the metadata parsed from the file
+func (m *LilRag) indexFileFrom(
+	ctx context.Context, filePath, sourcePath, id string, extraMetadata map[string]string,
+) error {
+	if m.documentHandler == nil {
+		return fmt.Errorf("document handler not initialized")
 	}
 
 	// Use document handler for all supported formats
 	if !m.documentHandler.IsSupported(filePath) {
-		return fmt.Errorf("unsupported file format: %s", filePath)
+		return fmt.Errorf("unsupported file format: %s", sourcePath)
+	}
+
+	docType := m.documentHandler.DetectDocumentType(filePath)
+	if docType == DocumentTypeArchive && !m.config.ArchiveCombined {
+		_, err := m.indexArchiveFrom(ctx, filePath, sourcePath, id)
+		return err
+	}
+
+	// Record the state of local files so that changes on disk can be detected later
+	if sourcePath == filePath {
+		fileMetadata, err := sourceFileMetadata(filePath)
+		if err != nil {
+			return err
+		}
+		extraMetadata = mergeMetadata(extraMetadata, fileMetadata)
 	}
 
-	// Parse and chunk the document
-	chunks, err := m.documentHandler.ParseFileWithChunks(filePath, id)
+	if m.documentHandler.CanStreamChunks(filePath) {
+		// Large tabular files are embedded and stored while they are read
+		metadata, err := m.fileMetadata(filePath, extraMetadata)
+		if err != nil {
+			return err
+		}
+		if err := m.indexStreamedChunks(ctx, filePath, sourcePath, id, docType, metadata); err != nil {
+			return err
+		}
+	} else {
+		// Parse and chunk the document
+		chunks, err := m.documentHandler.ParseFileWithChunksContext(ctx, filePath, id)
+		if err != nil {
+			return fmt.Errorf("failed to parse document: %w", err)
+		}
+		reportIndexEvent(ctx, IndexEvent{Type: IndexEventParsed, DocumentID: id})
+
+		metadata, err := m.fileMetadata(filePath, extraMetadata)
+		if err != nil {
+			return err
+		}
+		if err := m.indexParsedChunks(ctx, id, sourcePath, docType, chunks, metadata); err != nil {
+			return err
+		}
+	}
+
+	return m.storeTables(ctx, id, func(w TableWriter) error {
+		if err := m.documentHandler.StreamFileTables(filePath, w); err != nil {
+			return fmt.Errorf("failed to read document tables: %w", err)
+		}
+		return nil
+	})
+}
+
+// fileMetadata returns the metadata parsed from a file with extraMetadata added
+func (m *LilRag) fileMetadata(filePath string, extraMetadata map[string]string) (map[string]string, error) {
+	metadata, err := m.documentHandler.ParseFileMetadata(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to parse document: %w", err)
+		return nil, fmt.Errorf("failed to read document metadata: %w", err)
+	}
+	if len(extraMetadata) > 0 && metadata == nil {
+		metadata = make(map[string]string, len(extraMetadata))
+	}
+	for key, value := range extraMetadata {
+		metadata[key] = value
+	}
+	return metadata, nil
+}
+
+// IndexArchive indexes every supported file of a .zip, .tar or .tar.gz archive as its own
+// document. Entry IDs are derived from id and the entry path and are returned in archive
+// order; each entry document records "archive_id" and "archive_entry" metadata.
+func (m *LilRag) IndexArchive(ctx context.Context, filePath, id string) ([]string, error) {
+	return m.indexArchiveFrom(ctx, filePath, filePath, id)
+}
+
+// indexArchiveFrom indexes the entries of an archive whose content came from sourcePath
+func (m *LilRag) indexArchiveFrom(ctx context.Context, filePath, sourcePath, id string) ([]string, error) {
+	if m.documentHandler == nil {
+		return nil, fmt.Errorf("document handler not initialized")
+	}
+
+	var fileMetadata map[string]string
+	if sourcePath == filePath {
+		var err error
+		if fileMetadata, err = sourceFileMetadata(filePath); err != nil {
+			return nil, err
+		}
 	}
 
+	entries, err := m.documentHandler.ParseArchive(filePath, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse archive: %w", err)
+	}
+	reportIndexEvent(ctx, IndexEvent{Type: IndexEventParsed, DocumentID: id})
+
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		entry.Metadata = mergeMetadata(entry.Metadata, fileMetadata)
+		entry.Metadata["archive_id"] = id
+		if err := m.indexParsedChunks(ctx, entry.ID, sourcePath, entry.DocType, entry.Chunks, entry.Metadata); err != nil {
+			return ids, fmt.Errorf("failed to index %s: %w", entry.Path, err)
+		}
+		tables := entry.Tables
+		write := func(w TableWriter) error { return writeTables(w, tables) }
+		if err := m.storeTables(ctx, entry.ID, write); err != nil {
+			return ids, fmt.Errorf("failed to index %s: %w", entry.Path, err)
+		}
+		ids = append(ids, entry.ID)
+	}
+
+	if err := m.deleteRemovedArchiveEntries(ctx, id, ids); err != nil {
+		return ids, err
+	}
+	return ids, nil
+}
+
+// deleteRemovedArchiveEntries deletes the documents of entries indexed from an earlier
+// version of archive id that are not among the current entry ids
+func (m *LilRag) deleteRemovedArchiveEntries(ctx context.Context, id string, ids []string) error {
+	docs, err := m.storage.ListDocuments(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	current := make(map[string]bool, len(ids))
+	for _, entryID := range ids {
+		current[entryID] = true
+	}
+	for _, doc := range docs {
+		if doc.Metadata["archive_id"] != id || current[doc.ID] {
+			continue
+		}
+		if err := m.storage.DeleteDocument(ctx, doc.ID); err != nil {
+			return fmt.Errorf("failed to delete removed archive entry %s: %w", doc.ID, err)
+		}
+	}
+	return nil
+}
+
+// storeTables loads the sheets of a tabular document into SQL tables when the storage
+// supports table queries, replacing the tables of a previous version of the document.
+// The sheets are written by write while they are read from the file.
+func (m *LilRag) storeTables(ctx context.Context, id string, write func(TableWriter) error) error {
+	store, ok := m.storage.(TableStore)
+	if !ok {
+		return nil
+	}
+	if err := store.StoreTables(ctx, id, write); err != nil {
+		return fmt.Errorf("failed to store document tables: %w", err)
+	}
+	return nil
+}
+
+// indexParsedChunks embeds and stores the chunks of a parsed file
+func (m *LilRag) indexParsedChunks(
+	ctx context.Context, id, filePath string, docType DocumentType, chunks []Chunk, metadata map[string]string,
+) error {
 	if len(chunks) == 0 {
 		return fmt.Errorf("no content found in document")
 	}
 
+	if m.chunker != nil && m.chunker.IsHierarchical() {
+		chunks = m.chunker.BuildHierarchy(chunks)
+	}
+
 	// Record document tokens processed - determine document type from file path
 	totalTokens := 0
 	for _, chunk := range chunks {
-		totalTokens += chunk.TokenCount
+		if chunk.ParentIndex == nil {
+			totalTokens += chunk.TokenCount
+		}
 	}
-	docType := m.documentHandler.DetectDocumentType(filePath)
 	metrics.RecordDocumentTokens(string(docType), totalTokens)
 
 	// Generate embeddings for all chunks
 	embeddings := make([][]float32, len(chunks))
 	var combinedText strings.Builder
+	event := IndexEvent{Type: IndexEventChunked, DocumentID: id, Chunks: len(chunks), Total: embeddedChunkCount(chunks)}
+	reportIndexEvent(ctx, event)
 
 	for i, chunk := range chunks {
-		embedding, err := m.embedder.Embed(ctx, chunk.Text)
-		if err != nil {
-			return fmt.Errorf("failed to create embedding for chunk %d: %w", i, err)
+		if !chunk.IsParent {
+			embedding, err := m.embedder.Embed(ctx, chunk.EmbeddingText())
+			if err != nil {
+				return fmt.Errorf("failed to create embedding for chunk %d: %w", i, err)
+			}
+			embeddings[i] = embedding
+			event.Type = IndexEventEmbedded
+			event.Embedded++
+			reportIndexEvent(ctx, event)
 		}
-		embeddings[i] = embedding
 
-		// Build combined text for storage
-		if i > 0 {
+		// Build combined text for storage; child text is already covered by its parent
+		if chunk.ParentIndex != nil {
+			continue
+		}
+		if combinedText.Len() > 0 {
 			combinedText.WriteString("\n\n")
 		}
 		combinedText.WriteString(chunk.Text)
 	}
 
 	// Store document with chunks and metadata
-	return m.storage.IndexChunksWithMetadata(ctx, id, combinedText.String(), chunks, embeddings, filePath, string(docType))
+	if err := m.storage.IndexChunksWithMetadata(
+		ctx, id, combinedText.String(), chunks, embeddings, filePath, string(docType), metadata,
+	); err != nil {
+		return err
+	}
+	event.Type = IndexEventStored
+	reportIndexEvent(ctx, event)
+	return nil
+}
+
+// streamedChunkBatch is the number of chunks written to storage at a time while a file
+// is streamed
+const streamedChunkBatch = 64
+
+// indexStreamedChunks embeds the chunks of a file as its parser reads them and stores
+// them in batches, so neither the chunks nor the document text are held in memory whole.
+// The chunks are collected first for storage backends that do not implement ChunkStore.
+func (m *LilRag) indexStreamedChunks(
+	ctx context.Context, filePath, sourcePath, id string, docType DocumentType, metadata map[string]string,
+) error {
+	store, ok := m.storage.(ChunkStore)
+	if !ok {
+		chunks, err := m.documentHandler.ParseFileWithChunksContext(ctx, filePath, id)
+		if err != nil {
+			return fmt.Errorf("failed to parse document: %w", err)
+		}
+		reportIndexEvent(ctx, IndexEvent{Type: IndexEventParsed, DocumentID: id})
+		return m.indexParsedChunks(ctx, id, sourcePath, docType, chunks, metadata)
+	}
+
+	// The number of chunks is only known once the file is read, so embedded events
+	// report a Total of 0 until the chunked event
+	event := IndexEvent{DocumentID: id}
+	totalTokens := 0
+	err := store.StoreChunks(ctx, id, sourcePath, string(docType), metadata, func(w ChunkWriter) error {
+		batch := make([]Chunk, 0, streamedChunkBatch)
+		embeddings := make([][]float32, 0, streamedChunkBatch)
+		flush := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			err := w.WriteChunks(batch, embeddings)
+			batch, embeddings = batch[:0], embeddings[:0]
+			return err
+		}
+
+		var indexErr error
+		parseErr := m.documentHandler.StreamFileChunks(ctx, filePath, id, func(chunk Chunk) error {
+			if event.Chunks == 0 {
+				reportIndexEvent(ctx, IndexEvent{Type: IndexEventParsed, DocumentID: id})
+			}
+
+			// Each streamed chunk becomes a parent with its children in hierarchical mode
+			chunk.Index = 0
+			pieces := []Chunk{chunk}
+			if m.chunker != nil && m.chunker.IsHierarchical() {
+				pieces = m.chunker.BuildHierarchy(pieces)
+			}
+
+			base := event.Chunks
+			for _, piece := range pieces {
+				piece.Index += base
+				if piece.ParentIndex != nil {
+					parentIndex := *piece.ParentIndex + base
+					piece.ParentIndex = &parentIndex
+				} else {
+					totalTokens += piece.TokenCount
+				}
+
+				var embedding []float32
+				if !piece.IsParent {
+					var err error
+					embedding, err = m.embedder.Embed(ctx, piece.EmbeddingText())
+					if err != nil {
+						indexErr = fmt.Errorf("failed to create embedding for chunk %d: %w", piece.Index, err)
+						return indexErr
+					}
+					event.Type = IndexEventEmbedded
+					event.Embedded++
+					reportIndexEvent(ctx, event)
+				}
+
+				batch = append(batch, piece)
+				embeddings = append(embeddings, embedding)
+				if len(batch) == streamedChunkBatch {
+					if indexErr = flush(); indexErr != nil {
+						return indexErr
+					}
+				}
+			}
+			event.Chunks += len(pieces)
+			return nil
+		})
+		if indexErr != nil {
+			return indexErr
+		}
+		if parseErr != nil {
+			return fmt.Errorf("failed to parse document: %w", parseErr)
+		}
+		if event.Chunks == 0 {
+			return fmt.Errorf("no content found in document")
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+
+		event.Type, event.Total = IndexEventChunked, event.Embedded
+		reportIndexEvent(ctx, event)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	metrics.RecordDocumentTokens(string(docType), totalTokens)
+	event.Type = IndexEventStored
+	reportIndexEvent(ctx, event)
+	return nil
 }
 
 func (m *LilRag) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	return m.SearchWithOptions(ctx, query, limit, SearchOptions{})
+}
+
+// SearchWithOptions performs a semantic search restricted to chunks matching opts,
+// e.g. only Go source code
+func (m *LilRag) SearchWithOptions(
+	ctx context.Context, query string, limit int, opts SearchOptions,
+) ([]SearchResult, error) {
 	if query == "" {
 		return nil, fmt.Errorf("query cannot be empty")
 	}
@@ -362,14 +910,15 @@ func (m *LilRag) Search(ctx context.Context, query string, limit int) ([]SearchR
 	}
 
 	// Primary vector search
-	results, err := m.storage.Search(ctx, embedding, limit)
+	results, err := m.storage.SearchWithOptions(ctx, embedding, limit, opts)
 	if err != nil {
 		return nil, fmt.Errorf("vector search failed: %w", err)
 	}
 
 	// For better compatibility with image-derived content, check if we need to apply
-	// text-based fallback search for improved accuracy
-	if m.needsTextFallback(query) {
+	// text-based fallback search for improved accuracy. The fallback searches whole
+	// documents, so it cannot honor chunk-level filters.
+	if opts.IsEmpty() && m.needsTextFallback(query) {
 		// Always supplement business/contact queries with text-based search for better recall
 		textResults, err := m.performTextFallbackSearch(ctx, query, limit)
 		if err == nil && len(textResults) > 0 {
@@ -530,8 +1079,14 @@ func (m *LilRag) UpdateChunk(ctx context.Context, chunkID, newText string) error
 		return fmt.Errorf("chunk text cannot be empty")
 	}
 
+	// Keep the section heading path in the embedded text for structured documents
+	embedText := newText
+	if existing, err := m.storage.GetChunk(ctx, chunkID); err == nil && existing != nil {
+		embedText = Chunk{Text: newText, HeadingPath: existing.HeadingPath}.EmbeddingText()
+	}
+
 	// Generate new embedding for the updated text
-	embedding, err := m.embedder.Embed(ctx, newText)
+	embedding, err := m.embedder.Embed(ctx, embedText)
 	if err != nil {
 		return fmt.Errorf("failed to generate embedding for updated chunk: %w", err)
 	}
@@ -561,15 +1116,15 @@ func (m *LilRag) Chat(ctx context.Context, userMessage string, limit int) (strin
 	optimizedQuery, err := m.chatClient.OptimizeQuery(ctx, userMessage)
 	if err != nil {
 		// Log the error but continue with the original query
-		fmt.Printf("Warning: Query optimization failed, using original query: %v\n", err)
+		log.Printf("Warning: Query optimization failed, using original query: %v", err)
 		optimizedQuery = userMessage
 	}
 
 	// Log the query transformation for visibility
 	if optimizedQuery != userMessage {
-		fmt.Printf("Query optimization: '%s' → '%s'\n", userMessage, optimizedQuery)
+		log.Printf("Query optimization: '%s' → '%s'", userMessage, optimizedQuery)
 	} else {
-		fmt.Printf("Query optimization: No change needed for '%s'\n", userMessage)
+		log.Printf("Query optimization: No change needed for '%s'", userMessage)
 	}
 
 	// Search for relevant documents using the optimized query
@@ -578,6 +1133,17 @@ func (m *LilRag) Chat(ctx context.Context, userMessage string, limit int) (strin
 		return "", nil, fmt.Errorf("failed to search documents: %w", err)
 	}
 
+	// Let the model answer questions about totals and filters from the full sheets of
+	// retrieved spreadsheets
+	if m.config.TableQuery {
+		tableResult, err := m.answerFromTables(ctx, userMessage, searchResults)
+		if err != nil {
+			log.Printf("Warning: Table query failed: %v", err)
+		} else if tableResult != nil {
+			searchResults = append([]SearchResult{*tableResult}, searchResults...)
+		}
+	}
+
 	// Generate chat response using the original user message and search results as context
 	response, err := m.chatClient.GenerateResponse(ctx, userMessage, searchResults)
 	if err != nil {
@@ -587,6 +1153,75 @@ func (m *LilRag) Chat(ctx context.Context, userMessage string, limit int) (strin
 	return response, searchResults, nil
 }
 
+// QueryTables runs a read-only SQL query over the sheets of indexed XLSX and CSV files
+func (m *LilRag) QueryTables(ctx context.Context, query string) (*TableQueryResult, error) {
+	store, ok := m.storage.(TableStore)
+	if !ok {
+		return nil, fmt.Errorf("storage does not support table queries")
+	}
+	return store.QueryTables(ctx, query, m.config.TableQueryMaxRows)
+}
+
+// answerFromTables lets the chat model query the sheets of the tabular documents among the
+// search results. The rows it returns become a search result that cites the sheet; nil
+// means no sheet was retrieved or the model found no query that answers the question.
+func (m *LilRag) answerFromTables(
+	ctx context.Context, userMessage string, searchResults []SearchResult,
+) (*SearchResult, error) {
+	store, ok := m.storage.(TableStore)
+	if !ok || len(searchResults) == 0 {
+		return nil, nil
+	}
+
+	documentIDs := make([]string, len(searchResults))
+	for i, result := range searchResults {
+		documentIDs[i] = result.ID
+	}
+	tables, err := store.ListTables(ctx, documentIDs...)
+	if err != nil || len(tables) == 0 {
+		return nil, err
+	}
+
+	samples := make(map[string]*TableQueryResult, len(tables))
+	for _, table := range tables {
+		sample, err := store.QueryTables(ctx, "SELECT * FROM "+quoteIdentifier(table.Name)+" LIMIT 3", 3)
+		if err != nil {
+			return nil, err
+		}
+		samples[table.Name] = sample
+	}
+
+	query, err := m.chatClient.GenerateTableQuery(ctx, userMessage, tables, samples)
+	if err != nil || query == "" {
+		return nil, err
+	}
+
+	result, err := store.QueryTables(ctx, query, m.config.TableQueryMaxRows)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Tables) == 0 {
+		return nil, nil
+	}
+
+	text := result.Markdown()
+	if result.Truncated {
+		text += fmt.Sprintf("\n\n(first %d rows shown)", len(result.Rows))
+	}
+	return &SearchResult{
+		ID:    result.Tables[0].DocumentID,
+		Text:  text,
+		Score: 1,
+		Metadata: map[string]interface{}{
+			"chunk_type":  "table_query",
+			"table_query": result.SQL,
+			"sheet":       strings.Join(result.Sheets(), ", "),
+			"row_count":   len(result.Rows),
+			"truncated":   result.Truncated,
+		},
+	}, nil
+}
+
 func (m *LilRag) ListDocuments(ctx context.Context) ([]DocumentInfo, error) {
 	return m.storage.ListDocuments(ctx)
 }
@@ -620,14 +1255,37 @@ func (m *LilRag) DeleteDocument(ctx context.Context, documentID string) error {
 	return m.storage.DeleteDocument(ctx, documentID)
 }
 
+// ParseDocumentFile extracts the text of a file, detecting its format from the content
 func (m *LilRag) ParseDocumentFile(filePath string) (string, error) {
+	content, _, err := m.ParseDocumentFileWithType(filePath)
+	return content, err
+}
+
+// ParseDocumentFileWithType extracts the text of a file and returns the document and MIME
+// type detected from its content
+func (m *LilRag) ParseDocumentFileWithType(filePath string) (string, FileType, error) {
 	if m.documentHandler == nil {
-		return "", fmt.Errorf("document handler not initialized")
+		return "", FileType{}, fmt.Errorf("document handler not initialized")
 	}
 	if !m.documentHandler.IsSupported(filePath) {
-		return "", fmt.Errorf("unsupported file type: %s", filePath)
+		return "", FileType{}, fmt.Errorf("unsupported file type: %s", filePath)
+	}
+
+	fileType := m.documentHandler.DetectFileType(filePath)
+	content, err := m.documentHandler.ParseFile(filePath)
+	if err != nil {
+		return "", fileType, err
+	}
+	return content, fileType, nil
+}
+
+// DetectFileType detects the document and MIME type of a file from its content, using
+// the extension only as a hint
+func (m *LilRag) DetectFileType(filePath string) (FileType, error) {
+	if m.documentHandler == nil {
+		return FileType{}, fmt.Errorf("document handler not initialized")
 	}
-	return m.documentHandler.ParseFile(filePath)
+	return m.documentHandler.DetectFileType(filePath), nil
 }
 
 func (m *LilRag) Close() error {
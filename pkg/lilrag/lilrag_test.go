@@ -16,15 +16,19 @@ type MockStorage struct {
 	documents   map[string]string
 	embeddings  map[string][]float32
 	chunks      map[string][]Chunk
+	sourcePaths map[string]string
+	metadata    map[string]map[string]string
 	initialized bool
 	closed      bool
 }
 
 func NewMockStorage() *MockStorage {
 	return &MockStorage{
-		documents:  make(map[string]string),
-		embeddings: make(map[string][]float32),
-		chunks:     make(map[string][]Chunk),
+		documents:   make(map[string]string),
+		embeddings:  make(map[string][]float32),
+		chunks:      make(map[string][]Chunk),
+		sourcePaths: make(map[string]string),
+		metadata:    make(map[string]map[string]string),
 	}
 }
 
@@ -58,9 +62,27 @@ func (m *MockStorage) IndexChunks(_ context.Context, documentID, text string, ch
 	return nil
 }
 
-func (m *MockStorage) IndexChunksWithMetadata(_ context.Context, documentID, text string, chunks []Chunk, embeddings [][]float32, originalFilePath, docType string) error {
-	// For the mock, we'll just call the regular IndexChunks method and ignore metadata
-	return m.IndexChunks(context.Background(), documentID, text, chunks, embeddings)
+func (m *MockStorage) IndexChunksWithMetadata(_ context.Context, documentID, text string, chunks []Chunk, embeddings [][]float32, originalFilePath, docType string, metadata map[string]string) error {
+	if err := m.IndexChunks(context.Background(), documentID, text, chunks, embeddings); err != nil {
+		return err
+	}
+	m.sourcePaths[documentID] = originalFilePath
+	m.metadata[documentID] = metadata
+	return nil
+}
+
+func (m *MockStorage) UpdateDocumentSourcePath(_ context.Context, documentID, sourcePath string) error {
+	if _, exists := m.documents[documentID]; !exists {
+		return fmt.Errorf("document not found: %s", documentID)
+	}
+	m.sourcePaths[documentID] = sourcePath
+	return nil
+}
+
+func (m *MockStorage) SearchWithOptions(
+	ctx context.Context, embedding []float32, limit int, _ SearchOptions,
+) ([]SearchResult, error) {
+	return m.Search(ctx, embedding, limit)
 }
 
 func (m *MockStorage) Search(_ context.Context, _ []float32, limit int) ([]SearchResult, error) {
@@ -104,7 +126,9 @@ func (m *MockStorage) ListDocuments(_ context.Context) ([]DocumentInfo, error) {
 			ID:         id,
 			Text:       text,
 			ChunkCount: 1, // Mock with 1 chunk
+			SourcePath: m.sourcePaths[id],
 			UpdatedAt:  time.Now(),
+			Metadata:   m.metadata[id],
 		}
 		documents = append(documents, doc)
 	}
@@ -156,6 +180,8 @@ func (m *MockStorage) DeleteDocument(_ context.Context, documentID string) error
 	delete(m.documents, documentID)
 	delete(m.embeddings, documentID)
 	delete(m.chunks, documentID)
+	delete(m.sourcePaths, documentID)
+	delete(m.metadata, documentID)
 
 	return nil
 }
@@ -1088,3 +1114,61 @@ func BenchmarkLilRag_Search(b *testing.B) {
 		}
 	}
 }
+
+// heapSamplingEmbedder returns a fixed embedding and samples the live heap while indexing
+type heapSamplingEmbedder struct {
+	heap *heapPeak
+}
+
+func (e *heapSamplingEmbedder) Embed(context.Context, string) ([]float32, error) {
+	e.heap.sample()
+	return []float32{0.1, 0.2, 0.3}, nil
+}
+
+func TestLilRag_IndexFileLargeTabular(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(t *testing.T) string
+		last  string
+	}{
+		{"csv", func(t *testing.T) string { return writeLargeCSV(t, 100000) }, "Row 100000: id: 100000"},
+		{"xlsx", func(t *testing.T) string { return writeLargeXLSX(t, 75000) }, "Row 75001: ID: 75001"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := setupTableStorage(t)
+			filePath := tt.write(t)
+			info, err := os.Stat(filePath)
+			if err != nil {
+				t.Fatalf("Failed to stat file: %v", err)
+			}
+
+			chunker := NewTextChunker(256, 38)
+			embedder := &heapSamplingEmbedder{}
+			lilRag := &LilRag{
+				storage:         storage,
+				embedder:        embedder,
+				chunker:         chunker,
+				documentHandler: NewDocumentHandler(chunker),
+				config:          &Config{},
+			}
+
+			embedder.heap = newHeapPeak()
+			if err := lilRag.IndexFile(context.Background(), filePath, "large"); err != nil {
+				t.Fatalf("Failed to index file: %v", err)
+			}
+
+			chunks, err := storage.GetDocumentChunks(context.Background(), "large")
+			if err != nil || len(chunks) < 1000 {
+				t.Fatalf("Expected the file to be stored in many chunks, got %d (%v)", len(chunks), err)
+			}
+			if !strings.Contains(chunks[len(chunks)-1].Text, tt.last) {
+				t.Errorf("Expected the last chunk to hold the last row, got %q", chunks[len(chunks)-1].Text)
+			}
+			if peak := embedder.heap.peak; peak > maxStreamingHeap {
+				t.Errorf("Heap grew by %d bytes while indexing a %d byte file in %d chunks", peak, info.Size(), len(chunks))
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package lilrag
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// headingRegex matches ATX headings such as "## Install" or "### Linux ###"
+var headingRegex = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+
+// MarkdownParser handles Markdown files, chunking them along the heading hierarchy
+type MarkdownParser struct {
+	chunker *TextChunker
+}
+
+// markdownBlock is a structural unit of a Markdown section
+type markdownBlock struct {
+	start     int
+	end       int
+	atomic    bool // Fenced code blocks and tables are never split
+	isHeading bool
+}
+
+// markdownSection holds the blocks under a heading together with its heading path
+type markdownSection struct {
+	path   []string
+	blocks []markdownBlock
+}
+
+// NewMarkdownParser creates a new Markdown parser
+func NewMarkdownParser() *MarkdownParser {
+	return &MarkdownParser{}
+}
+
+// NewMarkdownParserWithChunker creates a Markdown parser that uses the given chunker
+func NewMarkdownParserWithChunker(chunker *TextChunker) *MarkdownParser {
+	return &MarkdownParser{chunker: chunker}
+}
+
+// Parse extracts text content from a Markdown file
+func (mp *MarkdownParser) Parse(filePath string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read Markdown file: %w", err)
+	}
+	return string(content), nil
+}
+
+// ParseWithChunks splits a Markdown file on its heading hierarchy. Each chunk carries
+// its heading path, and fenced code blocks and tables are kept whole.
+func (mp *MarkdownParser) ParseWithChunks(filePath, documentID string) ([]Chunk, error) {
+	return mp.ParseWithChunksContext(context.Background(), filePath, documentID)
+}
+
+// ParseWithChunksContext is ParseWithChunks with ctx passed to the chunker
+func (mp *MarkdownParser) ParseWithChunksContext(ctx context.Context, filePath, _ string) ([]Chunk, error) {
+	content, err := mp.Parse(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return mp.ChunkMarkdownContext(ctx, content), nil
+}
+
+// ChunkMarkdown chunks Markdown content along its heading hierarchy
+func (mp *MarkdownParser) ChunkMarkdown(content string) []Chunk {
+	return mp.ChunkMarkdownContext(context.Background(), content)
+}
+
+// ChunkMarkdownContext is ChunkMarkdown with ctx passed to the chunker
+func (mp *MarkdownParser) ChunkMarkdownContext(ctx context.Context, content string) []Chunk {
+	// Use a default chunker if none provided
+	if mp.chunker == nil {
+		mp.chunker = NewTextChunker(256, 38) // Use optimized defaults
+	}
+
+	var chunks []Chunk
+	for _, section := range mp.parseSections(content) {
+		chunks = append(chunks, mp.chunkSection(ctx, content, section, len(chunks))...)
+	}
+
+	return chunks
+}
+
+// parseSections splits Markdown content into sections of blocks keyed by heading path
+func (mp *MarkdownParser) parseSections(content string) []markdownSection {
+	type heading struct {
+		level int
+		title string
+	}
+
+	var sections []markdownSection
+	var headings []heading
+	current := markdownSection{}
+
+	blockStart := -1
+	blockIsTable := false
+	fenceMarker := ""
+
+	flush := func(end int, atomic bool) {
+		if blockStart >= 0 && strings.TrimSpace(content[blockStart:end]) != "" {
+			current.blocks = append(current.blocks, markdownBlock{start: blockStart, end: end, atomic: atomic})
+		}
+		blockStart = -1
+		blockIsTable = false
+	}
+
+	offset := 0
+	for _, line := range strings.SplitAfter(content, "\n") {
+		lineStart := offset
+		offset += len(line)
+		trimmed := strings.TrimSpace(line)
+
+		// Inside a fenced code block everything belongs to the block until the closing fence
+		if fenceMarker != "" {
+			if strings.HasPrefix(trimmed, fenceMarker) && strings.Trim(trimmed, fenceMarker[:1]) == "" {
+				fenceMarker = ""
+				flush(offset, true)
+			}
+			continue
+		}
+
+		if marker := fenceOpening(trimmed); marker != "" {
+			flush(lineStart, blockIsTable)
+			fenceMarker = marker
+			blockStart = lineStart
+			continue
+		}
+
+		if match := headingRegex.FindStringSubmatch(strings.TrimRight(line, "\r\n")); match != nil {
+			flush(lineStart, blockIsTable)
+			if len(current.blocks) > 0 {
+				sections = append(sections, current)
+			}
+
+			level := len(match[1])
+			for len(headings) > 0 && headings[len(headings)-1].level >= level {
+				headings = headings[:len(headings)-1]
+			}
+			headings = append(headings, heading{level: level, title: strings.TrimSpace(match[2])})
+
+			path := make([]string, 0, len(headings))
+			for _, h := range headings {
+				if h.title != "" {
+					path = append(path, h.title)
+				}
+			}
+			current = markdownSection{
+				path:   path,
+				blocks: []markdownBlock{{start: lineStart, end: offset, isHeading: true}},
+			}
+			continue
+		}
+
+		isTableLine := strings.HasPrefix(trimmed, "|")
+		switch {
+		case trimmed == "":
+			flush(lineStart, blockIsTable)
+		case isTableLine != blockIsTable:
+			// Switching between table rows and prose starts a new block
+			flush(lineStart, blockIsTable)
+			blockStart = lineStart
+			blockIsTable = isTableLine
+		case blockStart < 0:
+			blockStart = lineStart
+			blockIsTable = isTableLine
+		}
+	}
+
+	// An unterminated fence runs to the end of the document
+	flush(len(content), fenceMarker != "" || blockIsTable)
+	if len(current.blocks) > 0 {
+		sections = append(sections, current)
+	}
+
+	return sections
+}
+
+// fenceOpening returns the fence marker if the line opens a fenced code block
+func fenceOpening(trimmed string) string {
+	for _, char := range []string{"`", "~"} {
+		if strings.HasPrefix(trimmed, strings.Repeat(char, 3)) {
+			return trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, char))]
+		}
+	}
+	return ""
+}
+
+// chunkSection packs a section's blocks into chunks of up to MaxTokens
+func (mp *MarkdownParser) chunkSection(ctx context.Context, content string, section markdownSection, startIndex int) []Chunk {
+	headingPath := strings.Join(section.path, " > ")
+
+	var chunks []Chunk
+	var pending []markdownBlock
+	pendingTokens := 0
+
+	newChunk := func(text string, start, end int) Chunk {
+		return Chunk{
+			Text:        text,
+			Index:       startIndex + len(chunks),
+			StartPos:    start,
+			EndPos:      end,
+			TokenCount:  mp.chunker.EstimateTokenCount(text),
+			ChunkType:   "markdown",
+			HeadingPath: headingPath,
+		}
+	}
+
+	emit := func() {
+		if len(pending) == 0 {
+			return
+		}
+		onlyHeadings := true
+		for _, block := range pending {
+			if !block.isHeading {
+				onlyHeadings = false
+			}
+		}
+		// A heading without content is already represented in its subsections' paths
+		if !onlyHeadings {
+			start, end := pending[0].start, pending[len(pending)-1].end
+			chunks = append(chunks, newChunk(strings.TrimSpace(content[start:end]), start, end))
+		}
+		pending = nil
+		pendingTokens = 0
+	}
+
+	for _, block := range section.blocks {
+		blockTokens := mp.chunker.EstimateTokenCount(content[block.start:block.end])
+
+		if pendingTokens > 0 && pendingTokens+blockTokens > mp.chunker.MaxTokens {
+			emit()
+		}
+
+		// Oversized prose is split with the generic chunker; code and tables stay whole
+		if blockTokens > mp.chunker.MaxTokens && !block.atomic {
+			emit()
+			for _, piece := range mp.chunker.ChunkTextContext(ctx, content[block.start:block.end]) {
+				chunks = append(chunks, newChunk(piece.Text, block.start+piece.StartPos, block.start+piece.EndPos))
+			}
+			continue
+		}
+
+		pending = append(pending, block)
+		pendingTokens += blockTokens
+	}
+	emit()
+
+	return chunks
+}
+
+// SupportedExtensions returns the file extensions this parser supports
+func (mp *MarkdownParser) SupportedExtensions() []string {
+	return []string{".md", ".markdown"}
+}
+
+// GetDocumentType returns the type of documents this parser handles
+func (mp *MarkdownParser) GetDocumentType() DocumentType {
+	return DocumentTypeMarkdown
+}
@@ -0,0 +1,119 @@
+package lilrag
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdownParser_ChunkMarkdown_HeadingPaths(t *testing.T) {
+	content := `# Install
+
+Download the release archive.
+
+## Linux
+
+Extract it to /usr/local.
+
+### Troubleshooting
+
+Check the permissions on the binary.
+
+## macOS
+
+Use Homebrew.
+`
+	parser := NewMarkdownParser()
+	chunks := parser.ChunkMarkdown(content)
+
+	expected := []string{
+		"Install",
+		"Install > Linux",
+		"Install > Linux > Troubleshooting",
+		"Install > macOS",
+	}
+	if len(chunks) != len(expected) {
+		t.Fatalf("Expected %d chunks, got %d", len(expected), len(chunks))
+	}
+
+	for i, chunk := range chunks {
+		if chunk.HeadingPath != expected[i] {
+			t.Errorf("Chunk %d: expected heading path %q, got %q", i, expected[i], chunk.HeadingPath)
+		}
+		if chunk.Index != i {
+			t.Errorf("Chunk %d: expected index %d, got %d", i, i, chunk.Index)
+		}
+		if chunk.ChunkType != "markdown" {
+			t.Errorf("Chunk %d: expected chunk type markdown, got %s", i, chunk.ChunkType)
+		}
+	}
+
+	if !strings.HasPrefix(chunks[2].EmbeddingText(), "Install > Linux > Troubleshooting\n\n") {
+		t.Errorf("Expected embedding text to start with the heading path, got %q", chunks[2].EmbeddingText())
+	}
+}
+
+func TestMarkdownParser_ChunkMarkdown_KeepsCodeAndTablesWhole(t *testing.T) {
+	code := "```go\n" + strings.Repeat("fmt.Println(\"hello world\")\n\n", 10) + "# not a heading\n```"
+	table := "| Name | Value |\n| --- | --- |\n" + strings.Repeat("| key | some value here |\n", 8)
+	content := "# Usage\n\nIntro text.\n\n" + code + "\n\n" + table
+
+	parser := &MarkdownParser{chunker: NewTextChunker(20, 2)}
+	chunks := parser.ChunkMarkdown(content)
+
+	foundCode, foundTable := false, false
+	for _, chunk := range chunks {
+		if chunk.HeadingPath != "Usage" {
+			t.Errorf("Expected heading path Usage, got %q", chunk.HeadingPath)
+		}
+		if strings.Contains(chunk.Text, "```go") {
+			foundCode = true
+			if !strings.HasSuffix(chunk.Text, "```") {
+				t.Error("Expected fenced code block to be kept whole")
+			}
+		}
+		if strings.Contains(chunk.Text, "| Name | Value |") {
+			foundTable = true
+			if strings.Count(chunk.Text, "| key |") != 8 {
+				t.Error("Expected table to be kept whole")
+			}
+		}
+	}
+
+	if !foundCode || !foundTable {
+		t.Errorf("Expected code and table chunks, got %d chunks", len(chunks))
+	}
+}
+
+func TestMarkdownParser_ChunkMarkdown_NoHeadings(t *testing.T) {
+	parser := NewMarkdownParser()
+	chunks := parser.ChunkMarkdown("Just a paragraph.\n\nAnd another one.")
+
+	if len(chunks) != 1 {
+		t.Fatalf("Expected 1 chunk, got %d", len(chunks))
+	}
+	if chunks[0].HeadingPath != "" {
+		t.Errorf("Expected empty heading path, got %q", chunks[0].HeadingPath)
+	}
+	if chunks[0].EmbeddingText() != chunks[0].Text {
+		t.Error("Expected embedding text to equal chunk text without a heading path")
+	}
+}
+
+func TestMarkdownParser_UsesHandlerChunker(t *testing.T) {
+	content := "# Notes\n\n" + strings.Repeat("This sentence pads out a long Markdown section. ", 40)
+	path := writeTestFile(t, "notes.md", content)
+
+	handler := NewDocumentHandler(NewTextChunker(40, 4))
+	chunks, err := handler.ParseFileWithChunks(path, "notes")
+	if err != nil {
+		t.Fatalf("ParseFileWithChunks failed: %v", err)
+	}
+	if len(chunks) < 2 {
+		t.Fatalf("Expected the section to be split by the handler's chunker, got %d chunks", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if chunk.TokenCount > 40 {
+			t.Errorf("Chunk %d has %d tokens, exceeds the handler's max of 40", i, chunk.TokenCount)
+		}
+	}
+}
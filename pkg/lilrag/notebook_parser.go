@@ -0,0 +1,285 @@
+package lilrag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// maxNotebookOutputChars caps the output text kept per code cell; cells that print whole
+// data frames would otherwise dominate their chunk
+const maxNotebookOutputChars = 2000
+
+// NotebookParser handles Jupyter notebooks (.ipynb). Markdown and code cells become
+// separate chunks that record the index of the cell they came from.
+type NotebookParser struct {
+	chunker *TextChunker
+	outputs bool // Append the text outputs of code cells
+}
+
+// Notebook is the kernel language and the cells of a Jupyter notebook
+type Notebook struct {
+	Language string
+	Cells    []NotebookCell
+}
+
+// NotebookCell is one markdown or code cell
+type NotebookCell struct {
+	Index          int    // 1-based position of the cell in the notebook, counting every cell
+	Type           string // "markdown" or "code"
+	Source         string
+	ExecutionCount *int
+	Outputs        []string // Text outputs of a code cell; images and other binary data are skipped
+}
+
+// NewNotebookParser creates a new Jupyter notebook parser
+func NewNotebookParser() *NotebookParser {
+	return &NotebookParser{}
+}
+
+// NewNotebookParserWithChunker creates a notebook parser that chunks cells with chunker
+func NewNotebookParserWithChunker(chunker *TextChunker) *NotebookParser {
+	return &NotebookParser{chunker: chunker}
+}
+
+// NewNotebookParserWithOutputs creates a notebook parser that also indexes the text
+// outputs of code cells
+func NewNotebookParserWithOutputs(chunker *TextChunker) *NotebookParser {
+	return &NotebookParser{chunker: chunker, outputs: true}
+}
+
+// ipynbFile is the part of the nbformat 4 document needed to read a notebook. Multiline
+// strings may be stored either as a string or as a list of lines.
+type ipynbFile struct {
+	Metadata struct {
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+	Cells []struct {
+		CellType       string          `json:"cell_type"`
+		Source         json.RawMessage `json:"source"`
+		ExecutionCount *int            `json:"execution_count"`
+		Outputs        []struct {
+			OutputType string                     `json:"output_type"`
+			Text       json.RawMessage            `json:"text"`
+			Data       map[string]json.RawMessage `json:"data"`
+			EName      string                     `json:"ename"`
+			EValue     string                     `json:"evalue"`
+		} `json:"outputs"`
+	} `json:"cells"`
+}
+
+// ReadNotebook reads the markdown and code cells of a notebook. Raw cells and empty
+// cells are skipped but still count towards the cell index.
+func (np *NotebookParser) ReadNotebook(filePath string) (*Notebook, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notebook: %w", err)
+	}
+
+	var file ipynbFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse notebook JSON: %w", err)
+	}
+
+	notebook := &Notebook{Language: strings.ToLower(file.Metadata.Kernelspec.Language)}
+	if notebook.Language == "" {
+		notebook.Language = strings.ToLower(file.Metadata.LanguageInfo.Name)
+	}
+
+	for i, raw := range file.Cells {
+		if raw.CellType != "markdown" && raw.CellType != "code" {
+			continue
+		}
+		cell := NotebookCell{
+			Index:          i + 1,
+			Type:           raw.CellType,
+			Source:         strings.TrimSpace(notebookText(raw.Source)),
+			ExecutionCount: raw.ExecutionCount,
+		}
+		if cell.Source == "" {
+			continue
+		}
+
+		for _, output := range raw.Outputs {
+			var text string
+			switch output.OutputType {
+			case "stream":
+				text = notebookText(output.Text)
+			case "execute_result", "display_data":
+				// Only the plain text representation is kept; image/png and friends are base64
+				text = notebookText(output.Data["text/plain"])
+			case "error":
+				text = output.EName + ": " + output.EValue
+			}
+			if text = strings.TrimSpace(text); text != "" {
+				cell.Outputs = append(cell.Outputs, text)
+			}
+		}
+
+		notebook.Cells = append(notebook.Cells, cell)
+	}
+
+	return notebook, nil
+}
+
+// Parse extracts the notebook as Markdown with code cells in fenced blocks
+func (np *NotebookParser) Parse(filePath string) (string, error) {
+	notebook, err := np.ReadNotebook(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	var content strings.Builder
+	for _, cell := range notebook.Cells {
+		content.WriteString(np.cellText(cell, notebook.Language))
+		content.WriteString("\n\n")
+	}
+
+	return strings.TrimSpace(content.String()), nil
+}
+
+// ParseWithChunks chunks every cell on its own. Markdown cells become "nb_markdown"
+// chunks and code cells "nb_code" chunks with the kernel language; both carry the cell
+// index as metadata and the enclosing markdown headings as their heading path.
+func (np *NotebookParser) ParseWithChunks(filePath, documentID string) ([]Chunk, error) {
+	return np.ParseWithChunksContext(context.Background(), filePath, documentID)
+}
+
+// ParseWithChunksContext is ParseWithChunks with ctx passed to the chunker
+func (np *NotebookParser) ParseWithChunksContext(ctx context.Context, filePath, _ string) ([]Chunk, error) {
+	notebook, err := np.ReadNotebook(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use a default chunker if none provided
+	if np.chunker == nil {
+		np.chunker = NewTextChunker(256, 38)
+	}
+
+	var chunks []Chunk
+	var headings []string // Current heading at each markdown level
+	for _, cell := range notebook.Cells {
+		if cell.Type == "markdown" {
+			headings = notebookHeadings(headings, cell.Source)
+		}
+
+		metadata := map[string]string{"cell_index": strconv.Itoa(cell.Index)}
+		chunkType := "nb_markdown"
+		if cell.Type == "code" {
+			chunkType = "nb_code"
+			if cell.ExecutionCount != nil {
+				metadata["execution_count"] = strconv.Itoa(*cell.ExecutionCount)
+			}
+		}
+
+		headingPath := strings.Join(nonEmpty(headings), " > ")
+		for _, chunk := range np.chunker.ChunkTextContext(ctx, np.cellText(cell, notebook.Language)) {
+			chunk.Index = len(chunks)
+			chunk.ChunkType = chunkType
+			chunk.HeadingPath = headingPath
+			chunk.Metadata = metadata
+			if cell.Type == "code" {
+				chunk.Language = notebook.Language
+			}
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("no cells with content found in notebook")
+	}
+
+	return chunks, nil
+}
+
+// SupportedExtensions returns the file extensions this parser supports
+func (np *NotebookParser) SupportedExtensions() []string {
+	return []string{".ipynb"}
+}
+
+// GetDocumentType returns the type of documents this parser handles
+func (np *NotebookParser) GetDocumentType() DocumentType {
+	return DocumentTypeNotebook
+}
+
+// cellText renders a cell for indexing. Code is fenced with the kernel language and
+// followed by its outputs when the parser includes them.
+func (np *NotebookParser) cellText(cell NotebookCell, language string) string {
+	if cell.Type == "markdown" {
+		return cell.Source
+	}
+
+	text := "```" + language + "\n" + cell.Source + "\n```"
+	if !np.outputs || len(cell.Outputs) == 0 {
+		return text
+	}
+
+	outputs := strings.Join(cell.Outputs, "\n")
+	if len(outputs) > maxNotebookOutputChars {
+		outputs = strings.ToValidUTF8(outputs[:maxNotebookOutputChars], "") + "\n..."
+	}
+	return text + "\n\nOutput:\n" + outputs
+}
+
+// notebookText decodes a multiline notebook string stored as a string or a list of lines
+func notebookText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	var lines []string
+	if err := json.Unmarshal(raw, &lines); err == nil {
+		return strings.Join(lines, "")
+	}
+	return ""
+}
+
+// notebookHeadings updates the heading stack with the ATX headings of a markdown cell
+func notebookHeadings(headings []string, source string) []string {
+	inFence := false
+	for _, line := range strings.Split(source, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") {
+			inFence = !inFence
+		}
+		if inFence || !strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		level := len(line) - len(strings.TrimLeft(line, "#"))
+		title := strings.TrimSpace(line[level:])
+		if level > 6 || title == "" || line[level] != ' ' {
+			continue
+		}
+
+		for len(headings) < level {
+			headings = append(headings, "")
+		}
+		headings = append(headings[:level-1], title)
+	}
+	return headings
+}
+
+// nonEmpty returns the non-empty strings of values
+func nonEmpty(values []string) []string {
+	var result []string
+	for _, value := range values {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
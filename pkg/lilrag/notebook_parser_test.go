@@ -0,0 +1,89 @@
+package lilrag
+
+import (
+	"strings"
+	"testing"
+)
+
+const testNotebook = `{
+ "metadata": {"kernelspec": {"name": "python3", "language": "python"}},
+ "nbformat": 4,
+ "nbformat_minor": 5,
+ "cells": [
+  {"cell_type": "markdown", "metadata": {}, "source": ["# Churn analysis\n", "\n", "Monthly churn by plan."]},
+  {"cell_type": "code", "execution_count": 3, "metadata": {}, "source": "df = load()\ndf.groupby('plan').churn.mean()",
+   "outputs": [
+    {"output_type": "stream", "name": "stdout", "text": ["loaded 1200 rows\n"]},
+    {"output_type": "execute_result", "execution_count": 3, "metadata": {},
+     "data": {"text/plain": ["plan\n", "pro     0.04"], "text/html": "<table></table>"}},
+    {"output_type": "display_data", "metadata": {}, "data": {"image/png": "iVBORw0KGgoAAAANSUhEUgAAAAE="}}
+   ]},
+  {"cell_type": "raw", "metadata": {}, "source": "ignored"},
+  {"cell_type": "markdown", "metadata": {}, "source": "## Findings\n\nPro users churn the least."},
+  {"cell_type": "code", "execution_count": null, "metadata": {}, "source": "", "outputs": []}
+ ]
+}`
+
+func TestNotebookParser_ParseWithChunks(t *testing.T) {
+	filePath := writeTestFile(t, "churn.ipynb", testNotebook)
+
+	chunks, err := NewNotebookParser().ParseWithChunks(filePath, "churn")
+	if err != nil {
+		t.Fatalf("Failed to parse notebook: %v", err)
+	}
+	if len(chunks) != 3 {
+		t.Fatalf("Expected markdown, code and markdown chunks, got %+v", chunks)
+	}
+
+	code := chunks[1]
+	if code.ChunkType != "nb_code" || code.Language != "python" || code.Metadata["cell_index"] != "2" ||
+		code.Metadata["execution_count"] != "3" || code.HeadingPath != "Churn analysis" {
+		t.Errorf("Unexpected code chunk %+v", code)
+	}
+	if strings.Contains(code.Text, "Output:") {
+		t.Errorf("Expected outputs to be skipped by default, got %q", code.Text)
+	}
+
+	// The raw cell still counts towards the cell index
+	findings := chunks[2]
+	if findings.ChunkType != "nb_markdown" || findings.Metadata["cell_index"] != "4" ||
+		findings.HeadingPath != "Churn analysis > Findings" {
+		t.Errorf("Unexpected markdown chunk %+v", findings)
+	}
+	for i, chunk := range chunks {
+		if chunk.Index != i {
+			t.Errorf("Expected chunk %d to have index %d, got %d", i, i, chunk.Index)
+		}
+	}
+}
+
+func TestNotebookParser_Outputs(t *testing.T) {
+	filePath := writeTestFile(t, "churn.ipynb", testNotebook)
+
+	dh := NewDocumentHandler(NewTextChunker(256, 38))
+	dh.EnableNotebookOutputs()
+	chunks, err := dh.ParseFileWithChunks(filePath, "churn")
+	if err != nil {
+		t.Fatalf("Failed to parse notebook: %v", err)
+	}
+
+	expected := "```python\ndf = load()\ndf.groupby('plan').churn.mean()\n```\n\n" +
+		"Output:\nloaded 1200 rows\nplan\npro     0.04"
+	if chunks[1].Text != expected {
+		t.Errorf("Unexpected code chunk text:\n%s\nexpected:\n%s", chunks[1].Text, expected)
+	}
+	if strings.Contains(chunks[1].Text, "iVBOR") {
+		t.Error("Expected base64 image outputs to be skipped")
+	}
+}
+
+func TestDetectDocumentType_Notebook(t *testing.T) {
+	dh := NewDocumentHandler(NewTextChunker(256, 38))
+
+	if got := dh.DetectDocumentType("analysis.ipynb"); got != DocumentTypeNotebook {
+		t.Errorf("Expected notebook, got %s", got)
+	}
+	if _, ok := dh.GetSupportedFormats()[DocumentTypeNotebook]; !ok {
+		t.Error("Expected a parser for notebooks")
+	}
+}
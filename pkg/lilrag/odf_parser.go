@@ -0,0 +1,662 @@
+package lilrag
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// OpenDocument files are zip packages whose body lives in content.xml. The parsers
+// below stream that part and render it the same way as their OOXML counterparts.
+
+// ODTParser handles OpenDocument text (.odt) files
+type ODTParser struct {
+	chunker *TextChunker
+}
+
+// ODSParser handles OpenDocument spreadsheet (.ods) files
+type ODSParser struct {
+	chunker *TextChunker
+}
+
+// ODPParser handles OpenDocument presentation (.odp) files
+type ODPParser struct {
+	chunker *TextChunker
+}
+
+// NewODTParser creates a new ODT parser
+func NewODTParser() *ODTParser {
+	return &ODTParser{}
+}
+
+// NewODSParser creates a new ODS parser
+func NewODSParser() *ODSParser {
+	return &ODSParser{}
+}
+
+// NewODPParser creates a new ODP parser
+func NewODPParser() *ODPParser {
+	return &ODPParser{}
+}
+
+// NewODPParserWithChunker creates an ODP parser that splits oversized slides with chunker
+func NewODPParserWithChunker(chunker *TextChunker) *ODPParser {
+	return &ODPParser{chunker: chunker}
+}
+
+// Parse extracts text from an ODT file. Headings are rendered as Markdown headings
+// by outline level, list items as "- " lines and tables as Markdown tables.
+func (op *ODTParser) Parse(filePath string) (string, error) {
+	var blocks []string
+	err := readODFContent(filePath, func(decoder *xml.Decoder) error {
+		var err error
+		blocks, err = readODFTextBlocks(decoder)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join(blocks, "\n"), nil
+}
+
+// ParseWithChunks extracts and chunks content from an ODT file like DOCXParser does
+func (op *ODTParser) ParseWithChunks(filePath, _ string) ([]Chunk, error) {
+	content, err := op.Parse(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use a default chunker if none provided
+	if op.chunker == nil {
+		op.chunker = NewTextChunker(320, 48) // Slightly larger chunks for prose content
+	}
+
+	// Word processing documents share the DOCX content detection and chunking
+	dp := &DOCXParser{chunker: op.chunker}
+	content = dp.cleanContent(content)
+	contentType := dp.detectContentType(content)
+	chunks := dp.chunkByContentType(content, contentType)
+
+	for i, chunk := range chunks {
+		chunk.Index = i
+		chunk.ChunkType = fmt.Sprintf("odt_%s", contentType)
+		chunks[i] = chunk
+	}
+
+	return chunks, nil
+}
+
+// SupportedExtensions returns the file extensions this parser supports
+func (op *ODTParser) SupportedExtensions() []string {
+	return []string{".odt"}
+}
+
+// GetDocumentType returns the type of documents this parser handles
+func (op *ODTParser) GetDocumentType() DocumentType {
+	return DocumentTypeODT
+}
+
+// odsSheet is one table of a spreadsheet with its rows expanded into cells
+type odsSheet struct {
+	name string
+	rows [][]string
+}
+
+// readSheets reads every sheet of an ODS file
+func (op *ODSParser) readSheets(filePath string) ([]odsSheet, error) {
+	var sheets []odsSheet
+	err := readODFContent(filePath, func(decoder *xml.Decoder) error {
+		var err error
+		sheets, err = readODFSheets(decoder)
+		return err
+	})
+	return sheets, err
+}
+
+// Parse extracts text content from an ODS file
+func (op *ODSParser) Parse(filePath string) (string, error) {
+	sheets, err := op.readSheets(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	var content strings.Builder
+	xp := &XLSXParser{}
+	for _, sheet := range sheets {
+		xp.writeSheet(&content, sheet.name, sheet.rows)
+	}
+
+	return content.String(), nil
+}
+
+// ParseWithChunks extracts and chunks content from an ODS file like XLSXParser does
+func (op *ODSParser) ParseWithChunks(filePath, _ string) ([]Chunk, error) {
+	sheets, err := op.readSheets(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use a default chunker if none provided
+	if op.chunker == nil {
+		op.chunker = NewTextChunker(200, 30) // Smaller chunks for tabular data
+	}
+
+	var chunks []Chunk
+	xp := &XLSXParser{chunker: op.chunker}
+	for _, sheet := range sheets {
+		header := fmt.Sprintf("Spreadsheet Sheet: %s", sheet.name)
+		chunks = append(chunks, xp.chunkSheet(header, sheet.name, sheet.rows, "ods", len(chunks))...)
+	}
+
+	return chunks, nil
+}
+
+// SupportedExtensions returns the file extensions this parser supports
+func (op *ODSParser) SupportedExtensions() []string {
+	return []string{".ods"}
+}
+
+// GetDocumentType returns the type of documents this parser handles
+func (op *ODSParser) GetDocumentType() DocumentType {
+	return DocumentTypeODS
+}
+
+// ParseSlides reads every slide (draw:page) of an ODP file in order
+func (op *ODPParser) ParseSlides(filePath string) ([]Slide, error) {
+	var slides []Slide
+	err := readODFContent(filePath, func(decoder *xml.Decoder) error {
+		var err error
+		slides, err = readODFSlides(decoder)
+		return err
+	})
+	return slides, err
+}
+
+// Parse extracts text content from an ODP file
+func (op *ODPParser) Parse(filePath string) (string, error) {
+	slides, err := op.ParseSlides(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	var texts []string
+	for _, slide := range slides {
+		if !slide.isEmpty() {
+			texts = append(texts, slide.Text())
+		}
+	}
+
+	return strings.Join(texts, "\n\n"), nil
+}
+
+// ParseWithChunks creates one chunk per slide with the slide number as its page number
+func (op *ODPParser) ParseWithChunks(filePath, documentID string) ([]Chunk, error) {
+	return op.ParseWithChunksContext(context.Background(), filePath, documentID)
+}
+
+// ParseWithChunksContext is ParseWithChunks with ctx passed to the chunker
+func (op *ODPParser) ParseWithChunksContext(ctx context.Context, filePath, _ string) ([]Chunk, error) {
+	slides, err := op.ParseSlides(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := chunkSlides(ctx, slides, op.chunker, "odp")
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("no readable content found in ODP")
+	}
+
+	return chunks, nil
+}
+
+// SupportedExtensions returns the file extensions this parser supports
+func (op *ODPParser) SupportedExtensions() []string {
+	return []string{".odp"}
+}
+
+// GetDocumentType returns the type of documents this parser handles
+func (op *ODPParser) GetDocumentType() DocumentType {
+	return DocumentTypeODP
+}
+
+// readODFContent opens the content.xml part of an OpenDocument package and hands a
+// decoder for it to read
+func readODFContent(filePath string, read func(*xml.Decoder) error) error {
+	r, err := zip.OpenReader(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open OpenDocument file: %w", err)
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if f.Name != "content.xml" {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open content.xml: %w", err)
+		}
+		defer rc.Close()
+
+		if err := read(xml.NewDecoder(rc)); err != nil {
+			return fmt.Errorf("failed to parse content.xml: %w", err)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("content.xml not found in OpenDocument file")
+}
+
+// odfSkippedElements hold text that is not part of the body: footnotes, comments and
+// deleted tracked changes
+var odfSkippedElements = map[string]bool{
+	"note":            true,
+	"annotation":      true,
+	"tracked-changes": true,
+}
+
+// odfParagraph accumulates the text of a text:p or text:h element, expanding the
+// whitespace elements ODF uses instead of literal spaces
+type odfParagraph struct {
+	text   strings.Builder
+	active bool
+}
+
+func (p *odfParagraph) start() {
+	p.text.Reset()
+	p.active = true
+}
+
+// handle processes inline elements; it reports whether the element was consumed
+func (p *odfParagraph) handle(t xml.StartElement) bool {
+	if !p.active {
+		return false
+	}
+	switch t.Name.Local {
+	case "s":
+		count := 1
+		if c := odfAttr(t, "c"); c != "" {
+			if n, err := strconv.Atoi(c); err == nil && n > 0 {
+				count = n
+			}
+		}
+		p.text.WriteString(strings.Repeat(" ", count))
+	case "tab":
+		p.text.WriteString("\t")
+	case "line-break":
+		p.text.WriteString("\n")
+	default:
+		return false
+	}
+	return true
+}
+
+func (p *odfParagraph) write(data xml.CharData) {
+	if p.active {
+		p.text.Write(data)
+	}
+}
+
+func (p *odfParagraph) end() string {
+	p.active = false
+	return strings.TrimSpace(p.text.String())
+}
+
+// odfTable collects the rows of a table:table element
+type odfTable struct {
+	rows   [][]string
+	row    []string
+	cell   []string
+	inCell bool
+}
+
+// readODFTextBlocks returns the paragraphs, headings, list items and tables of an ODT body
+func readODFTextBlocks(decoder *xml.Decoder) ([]string, error) {
+	var (
+		blocks    []string
+		paragraph odfParagraph
+		prefix    string
+		listDepth int
+		skip      int
+		table     *odfTable
+	)
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return blocks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if odfSkippedElements[t.Name.Local] {
+				skip++
+			}
+			if skip > 0 || paragraph.handle(t) {
+				continue
+			}
+			switch t.Name.Local {
+			case "h":
+				level, err := strconv.Atoi(odfAttr(t, "outline-level"))
+				if err != nil || level < 1 {
+					level = 1
+				}
+				prefix = strings.Repeat("#", min(level, 6)) + " "
+				paragraph.start()
+			case "p":
+				prefix = ""
+				if listDepth > 0 {
+					prefix = strings.Repeat("  ", listDepth-1) + "- "
+				}
+				paragraph.start()
+			case "list":
+				listDepth++
+			case "table":
+				table = &odfTable{}
+			case "table-row":
+				if table != nil {
+					table.row = nil
+				}
+			case "table-cell", "covered-table-cell":
+				if table != nil {
+					table.cell, table.inCell = nil, true
+				}
+			}
+		case xml.CharData:
+			if skip == 0 {
+				paragraph.write(t)
+			}
+		case xml.EndElement:
+			if odfSkippedElements[t.Name.Local] {
+				skip--
+				continue
+			}
+			if skip > 0 {
+				continue
+			}
+			switch t.Name.Local {
+			case "h", "p":
+				text := paragraph.end()
+				if text == "" {
+					continue
+				}
+				if table != nil && table.inCell {
+					table.cell = append(table.cell, text)
+				} else {
+					blocks = append(blocks, prefix+text)
+				}
+			case "list":
+				listDepth--
+			case "table-cell", "covered-table-cell":
+				if table != nil {
+					table.row = append(table.row, strings.Join(table.cell, " "))
+					table.inCell = false
+				}
+			case "table-row":
+				if table != nil {
+					table.rows = append(table.rows, table.row)
+				}
+			case "table":
+				if table != nil && len(table.rows) > 0 {
+					blocks = append(blocks, markdownTable(table.rows))
+				}
+				table = nil
+			}
+		}
+	}
+}
+
+// readODFSheets returns the tables of an ODS body. Repeated rows and columns are
+// expanded, except trailing empty ones, which ODF uses to pad sheets to their full size.
+func readODFSheets(decoder *xml.Decoder) ([]odsSheet, error) {
+	var (
+		sheets       []odsSheet
+		sheet        *odsSheet
+		row          []string
+		rowRepeat    int
+		emptyRows    int
+		emptyCells   int
+		cellRepeat   int
+		cell         []string
+		inCell       bool
+		paragraph    odfParagraph
+		skip         int
+		rowHasValues bool
+	)
+
+	repeat := func(t xml.StartElement, attr string) int {
+		n, err := strconv.Atoi(odfAttr(t, attr))
+		if err != nil || n < 1 {
+			return 1
+		}
+		return n
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return sheets, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if odfSkippedElements[t.Name.Local] {
+				skip++
+			}
+			if skip > 0 || paragraph.handle(t) {
+				continue
+			}
+			switch t.Name.Local {
+			case "table":
+				sheet = &odsSheet{name: odfAttr(t, "name")}
+				emptyRows = 0
+			case "table-row":
+				row, rowHasValues, emptyCells = nil, false, 0
+				rowRepeat = repeat(t, "number-rows-repeated")
+			case "table-cell", "covered-table-cell":
+				cell, inCell = nil, true
+				cellRepeat = repeat(t, "number-columns-repeated")
+			case "p":
+				if inCell {
+					paragraph.start()
+				}
+			}
+		case xml.CharData:
+			if skip == 0 {
+				paragraph.write(t)
+			}
+		case xml.EndElement:
+			if odfSkippedElements[t.Name.Local] {
+				skip--
+				continue
+			}
+			if skip > 0 {
+				continue
+			}
+			switch t.Name.Local {
+			case "p":
+				if text := paragraph.end(); text != "" && inCell {
+					cell = append(cell, text)
+				}
+			case "table-cell", "covered-table-cell":
+				inCell = false
+				value := strings.Join(cell, " ")
+				if value == "" {
+					emptyCells += cellRepeat
+					continue
+				}
+				for ; emptyCells > 0; emptyCells-- {
+					row = append(row, "")
+				}
+				for i := 0; i < cellRepeat; i++ {
+					row = append(row, value)
+				}
+				rowHasValues = true
+			case "table-row":
+				if sheet == nil {
+					continue
+				}
+				if !rowHasValues {
+					emptyRows += rowRepeat
+					continue
+				}
+				for ; emptyRows > 0; emptyRows-- {
+					sheet.rows = append(sheet.rows, nil)
+				}
+				for i := 0; i < rowRepeat; i++ {
+					sheet.rows = append(sheet.rows, row)
+				}
+			case "table":
+				if sheet != nil {
+					sheets = append(sheets, *sheet)
+				}
+				sheet = nil
+			}
+		}
+	}
+}
+
+// readODFSlides returns the slides of an ODP body. Frames with the presentation class
+// "title" give the slide title, and the notes page's "notes" frame gives speaker notes.
+func readODFSlides(decoder *xml.Decoder) ([]Slide, error) {
+	var (
+		slides     []Slide
+		slide      *Slide
+		frameClass string
+		frameText  []string
+		inNotes    bool
+		paragraph  odfParagraph
+		prefix     string
+		listDepth  int
+		skip       int
+		table      *odfTable
+	)
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return slides, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if odfSkippedElements[t.Name.Local] {
+				skip++
+			}
+			if skip > 0 || paragraph.handle(t) {
+				continue
+			}
+			switch t.Name.Local {
+			case "page":
+				slide = &Slide{Number: len(slides) + 1}
+			case "notes":
+				inNotes = true
+			case "frame", "custom-shape":
+				frameClass, frameText = odfAttr(t, "class"), nil
+			case "h", "p":
+				prefix = ""
+				if listDepth > 0 {
+					prefix = strings.Repeat("  ", listDepth-1) + "- "
+				}
+				paragraph.start()
+			case "list":
+				listDepth++
+			case "table":
+				table = &odfTable{}
+			case "table-row":
+				if table != nil {
+					table.row = nil
+				}
+			case "table-cell", "covered-table-cell":
+				if table != nil {
+					table.cell, table.inCell = nil, true
+				}
+			}
+		case xml.CharData:
+			if skip == 0 {
+				paragraph.write(t)
+			}
+		case xml.EndElement:
+			if odfSkippedElements[t.Name.Local] {
+				skip--
+				continue
+			}
+			if skip > 0 || slide == nil {
+				continue
+			}
+			switch t.Name.Local {
+			case "h", "p":
+				text := paragraph.end()
+				if text == "" {
+					continue
+				}
+				if table != nil && table.inCell {
+					table.cell = append(table.cell, text)
+				} else {
+					frameText = append(frameText, prefix+text)
+				}
+			case "list":
+				listDepth--
+			case "table-cell", "covered-table-cell":
+				if table != nil {
+					table.row = append(table.row, strings.Join(table.cell, " "))
+					table.inCell = false
+				}
+			case "table-row":
+				if table != nil {
+					table.rows = append(table.rows, table.row)
+				}
+			case "table":
+				if table != nil && len(table.rows) > 0 && !inNotes {
+					slide.Blocks = append(slide.Blocks, markdownTable(table.rows))
+				}
+				table = nil
+			case "frame", "custom-shape":
+				switch {
+				case len(frameText) == 0:
+				case inNotes:
+					// The notes page also carries a slide thumbnail and page number
+					if frameClass == "notes" {
+						slide.Notes = strings.Join(frameText, "\n")
+					}
+				case (frameClass == "title" || frameClass == "subtitle") && slide.Title == "":
+					slide.Title = strings.Join(frameText, " ")
+				case frameClass == "page-number" || frameClass == "date-time" || frameClass == "footer":
+				default:
+					slide.Blocks = append(slide.Blocks, frameText...)
+				}
+				frameText = nil
+			case "notes":
+				inNotes = false
+			case "page":
+				slides = append(slides, *slide)
+				slide = nil
+			}
+		}
+	}
+}
+
+// odfAttr returns the value of an attribute by local name
+func odfAttr(t xml.StartElement, name string) string {
+	for _, attr := range t.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
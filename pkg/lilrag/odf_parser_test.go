@@ -0,0 +1,154 @@
+package lilrag
+
+import (
+	"strings"
+	"testing"
+)
+
+const odfNamespaces = `xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" ` +
+	`xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0" ` +
+	`xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0" ` +
+	`xmlns:draw="urn:oasis:names:tc:opendocument:xmlns:drawing:1.0" ` +
+	`xmlns:presentation="urn:oasis:names:tc:opendocument:xmlns:presentation:1.0"`
+
+// writeTestODF writes an OpenDocument package whose content.xml holds body
+func writeTestODF(t *testing.T, fileName, body string) string {
+	t.Helper()
+	return writeTestZip(t, fileName, map[string]string{
+		"mimetype": "application/vnd.oasis.opendocument",
+		"content.xml": `<?xml version="1.0" encoding="UTF-8"?><office:document-content ` + odfNamespaces +
+			`><office:body>` + body + `</office:body></office:document-content>`,
+	})
+}
+
+func TestODTParser_Parse(t *testing.T) {
+	filePath := writeTestODF(t, "policy.odt", `<office:text>`+
+		`<text:h text:outline-level="1">Travel Policy</text:h>`+
+		`<text:p>Employees book<text:s text:c="2"/>travel through the portal.`+
+		`<text:note><text:note-citation>1</text:note-citation><text:note-body>`+
+		`<text:p>Footnote text</text:p></text:note-body></text:note></text:p>`+
+		`<text:h text:outline-level="2">Approvals</text:h>`+
+		`<text:list><text:list-item><text:p>Managers approve trips</text:p></text:list-item>`+
+		`<text:list-item><text:p>Finance approves <text:span>budgets</text:span></text:p></text:list-item></text:list>`+
+		`<table:table table:name="Limits"><table:table-row>`+
+		`<table:table-cell><text:p>City</text:p></table:table-cell>`+
+		`<table:table-cell><text:p>Per diem</text:p></table:table-cell></table:table-row>`+
+		`<table:table-row><table:table-cell><text:p>Berlin</text:p></table:table-cell>`+
+		`<table:table-cell><text:p>€60</text:p></table:table-cell></table:table-row></table:table>`+
+		`</office:text>`)
+
+	content, err := NewODTParser().Parse(filePath)
+	if err != nil {
+		t.Fatalf("Failed to parse ODT: %v", err)
+	}
+
+	expected := strings.Join([]string{
+		"# Travel Policy",
+		"Employees book  travel through the portal.",
+		"## Approvals",
+		"- Managers approve trips",
+		"- Finance approves budgets",
+		"| City | Per diem |\n| --- | --- |\n| Berlin | €60 |",
+	}, "\n")
+	if content != expected {
+		t.Errorf("Unexpected content:\n%s\nexpected:\n%s", content, expected)
+	}
+
+	chunks, err := NewODTParser().ParseWithChunks(filePath, "policy")
+	if err != nil {
+		t.Fatalf("Failed to chunk ODT: %v", err)
+	}
+	if len(chunks) == 0 || !strings.HasPrefix(chunks[0].ChunkType, "odt_") {
+		t.Errorf("Expected odt chunks, got %+v", chunks)
+	}
+}
+
+func TestODSParser_ParseWithChunks(t *testing.T) {
+	filePath := writeTestODF(t, "budget.ods", `<office:spreadsheet>`+
+		`<table:table table:name="Q3"><table:table-column table:number-columns-repeated="3"/>`+
+		`<table:table-row><table:table-cell><text:p>Team</text:p></table:table-cell>`+
+		`<table:table-cell><text:p>Budget</text:p></table:table-cell>`+
+		`<table:table-cell table:number-columns-repeated="1020"/></table:table-row>`+
+		`<table:table-row table:number-rows-repeated="2"><table:table-cell/></table:table-row>`+
+		`<table:table-row><table:table-cell><text:p>Platform</text:p></table:table-cell>`+
+		`<table:table-cell office:value-type="float" office:value="1200"><text:p>1,200</text:p></table:table-cell>`+
+		`</table:table-row>`+
+		`<table:table-row table:number-rows-repeated="1048570"><table:table-cell/></table:table-row>`+
+		`</table:table></office:spreadsheet>`)
+
+	chunks, err := NewODSParser().ParseWithChunks(filePath, "budget")
+	if err != nil {
+		t.Fatalf("Failed to parse ODS: %v", err)
+	}
+
+	types := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		types[i] = chunk.ChunkType
+		if chunk.Index != i {
+			t.Errorf("Expected chunk %d to have index %d, got %d", i, i, chunk.Index)
+		}
+	}
+	if strings.Join(types, ",") != "ods_sheet_header,ods_headers,ods_data" {
+		t.Fatalf("Unexpected chunk types %v", types)
+	}
+	if chunks[0].Text != "Spreadsheet Sheet: Q3" {
+		t.Errorf("Unexpected sheet header %q", chunks[0].Text)
+	}
+	// Repeated empty rows keep the original row numbers
+	if chunks[2].Text != "Row 4: Team: Platform | Budget: 1,200\n" {
+		t.Errorf("Unexpected data chunk %q", chunks[2].Text)
+	}
+}
+
+func TestODPParser_ParseWithChunks(t *testing.T) {
+	filePath := writeTestODF(t, "deck.odp", `<office:presentation>`+
+		`<draw:page draw:name="page1">`+
+		`<draw:frame presentation:class="title"><draw:text-box><text:p>Roadmap</text:p></draw:text-box></draw:frame>`+
+		`<draw:frame presentation:class="outline"><draw:text-box><text:list><text:list-item>`+
+		`<text:p>Ship search filters</text:p></text:list-item></text:list></draw:text-box></draw:frame>`+
+		`<draw:frame presentation:class="page-number"><draw:text-box><text:p>1</text:p></draw:text-box></draw:frame>`+
+		`<presentation:notes><draw:page-thumbnail/>`+
+		`<draw:frame presentation:class="notes"><draw:text-box><text:p>Keep it short</text:p></draw:text-box>`+
+		`</draw:frame></presentation:notes></draw:page>`+
+		`<draw:page draw:name="page2"><draw:frame><table:table>`+
+		`<table:table-row><table:table-cell><text:p>Milestone</text:p></table:table-cell></table:table-row>`+
+		`<table:table-row><table:table-cell><text:p>Beta</text:p></table:table-cell></table:table-row>`+
+		`</table:table></draw:frame></draw:page>`+
+		`</office:presentation>`)
+
+	chunks, err := NewODPParser().ParseWithChunks(filePath, "deck")
+	if err != nil {
+		t.Fatalf("Failed to parse ODP: %v", err)
+	}
+
+	expected := []string{
+		"Slide 1: Roadmap\n\n- Ship search filters\n\nNotes: Keep it short",
+		"Slide 2\n\n| Milestone |\n| --- |\n| Beta |",
+	}
+	if len(chunks) != len(expected) {
+		t.Fatalf("Expected %d chunks, got %d", len(expected), len(chunks))
+	}
+	for i, chunk := range chunks {
+		if chunk.Text != expected[i] {
+			t.Errorf("Chunk %d: expected %q, got %q", i, expected[i], chunk.Text)
+		}
+		if chunk.ChunkType != "odp_slide" || chunk.PageNumber == nil || *chunk.PageNumber != i+1 {
+			t.Errorf("Chunk %d: unexpected metadata %s/%v", i, chunk.ChunkType, chunk.PageNumber)
+		}
+	}
+}
+
+func TestDetectDocumentType_ODF(t *testing.T) {
+	dh := NewDocumentHandler(NewTextChunker(256, 38))
+
+	for path, docType := range map[string]DocumentType{
+		"a.odt": DocumentTypeODT, "b.ods": DocumentTypeODS, "c.odp": DocumentTypeODP,
+	} {
+		if got := dh.DetectDocumentType(path); got != docType {
+			t.Errorf("Expected %s to be %s, got %s", path, docType, got)
+		}
+		if _, ok := dh.GetSupportedFormats()[docType]; !ok {
+			t.Errorf("Expected a parser for %s", docType)
+		}
+	}
+}
@@ -1,21 +1,27 @@
 package lilrag
 
 import (
+	"context"
 	"fmt"
+	"image"
 	"log"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"unicode"
 
 	"github.com/dslipak/pdf"
+	"github.com/gen2brain/go-fitz"
 )
 
 type PDFPage struct {
 	PageNumber int
 	Text       string
 	Words      int
+	OCR        bool // Text was read from a rendered image of the page
 }
 
 type PDFDocument struct {
@@ -24,7 +30,30 @@ type PDFDocument struct {
 	TotalPages int
 }
 
-type PDFParser struct{}
+// ImageOCR extracts text from an image. ImageParser implements it with the vision model.
+type ImageOCR interface {
+	OCRImage(ctx context.Context, img image.Image) (string, error)
+}
+
+type PDFParser struct {
+	// ocr reads pages without a usable text layer; nil disables the fallback
+	ocr            ImageOCR
+	ocrMaxPages    int
+	ocrConcurrency int
+}
+
+const (
+	// maxPDFPageWords is the largest page kept as a single chunk; longer pages are split
+	maxPDFPageWords = 1600
+	// minPDFChunkWords is the size below which a page is combined with the following
+	// pages, producing a chunk that spans a page range
+	minPDFChunkWords = 100
+	// minPDFTextLayerWords is the word count below which a page with embedded images is
+	// treated as scanned and sent to OCR. Pages without any text are always sent.
+	minPDFTextLayerWords = 10
+	// pdfOCRRenderDPI is the resolution pages are rendered at before OCR
+	pdfOCRRenderDPI = 150
+)
 
 // cleanText removes invisible characters and normalizes text for better searchability
 func cleanText(text string) string {
@@ -156,21 +185,187 @@ func NewPDFParser() *PDFParser {
 	return &PDFParser{}
 }
 
+// NewPDFParserWithOCR creates a PDF parser that renders pages with little or no
+// extractable text and reads them with ocr. At most maxPages pages per document are
+// sent to OCR, concurrency at a time.
+func NewPDFParserWithOCR(ocr ImageOCR, maxPages, concurrency int) *PDFParser {
+	if maxPages <= 0 {
+		maxPages = 50
+	}
+	if concurrency <= 0 {
+		concurrency = 2
+	}
+
+	return &PDFParser{
+		ocr:            ocr,
+		ocrMaxPages:    maxPages,
+		ocrConcurrency: concurrency,
+	}
+}
+
 func (p *PDFParser) ParsePDF(filePath string) (*PDFDocument, error) {
+	return p.ParsePDFContext(context.Background(), filePath)
+}
+
+// ParsePDFContext extracts the pages of a PDF, passing ctx to the OCR of scanned pages
+func (p *PDFParser) ParsePDFContext(ctx context.Context, filePath string) (*PDFDocument, error) {
 	// Try pdftotext CLI tool first for best compatibility
 	doc, err := p.parsePDFWithPDFToText(filePath)
 	if err != nil {
 		// Fallback to dslipak/pdf library
 		fallbackDoc, dslipakErr := p.parsePDFWithDslipak(filePath)
 		if dslipakErr != nil {
-			return nil, fmt.Errorf("failed to extract text: pdftotext error: %w; dslipak/pdf error: %v", err, dslipakErr)
+			if p.ocr == nil {
+				return nil, fmt.Errorf("failed to extract text: pdftotext error: %w; dslipak/pdf error: %v", err, dslipakErr)
+			}
+			// Neither extractor could read the file; OCR may still recover every page
+			log.Printf("WARNING: Text extraction failed for %s, relying on OCR: %v", filePath, err)
+			fallbackDoc = &PDFDocument{Title: filepath.Base(filePath)}
+		} else {
+			log.Printf("WARNING: Using fallback PDF parser for %s - text extraction quality may vary.", filePath)
+		}
+		doc = fallbackDoc
+	}
+
+	if p.ocr != nil {
+		if err := p.ocrScannedPages(ctx, filePath, doc); err != nil {
+			log.Printf("WARNING: OCR fallback failed for %s: %v", filePath, err)
 		}
-		log.Printf("WARNING: Using fallback PDF parser for %s - text extraction quality may vary.", filePath)
-		return fallbackDoc, nil
 	}
+
 	return doc, nil
 }
 
+// ocrScannedPages renders pages without a text layer, and pages with images whose text
+// layer has fewer than minPDFTextLayerWords words, and replaces their text with the OCR
+// result
+func (p *PDFParser) ocrScannedPages(ctx context.Context, filePath string, doc *PDFDocument) error {
+	rendered, err := fitz.New(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open PDF for rendering: %w", err)
+	}
+	defer rendered.Close()
+
+	if doc.TotalPages == 0 {
+		doc.TotalPages = rendered.NumPage()
+	}
+
+	candidates := scannedPDFPages(doc, pdfImagePages(filePath))
+	if len(candidates) == 0 {
+		return nil
+	}
+	if len(candidates) > p.ocrMaxPages {
+		log.Printf("WARNING: %s has %d pages without a text layer, only the first %d will be read with OCR",
+			filePath, len(candidates), p.ocrMaxPages)
+		candidates = candidates[:p.ocrMaxPages]
+	}
+
+	results := make([]*PDFPage, len(candidates))
+	sem := make(chan struct{}, p.ocrConcurrency)
+	var wg sync.WaitGroup
+
+	for i, pageNumber := range candidates {
+		if ctx.Err() != nil {
+			break
+		}
+
+		// Render pages one at a time; only concurrency rendered pages are held in memory
+		sem <- struct{}{}
+		img, err := rendered.ImageDPI(pageNumber-1, pdfOCRRenderDPI)
+		if err != nil {
+			<-sem
+			log.Printf("WARNING: Failed to render page %d of %s: %v", pageNumber, filePath, err)
+			continue
+		}
+
+		wg.Add(1)
+		go func(i, pageNumber int, img image.Image) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			text, err := p.ocr.OCRImage(ctx, img)
+			if err != nil {
+				log.Printf("WARNING: OCR failed for page %d of %s: %v", pageNumber, filePath, err)
+				return
+			}
+			results[i] = &PDFPage{
+				PageNumber: pageNumber,
+				Text:       text,
+				Words:      len(strings.Fields(text)),
+				OCR:        true,
+			}
+		}(i, pageNumber, img)
+	}
+	wg.Wait()
+
+	mergeOCRPages(doc, results)
+	return nil
+}
+
+// scannedPDFPages returns the page numbers without extractable text, including pages
+// the extractors skipped entirely, and the pages in imagePages with too little text
+func scannedPDFPages(doc *PDFDocument, imagePages map[int]bool) []int {
+	words := make(map[int]int, len(doc.Pages))
+	for _, page := range doc.Pages {
+		words[page.PageNumber] = page.Words
+	}
+
+	var pages []int
+	for pageNumber := 1; pageNumber <= doc.TotalPages; pageNumber++ {
+		if words[pageNumber] == 0 || (imagePages[pageNumber] && words[pageNumber] < minPDFTextLayerWords) {
+			pages = append(pages, pageNumber)
+		}
+	}
+	return pages
+}
+
+// pdfImagePages returns the numbers of the pages that draw an embedded image. A file
+// the PDF library cannot read has no image pages.
+func pdfImagePages(filePath string) map[int]bool {
+	r, err := pdf.Open(filePath)
+	if err != nil {
+		return nil
+	}
+
+	pages := make(map[int]bool)
+	for i := 1; i <= r.NumPage(); i++ {
+		xObjects := r.Page(i).Resources().Key("XObject")
+		for _, name := range xObjects.Keys() {
+			if xObjects.Key(name).Key("Subtype").Name() == "Image" {
+				pages[i] = true
+				break
+			}
+		}
+	}
+	return pages
+}
+
+// mergeOCRPages replaces or inserts OCR'd pages, keeping the document in page order.
+// A page keeps its text layer when OCR found fewer words.
+func mergeOCRPages(doc *PDFDocument, results []*PDFPage) {
+	byNumber := make(map[int]int, len(doc.Pages))
+	for i, page := range doc.Pages {
+		byNumber[page.PageNumber] = i
+	}
+
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		if i, ok := byNumber[result.PageNumber]; ok {
+			if result.Words > doc.Pages[i].Words {
+				doc.Pages[i] = *result
+			}
+			continue
+		}
+		doc.Pages = append(doc.Pages, *result)
+	}
+
+	sort.Slice(doc.Pages, func(i, j int) bool {
+		return doc.Pages[i].PageNumber < doc.Pages[j].PageNumber
+	})
+}
+
 func (p *PDFParser) parsePDFWithPDFToText(filePath string) (*PDFDocument, error) {
 	// Check if pdftotext is available
 	_, err := exec.LookPath("pdftotext")
@@ -185,26 +380,39 @@ func (p *PDFParser) parsePDFWithPDFToText(filePath string) (*PDFDocument, error)
 		return nil, fmt.Errorf("pdftotext failed: %w", err)
 	}
 
-	text := string(output)
-	cleanedText := cleanTextPreserveLayout(text)
+	pages, totalPages := splitPDFToTextPages(string(output))
 
-	doc := &PDFDocument{
-		Pages:      make([]PDFPage, 0),
+	return &PDFDocument{
+		Pages:      pages,
 		Title:      filepath.Base(filePath),
-		TotalPages: 1, // pdftotext output as single combined text with preserved layout
+		TotalPages: totalPages,
+	}, nil
+}
+
+// splitPDFToTextPages splits pdftotext output into pages. pdftotext ends every page
+// with a form feed, so page numbers follow the form feeds; blank pages are skipped
+// but still counted.
+func splitPDFToTextPages(output string) ([]PDFPage, int) {
+	parts := strings.Split(output, "\f")
+	if len(parts) > 1 && strings.TrimSpace(parts[len(parts)-1]) == "" {
+		parts = parts[:len(parts)-1]
 	}
 
-	if strings.TrimSpace(cleanedText) != "" {
-		wordCount := len(strings.Fields(cleanedText))
+	pages := make([]PDFPage, 0, len(parts))
+	for i, part := range parts {
+		cleanedText := cleanTextPreserveLayout(part)
+		if strings.TrimSpace(cleanedText) == "" {
+			continue
+		}
 
-		doc.Pages = append(doc.Pages, PDFPage{
-			PageNumber: 1,
+		pages = append(pages, PDFPage{
+			PageNumber: i + 1,
 			Text:       cleanedText,
-			Words:      wordCount,
+			Words:      len(strings.Fields(cleanedText)),
 		})
 	}
 
-	return doc, nil
+	return pages, len(parts)
 }
 
 func (p *PDFParser) parsePDFWithDslipak(filePath string) (*PDFDocument, error) {
@@ -219,7 +427,33 @@ func (p *PDFParser) parsePDFWithDslipak(filePath string) (*PDFDocument, error) {
 		TotalPages: r.NumPage(),
 	}
 
-	// Try to get all text at once first
+	// Extract page by page so each page keeps its real number
+	for i := 1; i <= r.NumPage(); i++ {
+		page := r.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+
+		plainText, err := page.GetPlainText(nil)
+		if err != nil {
+			// Continue with other pages if one fails
+			continue
+		}
+
+		cleanedText := cleanText(plainText)
+		if strings.TrimSpace(cleanedText) != "" {
+			doc.Pages = append(doc.Pages, PDFPage{
+				PageNumber: i,
+				Text:       cleanedText,
+				Words:      len(strings.Fields(cleanedText)),
+			})
+		}
+	}
+	if len(doc.Pages) > 0 {
+		return doc, nil
+	}
+
+	// If that fails, get all text at once as a single page
 	plainTextReader, err := r.GetPlainText()
 	if err == nil {
 		buf := make([]byte, 1024*1024) // 1MB buffer
@@ -244,39 +478,6 @@ func (p *PDFParser) parsePDFWithDslipak(filePath string) (*PDFDocument, error) {
 				Words:      wordCount,
 			})
 		}
-		return doc, nil
-	}
-
-	// If that fails, try page by page
-	var allText strings.Builder
-	for i := 1; i <= r.NumPage(); i++ {
-		page := r.Page(i)
-		if page.V.IsNull() {
-			continue
-		}
-
-		plainText, err := page.GetPlainText(nil)
-		if err != nil {
-			// Continue with other pages if one fails
-			continue
-		}
-
-		cleanedText := cleanText(plainText)
-		if strings.TrimSpace(cleanedText) != "" {
-			allText.WriteString(cleanedText)
-			allText.WriteString("\n\n")
-		}
-	}
-
-	// Since dslipak/pdf doesn't easily give us per-page text, we'll treat the entire document as one page
-	fullText := strings.TrimSpace(allText.String())
-	if fullText != "" {
-		wordCount := len(strings.Fields(fullText))
-		doc.Pages = append(doc.Pages, PDFPage{
-			PageNumber: 1,
-			Text:       fullText,
-			Words:      wordCount,
-		})
 	}
 
 	return doc, nil
@@ -288,18 +489,32 @@ func GetPDFPageID(documentID string, pageNumber int) string {
 }
 
 // ParsePDFWithPageChunks parses PDF and returns optimally chunked content
-func (p *PDFParser) ParsePDFWithPageChunks(filePath, _ string) ([]Chunk, error) {
-	doc, err := p.ParsePDF(filePath)
+func (p *PDFParser) ParsePDFWithPageChunks(filePath, documentID string) ([]Chunk, error) {
+	return p.ParseWithChunksContext(context.Background(), filePath, documentID)
+}
+
+// ParseWithChunksContext is ParsePDFWithPageChunks with ctx passed to the OCR of
+// scanned pages
+func (p *PDFParser) ParseWithChunksContext(ctx context.Context, filePath, _ string) ([]Chunk, error) {
+	doc, err := p.ParsePDFContext(ctx, filePath)
 	if err != nil {
 		return nil, err
 	}
 
+	return p.chunkPDFPages(doc.Pages), nil
+}
+
+// chunkPDFPages turns extracted pages into chunks. Short pages are combined with the
+// following pages and the chunk records the page range it spans.
+func (p *PDFParser) chunkPDFPages(pages []PDFPage) []Chunk {
 	// Create a simple chunker for PDF content
 	chunker := NewTextChunker(1800, 200) // Use default chunking params
 	var allChunks []Chunk
 	chunkIndex := 0
 
-	for _, page := range doc.Pages {
+	for i := 0; i < len(pages); i++ {
+		page := pages[i]
+
 		// Skip empty pages
 		if strings.TrimSpace(page.Text) == "" {
 			continue
@@ -308,15 +523,27 @@ func (p *PDFParser) ParsePDFWithPageChunks(filePath, _ string) ([]Chunk, error)
 		pageNum := page.PageNumber
 
 		// For smaller pages, keep as single chunk
-		if page.Words <= 1600 {
+		if page.Words <= maxPDFPageWords {
+			text, words, lastPage := page.Text, page.Words, pageNum
+			for words < minPDFChunkWords && i+1 < len(pages) && pages[i+1].OCR == page.OCR &&
+				words+pages[i+1].Words <= maxPDFPageWords && strings.TrimSpace(pages[i+1].Text) != "" {
+				i++
+				text += "\n\n" + pages[i].Text
+				words += pages[i].Words
+				lastPage = pages[i].PageNumber
+			}
+
 			chunk := Chunk{
-				Text:       page.Text,
+				Text:       text,
 				Index:      chunkIndex,
 				StartPos:   0,
-				EndPos:     len(page.Text),
-				TokenCount: page.Words,
+				EndPos:     len(text),
+				TokenCount: words,
 				PageNumber: &pageNum,
-				ChunkType:  "pdf_page",
+				ChunkType:  pdfChunkType(page, "pdf_page"),
+			}
+			if lastPage != pageNum {
+				chunk.EndPageNumber = &lastPage
 			}
 			allChunks = append(allChunks, chunk)
 			chunkIndex++
@@ -328,7 +555,7 @@ func (p *PDFParser) ParsePDFWithPageChunks(filePath, _ string) ([]Chunk, error)
 				// Add page number and adjust chunk metadata
 				chunk.Index = chunkIndex
 				chunk.PageNumber = &pageNum
-				chunk.ChunkType = "pdf_page_section"
+				chunk.ChunkType = pdfChunkType(page, "pdf_page_section")
 
 				// Add page context prefix for better semantic understanding
 				pageContext := fmt.Sprintf("Page %d: ", pageNum)
@@ -341,7 +568,15 @@ func (p *PDFParser) ParsePDFWithPageChunks(filePath, _ string) ([]Chunk, error)
 		}
 	}
 
-	return p.optimizePDFChunks(allChunks), nil
+	return p.optimizePDFChunks(allChunks)
+}
+
+// pdfChunkType returns the chunk type for a page, flagging text that came from OCR
+func pdfChunkType(page PDFPage, textLayerType string) string {
+	if page.OCR {
+		return "pdf_ocr"
+	}
+	return textLayerType
 }
 
 // optimizePDFChunks post-processes PDF chunks for optimal retrieval
@@ -0,0 +1,330 @@
+package lilrag
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// PDF fixtures in test_pdfs/: a single-page reference card and a three-page report
+// whose pages start with "Page N:"
+var (
+	singlePagePDFPath = filepath.Join("..", "..", "test_pdfs", "test_document.pdf")
+	multiPagePDFPath  = filepath.Join("..", "..", "test_pdfs", "multi_page.pdf")
+)
+
+func TestSplitPDFToTextPages(t *testing.T) {
+	output := "First page text\n\f\f  Third page   text\n\fFourth page\n\f"
+
+	pages, total := splitPDFToTextPages(output)
+
+	if total != 4 {
+		t.Errorf("Expected 4 pages, got %d", total)
+	}
+	if len(pages) != 3 {
+		t.Fatalf("Expected blank page to be skipped, got %d pages", len(pages))
+	}
+
+	expected := []int{1, 3, 4}
+	for i, page := range pages {
+		if page.PageNumber != expected[i] {
+			t.Errorf("Expected page %d to have number %d, got %d", i, expected[i], page.PageNumber)
+		}
+	}
+	if !strings.Contains(pages[1].Text, "Third page") || pages[1].Words != 3 {
+		t.Errorf("Unexpected third page: %+v", pages[1])
+	}
+}
+
+func TestPDFParser_ChunkPDFPages_PageRanges(t *testing.T) {
+	long := strings.Repeat("word ", 300)
+	pages := []PDFPage{
+		{PageNumber: 1, Text: long, Words: 300},
+		{PageNumber: 2, Text: "Short heading page", Words: 3},
+		{PageNumber: 3, Text: "Another short page", Words: 3},
+		{PageNumber: 4, Text: long, Words: 300},
+		{PageNumber: 5, Text: long, Words: 300},
+	}
+
+	chunks := NewPDFParser().chunkPDFPages(pages)
+
+	expected := []string{"1", "2-4", "5"}
+	if len(chunks) != len(expected) {
+		t.Fatalf("Expected %d chunks, got %d", len(expected), len(chunks))
+	}
+	for i, chunk := range chunks {
+		if got := chunk.PageLabel(); got != expected[i] {
+			t.Errorf("Chunk %d: expected pages %s, got %s", i, expected[i], got)
+		}
+	}
+	if chunks[0].EndPageNumber != nil {
+		t.Error("Expected single-page chunk to have no end page")
+	}
+	if !strings.Contains(chunks[1].Text, "Short heading page") || !strings.Contains(chunks[1].Text, "Another short page") {
+		t.Errorf("Expected short pages to be combined, got %q", chunks[1].Text)
+	}
+}
+
+func TestPDFParser_ParsePDF_PageNumbers(t *testing.T) {
+	parser := NewPDFParser()
+
+	check := func(t *testing.T, extract func(string) (*PDFDocument, error)) {
+		t.Helper()
+
+		doc, err := extract(multiPagePDFPath)
+		if err != nil {
+			t.Fatalf("Extraction failed: %v", err)
+		}
+		if len(doc.Pages) != 3 || doc.TotalPages != 3 {
+			t.Fatalf("Expected 3 pages, got %d (total %d)", len(doc.Pages), doc.TotalPages)
+		}
+		for i, page := range doc.Pages {
+			if page.PageNumber != i+1 {
+				t.Errorf("Expected page number %d, got %d", i+1, page.PageNumber)
+			}
+			if !strings.Contains(page.Text, fmt.Sprintf("Page %d:", page.PageNumber)) {
+				t.Errorf("Page %d does not contain its own heading: %q", page.PageNumber, page.Text)
+			}
+		}
+
+		doc, err = extract(singlePagePDFPath)
+		if err != nil {
+			t.Fatalf("Extraction failed: %v", err)
+		}
+		if len(doc.Pages) != 1 || doc.Pages[0].PageNumber != 1 || doc.TotalPages != 1 {
+			t.Errorf("Expected a single page 1, got %d pages (total %d)", len(doc.Pages), doc.TotalPages)
+		}
+	}
+
+	t.Run("pdftotext", func(t *testing.T) {
+		if _, err := exec.LookPath("pdftotext"); err != nil {
+			t.Skip("Skipping test: pdftotext not installed")
+		}
+		check(t, parser.parsePDFWithPDFToText)
+	})
+
+	t.Run("dslipak", func(t *testing.T) {
+		check(t, parser.parsePDFWithDslipak)
+	})
+}
+
+func TestPDFParser_ParsePDFWithPageChunks(t *testing.T) {
+	chunks, err := NewPDFParser().ParsePDFWithPageChunks(multiPagePDFPath, "report")
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+
+	// The three pages are short, so they form one chunk spanning the whole range
+	if len(chunks) != 1 {
+		t.Fatalf("Expected 1 chunk, got %d", len(chunks))
+	}
+	if label := chunks[0].PageLabel(); label != "1-3" {
+		t.Errorf("Expected chunk to span pages 1-3, got %q", label)
+	}
+	for _, heading := range []string{"Introduction", "Retry Policy", "Monitoring"} {
+		if !strings.Contains(chunks[0].Text, heading) {
+			t.Errorf("Expected chunk to contain %q", heading)
+		}
+	}
+}
+
+// scannedPDFPath has a text page followed by two pages drawn without any text
+var scannedPDFPath = filepath.Join("..", "..", "test_pdfs", "scanned_pages.pdf")
+
+// fakeOCR records calls and the peak number of concurrent requests
+type fakeOCR struct {
+	mu     sync.Mutex
+	calls  int
+	active int
+	peak   int
+}
+
+func (f *fakeOCR) OCRImage(_ context.Context, img image.Image) (string, error) {
+	f.mu.Lock()
+	f.calls++
+	f.active++
+	if f.active > f.peak {
+		f.peak = f.active
+	}
+	f.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	f.mu.Lock()
+	f.active--
+	f.mu.Unlock()
+
+	if img.Bounds().Dx() == 0 {
+		return "", fmt.Errorf("empty image")
+	}
+	return "Scanned invoice total due thirty days", nil
+}
+
+func TestPDFParser_OCRScannedPages(t *testing.T) {
+	ocr := &fakeOCR{}
+	parser := NewPDFParserWithOCR(ocr, 10, 1)
+
+	chunks, err := parser.ParsePDFWithPageChunks(scannedPDFPath, "scan")
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+
+	if ocr.calls != 2 {
+		t.Errorf("Expected the two scanned pages to be sent to OCR, got %d calls", ocr.calls)
+	}
+	if ocr.peak > 1 {
+		t.Errorf("Expected at most 1 concurrent OCR request, got %d", ocr.peak)
+	}
+
+	// The text page and the OCR pages are never combined into one chunk
+	if len(chunks) != 2 {
+		t.Fatalf("Expected 2 chunks, got %d", len(chunks))
+	}
+	if chunks[0].ChunkType != "pdf_page" || chunks[0].PageLabel() != "1" {
+		t.Errorf("Expected text layer chunk for page 1, got %s for %s", chunks[0].ChunkType, chunks[0].PageLabel())
+	}
+	if chunks[1].ChunkType != "pdf_ocr" || chunks[1].PageLabel() != "2-3" {
+		t.Errorf("Expected OCR chunk for pages 2-3, got %s for %s", chunks[1].ChunkType, chunks[1].PageLabel())
+	}
+	if !strings.Contains(chunks[1].Text, "Scanned invoice") {
+		t.Errorf("Expected OCR text in chunk, got %q", chunks[1].Text)
+	}
+}
+
+func TestPDFParser_OCRMaxPages(t *testing.T) {
+	ocr := &fakeOCR{}
+	doc, err := NewPDFParserWithOCR(ocr, 1, 2).ParsePDF(scannedPDFPath)
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+
+	if ocr.calls != 1 {
+		t.Errorf("Expected OCR to stop after 1 page, got %d calls", ocr.calls)
+	}
+	if len(doc.Pages) != 2 || !doc.Pages[1].OCR || doc.Pages[1].PageNumber != 2 {
+		t.Errorf("Expected page 1 and OCR page 2, got %+v", doc.Pages)
+	}
+
+	// Without OCR the scanned pages are simply missing
+	doc, err = NewPDFParser().ParsePDF(scannedPDFPath)
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+	if len(doc.Pages) != 1 || doc.TotalPages != 3 {
+		t.Errorf("Expected only the text page, got %d pages (total %d)", len(doc.Pages), doc.TotalPages)
+	}
+}
+
+func TestPDFParser_OCRCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ocr := &fakeOCR{}
+	doc, err := NewPDFParserWithOCR(ocr, 10, 1).ParsePDFContext(ctx, scannedPDFPath)
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+	if ocr.calls != 0 {
+		t.Errorf("Expected no OCR after the context was canceled, got %d calls", ocr.calls)
+	}
+	if len(doc.Pages) != 1 {
+		t.Errorf("Expected only the text page, got %d pages", len(doc.Pages))
+	}
+}
+
+func TestScannedPDFPages(t *testing.T) {
+	doc := &PDFDocument{
+		Pages: []PDFPage{
+			{PageNumber: 1, Text: "Part One", Words: 2},
+			{PageNumber: 2, Text: "Figure 3", Words: 2},
+			{PageNumber: 4, Text: "A full page of text with more than ten words in its layer", Words: 13},
+		},
+		TotalPages: 4,
+	}
+
+	// Short text-only pages keep their text layer; missing pages and short image pages are read
+	pages := scannedPDFPages(doc, map[int]bool{2: true, 4: true})
+	if fmt.Sprint(pages) != "[2 3]" {
+		t.Errorf("Expected pages 2 and 3 to be sent to OCR, got %v", pages)
+	}
+}
+
+func TestMergeOCRPages(t *testing.T) {
+	doc := &PDFDocument{
+		Pages: []PDFPage{
+			{PageNumber: 1, Text: "Cover", Words: 1},
+			{PageNumber: 3, Text: "Page three has a text layer stamp", Words: 7},
+		},
+		TotalPages: 4,
+	}
+
+	mergeOCRPages(doc, []*PDFPage{
+		{PageNumber: 1, Text: "Annual report 2024 prepared by finance", Words: 6, OCR: true},
+		{PageNumber: 3, Text: "Short", Words: 1, OCR: true},
+		{PageNumber: 2, Text: "Table of contents", Words: 3, OCR: true},
+		nil, // failed page
+	})
+
+	if len(doc.Pages) != 3 {
+		t.Fatalf("Expected 3 pages, got %d", len(doc.Pages))
+	}
+	for i, page := range doc.Pages {
+		if page.PageNumber != i+1 {
+			t.Errorf("Expected pages in order, got page %d at %d", page.PageNumber, i)
+		}
+	}
+	if !doc.Pages[0].OCR || !doc.Pages[1].OCR {
+		t.Error("Expected OCR text to replace the sparse cover and fill the missing page")
+	}
+	if doc.Pages[2].OCR {
+		t.Error("Expected text layer to be kept when OCR found fewer words")
+	}
+}
+
+func TestImageParser_OCRImage(t *testing.T) {
+	var request VisionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		_ = json.NewEncoder(w).Encode(VisionResponse{Message: VisionMessage{Content: "# Scanned page\n"}})
+	}))
+	defer server.Close()
+
+	parser := NewImageParserWithTimeout(server.URL, "llava", nil, 5, 100)
+	text, err := parser.OCRImage(context.Background(), image.NewRGBA(image.Rect(0, 0, 400, 200)))
+	if err != nil {
+		t.Fatalf("OCRImage failed: %v", err)
+	}
+
+	if text != "# Scanned page" {
+		t.Errorf("Unexpected OCR text %q", text)
+	}
+	if len(request.Messages) != 1 || request.Messages[0].Content != ocrPrompt || len(request.Messages[0].Images) != 1 {
+		t.Fatalf("Expected the OCR prompt with one image, got %+v", request.Messages)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(request.Messages[0].Images[0])
+	if err != nil {
+		t.Fatalf("Image is not base64: %v", err)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Image is not a JPEG: %v", err)
+	}
+	if img.Bounds().Dx() != 100 || img.Bounds().Dy() != 50 {
+		t.Errorf("Expected image resized to 100x50, got %v", img.Bounds())
+	}
+}
@@ -0,0 +1,466 @@
+package lilrag
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PPTXParser handles PowerPoint .pptx files by reading the OOXML package directly
+type PPTXParser struct {
+	chunker *TextChunker
+}
+
+// Slide is the text content of a single presentation slide (PPTX or ODP)
+type Slide struct {
+	Number int
+	Title  string
+	Blocks []string // Body paragraphs and Markdown tables in reading order
+	Notes  string
+}
+
+// NewPPTXParser creates a new PPTX parser
+func NewPPTXParser() *PPTXParser {
+	return &PPTXParser{}
+}
+
+// NewPPTXParserWithChunker creates a PPTX parser that splits oversized slides with chunker
+func NewPPTXParserWithChunker(chunker *TextChunker) *PPTXParser {
+	return &PPTXParser{chunker: chunker}
+}
+
+// Text renders the slide as plain text: title, body, tables, then speaker notes
+func (s Slide) Text() string {
+	var parts []string
+	if s.Title != "" {
+		parts = append(parts, fmt.Sprintf("Slide %d: %s", s.Number, s.Title))
+	} else {
+		parts = append(parts, fmt.Sprintf("Slide %d", s.Number))
+	}
+	parts = append(parts, s.Blocks...)
+	if s.Notes != "" {
+		parts = append(parts, "Notes: "+s.Notes)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// isEmpty reports whether the slide has no text at all
+func (s Slide) isEmpty() bool {
+	return s.Title == "" && len(s.Blocks) == 0 && s.Notes == ""
+}
+
+// ParseSlides reads every slide of a presentation in presentation order
+func (pp *PPTXParser) ParseSlides(filePath string) ([]Slide, error) {
+	r, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PPTX file: %w", err)
+	}
+	defer r.Close()
+
+	files := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		files[f.Name] = f
+	}
+
+	slidePaths, err := pptxSlidePaths(files)
+	if err != nil {
+		return nil, err
+	}
+
+	slides := make([]Slide, 0, len(slidePaths))
+	for i, slidePath := range slidePaths {
+		slide, err := readPPTXSlide(files, slidePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read slide %d: %w", i+1, err)
+		}
+		slide.Number = i + 1
+		slides = append(slides, slide)
+	}
+
+	return slides, nil
+}
+
+// Parse extracts text content from a PPTX file
+func (pp *PPTXParser) Parse(filePath string) (string, error) {
+	slides, err := pp.ParseSlides(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	var texts []string
+	for _, slide := range slides {
+		if !slide.isEmpty() {
+			texts = append(texts, slide.Text())
+		}
+	}
+
+	return strings.Join(texts, "\n\n"), nil
+}
+
+// ParseWithChunks creates one chunk per slide with the slide number as its page number
+func (pp *PPTXParser) ParseWithChunks(filePath, documentID string) ([]Chunk, error) {
+	return pp.ParseWithChunksContext(context.Background(), filePath, documentID)
+}
+
+// ParseWithChunksContext is ParseWithChunks with ctx passed to the chunker
+func (pp *PPTXParser) ParseWithChunksContext(ctx context.Context, filePath, _ string) ([]Chunk, error) {
+	slides, err := pp.ParseSlides(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := chunkSlides(ctx, slides, pp.chunker, "pptx")
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("no readable content found in PPTX")
+	}
+
+	return chunks, nil
+}
+
+// chunkSlides creates one chunk per non-empty slide with the slide number as its page
+// number. Slides longer than the chunker's limit are split into sections of the same slide.
+func chunkSlides(ctx context.Context, slides []Slide, chunker *TextChunker, typePrefix string) []Chunk {
+	if chunker == nil {
+		chunker = NewTextChunker(320, 48)
+	}
+
+	var chunks []Chunk
+	for _, slide := range slides {
+		if slide.isEmpty() {
+			continue
+		}
+
+		slideNumber := slide.Number
+		text := slide.Text()
+		tokens := chunker.EstimateTokenCount(text)
+
+		if tokens <= chunker.MaxTokens {
+			chunks = append(chunks, Chunk{
+				Text:       text,
+				Index:      len(chunks),
+				StartPos:   0,
+				EndPos:     len(text),
+				TokenCount: tokens,
+				PageNumber: &slideNumber,
+				ChunkType:  typePrefix + "_slide",
+			})
+			continue
+		}
+
+		// Each section keeps the slide heading for context
+		heading := fmt.Sprintf("Slide %d", slideNumber)
+		if slide.Title != "" {
+			heading += ": " + slide.Title
+		}
+		for _, section := range chunker.ChunkTextContext(ctx, text) {
+			section.Index = len(chunks)
+			section.PageNumber = &slideNumber
+			section.ChunkType = typePrefix + "_slide_section"
+			section.HeadingPath = heading
+			chunks = append(chunks, section)
+		}
+	}
+
+	return chunks
+}
+
+// SupportedExtensions returns the file extensions this parser supports
+func (pp *PPTXParser) SupportedExtensions() []string {
+	return []string{".pptx"}
+}
+
+// GetDocumentType returns the type of documents this parser handles
+func (pp *PPTXParser) GetDocumentType() DocumentType {
+	return DocumentTypePPTX
+}
+
+// pptxRelationships maps relationship IDs to package paths for a part's .rels file
+type pptxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Type   string `xml:"Type,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+var pptxSlideNameRegex = regexp.MustCompile(`^ppt/slides/slide(\d+)\.xml$`)
+
+// pptxSlidePaths returns slide part paths in the order of the presentation's slide list,
+// falling back to the slide file numbering when presentation.xml cannot be read
+func pptxSlidePaths(files map[string]*zip.File) ([]string, error) {
+	var presentation struct {
+		SlideIDs []struct {
+			RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sldIdLst>sldId"`
+	}
+	var rels pptxRelationships
+
+	if err := readZipXML(files, "ppt/presentation.xml", &presentation); err == nil {
+		if err := readZipXML(files, "ppt/_rels/presentation.xml.rels", &rels); err == nil {
+			targets := make(map[string]string, len(rels.Relationships))
+			for _, rel := range rels.Relationships {
+				targets[rel.ID] = resolvePartPath("ppt/presentation.xml", rel.Target)
+			}
+
+			var paths []string
+			for _, id := range presentation.SlideIDs {
+				if target, ok := targets[id.RelID]; ok && files[target] != nil {
+					paths = append(paths, target)
+				}
+			}
+			if len(paths) > 0 {
+				return paths, nil
+			}
+		}
+	}
+
+	type numberedSlide struct {
+		path   string
+		number int
+	}
+	var numbered []numberedSlide
+	for name := range files {
+		if match := pptxSlideNameRegex.FindStringSubmatch(name); match != nil {
+			n, _ := strconv.Atoi(match[1])
+			numbered = append(numbered, numberedSlide{path: name, number: n})
+		}
+	}
+	if len(numbered) == 0 {
+		return nil, fmt.Errorf("no slides found in PPTX")
+	}
+	sort.Slice(numbered, func(i, j int) bool { return numbered[i].number < numbered[j].number })
+
+	paths := make([]string, len(numbered))
+	for i, slide := range numbered {
+		paths[i] = slide.path
+	}
+	return paths, nil
+}
+
+// readPPTXSlide extracts a slide's shapes and tables and the text of its notes page
+func readPPTXSlide(files map[string]*zip.File, slidePath string) (Slide, error) {
+	var slide Slide
+
+	shapes, err := readPPTXShapes(files, slidePath)
+	if err != nil {
+		return slide, err
+	}
+	for _, shape := range shapes {
+		switch {
+		case shape.isTitle():
+			if slide.Title == "" {
+				slide.Title = strings.Join(shape.paragraphs, " ")
+				continue
+			}
+			slide.Blocks = append(slide.Blocks, shape.text())
+		case shape.table != nil:
+			slide.Blocks = append(slide.Blocks, markdownTable(shape.table))
+		default:
+			slide.Blocks = append(slide.Blocks, shape.paragraphs...)
+		}
+	}
+
+	// Speaker notes are linked from the slide's relationships
+	var rels pptxRelationships
+	relsPath := path.Join(path.Dir(slidePath), "_rels", path.Base(slidePath)+".rels")
+	if err := readZipXML(files, relsPath, &rels); err != nil {
+		return slide, nil
+	}
+	for _, rel := range rels.Relationships {
+		if !strings.HasSuffix(rel.Type, "/notesSlide") {
+			continue
+		}
+		notesShapes, err := readPPTXShapes(files, resolvePartPath(slidePath, rel.Target))
+		if err != nil {
+			break
+		}
+		var notes []string
+		for _, shape := range notesShapes {
+			// The notes page repeats the slide image and number; only the body holds notes
+			if shape.placeholder == "body" {
+				notes = append(notes, shape.paragraphs...)
+			}
+		}
+		slide.Notes = strings.Join(notes, "\n")
+		break
+	}
+
+	return slide, nil
+}
+
+// pptxShape is the text of one shape or table on a slide
+type pptxShape struct {
+	placeholder string // Placeholder type, e.g. "title", "ctrTitle" or "body"
+	paragraphs  []string
+	table       [][]string
+}
+
+func (s pptxShape) isTitle() bool {
+	return s.placeholder == "title" || s.placeholder == "ctrTitle"
+}
+
+func (s pptxShape) text() string {
+	return strings.Join(s.paragraphs, "\n")
+}
+
+// readPPTXShapes walks a slide or notes part and returns its text shapes and tables in
+// document order. Group shapes are flattened.
+func readPPTXShapes(files map[string]*zip.File, partPath string) ([]pptxShape, error) {
+	f, ok := files[partPath]
+	if !ok {
+		return nil, fmt.Errorf("missing part %s", partPath)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", partPath, err)
+	}
+	defer rc.Close()
+
+	var (
+		shapes    []pptxShape
+		current   *pptxShape
+		paragraph strings.Builder
+		inText    bool
+		table     [][]string
+		row       []string
+		cell      []string
+		inCell    bool
+		inTable   bool
+	)
+
+	decoder := xml.NewDecoder(rc)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", partPath, err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "sp":
+				current = &pptxShape{}
+			case "ph":
+				if current != nil {
+					current.placeholder = "body" // Placeholders without a type are body text
+					for _, attr := range t.Attr {
+						if attr.Name.Local == "type" {
+							current.placeholder = attr.Value
+						}
+					}
+				}
+			case "tbl":
+				inTable, table = true, nil
+			case "tr":
+				row = nil
+			case "tc":
+				inCell, cell = true, nil
+			case "p":
+				paragraph.Reset()
+			case "t":
+				inText = true
+			case "br":
+				paragraph.WriteString("\n")
+			}
+		case xml.CharData:
+			if inText {
+				paragraph.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text := strings.TrimSpace(paragraph.String())
+				if text == "" {
+					continue
+				}
+				if inCell {
+					cell = append(cell, text)
+				} else if current != nil {
+					current.paragraphs = append(current.paragraphs, text)
+				}
+			case "tc":
+				row = append(row, strings.Join(cell, " "))
+				inCell = false
+			case "tr":
+				if inTable {
+					table = append(table, row)
+				}
+			case "tbl":
+				if len(table) > 0 {
+					shapes = append(shapes, pptxShape{table: table})
+				}
+				inTable = false
+			case "sp":
+				if current != nil && len(current.paragraphs) > 0 {
+					shapes = append(shapes, *current)
+				}
+				current = nil
+			}
+		}
+	}
+
+	return shapes, nil
+}
+
+// markdownTable renders rows as a Markdown table with the first row as its header
+func markdownTable(rows [][]string) string {
+	columns := 0
+	for _, row := range rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+
+	var b strings.Builder
+	for i, row := range rows {
+		cells := make([]string, columns)
+		for j := range cells {
+			if j < len(row) {
+				cells[j] = strings.ReplaceAll(row[j], "|", `\|`)
+			}
+		}
+		b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		if i == 0 {
+			b.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// readZipXML decodes an XML part of an OOXML package
+func readZipXML(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("missing part %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer rc.Close()
+
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
+
+// resolvePartPath resolves a relationship target relative to the part that declares it
+func resolvePartPath(sourcePart, target string) string {
+	if strings.HasPrefix(target, "/") {
+		return strings.TrimPrefix(path.Clean(target), "/")
+	}
+	return path.Clean(path.Join(path.Dir(sourcePart), target))
+}
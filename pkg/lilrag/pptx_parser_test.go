@@ -0,0 +1,153 @@
+package lilrag
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	pptxNamespaces = `xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" ` +
+		`xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main"`
+	pptxRelsNamespace = `xmlns="http://schemas.openxmlformats.org/package/2006/relationships"`
+)
+
+// writeTestZip writes a zip package such as a minimal PPTX or ODF document to a temp file
+func writeTestZip(t *testing.T, fileName string, parts map[string]string) string {
+	t.Helper()
+
+	filePath := filepath.Join(t.TempDir(), fileName)
+	f, err := os.Create(filePath)
+	if err != nil {
+		t.Fatalf("Failed to create %s: %v", fileName, err)
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for name, content := range parts {
+		part, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+		if _, err := part.Write([]byte(content)); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to finish %s: %v", fileName, err)
+	}
+
+	return filePath
+}
+
+// pptxShapeXML builds a text shape; an empty placeholder type means a plain text box
+func pptxShapeXML(placeholder string, paragraphs ...string) string {
+	ph := ""
+	if placeholder != "" {
+		ph = `<p:ph type="` + placeholder + `"/>`
+	}
+	var body strings.Builder
+	for _, paragraph := range paragraphs {
+		body.WriteString(`<a:p><a:r><a:t>` + paragraph + `</a:t></a:r></a:p>`)
+	}
+	return `<p:sp><p:nvSpPr><p:cNvPr id="2" name="Shape"/><p:cNvSpPr/><p:nvPr>` + ph + `</p:nvPr></p:nvSpPr>` +
+		`<p:txBody><a:bodyPr/>` + body.String() + `</p:txBody></p:sp>`
+}
+
+func pptxSlideXML(shapes ...string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?><p:sld ` + pptxNamespaces + `><p:cSld><p:spTree>` +
+		strings.Join(shapes, "") + `</p:spTree></p:cSld></p:sld>`
+}
+
+func TestPPTXParser_ParseWithChunks(t *testing.T) {
+	table := `<p:graphicFrame><a:graphic><a:graphicData><a:tbl>` +
+		`<a:tr><a:tc><a:txBody><a:p><a:r><a:t>Region</a:t></a:r></a:p></a:txBody></a:tc>` +
+		`<a:tc><a:txBody><a:p><a:r><a:t>Revenue</a:t></a:r></a:p></a:txBody></a:tc></a:tr>` +
+		`<a:tr><a:tc><a:txBody><a:p><a:r><a:t>EMEA</a:t></a:r></a:p></a:txBody></a:tc>` +
+		`<a:tc><a:txBody><a:p><a:r><a:t>$4.2M</a:t></a:r></a:p></a:txBody></a:tc></a:tr>` +
+		`</a:tbl></a:graphicData></a:graphic></p:graphicFrame>`
+
+	filePath := writeTestZip(t, "deck.pptx", map[string]string{
+		// The slide list order differs from the file numbering
+		"ppt/presentation.xml": `<p:presentation ` + pptxNamespaces + `><p:sldIdLst>` +
+			`<p:sldId id="256" r:id="rId3"/><p:sldId id="257" r:id="rId2"/></p:sldIdLst></p:presentation>`,
+		"ppt/_rels/presentation.xml.rels": `<Relationships ` + pptxRelsNamespace + `>` +
+			`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" ` +
+			`Target="slides/slide1.xml"/>` +
+			`<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" ` +
+			`Target="slides/slide2.xml"/></Relationships>`,
+		"ppt/slides/slide2.xml": pptxSlideXML(pptxShapeXML("ctrTitle", "Q3 Business Review")),
+		"ppt/slides/slide1.xml": pptxSlideXML(
+			pptxShapeXML("title", "Revenue by Region"),
+			pptxShapeXML("body", "EMEA grew 18% year over year", "APAC was flat"),
+			table,
+		),
+		"ppt/slides/_rels/slide1.xml.rels": `<Relationships ` + pptxRelsNamespace + `>` +
+			`<Relationship Id="rId1" ` +
+			`Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/notesSlide" ` +
+			`Target="../notesSlides/notesSlide1.xml"/></Relationships>`,
+		"ppt/notesSlides/notesSlide1.xml": `<p:notes ` + pptxNamespaces + `><p:cSld><p:spTree>` +
+			pptxShapeXML("sldImg") + pptxShapeXML("body", "Mention the new Berlin office") +
+			pptxShapeXML("sldNum", "2") + `</p:spTree></p:cSld></p:notes>`,
+	})
+
+	chunks, err := NewPPTXParser().ParseWithChunks(filePath, "deck")
+	if err != nil {
+		t.Fatalf("Failed to parse PPTX: %v", err)
+	}
+
+	if len(chunks) != 2 {
+		t.Fatalf("Expected one chunk per slide, got %d", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if chunk.PageNumber == nil || *chunk.PageNumber != i+1 {
+			t.Errorf("Expected chunk %d to be slide %d, got %v", i, i+1, chunk.PageNumber)
+		}
+		if chunk.ChunkType != "pptx_slide" || chunk.Index != i {
+			t.Errorf("Unexpected chunk %d metadata: %s/%d", i, chunk.ChunkType, chunk.Index)
+		}
+	}
+
+	if chunks[0].Text != "Slide 1: Q3 Business Review" {
+		t.Errorf("Unexpected title slide text %q", chunks[0].Text)
+	}
+
+	expected := "Slide 2: Revenue by Region\n\n" +
+		"EMEA grew 18% year over year\n\nAPAC was flat\n\n" +
+		"| Region | Revenue |\n| --- | --- |\n| EMEA | $4.2M |\n\n" +
+		"Notes: Mention the new Berlin office"
+	if chunks[1].Text != expected {
+		t.Errorf("Unexpected slide text:\n%s\nexpected:\n%s", chunks[1].Text, expected)
+	}
+}
+
+func TestPPTXParser_FallsBackToSlideFileOrder(t *testing.T) {
+	filePath := writeTestZip(t, "deck.pptx", map[string]string{
+		"ppt/slides/slide10.xml": pptxSlideXML(pptxShapeXML("title", "Appendix")),
+		"ppt/slides/slide2.xml":  pptxSlideXML(pptxShapeXML("", "Plain text box")),
+	})
+
+	content, err := NewPPTXParser().Parse(filePath)
+	if err != nil {
+		t.Fatalf("Failed to parse PPTX: %v", err)
+	}
+
+	expected := "Slide 1\n\nPlain text box\n\nSlide 2: Appendix"
+	if content != expected {
+		t.Errorf("Expected %q, got %q", expected, content)
+	}
+}
+
+func TestDetectDocumentType_PPTX(t *testing.T) {
+	dh := NewDocumentHandler(NewTextChunker(256, 38))
+
+	if got := dh.DetectDocumentType("deck.pptx"); got != DocumentTypePPTX {
+		t.Errorf("Expected pptx, got %s", got)
+	}
+	if _, ok := dh.GetSupportedFormats()[DocumentTypePPTX]; !ok {
+		t.Error("Expected PPTX parser to be registered")
+	}
+}
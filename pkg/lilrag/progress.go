@@ -0,0 +1,69 @@
+package lilrag
+
+import "context"
+
+// Types of the events reported while a document is indexed
+const (
+	IndexEventParsed   = "parsed"   // The file was parsed
+	IndexEventChunked  = "chunked"  // The content was split into chunks
+	IndexEventEmbedded = "embedded" // Another chunk was embedded, see Embedded and Total
+	IndexEventStored   = "stored"   // The chunks and embeddings were stored
+	IndexEventError    = "error"    // Indexing failed, see Err
+)
+
+// IndexEvent reports the progress of indexing a document. The entries of an archive are
+// reported as documents of their own, after the archive is parsed. Streamed CSV and XLSX
+// files are embedded while they are read, so their number of chunks is unknown until the
+// end: the parsed event comes once the file yields its first chunk, the embedded events
+// report a Total of 0, and the chunked event follows once the whole file was read.
+type IndexEvent struct {
+	Type       string
+	DocumentID string
+	Chunks     int   // Number of chunks, including parents, once chunked
+	Embedded   int   // Number of chunks embedded so far
+	Total      int   // Number of chunks that are embedded; parents are not. 0 if unknown
+	Err        error // Why indexing failed, for IndexEventError
+}
+
+// IndexOptions configures IndexWithOptions, IndexPDFWithOptions and IndexFileWithOptions
+type IndexOptions struct {
+	// OnProgress receives the events of the operation in order, on the calling goroutine
+	OnProgress func(IndexEvent)
+}
+
+type indexProgressKey struct{}
+
+// withIndexProgress returns a context whose indexing operations report their events to fn.
+// Without fn ctx is returned, so nested operations report to an enclosing callback.
+func withIndexProgress(ctx context.Context, fn func(IndexEvent)) context.Context {
+	if fn == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, indexProgressKey{}, fn)
+}
+
+// reportIndexEvent passes an event to the progress callback of ctx, if any
+func reportIndexEvent(ctx context.Context, event IndexEvent) {
+	if fn, ok := ctx.Value(indexProgressKey{}).(func(IndexEvent)); ok {
+		fn(event)
+	}
+}
+
+// reportIndexError reports the error of a failed indexing operation and returns it
+func reportIndexError(ctx context.Context, id string, err error) error {
+	if err != nil {
+		reportIndexEvent(ctx, IndexEvent{Type: IndexEventError, DocumentID: id, Err: err})
+	}
+	return err
+}
+
+// embeddedChunkCount returns the number of chunks that are embedded; parents are not
+func embeddedChunkCount(chunks []Chunk) int {
+	count := 0
+	for _, chunk := range chunks {
+		if !chunk.IsParent {
+			count++
+		}
+	}
+	return count
+}
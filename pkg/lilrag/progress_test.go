@@ -0,0 +1,139 @@
+package lilrag
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// failingEmbedder fails every embedding
+type failingEmbedder struct{}
+
+func (failingEmbedder) Embed(context.Context, string) ([]float32, error) {
+	return nil, errors.New("embedding service unavailable")
+}
+
+func newProgressTestRag(t *testing.T, embedder Embedder) *LilRag {
+	t.Helper()
+	storage := NewMockStorage()
+	if err := storage.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize mock storage: %v", err)
+	}
+	return &LilRag{
+		storage:         storage,
+		embedder:        embedder,
+		chunker:         NewTextChunker(100, 20),
+		documentHandler: NewDocumentHandler(NewTextChunker(100, 20)),
+		config:          &Config{},
+	}
+}
+
+// recordEvents returns index options that append the reported events to events
+func recordEvents(events *[]IndexEvent) IndexOptions {
+	return IndexOptions{OnProgress: func(event IndexEvent) { *events = append(*events, event) }}
+}
+
+// eventTypes returns the types of events, collapsing repeated types
+func eventTypes(events []IndexEvent) string {
+	var types []string
+	for _, event := range events {
+		if len(types) == 0 || types[len(types)-1] != event.Type {
+			types = append(types, event.Type)
+		}
+	}
+	return strings.Join(types, ",")
+}
+
+func TestLilRag_IndexWithOptions_Progress(t *testing.T) {
+	lilRag := newProgressTestRag(t, NewMockEmbedder())
+	text := strings.Repeat("Rotate the API keys every ninety days and test the backup restore. ", 30)
+
+	var events []IndexEvent
+	if err := lilRag.IndexWithOptions(context.Background(), text, "keys", recordEvents(&events)); err != nil {
+		t.Fatalf("Failed to index: %v", err)
+	}
+	if types := eventTypes(events); types != "chunked,embedded,stored" {
+		t.Fatalf("Expected chunked, embedded and stored events, got %s", types)
+	}
+
+	chunked := events[0]
+	if chunked.DocumentID != "keys" || chunked.Total < 2 || chunked.Embedded != 0 {
+		t.Errorf("Unexpected chunked event %+v", chunked)
+	}
+	for i, event := range events[1 : len(events)-1] {
+		if event.Embedded != i+1 || event.Total != chunked.Total {
+			t.Errorf("Expected %d/%d chunks embedded, got %+v", i+1, chunked.Total, event)
+		}
+	}
+	if stored := events[len(events)-1]; stored.Embedded != chunked.Total || stored.Chunks != chunked.Chunks {
+		t.Errorf("Unexpected stored event %+v", stored)
+	}
+}
+
+func TestLilRag_IndexFileWithOptions_Progress(t *testing.T) {
+	root := writeTestTree(t, map[string]string{"guide.md": "# Guide\n\nRun make install on a new laptop."})
+
+	var events []IndexEvent
+	lilRag := newProgressTestRag(t, NewMockEmbedder())
+	err := lilRag.IndexFileWithOptions(context.Background(), filepath.Join(root, "guide.md"), "guide",
+		recordEvents(&events))
+	if err != nil {
+		t.Fatalf("Failed to index file: %v", err)
+	}
+	if types := eventTypes(events); types != "parsed,chunked,embedded,stored" {
+		t.Errorf("Expected parsed, chunked, embedded and stored events, got %s", types)
+	}
+
+	events = nil
+	lilRag = newProgressTestRag(t, failingEmbedder{})
+	err = lilRag.IndexFileWithOptions(context.Background(), filepath.Join(root, "guide.md"), "guide",
+		recordEvents(&events))
+	if err == nil {
+		t.Fatal("Expected indexing to fail")
+	}
+	if types := eventTypes(events); types != "parsed,chunked,error" {
+		t.Fatalf("Expected parsed, chunked and error events, got %s", types)
+	}
+	if failed := events[len(events)-1]; failed.DocumentID != "guide" || !errors.Is(failed.Err, err) {
+		t.Errorf("Expected the error event to carry the error, got %+v", failed)
+	}
+}
+
+func TestLilRag_IndexFileWithOptions_StreamedProgress(t *testing.T) {
+	storage := setupTableStorage(t)
+	lilRag := newProgressTestRag(t, NewMockEmbedder())
+	lilRag.storage = storage
+	filePath := writeTestFile(t, "spend.csv", testSpendCSV)
+
+	var events []IndexEvent
+	if err := lilRag.IndexFileWithOptions(context.Background(), filePath, "spend", recordEvents(&events)); err != nil {
+		t.Fatalf("Failed to index file: %v", err)
+	}
+	if types := eventTypes(events); types != "parsed,embedded,chunked,stored" {
+		t.Fatalf("Expected the parsed event before the embedded events, got %s", types)
+	}
+	if events[1].Total != 0 {
+		t.Errorf("Expected an unknown total while streaming, got %+v", events[1])
+	}
+	if chunked := events[len(events)-2]; chunked.Chunks != 2 || chunked.Embedded != 2 || chunked.Total != 2 {
+		t.Errorf("Unexpected chunked event %+v", chunked)
+	}
+
+	// Re-indexing replaces the chunks, and results show the matching chunk
+	if err := lilRag.IndexFile(context.Background(), filePath, "spend"); err != nil {
+		t.Fatalf("Failed to re-index file: %v", err)
+	}
+	chunks, err := storage.GetDocumentChunks(context.Background(), "spend")
+	if err != nil || len(chunks) != 2 {
+		t.Fatalf("Expected 2 chunks after re-indexing, got %d (%v)", len(chunks), err)
+	}
+	results, err := lilRag.Search(context.Background(), "Acme Q2", 5)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("Expected one result, got %d (%v)", len(results), err)
+	}
+	if results[0].Text != results[0].Metadata["matching_chunk"] {
+		t.Errorf("Expected the matching chunk as the result text, got %q", results[0].Text)
+	}
+}
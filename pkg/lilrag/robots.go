@@ -0,0 +1,135 @@
+package lilrag
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRobotsSize caps how much of a robots.txt file is read
+const maxRobotsSize = 512 << 10
+
+// robotsRules are the robots.txt rules that apply to the crawler's user agent
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+	disallowed bool // robots.txt could not be fetched, so nothing may be crawled
+}
+
+// robotsRule is an Allow or Disallow line of robots.txt
+type robotsRule struct {
+	allow   bool
+	length  int // Length of the pattern; the longest matching rule wins
+	pattern *regexp.Regexp
+}
+
+// parseRobots reads the groups of a robots.txt file that apply to userAgent. Groups
+// naming the agent take precedence over the "*" group.
+func parseRobots(r io.Reader, userAgent string) *robotsRules {
+	agent := strings.ToLower(userAgent)
+	if i := strings.IndexAny(agent, "/ "); i >= 0 {
+		agent = agent[:i] // Match on the product token, e.g. "lil-rag" of "lil-rag/1.0"
+	}
+
+	specific, wildcard := &robotsRules{}, &robotsRules{}
+	var matchesSpecific, matchesWildcard, inRules, hasSpecific bool
+
+	scanner := bufio.NewScanner(io.LimitReader(r, maxRobotsSize))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if inRules {
+				// A user-agent line after rules starts a new group
+				matchesSpecific, matchesWildcard, inRules = false, false, false
+			}
+			name := strings.ToLower(value)
+			if name == "*" {
+				matchesWildcard = true
+			} else if name != "" && strings.Contains(agent, name) {
+				matchesSpecific = true
+			}
+		case "allow", "disallow", "crawl-delay":
+			inRules = true
+			if matchesSpecific {
+				specific.add(key, value)
+				hasSpecific = true
+			}
+			if matchesWildcard {
+				wildcard.add(key, value)
+			}
+		}
+	}
+
+	if hasSpecific {
+		return specific
+	}
+	return wildcard
+}
+
+// add records an Allow, Disallow or Crawl-delay line
+func (r *robotsRules) add(key, value string) {
+	if key == "crawl-delay" {
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+			r.crawlDelay = time.Duration(seconds * float64(time.Second))
+		}
+		return
+	}
+	if value == "" {
+		return // An empty Disallow allows everything
+	}
+
+	// "*" matches any characters and a trailing "$" anchors the end of the path
+	anchored := strings.HasSuffix(value, "$")
+	parts := strings.Split(strings.TrimSuffix(value, "$"), "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return
+	}
+	r.rules = append(r.rules, robotsRule{allow: key == "allow", length: len(value), pattern: pattern})
+}
+
+// Allowed reports whether a path, including its query, may be crawled. The longest
+// matching rule decides, and Allow wins a tie.
+func (r *robotsRules) Allowed(path string) bool {
+	if r == nil {
+		return true
+	}
+	if r.disallowed {
+		return false
+	}
+	if path == "/robots.txt" {
+		return true
+	}
+
+	allowed, longest := true, -1
+	for _, rule := range r.rules {
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+		if rule.length > longest || (rule.length == longest && rule.allow) {
+			allowed, longest = rule.allow, rule.length
+		}
+	}
+	return allowed
+}
@@ -0,0 +1,182 @@
+package lilrag
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Chunking strategies selectable in ChunkConfig
+const (
+	ChunkStrategyAdaptive = "adaptive"
+	ChunkStrategySemantic = "semantic"
+)
+
+// DefaultSemanticPercentile places chunk boundaries at the lowest 10% of adjacent-sentence similarities
+const DefaultSemanticPercentile = 10.0
+
+// ChunkTextSemantic splits text at topic shifts. Sentences are embedded, and a boundary is
+// placed wherever the similarity between adjacent sentences falls below the configured
+// percentile of all adjacent similarities, as long as the chunk has reached MinTokens.
+// Chunks never grow beyond MaxTokens.
+func (tc *TextChunker) ChunkTextSemantic(ctx context.Context, text string) ([]Chunk, error) {
+	if tc.Embedder == nil {
+		return nil, fmt.Errorf("semantic chunking requires an embedder")
+	}
+
+	source := text
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+
+	contentType := tc.detectContentType(text)
+	sentences := tc.semanticUnits(text)
+
+	// Too few sentences to find a topic shift
+	if len(sentences) < 3 {
+		return tc.adaptiveChunk(text, contentType), nil
+	}
+
+	embeddings := make([][]float32, len(sentences))
+	for i, sentence := range sentences {
+		embedding, err := tc.Embedder.Embed(ctx, sentence)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed sentence %d: %w", i, err)
+		}
+		embeddings[i] = embedding
+	}
+
+	similarities := make([]float64, len(sentences)-1)
+	for i := range similarities {
+		similarities[i] = cosineSimilarity(embeddings[i], embeddings[i+1])
+	}
+
+	percentile := tc.SemanticPercentile
+	if percentile <= 0 {
+		percentile = DefaultSemanticPercentile
+	}
+	threshold := percentileOf(similarities, percentile)
+
+	minTokens := tc.MinTokens
+	if minTokens <= 0 {
+		minTokens = tc.MaxTokens / 4
+	}
+
+	separator := tc.getSeparator(contentType)
+	var chunks []Chunk
+	var current []string
+	currentTokens := 0
+
+	// Sentences are trimmed substrings of the source, so they are located in order to
+	// give each chunk its offsets into the source text
+	cursor, startPos, endPos := 0, 0, 0
+	locate := func(sentence string) (int, int) {
+		if idx := strings.Index(source[cursor:], sentence); idx != -1 {
+			cursor += idx
+		}
+		start := cursor
+		cursor += len(sentence)
+		return start, cursor
+	}
+
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		chunkText := strings.Join(current, separator)
+		chunks = append(chunks, Chunk{
+			Text:       chunkText,
+			Index:      len(chunks),
+			StartPos:   startPos,
+			EndPos:     endPos,
+			TokenCount: tc.EstimateTokenCount(chunkText),
+			ChunkType:  contentType,
+		})
+		current = nil
+		currentTokens = 0
+	}
+
+	for i, sentence := range sentences {
+		sentenceTokens := tc.EstimateTokenCount(sentence)
+
+		if len(current) > 0 {
+			topicShift := similarities[i-1] < threshold && currentTokens >= minTokens
+			if topicShift || currentTokens+sentenceTokens > tc.MaxTokens {
+				flush()
+			}
+		}
+
+		start, end := locate(sentence)
+		if len(current) == 0 {
+			startPos = start
+		}
+		endPos = end
+
+		current = append(current, sentence)
+		currentTokens += sentenceTokens
+	}
+	flush()
+
+	// Single sentences that exceed MaxTokens are split further
+	return tc.handleOversizedChunks(chunks), nil
+}
+
+// semanticUnits splits text into sentence-sized units for embedding
+func (tc *TextChunker) semanticUnits(text string) []string {
+	var units []string
+	for _, part := range tc.splitIntoSentences(text) {
+		// splitIntoSentences prefers paragraphs, so break long ones into sentences
+		if tc.EstimateTokenCount(part) > tc.MaxTokens {
+			units = append(units, tc.splitBySentences(part)...)
+			continue
+		}
+		units = append(units, part)
+	}
+	return units
+}
+
+// cosineSimilarity returns the cosine similarity of two vectors, or 0 if either is empty
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// percentileOf returns the p-th percentile (0-100) of values using linear interpolation
+func percentileOf(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	if p <= 0 {
+		return sorted[0]
+	}
+	if p >= 100 {
+		return sorted[len(sorted)-1]
+	}
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	weight := rank - float64(lower)
+
+	return sorted[lower]*(1-weight) + sorted[upper]*weight
+}
@@ -0,0 +1,234 @@
+package lilrag
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// topicEmbedder is a deterministic fake embedder that maps each sentence to a unit
+// vector for the first topic keyword it contains
+type topicEmbedder struct {
+	topics   []string
+	calls    int
+	canceled int // calls made with a canceled context
+	err      error
+}
+
+func (e *topicEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	e.calls++
+	if err := ctx.Err(); err != nil {
+		e.canceled++
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	embedding := make([]float32, len(e.topics)+1)
+	text = strings.ToLower(text)
+	for i, topic := range e.topics {
+		if strings.Contains(text, topic) {
+			embedding[i] = 1
+			return embedding, nil
+		}
+	}
+	embedding[len(e.topics)] = 1
+	return embedding, nil
+}
+
+func semanticTestText() string {
+	return "Cats sleep most of the day. Cats groom their fur often. Cats purr when content. " +
+		"Stocks fell sharply on Monday. Stocks recovered by the close. Stocks remain volatile. " +
+		"Rockets launch from the coast. Rockets carry satellites to orbit. Rockets are reusable now."
+}
+
+func TestTextChunker_ChunkTextSemantic_SplitsOnTopicShifts(t *testing.T) {
+	embedder := &topicEmbedder{topics: []string{"cats", "stocks", "rockets"}}
+	chunker := NewTextChunker(100, 0)
+	chunker.Strategy = ChunkStrategySemantic
+	chunker.Embedder = embedder
+	chunker.SemanticPercentile = 25
+	chunker.MinTokens = 5
+
+	chunks, err := chunker.ChunkTextSemantic(context.Background(), semanticTestText())
+	if err != nil {
+		t.Fatalf("ChunkTextSemantic failed: %v", err)
+	}
+
+	if len(chunks) != 3 {
+		t.Fatalf("Expected 3 chunks, got %d: %v", len(chunks), chunks)
+	}
+
+	for i, topic := range []string{"Cats", "Stocks", "Rockets"} {
+		if strings.Count(chunks[i].Text, topic) != 3 {
+			t.Errorf("Expected chunk %d to contain all %s sentences, got %q", i, topic, chunks[i].Text)
+		}
+		if chunks[i].Index != i {
+			t.Errorf("Expected chunk %d to have index %d, got %d", i, i, chunks[i].Index)
+		}
+	}
+
+	if embedder.calls != 9 {
+		t.Errorf("Expected one embedding per sentence (9), got %d", embedder.calls)
+	}
+}
+
+func TestTextChunker_ChunkTextSemantic_Offsets(t *testing.T) {
+	chunker := NewTextChunker(100, 0)
+	chunker.Embedder = &topicEmbedder{topics: []string{"cats", "stocks", "rockets"}}
+	chunker.SemanticPercentile = 25
+	chunker.MinTokens = 5
+
+	source := "\n  " + semanticTestText()
+	chunks, err := chunker.ChunkTextSemantic(context.Background(), source)
+	if err != nil {
+		t.Fatalf("ChunkTextSemantic failed: %v", err)
+	}
+	if len(chunks) != 3 {
+		t.Fatalf("Expected 3 chunks, got %d", len(chunks))
+	}
+
+	expected := []struct{ first, last string }{
+		{"Cats sleep", "when content"},
+		{"Stocks fell", "remain volatile"},
+		{"Rockets launch", "reusable now."},
+	}
+	for i, chunk := range chunks {
+		if chunk.StartPos < 0 || chunk.EndPos > len(source) || chunk.StartPos >= chunk.EndPos {
+			t.Fatalf("Chunk %d has invalid offsets %d-%d", i, chunk.StartPos, chunk.EndPos)
+		}
+		span := source[chunk.StartPos:chunk.EndPos]
+		if !strings.HasPrefix(span, expected[i].first) || !strings.HasSuffix(span, expected[i].last) {
+			t.Errorf("Chunk %d offsets point at %q", i, span)
+		}
+		if i > 0 && chunk.StartPos < chunks[i-1].EndPos {
+			t.Errorf("Chunk %d starts at %d, before chunk %d ends at %d", i, chunk.StartPos, i-1, chunks[i-1].EndPos)
+		}
+	}
+}
+
+func TestTextChunker_ChunkTextSemantic_RespectsTokenBounds(t *testing.T) {
+	embedder := &topicEmbedder{topics: []string{"cats", "stocks", "rockets"}}
+
+	// A small MaxTokens forces splits inside a topic
+	chunker := NewTextChunker(12, 0)
+	chunker.Embedder = embedder
+	chunker.SemanticPercentile = 25
+	chunker.MinTokens = 1
+
+	chunks, err := chunker.ChunkTextSemantic(context.Background(), semanticTestText())
+	if err != nil {
+		t.Fatalf("ChunkTextSemantic failed: %v", err)
+	}
+	if len(chunks) <= 3 {
+		t.Errorf("Expected more than 3 chunks with a small MaxTokens, got %d", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if chunk.TokenCount > chunker.MaxTokens {
+			t.Errorf("Chunk %d has %d tokens, exceeds max %d", i, chunk.TokenCount, chunker.MaxTokens)
+		}
+	}
+
+	// A large MinTokens suppresses topic-shift boundaries
+	chunker = NewTextChunker(100, 0)
+	chunker.Embedder = embedder
+	chunker.SemanticPercentile = 25
+	chunker.MinTokens = 100
+
+	chunks, err = chunker.ChunkTextSemantic(context.Background(), semanticTestText())
+	if err != nil {
+		t.Fatalf("ChunkTextSemantic failed: %v", err)
+	}
+	if len(chunks) != 1 {
+		t.Errorf("Expected topic shifts below MinTokens to be ignored, got %d chunks", len(chunks))
+	}
+}
+
+func TestTextChunker_ChunkText_SemanticStrategy(t *testing.T) {
+	chunker := NewTextChunker(20, 0)
+	chunker.Strategy = ChunkStrategySemantic
+	chunker.SemanticPercentile = 25
+	chunker.MinTokens = 5
+
+	// Without an embedder the adaptive strategy is used
+	text := strings.Repeat(semanticTestText()+" ", 5)
+	adaptive := NewTextChunker(20, 0).ChunkText(text)
+	if got := chunker.ChunkText(text); len(got) != len(adaptive) {
+		t.Errorf("Expected adaptive fallback without embedder: %d vs %d chunks", len(got), len(adaptive))
+	}
+
+	// Embedding errors also fall back to adaptive chunking
+	chunker.Embedder = &topicEmbedder{err: fmt.Errorf("embedding service unavailable")}
+	if got := chunker.ChunkText(text); len(got) != len(adaptive) {
+		t.Errorf("Expected adaptive fallback on embedding error: %d vs %d chunks", len(got), len(adaptive))
+	}
+
+	if _, err := NewTextChunker(20, 0).ChunkTextSemantic(context.Background(), text); err == nil {
+		t.Error("Expected error from ChunkTextSemantic without an embedder")
+	}
+}
+
+func TestTextChunker_ChunkTextContext_PassesContext(t *testing.T) {
+	root := writeTestTree(t, map[string]string{"notes.txt": strings.Repeat(semanticTestText()+" ", 5)})
+	chunker := NewTextChunker(20, 0)
+	chunker.Strategy = ChunkStrategySemantic
+	chunker.SemanticPercentile = 25
+	chunker.MinTokens = 5
+	embedder := &topicEmbedder{topics: []string{"cats", "stocks", "rockets"}}
+	chunker.Embedder = embedder
+	handler := NewDocumentHandler(chunker)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	chunks, err := handler.ParseFileWithChunksContext(ctx, filepath.Join(root, "notes.txt"), "notes")
+	if err != nil {
+		t.Fatalf("Failed to parse file: %v", err)
+	}
+
+	// The canceled context reaches the embedder, so chunking falls back to adaptive
+	if embedder.canceled == 0 {
+		t.Error("Expected the embedder to receive the canceled context")
+	}
+	adaptive := NewTextChunker(20, 0).ChunkText(strings.Repeat(semanticTestText()+" ", 5))
+	if len(chunks) != len(adaptive) {
+		t.Errorf("Expected adaptive fallback with a canceled context: %d vs %d chunks", len(chunks), len(adaptive))
+	}
+}
+
+func TestPercentileOf(t *testing.T) {
+	values := []float64{0.9, 0.1, 0.5, 0.3}
+
+	tests := []struct {
+		p        float64
+		expected float64
+	}{
+		{0, 0.1},
+		{100, 0.9},
+		{50, 0.4},
+	}
+
+	for _, tt := range tests {
+		if got := percentileOf(values, tt.p); got < tt.expected-1e-9 || got > tt.expected+1e-9 {
+			t.Errorf("percentileOf(%v) = %f, expected %f", tt.p, got, tt.expected)
+		}
+	}
+
+	if got := percentileOf(nil, 50); got != 0 {
+		t.Errorf("Expected 0 for empty values, got %f", got)
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	if got := cosineSimilarity([]float32{1, 0}, []float32{1, 0}); got < 0.999 {
+		t.Errorf("Expected identical vectors to have similarity 1, got %f", got)
+	}
+	if got := cosineSimilarity([]float32{1, 0}, []float32{0, 1}); got != 0 {
+		t.Errorf("Expected orthogonal vectors to have similarity 0, got %f", got)
+	}
+	if got := cosineSimilarity([]float32{1}, []float32{1, 0}); got != 0 {
+		t.Errorf("Expected mismatched vectors to have similarity 0, got %f", got)
+	}
+}
@@ -1,15 +1,19 @@
 package lilrag
 
 import (
+	"compress/gzip"
 	"context"
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"hash"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -95,7 +99,16 @@ func (s *SQLiteStorage) createTables() error {
 			end_pos INTEGER,
 			token_count INTEGER,
 			page_number INTEGER,
+			end_page_number INTEGER,
 			chunk_type TEXT DEFAULT 'text',
+			parent_chunk_id TEXT,
+			heading_path TEXT,
+			language TEXT,
+			symbol_name TEXT,
+			symbol_kind TEXT,
+			start_line INTEGER,
+			end_line INTEGER,
+			metadata TEXT,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (document_id) REFERENCES documents(id) ON DELETE CASCADE
 		);
@@ -106,26 +119,220 @@ func (s *SQLiteStorage) createTables() error {
 			embedding FLOAT[%d]
 		);
 
+		-- Sheets of tabular documents loaded as SQL tables
+		CREATE TABLE IF NOT EXISTS document_tables (
+			table_name TEXT PRIMARY KEY,
+			document_id TEXT NOT NULL,
+			sheet_name TEXT NOT NULL,
+			columns TEXT NOT NULL,
+			row_count INTEGER NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		-- Background ingestion jobs
+		CREATE TABLE IF NOT EXISTS jobs (
+			id TEXT PRIMARY KEY,
+			document_id TEXT NOT NULL,
+			file_name TEXT NOT NULL,
+			file_path TEXT NOT NULL,
+			source_path TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL,
+			stage TEXT NOT NULL DEFAULT '',
+			chunks_embedded INTEGER NOT NULL DEFAULT 0,
+			chunks_total INTEGER NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		);
+
 		-- Indexes
 		CREATE INDEX IF NOT EXISTS idx_documents_content_hash ON documents(content_hash);
 		CREATE INDEX IF NOT EXISTS idx_documents_created_at ON documents(created_at);
 		CREATE INDEX IF NOT EXISTS idx_chunks_document_id ON chunks(document_id);
 		CREATE INDEX IF NOT EXISTS idx_chunks_document_chunk ON chunks(document_id, chunk_index);
+		CREATE INDEX IF NOT EXISTS idx_document_tables_document ON document_tables(document_id);
+		CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs(status, created_at);
 	`, s.vectorSize)
 
-	_, err := s.db.Exec(schema)
+	if _, err := s.db.Exec(schema); err != nil {
+		return err
+	}
+
+	return s.migrateSchema()
+}
+
+// migrateSchema adds columns introduced after the initial schema to existing databases
+func (s *SQLiteStorage) migrateSchema() error {
+	if err := s.ensureColumn("chunks", "parent_chunk_id", "TEXT"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("chunks", "heading_path", "TEXT"); err != nil {
+		return err
+	}
+	for _, column := range []struct{ name, definition string }{
+		{"end_page_number", "INTEGER"},
+		{"language", "TEXT"},
+		{"symbol_name", "TEXT"},
+		{"symbol_kind", "TEXT"},
+		{"start_line", "INTEGER"},
+		{"end_line", "INTEGER"},
+		{"metadata", "TEXT"},
+	} {
+		if err := s.ensureColumn("chunks", column.name, column.definition); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_chunks_parent ON chunks(parent_chunk_id)`); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_chunks_language ON chunks(language)`)
 	return err
 }
 
+// chunkDetails scans the nullable source code symbol and metadata columns of a chunk row
+type chunkDetails struct {
+	language   sql.NullString
+	symbolName sql.NullString
+	symbolKind sql.NullString
+	startLine  sql.NullInt64
+	endLine    sql.NullInt64
+	metadata   sql.NullString
+}
+
+// dest returns scan destinations in column order:
+// language, symbol_name, symbol_kind, start_line, end_line, metadata
+func (c *chunkDetails) dest() []interface{} {
+	return []interface{}{&c.language, &c.symbolName, &c.symbolKind, &c.startLine, &c.endLine, &c.metadata}
+}
+
+// chunkMetadata decodes the JSON metadata column
+func (c *chunkDetails) chunkMetadata() map[string]string {
+	return decodeMetadata(c.metadata)
+}
+
+// decodeMetadata decodes a JSON metadata column; malformed values are ignored
+func decodeMetadata(column sql.NullString) map[string]string {
+	if !column.Valid || column.String == "" {
+		return nil
+	}
+	var metadata map[string]string
+	if err := json.Unmarshal([]byte(column.String), &metadata); err != nil {
+		return nil
+	}
+	return metadata
+}
+
+// encodeMetadata encodes metadata for a JSON metadata column; empty maps are stored as NULL
+func encodeMetadata(metadata map[string]string) (interface{}, error) {
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (c *chunkDetails) applyToChunk(chunk *Chunk) {
+	chunk.Language = c.language.String
+	chunk.SymbolName = c.symbolName.String
+	chunk.SymbolKind = c.symbolKind.String
+	chunk.StartLine = int(c.startLine.Int64)
+	chunk.EndLine = int(c.endLine.Int64)
+	chunk.Metadata = c.chunkMetadata()
+}
+
+func (c *chunkDetails) applyToInfo(chunk *ChunkInfo) {
+	chunk.Language = c.language.String
+	chunk.SymbolName = c.symbolName.String
+	chunk.SymbolKind = c.symbolKind.String
+	chunk.StartLine = int(c.startLine.Int64)
+	chunk.EndLine = int(c.endLine.Int64)
+	chunk.Metadata = c.chunkMetadata()
+}
+
+// addToMetadata adds the symbol information of code chunks and the format-specific
+// chunk metadata to search result metadata. Built-in keys take precedence.
+func (c *chunkDetails) addToMetadata(metadata map[string]interface{}) {
+	if c.language.Valid && c.language.String != "" {
+		metadata["language"] = c.language.String
+	}
+	if c.symbolName.Valid && c.symbolName.String != "" {
+		metadata["symbol_name"] = c.symbolName.String
+		metadata["symbol_kind"] = c.symbolKind.String
+	}
+	if c.startLine.Valid && c.startLine.Int64 > 0 {
+		metadata["start_line"] = int(c.startLine.Int64)
+		metadata["end_line"] = int(c.endLine.Int64)
+	}
+	for key, value := range c.chunkMetadata() {
+		if _, exists := metadata[key]; !exists {
+			metadata[key] = value
+		}
+	}
+}
+
+// sortedKeys returns the keys of a metadata map in a stable order
+func sortedKeys(metadata map[string]string) []string {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// escapeLike escapes LIKE wildcards so filter values match literally
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+// ensureColumn adds a column to a table if it does not exist yet
+func (s *SQLiteStorage) ensureColumn(table, column, definition string) error {
+	rows, err := s.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			columnType string
+			notNull    int
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultVal, &primaryKey); err != nil {
+			return fmt.Errorf("failed to scan table info for %s: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read table info for %s: %w", table, err)
+	}
+
+	if _, err := s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
 // IndexChunks indexes a document with its chunks and embeddings
 func (s *SQLiteStorage) IndexChunks(ctx context.Context, documentID, text string,
 	chunks []Chunk, embeddings [][]float32) error {
-	return s.IndexChunksWithMetadata(ctx, documentID, text, chunks, embeddings, "", "")
+	return s.IndexChunksWithMetadata(ctx, documentID, text, chunks, embeddings, "", "", nil)
 }
 
-// IndexChunksWithMetadata indexes a document with metadata including original file path
+// IndexChunksWithMetadata indexes a document with metadata including original file path and
+// document-level metadata such as email headers
 func (s *SQLiteStorage) IndexChunksWithMetadata(ctx context.Context, documentID, text string,
-	chunks []Chunk, embeddings [][]float32, originalFilePath, docType string) error {
+	chunks []Chunk, embeddings [][]float32, originalFilePath, docType string, metadata map[string]string) error {
 	if s.db == nil {
 		return fmt.Errorf("storage not initialized - call Initialize() first")
 	}
@@ -151,7 +358,7 @@ func (s *SQLiteStorage) IndexChunksWithMetadata(ctx context.Context, documentID,
 		if !committed {
 			if rbErr := tx.Rollback(); rbErr != nil {
 				// Log rollback error if needed, but don't override the main error
-				fmt.Printf("Warning: failed to rollback transaction: %v\n", rbErr)
+				log.Printf("Warning: failed to rollback transaction: %v", rbErr)
 			}
 		}
 	}()
@@ -162,81 +369,354 @@ func (s *SQLiteStorage) IndexChunksWithMetadata(ctx context.Context, documentID,
 		return fmt.Errorf("failed to compress document text: %w", err)
 	}
 
+	documentMetadata, err := encodeMetadata(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal document metadata: %w", err)
+	}
+
 	// Insert or update document
 	_, err = tx.ExecContext(ctx, `
 		INSERT OR REPLACE INTO documents (
-			id, original_text_compressed, content_hash, file_path, source_path, doc_type, chunk_count, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
-	`, documentID, compressedText, contentHash, filePath, originalFilePath, docType, len(chunks), time.Now().UTC())
+			id, original_text_compressed, content_hash, file_path, source_path, doc_type, metadata, chunk_count,
+			updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, documentID, compressedText, contentHash, filePath, originalFilePath, docType, documentMetadata, len(chunks),
+		time.Now().UTC())
 	if err != nil {
 		return fmt.Errorf("failed to insert document: %w", err)
 	}
 
 	// Delete existing chunks and embeddings for this document
-	_, err = tx.ExecContext(ctx, `DELETE FROM chunks WHERE document_id = ?`, documentID)
-	if err != nil {
-		return fmt.Errorf("failed to delete old chunks: %w", err)
+	if err := deleteDocumentChunks(ctx, tx, documentID); err != nil {
+		return err
+	}
+
+	// Insert new chunks and embeddings
+	for i, chunk := range chunks {
+		if err := insertChunk(ctx, tx, documentID, i, chunk, embeddings[i]); err != nil {
+			return err
+		}
 	}
 
-	_, err = tx.ExecContext(ctx, `DELETE FROM embeddings WHERE chunk_id LIKE ?`, documentID+"%")
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	committed = true
+	return nil
+}
+
+// deleteDocumentChunks deletes a document's chunks and their embeddings. Embeddings are
+// matched through the chunks table rather than by chunk ID prefix, because one document ID
+// can be a prefix of another (docs-a and docs-a-md).
+func deleteDocumentChunks(ctx context.Context, tx *sql.Tx, documentID string) error {
+	// Delete embeddings first, while the chunk rows still identify them
+	_, err := tx.ExecContext(ctx,
+		`DELETE FROM embeddings WHERE chunk_id IN (SELECT chunk_id FROM chunks WHERE document_id = ?)`, documentID)
 	if err != nil {
 		return fmt.Errorf("failed to delete old embeddings: %w", err)
 	}
 
-	// Insert new chunks and embeddings
-	for i, chunk := range chunks {
-		chunkID := GetChunkID(documentID, chunk.Index)
+	if _, err := tx.ExecContext(ctx, `DELETE FROM chunks WHERE document_id = ?`, documentID); err != nil {
+		return fmt.Errorf("failed to delete old chunks: %w", err)
+	}
+	return nil
+}
 
-		// Insert chunk with page metadata
-		pageNumber := sql.NullInt32{}
-		if chunk.PageNumber != nil {
-			if *chunk.PageNumber > 2147483647 { // Max int32 value
-				return fmt.Errorf("page number %d exceeds maximum allowed value", *chunk.PageNumber)
-			}
-			// #nosec G115 - Page number range already validated above
-			pageNumber.Int32 = int32(*chunk.PageNumber)
-			pageNumber.Valid = true
+// insertChunk inserts chunk i of a document with its embedding, if it has one
+func insertChunk(ctx context.Context, tx *sql.Tx, documentID string, i int, chunk Chunk, embedding []float32) error {
+	chunkID := GetChunkID(documentID, chunk.Index)
+
+	// Insert chunk with page metadata
+	pageNumber := sql.NullInt32{}
+	if chunk.PageNumber != nil {
+		if *chunk.PageNumber > 2147483647 { // Max int32 value
+			return fmt.Errorf("page number %d exceeds maximum allowed value", *chunk.PageNumber)
 		}
+		// #nosec G115 - Page number range already validated above
+		pageNumber.Int32 = int32(*chunk.PageNumber)
+		pageNumber.Valid = true
+	}
+	endPageNumber := sql.NullInt64{}
+	if chunk.EndPageNumber != nil {
+		endPageNumber = sql.NullInt64{Int64: int64(*chunk.EndPageNumber), Valid: true}
+	}
+
+	chunkType := chunk.ChunkType
+	if chunkType == "" {
+		chunkType = "text"
+	}
+
+	parentChunkID := sql.NullString{}
+	if chunk.ParentIndex != nil {
+		parentChunkID.String = GetChunkID(documentID, *chunk.ParentIndex)
+		parentChunkID.Valid = true
+	}
+
+	headingPath := sql.NullString{String: chunk.HeadingPath, Valid: chunk.HeadingPath != ""}
+	language := sql.NullString{String: chunk.Language, Valid: chunk.Language != ""}
+	symbolName := sql.NullString{String: chunk.SymbolName, Valid: chunk.SymbolName != ""}
+	symbolKind := sql.NullString{String: chunk.SymbolKind, Valid: chunk.SymbolKind != ""}
+	startLine := sql.NullInt64{Int64: int64(chunk.StartLine), Valid: chunk.StartLine > 0}
+	endLine := sql.NullInt64{Int64: int64(chunk.EndLine), Valid: chunk.EndLine > 0}
+	metadata, err := encodeMetadata(chunk.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal chunk %d metadata: %w", i, err)
+	}
+
+	// Compress chunk text for storage
+	compressedChunkText, err := CompressText(chunk.Text)
+	if err != nil {
+		return fmt.Errorf("failed to compress chunk %d text: %w", i, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO chunks (chunk_id, document_id, chunk_index, chunk_text_compressed, 
+		                   start_pos, end_pos, token_count, page_number, end_page_number, chunk_type,
+		                   parent_chunk_id, heading_path, language, symbol_name, symbol_kind, start_line, end_line,
+		                   metadata)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, chunkID, documentID, chunk.Index, compressedChunkText, chunk.StartPos, chunk.EndPos,
+		chunk.TokenCount, pageNumber, endPageNumber, chunkType, parentChunkID, headingPath,
+		language, symbolName, symbolKind, startLine, endLine, metadata)
+	if err != nil {
+		return fmt.Errorf("failed to insert chunk %d: %w", i, err)
+	}
+
+	// Parent chunks are only stored for context and have no embedding
+	if embedding == nil {
+		return nil
+	}
+
+	// Insert embedding
+	embeddingJSON, err := json.Marshal(embedding)
+	if err != nil {
+		return fmt.Errorf("failed to marshal embedding for chunk %d: %w", i, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO embeddings (chunk_id, embedding) VALUES (?, ?)
+	`, chunkID, string(embeddingJSON))
+	if err != nil {
+		return fmt.Errorf("failed to insert embedding for chunk %d: %w", i, err)
+	}
+	return nil
+}
+
+// ChunkWriter receives the chunks of a document in batches while they are embedded.
+// Parent chunks have a nil embedding.
+type ChunkWriter interface {
+	WriteChunks(chunks []Chunk, embeddings [][]float32) error
+}
+
+// ChunkStore is implemented by storage backends that can store the chunks of a document
+// in batches, so very large documents are never held in memory whole
+type ChunkStore interface {
+	// StoreChunks replaces a document with the chunks written by write. The document
+	// text is only kept in its content file.
+	StoreChunks(ctx context.Context, documentID, originalFilePath, docType string, metadata map[string]string,
+		write func(ChunkWriter) error) error
+}
+
+// StoreChunks replaces a document with the chunks written by write. Each batch is
+// committed on its own under a staging document ID, so no write lock is held while the
+// caller embeds the next batch, and the staged chunks are swapped in for the old ones in
+// one short transaction at the end. Until then searches keep finding the old version.
+// The text of the top-level chunks is compressed into the content file as it arrives;
+// the document row keeps no copy, so search results show the matching chunk instead.
+func (s *SQLiteStorage) StoreChunks(ctx context.Context, documentID, originalFilePath, docType string,
+	metadata map[string]string, write func(ChunkWriter) error) error {
+	if s.db == nil {
+		return fmt.Errorf("storage not initialized - call Initialize() first")
+	}
+
+	documentMetadata, err := encodeMetadata(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal document metadata: %w", err)
+	}
 
-		chunkType := chunk.ChunkType
-		if chunkType == "" {
-			chunkType = "text"
+	content, err := os.CreateTemp(s.dataDir, "content_*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create content file: %w", err)
+	}
+	defer os.Remove(content.Name()) // Renamed once the document is stored
+
+	// Staged chunks have no document row, so searches and listings skip them. The temp
+	// file name keeps concurrent stores of the same document apart.
+	stagingID := fmt.Sprintf("%s#%s", documentID, strings.TrimSuffix(filepath.Base(content.Name()), ".tmp"))
+	swapped := false
+	defer func() {
+		if !swapped {
+			s.deleteStagedChunks(stagingID)
 		}
+	}()
 
-		// Compress chunk text for storage
-		compressedChunkText, err := CompressText(chunk.Text)
-		if err != nil {
-			return fmt.Errorf("failed to compress chunk %d text: %w", i, err)
+	w := &sqliteChunkWriter{ctx: ctx, db: s.db, documentID: stagingID, hash: sha256.New()}
+	w.text = gzip.NewWriter(content)
+	err = write(w)
+	if closeErr := w.text.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to compress content: %w", closeErr)
+	}
+	if closeErr := content.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write content file: %w", closeErr)
+	}
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("Warning: failed to rollback transaction: %v", rbErr)
+			}
 		}
+	}()
 
-		_, err = tx.ExecContext(ctx, `
-			INSERT INTO chunks (chunk_id, document_id, chunk_index, chunk_text_compressed, 
-			                   start_pos, end_pos, token_count, page_number, chunk_type) 
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-		`, chunkID, documentID, chunk.Index, compressedChunkText, chunk.StartPos, chunk.EndPos,
-			chunk.TokenCount, pageNumber, chunkType)
-		if err != nil {
-			return fmt.Errorf("failed to insert chunk %d: %w", i, err)
+	contentHash := hex.EncodeToString(w.hash.Sum(nil))
+	filePath := filepath.Join(s.dataDir, fmt.Sprintf("%s_%s.txt.gz", documentID, contentHash[:8]))
+	_, err = tx.ExecContext(ctx, `
+		INSERT OR REPLACE INTO documents (
+			id, original_text_compressed, content_hash, file_path, source_path, doc_type, metadata, chunk_count,
+			updated_at
+		) VALUES (?, NULL, ?, ?, ?, ?, ?, ?, ?)
+	`, documentID, contentHash, filePath, originalFilePath, docType, documentMetadata, w.chunks, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to insert document: %w", err)
+	}
+	if err := deleteDocumentChunks(ctx, tx, documentID); err != nil {
+		return err
+	}
+	if err := swapStagedChunks(ctx, tx, stagingID, documentID); err != nil {
+		return err
+	}
+	if err := os.Rename(content.Name(), filePath); err != nil {
+		return fmt.Errorf("failed to write compressed content file: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		os.Remove(filePath)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	committed = true
+	swapped = true
+	return nil
+}
+
+// swapStagedChunks moves the chunks and embeddings staged under stagingID to documentID.
+// Chunk IDs start with their document ID, so only that prefix is rewritten.
+func swapStagedChunks(ctx context.Context, tx *sql.Tx, stagingID, documentID string) error {
+	suffix := len(stagingID) + 1
+
+	// Embeddings are copied rather than updated, as the vector table's key cannot change
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO embeddings (chunk_id, embedding)
+		SELECT ? || substr(chunk_id, ?), embedding FROM embeddings
+		WHERE chunk_id IN (SELECT chunk_id FROM chunks WHERE document_id = ?)
+	`, documentID, suffix, stagingID)
+	if err != nil {
+		return fmt.Errorf("failed to move staged embeddings: %w", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM embeddings WHERE chunk_id IN (SELECT chunk_id FROM chunks WHERE document_id = ?)`, stagingID)
+	if err != nil {
+		return fmt.Errorf("failed to move staged embeddings: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE chunks SET
+			chunk_id = ? || substr(chunk_id, ?),
+			document_id = ?,
+			parent_chunk_id = CASE WHEN parent_chunk_id IS NULL THEN NULL ELSE ? || substr(parent_chunk_id, ?) END
+		WHERE document_id = ?
+	`, documentID, suffix, documentID, documentID, suffix, stagingID)
+	if err != nil {
+		return fmt.Errorf("failed to move staged chunks: %w", err)
+	}
+	return nil
+}
+
+// deleteStagedChunks removes the chunks of a store that failed. It runs on its own
+// context, since the store's context may be what was canceled.
+func (s *SQLiteStorage) deleteStagedChunks(stagingID string) {
+	ctx := context.Background()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("Warning: failed to delete staged chunks of %s: %v", stagingID, err)
+		return
+	}
+	if err := deleteDocumentChunks(ctx, tx, stagingID); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			log.Printf("Warning: failed to rollback transaction: %v", rbErr)
 		}
+		log.Printf("Warning: failed to delete staged chunks of %s: %v", stagingID, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Warning: failed to delete staged chunks of %s: %v", stagingID, err)
+	}
+}
 
-		// Insert embedding
-		embeddingJSON, err := json.Marshal(embeddings[i])
-		if err != nil {
-			return fmt.Errorf("failed to marshal embedding for chunk %d: %w", i, err)
+// sqliteChunkWriter commits each batch of chunks in its own short transaction and
+// compresses the document text into the content file as it goes
+type sqliteChunkWriter struct {
+	ctx        context.Context
+	db         *sql.DB
+	documentID string
+	text       *gzip.Writer
+	hash       hash.Hash
+	written    bool // Whether any text was written, to separate the chunks
+	chunks     int
+}
+
+func (w *sqliteChunkWriter) WriteChunks(chunks []Chunk, embeddings [][]float32) error {
+	if len(chunks) != len(embeddings) {
+		return fmt.Errorf("chunk count (%d) doesn't match embedding count (%d)", len(chunks), len(embeddings))
+	}
+
+	tx, err := w.db.BeginTx(w.ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("Warning: failed to rollback transaction: %v", rbErr)
+			}
 		}
+	}()
 
-		_, err = tx.ExecContext(ctx, `
-			INSERT INTO embeddings (chunk_id, embedding) VALUES (?, ?)
-		`, chunkID, string(embeddingJSON))
-		if err != nil {
-			return fmt.Errorf("failed to insert embedding for chunk %d: %w", i, err)
+	for i, chunk := range chunks {
+		if err := insertChunk(w.ctx, tx, w.documentID, chunk.Index, chunk, embeddings[i]); err != nil {
+			return err
 		}
 	}
-
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 	committed = true
+
+	for _, chunk := range chunks {
+		w.chunks++
+
+		// The document text joins the top-level chunks like IndexChunksWithMetadata's text
+		if chunk.ParentIndex != nil {
+			continue
+		}
+		text := chunk.Text
+		if w.written {
+			text = "\n\n" + text
+		}
+		w.written = true
+		w.hash.Write([]byte(text))
+		if _, err := w.text.Write([]byte(text)); err != nil {
+			return fmt.Errorf("failed to compress content: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -279,6 +759,13 @@ func (s *SQLiteStorage) storeContent(id, text, contentHash string) (string, erro
 }
 
 func (s *SQLiteStorage) Search(ctx context.Context, embedding []float32, limit int) ([]SearchResult, error) {
+	return s.SearchWithOptions(ctx, embedding, limit, SearchOptions{})
+}
+
+// SearchWithOptions performs a vector search restricted to chunks matching opts
+func (s *SQLiteStorage) SearchWithOptions(
+	ctx context.Context, embedding []float32, limit int, opts SearchOptions,
+) ([]SearchResult, error) {
 	if s.db == nil {
 		return nil, fmt.Errorf("storage not initialized - call Initialize() first")
 	}
@@ -299,25 +786,60 @@ func (s *SQLiteStorage) Search(ctx context.Context, embedding []float32, limit i
 			c.chunk_text_compressed,
 			c.chunk_index,
 			c.page_number,
+			c.end_page_number,
 			c.chunk_type,
+			c.heading_path,
+			c.language,
+			c.symbol_name,
+			c.symbol_kind,
+			c.start_line,
+			c.end_line,
+			c.metadata,
 			d.original_text_compressed,
 			d.file_path,
 			d.source_path,
+			d.metadata,
+			c.parent_chunk_id,
+			p.chunk_index,
+			p.chunk_text_compressed,
 			vec_distance_cosine(e.embedding, ?) as distance
 		FROM chunks c
 		JOIN documents d ON c.document_id = d.id
 		JOIN embeddings e ON c.chunk_id = e.chunk_id
+		LEFT JOIN chunks p ON p.chunk_id = c.parent_chunk_id
+		%s
 		ORDER BY distance
 		LIMIT ?
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, string(embeddingJSON), limit)
+	args := []interface{}{string(embeddingJSON)}
+	var conditions []string
+	if opts.Language != "" {
+		conditions = append(conditions, "c.language = ?")
+		args = append(args, strings.ToLower(opts.Language))
+	}
+	// Metadata filters match chunk metadata first, then document metadata, ignoring case
+	for _, key := range sortedKeys(opts.Metadata) {
+		path := "$." + strconv.Quote(key)
+		conditions = append(conditions,
+			"LOWER(COALESCE(json_extract(c.metadata, ?), json_extract(d.metadata, ?), '')) LIKE ? ESCAPE '\\'")
+		args = append(args, path, path, "%"+escapeLike(strings.ToLower(opts.Metadata[key]))+"%")
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	query = fmt.Sprintf(query, where)
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute search query: %w", err)
 	}
 	defer rows.Close()
 
-	// Use a map to deduplicate results by document ID, keeping the best score per document
+	// Use a map to deduplicate results, keeping the best score per document. A child chunk
+	// hit is lifted to its parent section, which is returned for the document.
 	documentResults := make(map[string]SearchResult)
 
 	for rows.Next() {
@@ -327,16 +849,28 @@ func (s *SQLiteStorage) Search(ctx context.Context, embedding []float32, limit i
 		var compressedChunkText []byte
 		var compressedOriginalText []byte
 		var pageNumber sql.NullInt32
+		var endPageNumber sql.NullInt64
 		var chunkType string
+		var headingPath sql.NullString
+		var code chunkDetails
 		var filePath sql.NullString
 		var sourcePath sql.NullString
-
-		if err := rows.Scan(&result.ID, &compressedChunkText, &chunkIndex, &pageNumber,
-			&chunkType, &compressedOriginalText, &filePath, &sourcePath, &distance); err != nil {
+		var documentMetadata sql.NullString
+		var parentChunkID sql.NullString
+		var parentIndex sql.NullInt64
+		var compressedParentText []byte
+
+		dest := []interface{}{&result.ID, &compressedChunkText, &chunkIndex, &pageNumber, &endPageNumber,
+			&chunkType, &headingPath}
+		dest = append(dest, code.dest()...)
+		dest = append(dest, &compressedOriginalText, &filePath, &sourcePath, &documentMetadata,
+			&parentChunkID, &parentIndex, &compressedParentText, &distance)
+		if err := rows.Scan(dest...); err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
 
 		score := 1.0 - distance
+		hasParent := parentChunkID.Valid && len(compressedParentText) > 0
 
 		// Check if we already have a result for this document
 		if existingResult, exists := documentResults[result.ID]; exists {
@@ -358,6 +892,11 @@ func (s *SQLiteStorage) Search(ctx context.Context, embedding []float32, limit i
 			return nil, fmt.Errorf("failed to decompress original text: %w", err)
 		}
 
+		// Documents stored in batches keep no copy of their text; show the matching chunk
+		if originalText == "" {
+			originalText = chunkText
+		}
+
 		// Set the result text to the full document content
 		result.Text = originalText
 		result.Score = score
@@ -374,6 +913,30 @@ func (s *SQLiteStorage) Search(ctx context.Context, embedding []float32, limit i
 		// Add page number if available
 		if pageNumber.Valid {
 			metadata["page_number"] = int(pageNumber.Int32)
+			// Chunks combining several short pages carry the last page as well
+			if endPageNumber.Valid && endPageNumber.Int64 > int64(pageNumber.Int32) {
+				metadata["end_page_number"] = int(endPageNumber.Int64)
+			}
+		}
+
+		// Flag text read by the vision model rather than extracted from a text layer
+		if chunkType == "pdf_ocr" || chunkType == "image_ocr" {
+			metadata["ocr"] = true
+		}
+
+		// Add the section heading path for structured documents
+		if headingPath.Valid && headingPath.String != "" {
+			metadata["heading_path"] = headingPath.String
+		}
+
+		// Add symbol information for source code chunks
+		code.addToMetadata(metadata)
+
+		// Add document metadata such as email headers
+		for key, value := range decodeMetadata(documentMetadata) {
+			if _, exists := metadata[key]; !exists {
+				metadata[key] = value
+			}
 		}
 
 		// Add file path if available
@@ -386,6 +949,17 @@ func (s *SQLiteStorage) Search(ctx context.Context, embedding []float32, limit i
 			metadata["source_path"] = sourcePath.String
 		}
 
+		// Lift child chunk hits to their parent section, which is what gets returned as context
+		if hasParent {
+			parentText, err := DecompressText(compressedParentText)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decompress parent chunk text: %w", err)
+			}
+			result.Text = parentText
+			metadata["parent_chunk_id"] = parentChunkID.String
+			metadata["parent_chunk_index"] = int(parentIndex.Int64)
+		}
+
 		result.Metadata = metadata
 		documentResults[result.ID] = result
 	}
@@ -429,7 +1003,7 @@ func (s *SQLiteStorage) hasMultipleChunks(ctx context.Context, documentID string
 
 func (s *SQLiteStorage) ListDocuments(ctx context.Context) ([]DocumentInfo, error) {
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, original_text_compressed, chunk_count, source_path, doc_type, created_at, updated_at 
+		SELECT id, original_text_compressed, chunk_count, source_path, doc_type, metadata, created_at, updated_at
 		FROM documents 
 		ORDER BY updated_at DESC
 	`)
@@ -444,10 +1018,12 @@ func (s *SQLiteStorage) ListDocuments(ctx context.Context) ([]DocumentInfo, erro
 		var compressedText []byte
 		var sourcePath sql.NullString
 		var docType sql.NullString
+		var metadata sql.NullString
 		var updatedAtStr string
 		var createdAtStr string
 
-		err := rows.Scan(&doc.ID, &compressedText, &doc.ChunkCount, &sourcePath, &docType, &createdAtStr, &updatedAtStr)
+		err := rows.Scan(&doc.ID, &compressedText, &doc.ChunkCount, &sourcePath, &docType, &metadata,
+			&createdAtStr, &updatedAtStr)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan document row: %w", err)
 		}
@@ -456,6 +1032,7 @@ func (s *SQLiteStorage) ListDocuments(ctx context.Context) ([]DocumentInfo, erro
 		doc.SourcePath = sourcePath.String
 		doc.DocType = docType.String
 		doc.IsImage = docType.String == "image"
+		doc.Metadata = decodeMetadata(metadata)
 
 		// Decompress the text
 		doc.Text, err = DecompressText(compressedText)
@@ -499,7 +1076,7 @@ func (s *SQLiteStorage) GetDocumentByID(ctx context.Context, documentID string)
 	}
 
 	row := s.db.QueryRowContext(ctx, `
-		SELECT id, source_path, doc_type, chunk_count, created_at, updated_at
+		SELECT id, source_path, doc_type, metadata, chunk_count, created_at, updated_at
 		FROM documents 
 		WHERE id = ?
 	`, documentID)
@@ -507,8 +1084,9 @@ func (s *SQLiteStorage) GetDocumentByID(ctx context.Context, documentID string)
 	var doc DocumentInfo
 	var sourcePath sql.NullString
 	var docType sql.NullString
+	var metadata sql.NullString
 
-	err := row.Scan(&doc.ID, &sourcePath, &docType, &doc.ChunkCount, &doc.CreatedAt, &doc.UpdatedAt)
+	err := row.Scan(&doc.ID, &sourcePath, &docType, &metadata, &doc.ChunkCount, &doc.CreatedAt, &doc.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("document not found: %s", documentID)
@@ -519,6 +1097,7 @@ func (s *SQLiteStorage) GetDocumentByID(ctx context.Context, documentID string)
 	doc.SourcePath = sourcePath.String
 	doc.DocType = docType.String
 	doc.IsImage = docType.String == "image"
+	doc.Metadata = decodeMetadata(metadata)
 
 	return &doc, nil
 }
@@ -530,10 +1109,14 @@ func (s *SQLiteStorage) GetDocumentChunks(ctx context.Context, documentID string
 	}
 
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT chunk_index, chunk_text_compressed, start_pos, end_pos, token_count, page_number, chunk_type
-		FROM chunks 
-		WHERE document_id = ?
-		ORDER BY chunk_index
+		SELECT c.chunk_index, c.chunk_text_compressed, c.start_pos, c.end_pos, c.token_count,
+		       c.page_number, c.end_page_number, c.chunk_type, c.heading_path,
+		       c.language, c.symbol_name, c.symbol_kind, c.start_line, c.end_line, c.metadata, p.chunk_index,
+		       EXISTS (SELECT 1 FROM chunks ch WHERE ch.parent_chunk_id = c.chunk_id)
+		FROM chunks c
+		LEFT JOIN chunks p ON p.chunk_id = c.parent_chunk_id
+		WHERE c.document_id = ?
+		ORDER BY c.chunk_index
 	`, documentID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query chunks: %w", err)
@@ -545,12 +1128,25 @@ func (s *SQLiteStorage) GetDocumentChunks(ctx context.Context, documentID string
 		var chunk Chunk
 		var compressedText []byte
 		var pageNumber sql.NullInt32
-
-		err := rows.Scan(&chunk.Index, &compressedText, &chunk.StartPos, &chunk.EndPos,
-			&chunk.TokenCount, &pageNumber, &chunk.ChunkType)
-		if err != nil {
+		var endPageNumber sql.NullInt64
+		var parentIndex sql.NullInt64
+		var headingPath sql.NullString
+		var code chunkDetails
+
+		dest := []interface{}{&chunk.Index, &compressedText, &chunk.StartPos, &chunk.EndPos,
+			&chunk.TokenCount, &pageNumber, &endPageNumber, &chunk.ChunkType, &headingPath}
+		dest = append(dest, code.dest()...)
+		dest = append(dest, &parentIndex, &chunk.IsParent)
+		if err := rows.Scan(dest...); err != nil {
 			return nil, fmt.Errorf("failed to scan chunk row: %w", err)
 		}
+		chunk.HeadingPath = headingPath.String
+		code.applyToChunk(&chunk)
+
+		if parentIndex.Valid {
+			index := int(parentIndex.Int64)
+			chunk.ParentIndex = &index
+		}
 
 		// Decompress chunk text
 		chunk.Text, err = DecompressText(compressedText)
@@ -563,6 +1159,10 @@ func (s *SQLiteStorage) GetDocumentChunks(ctx context.Context, documentID string
 			pageNum := int(pageNumber.Int32)
 			chunk.PageNumber = &pageNum
 		}
+		if endPageNumber.Valid {
+			endPage := int(endPageNumber.Int64)
+			chunk.EndPageNumber = &endPage
+		}
 
 		chunks = append(chunks, chunk)
 	}
@@ -588,7 +1188,7 @@ func (s *SQLiteStorage) DeleteDocument(ctx context.Context, documentID string) e
 	defer func() {
 		if !committed {
 			if rbErr := tx.Rollback(); rbErr != nil {
-				fmt.Printf("Warning: failed to rollback transaction: %v\n", rbErr)
+				log.Printf("Warning: failed to rollback transaction: %v", rbErr)
 			}
 		}
 	}()
@@ -603,16 +1203,12 @@ func (s *SQLiteStorage) DeleteDocument(ctx context.Context, documentID string) e
 		return fmt.Errorf("failed to get document info: %w", err)
 	}
 
-	// Delete embeddings first (foreign key constraints)
-	_, err = tx.ExecContext(ctx, "DELETE FROM embeddings WHERE chunk_id LIKE ?", documentID+"%")
-	if err != nil {
-		return fmt.Errorf("failed to delete embeddings: %w", err)
+	if err := dropDocumentTables(ctx, tx, documentID); err != nil {
+		return err
 	}
 
-	// Delete chunks
-	_, err = tx.ExecContext(ctx, "DELETE FROM chunks WHERE document_id = ?", documentID)
-	if err != nil {
-		return fmt.Errorf("failed to delete chunks: %w", err)
+	if err := deleteDocumentChunks(ctx, tx, documentID); err != nil {
+		return err
 	}
 
 	// Delete document
@@ -640,7 +1236,7 @@ func (s *SQLiteStorage) DeleteDocument(ctx context.Context, documentID string) e
 	if filePath.Valid && filePath.String != "" {
 		if err := os.Remove(filePath.String); err != nil {
 			// Log but don't fail - file cleanup is not critical
-			fmt.Printf("Warning: failed to delete file %s: %v\n", filePath.String, err)
+			log.Printf("Warning: failed to delete file %s: %v", filePath.String, err)
 		}
 	}
 
@@ -700,15 +1296,22 @@ func (s *SQLiteStorage) GetChunk(ctx context.Context, chunkID string) (*ChunkInf
 
 	var chunk ChunkInfo
 	row := s.db.QueryRowContext(ctx, `
-		SELECT chunk_id, document_id, chunk_text, chunk_index, start_pos, end_pos, token_count, chunk_type, page_number
+		SELECT chunk_id, document_id, chunk_text, chunk_index, start_pos, end_pos, token_count, chunk_type,
+		       page_number, end_page_number, parent_chunk_id, heading_path,
+		       language, symbol_name, symbol_kind, start_line, end_line, metadata
 		FROM chunks 
 		WHERE chunk_id = ?
 	`, chunkID)
 
-	var pageNumber sql.NullInt64
+	var pageNumber, endPageNumber sql.NullInt64
 	var chunkText sql.NullString
-	err := row.Scan(&chunk.ID, &chunk.DocumentID, &chunkText, &chunk.Index,
-		&chunk.StartPos, &chunk.EndPos, &chunk.TokenCount, &chunk.ChunkType, &pageNumber)
+	var parentChunkID sql.NullString
+	var headingPath sql.NullString
+	var code chunkDetails
+	dest := []interface{}{&chunk.ID, &chunk.DocumentID, &chunkText, &chunk.Index,
+		&chunk.StartPos, &chunk.EndPos, &chunk.TokenCount, &chunk.ChunkType, &pageNumber, &endPageNumber,
+		&parentChunkID, &headingPath}
+	err := row.Scan(append(dest, code.dest()...)...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("chunk not found: %s", chunkID)
@@ -736,6 +1339,13 @@ func (s *SQLiteStorage) GetChunk(ctx context.Context, chunkID string) (*ChunkInf
 		pageNum := int(pageNumber.Int64)
 		chunk.PageNumber = &pageNum
 	}
+	if endPageNumber.Valid {
+		endPage := int(endPageNumber.Int64)
+		chunk.EndPageNumber = &endPage
+	}
+	chunk.ParentChunkID = parentChunkID.String
+	chunk.HeadingPath = headingPath.String
+	code.applyToInfo(&chunk)
 
 	return &chunk, nil
 }
@@ -748,7 +1358,9 @@ func (s *SQLiteStorage) GetDocumentChunksWithInfo(ctx context.Context, documentI
 
 	rows, err := s.db.QueryContext(ctx, `
 		SELECT chunk_id, document_id, chunk_text, chunk_text_compressed, chunk_index, 
-		       start_pos, end_pos, token_count, page_number, chunk_type
+		       start_pos, end_pos, token_count, page_number, end_page_number, chunk_type, parent_chunk_id,
+		       heading_path,
+		       language, symbol_name, symbol_kind, start_line, end_line, metadata
 		FROM chunks 
 		WHERE document_id = ?
 		ORDER BY chunk_index
@@ -763,13 +1375,21 @@ func (s *SQLiteStorage) GetDocumentChunksWithInfo(ctx context.Context, documentI
 		var chunk ChunkInfo
 		var compressedText []byte
 		var chunkText sql.NullString
-		var pageNumber sql.NullInt64
-
-		err := rows.Scan(&chunk.ID, &chunk.DocumentID, &chunkText, &compressedText, &chunk.Index,
-			&chunk.StartPos, &chunk.EndPos, &chunk.TokenCount, &pageNumber, &chunk.ChunkType)
-		if err != nil {
+		var pageNumber, endPageNumber sql.NullInt64
+		var parentChunkID sql.NullString
+		var headingPath sql.NullString
+		var code chunkDetails
+
+		dest := []interface{}{&chunk.ID, &chunk.DocumentID, &chunkText, &compressedText, &chunk.Index,
+			&chunk.StartPos, &chunk.EndPos, &chunk.TokenCount, &pageNumber, &endPageNumber, &chunk.ChunkType,
+			&parentChunkID,
+			&headingPath}
+		if err := rows.Scan(append(dest, code.dest()...)...); err != nil {
 			return nil, fmt.Errorf("failed to scan chunk row: %w", err)
 		}
+		chunk.ParentChunkID = parentChunkID.String
+		chunk.HeadingPath = headingPath.String
+		code.applyToInfo(&chunk)
 
 		// Handle text (prefer uncompressed if available)
 		if chunkText.Valid && chunkText.String != "" {
@@ -786,6 +1406,10 @@ func (s *SQLiteStorage) GetDocumentChunksWithInfo(ctx context.Context, documentI
 			pageNum := int(pageNumber.Int64)
 			chunk.PageNumber = &pageNum
 		}
+		if endPageNumber.Valid {
+			endPage := int(endPageNumber.Int64)
+			chunk.EndPageNumber = &endPage
+		}
 
 		chunks = append(chunks, chunk)
 	}
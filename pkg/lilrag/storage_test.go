@@ -932,3 +932,408 @@ func BenchmarkSQLiteStorage_Search(b *testing.B) {
 		}
 	}
 }
+
+func TestSQLiteStorage_Search_ParentChildChunks(t *testing.T) {
+	storage, tempDir := setupTestStorage(t)
+	defer os.RemoveAll(tempDir)
+
+	err := storage.Initialize()
+	if err != nil {
+		if strings.Contains(err.Error(), "sqlite-vec extension not available") {
+			t.Skip("Skipping test: sqlite-vec extension not available")
+		}
+		t.Fatalf("Failed to initialize storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+
+	parentIndex := 0
+	parentText := "Section about apples. Apples are red. Apples grow on trees."
+	chunks := []Chunk{
+		{Index: 0, Text: parentText, TokenCount: 10, ChunkType: "text", IsParent: true},
+		{Index: 1, Text: "Apples are red.", TokenCount: 3, ChunkType: "text", ParentIndex: &parentIndex},
+		{Index: 2, Text: "Apples grow on trees.", TokenCount: 4, ChunkType: "text", ParentIndex: &parentIndex},
+		{Index: 3, Text: "An unrelated closing note.", TokenCount: 4, ChunkType: "text"},
+	}
+	embeddings := [][]float32{
+		nil,
+		{0.9, 0.1, 0.0},
+		{0.8, 0.2, 0.0},
+		{0.0, 0.1, 0.9},
+	}
+
+	if err := storage.IndexChunks(ctx, "doc1", parentText+"\n\nAn unrelated closing note.", chunks, embeddings); err != nil {
+		t.Fatalf("Failed to index chunks: %v", err)
+	}
+
+	otherChunks := []Chunk{{Index: 0, Text: "Pears are green.", TokenCount: 3, ChunkType: "text"}}
+	if err := storage.IndexChunks(ctx, "doc2", "Pears are green.", otherChunks, [][]float32{{0.7, 0.3, 0.0}}); err != nil {
+		t.Fatalf("Failed to index chunks: %v", err)
+	}
+
+	results, err := storage.Search(ctx, []float32{1.0, 0.0, 0.0}, 5)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	// Every chunk of doc1 matches, but each document is returned once
+	if len(results) != 2 || results[0].ID != "doc1" || results[1].ID != "doc2" {
+		t.Fatalf("Expected one result per document, got %+v", results)
+	}
+
+	top := results[0]
+	if top.Text != parentText {
+		t.Errorf("Expected child hit to return parent text, got %q", top.Text)
+	}
+	if top.Metadata["parent_chunk_id"] != "doc1" {
+		t.Errorf("Expected parent_chunk_id doc1, got %v", top.Metadata["parent_chunk_id"])
+	}
+	if top.Metadata["matching_chunk"] != "Apples are red." {
+		t.Errorf("Expected matching_chunk to be the child text, got %v", top.Metadata["matching_chunk"])
+	}
+
+	if _, ok := results[1].Metadata["parent_chunk_id"]; ok {
+		t.Error("Expected chunk without a parent to have no parent_chunk_id")
+	}
+
+	stored, err := storage.GetDocumentChunks(ctx, "doc1")
+	if err != nil {
+		t.Fatalf("Failed to get chunks: %v", err)
+	}
+	if len(stored) != 4 {
+		t.Fatalf("Expected 4 stored chunks, got %d", len(stored))
+	}
+	if !stored[0].IsParent {
+		t.Error("Expected first chunk to be restored as a parent")
+	}
+	if stored[1].ParentIndex == nil || *stored[1].ParentIndex != 0 {
+		t.Error("Expected child chunk to be linked to parent index 0")
+	}
+}
+
+func TestSQLiteStorage_PrefixOverlappingDocumentIDs(t *testing.T) {
+	storage := setupTableStorage(t)
+	ctx := context.Background()
+
+	// docs-a is a prefix of docs-a-md, as produced for docs/a and docs/a.md
+	index := func(id, text string, embedding []float32) {
+		t.Helper()
+		chunks := []Chunk{
+			{Index: 0, Text: text, TokenCount: 3, ChunkType: "text"},
+			{Index: 1, Text: text + " Again.", TokenCount: 4, ChunkType: "text"},
+		}
+		if err := storage.IndexChunks(ctx, id, text, chunks, [][]float32{embedding, embedding}); err != nil {
+			t.Fatalf("Failed to index %s: %v", id, err)
+		}
+	}
+	index("docs-a-md", "Pears are green.", []float32{0.0, 1.0, 0.0})
+	index("docs-a", "Apples are red.", []float32{1.0, 0.0, 0.0})
+
+	countEmbeddings := func() int {
+		t.Helper()
+		var count int
+		if err := storage.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM embeddings").Scan(&count); err != nil {
+			t.Fatalf("Failed to count embeddings: %v", err)
+		}
+		return count
+	}
+
+	// Re-indexing docs-a must only replace its own embeddings
+	index("docs-a", "Apples are crisp.", []float32{1.0, 0.0, 0.0})
+	if got := countEmbeddings(); got != 4 {
+		t.Fatalf("Expected 4 embeddings after re-indexing docs-a, got %d", got)
+	}
+
+	if err := storage.DeleteDocument(ctx, "docs-a"); err != nil {
+		t.Fatalf("Failed to delete docs-a: %v", err)
+	}
+	if got := countEmbeddings(); got != 2 {
+		t.Fatalf("Expected docs-a-md's 2 embeddings to remain, got %d", got)
+	}
+
+	results, err := storage.Search(ctx, []float32{0.0, 1.0, 0.0}, 5)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "docs-a-md" {
+		t.Fatalf("Expected docs-a-md to remain searchable, got %+v", results)
+	}
+}
+
+func TestSQLiteStorage_StoreChunks(t *testing.T) {
+	storage := setupTableStorage(t)
+	ctx := context.Background()
+
+	parentIndex := 0
+	store := func(text string, between func() error) error {
+		return storage.StoreChunks(ctx, "report", "/tmp/report.csv", "csv", nil, func(w ChunkWriter) error {
+			parent := Chunk{Index: 0, Text: text, TokenCount: 6, IsParent: true}
+			child := Chunk{Index: 1, Text: text, TokenCount: 6, ParentIndex: &parentIndex}
+			if err := w.WriteChunks([]Chunk{parent, child}, [][]float32{nil, {1.0, 0.0, 0.0}}); err != nil {
+				return err
+			}
+			if err := between(); err != nil {
+				return err
+			}
+			last := Chunk{Index: 2, Text: text + " Totals.", TokenCount: 7}
+			return w.WriteChunks([]Chunk{last}, [][]float32{{0.9, 0.1, 0.0}})
+		})
+	}
+
+	// Other writers are not locked out while the next batch is embedded
+	err := store("Revenue by region.", func() error {
+		other := []Chunk{{Index: 0, Text: "Pears are green.", TokenCount: 3}}
+		return storage.IndexChunks(ctx, "other", "Pears are green.", other, [][]float32{{0.0, 1.0, 0.0}})
+	})
+	if err != nil {
+		t.Fatalf("Failed to store chunks: %v", err)
+	}
+
+	chunks, err := storage.GetDocumentChunks(ctx, "report")
+	if err != nil || len(chunks) != 3 {
+		t.Fatalf("Expected 3 chunks, got %d (%v)", len(chunks), err)
+	}
+	if chunks[1].ParentIndex == nil || *chunks[1].ParentIndex != 0 {
+		t.Error("Expected the child chunk to stay linked to its parent")
+	}
+	results, err := storage.Search(ctx, []float32{1.0, 0.0, 0.0}, 5)
+	if err != nil || len(results) != 2 || results[0].ID != "report" {
+		t.Fatalf("Expected report and other in the results, got %+v (%v)", results, err)
+	}
+	if results[0].Metadata["parent_chunk_id"] != "report" {
+		t.Errorf("Expected parent_chunk_id report, got %v", results[0].Metadata["parent_chunk_id"])
+	}
+
+	// A failed store keeps the previous version and leaves nothing staged behind
+	if err := store("Costs by region.", func() error { return fmt.Errorf("embedding failed") }); err == nil {
+		t.Fatal("Expected the failed write to be returned")
+	}
+	chunks, err = storage.GetDocumentChunks(ctx, "report")
+	if err != nil || len(chunks) != 3 || chunks[0].Text != "Revenue by region." {
+		t.Fatalf("Expected the previous version to be kept, got %+v (%v)", chunks, err)
+	}
+	var total, embeddings int
+	if err := storage.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM chunks").Scan(&total); err != nil {
+		t.Fatalf("Failed to count chunks: %v", err)
+	}
+	if err := storage.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM embeddings").Scan(&embeddings); err != nil {
+		t.Fatalf("Failed to count embeddings: %v", err)
+	}
+	if total != 4 || embeddings != 3 {
+		t.Errorf("Expected 4 chunks and 3 embeddings, got %d and %d", total, embeddings)
+	}
+}
+
+func TestSQLiteStorage_SearchWithOptions_Language(t *testing.T) {
+	storage, tempDir := setupTestStorage(t)
+	defer os.RemoveAll(tempDir)
+
+	err := storage.Initialize()
+	if err != nil {
+		if strings.Contains(err.Error(), "sqlite-vec extension not available") {
+			t.Skip("Skipping test: sqlite-vec extension not available")
+		}
+		t.Fatalf("Failed to initialize storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+
+	goChunks := []Chunk{{
+		Index: 0, Text: "func (u *Uploader) Upload() error { return retry(u.put) }", TokenCount: 8,
+		ChunkType: ContentTypeCode, Language: "go", SymbolName: "Uploader.Upload", SymbolKind: SymbolKindMethod,
+		StartLine: 12, EndLine: 20,
+	}}
+	pyChunks := []Chunk{{
+		Index: 0, Text: "def upload(path): return retry(put, path)", TokenCount: 6,
+		ChunkType: ContentTypeCode, Language: "python", SymbolName: "upload", SymbolKind: SymbolKindFunction,
+		StartLine: 3, EndLine: 5,
+	}}
+
+	if err := storage.IndexChunks(ctx, "upload.go", goChunks[0].Text, goChunks, [][]float32{{0.9, 0.1, 0.0}}); err != nil {
+		t.Fatalf("Failed to index Go chunks: %v", err)
+	}
+	if err := storage.IndexChunks(ctx, "upload.py", pyChunks[0].Text, pyChunks, [][]float32{{1.0, 0.0, 0.0}}); err != nil {
+		t.Fatalf("Failed to index Python chunks: %v", err)
+	}
+
+	results, err := storage.SearchWithOptions(ctx, []float32{1.0, 0.0, 0.0}, 5, SearchOptions{Language: "Go"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "upload.go" {
+		t.Fatalf("Expected only the Go chunk, got %+v", results)
+	}
+
+	metadata := results[0].Metadata
+	if metadata["language"] != "go" || metadata["symbol_name"] != "Uploader.Upload" ||
+		metadata["symbol_kind"] != SymbolKindMethod || metadata["start_line"] != 12 || metadata["end_line"] != 20 {
+		t.Errorf("Unexpected code metadata: %v", metadata)
+	}
+
+	results, err = storage.Search(ctx, []float32{1.0, 0.0, 0.0}, 5)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("Expected unfiltered search to return both documents, got %d", len(results))
+	}
+
+	chunk, err := storage.GetChunk(ctx, "upload.py")
+	if err != nil {
+		t.Fatalf("Failed to get chunk: %v", err)
+	}
+	if chunk.Language != "python" || chunk.SymbolName != "upload" || chunk.StartLine != 3 || chunk.EndLine != 5 {
+		t.Errorf("Expected symbol info to round-trip, got %+v", chunk)
+	}
+}
+
+func TestSQLiteStorage_PageRanges(t *testing.T) {
+	storage, tempDir := setupTestStorage(t)
+	defer os.RemoveAll(tempDir)
+
+	err := storage.Initialize()
+	if err != nil {
+		if strings.Contains(err.Error(), "sqlite-vec extension not available") {
+			t.Skip("Skipping test: sqlite-vec extension not available")
+		}
+		t.Fatalf("Failed to initialize storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+
+	firstPage, endPage := 2, 4
+	chunks := []Chunk{{
+		Index: 0, Text: "Short pages combined into one chunk", TokenCount: 6, ChunkType: "pdf_page",
+		PageNumber: &firstPage, EndPageNumber: &endPage,
+	}}
+	if err := storage.IndexChunks(ctx, "report", chunks[0].Text, chunks, [][]float32{{1.0, 0.0, 0.0}}); err != nil {
+		t.Fatalf("Failed to index chunks: %v", err)
+	}
+
+	results, err := storage.Search(ctx, []float32{1.0, 0.0, 0.0}, 1)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Metadata["page_number"] != 2 || results[0].Metadata["end_page_number"] != 4 {
+		t.Fatalf("Expected page range 2-4 in metadata, got %+v", results)
+	}
+
+	stored, err := storage.GetDocumentChunks(ctx, "report")
+	if err != nil {
+		t.Fatalf("Failed to get chunks: %v", err)
+	}
+	if len(stored) != 1 || stored[0].PageLabel() != "2-4" {
+		t.Errorf("Expected stored chunk to span pages 2-4, got %+v", stored)
+	}
+}
+
+func TestSQLiteStorage_ChunkMetadata(t *testing.T) {
+	storage, tempDir := setupTestStorage(t)
+	defer os.RemoveAll(tempDir)
+
+	err := storage.Initialize()
+	if err != nil {
+		if strings.Contains(err.Error(), "sqlite-vec extension not available") {
+			t.Skip("Skipping test: sqlite-vec extension not available")
+		}
+		t.Fatalf("Failed to initialize storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+
+	chunks := []Chunk{{
+		Index: 0, Text: "Goroutines are cheap", TokenCount: 4, ChunkType: "epub_section",
+		Metadata: map[string]string{"chapter_title": "Concurrency", "chapter_index": "3", "chunk_type": "ignored"},
+	}}
+	if err := storage.IndexChunks(ctx, "book", chunks[0].Text, chunks, [][]float32{{1.0, 0.0, 0.0}}); err != nil {
+		t.Fatalf("Failed to index chunks: %v", err)
+	}
+
+	results, err := storage.Search(ctx, []float32{1.0, 0.0, 0.0}, 1)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Metadata["chapter_title"] != "Concurrency" ||
+		results[0].Metadata["chapter_index"] != "3" {
+		t.Fatalf("Expected chapter metadata in search results, got %+v", results)
+	}
+	// Built-in keys are not overridden by chunk metadata
+	if results[0].Metadata["chunk_type"] != "epub_section" {
+		t.Errorf("Expected built-in chunk_type to win, got %v", results[0].Metadata["chunk_type"])
+	}
+
+	stored, err := storage.GetDocumentChunks(ctx, "book")
+	if err != nil {
+		t.Fatalf("Failed to get chunks: %v", err)
+	}
+	if len(stored) != 1 || stored[0].Metadata["chapter_title"] != "Concurrency" {
+		t.Errorf("Expected stored chunk metadata, got %+v", stored)
+	}
+}
+
+func TestSQLiteStorage_SearchWithOptions_Metadata(t *testing.T) {
+	storage, tempDir := setupTestStorage(t)
+	defer os.RemoveAll(tempDir)
+
+	err := storage.Initialize()
+	if err != nil {
+		if strings.Contains(err.Error(), "sqlite-vec extension not available") {
+			t.Skip("Skipping test: sqlite-vec extension not available")
+		}
+		t.Fatalf("Failed to initialize storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+
+	index := func(id, from string, embedding []float32) {
+		chunks := []Chunk{{Index: 0, Text: "Incident update from " + from, TokenCount: 4, ChunkType: "email_message"}}
+		metadata := map[string]string{"from": from, "subject": "[INC-42] 100% CPU"}
+		err := storage.IndexChunksWithMetadata(ctx, id, chunks[0].Text, chunks, [][]float32{embedding}, "", "email",
+			metadata)
+		if err != nil {
+			t.Fatalf("Failed to index %s: %v", id, err)
+		}
+	}
+	index("alice.eml", "Alice <alice@example.com>", []float32{0.9, 0.1, 0.0})
+	index("bob.eml", "Bob <bob@example.com>", []float32{1.0, 0.0, 0.0})
+
+	results, err := storage.SearchWithOptions(ctx, []float32{1.0, 0.0, 0.0}, 5,
+		SearchOptions{Metadata: map[string]string{"from": "ALICE@example"}})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "alice.eml" || results[0].Metadata["subject"] != "[INC-42] 100% CPU" {
+		t.Fatalf("Expected only alice.eml with its document metadata, got %+v", results)
+	}
+
+	// LIKE wildcards in filter values match literally
+	results, err = storage.SearchWithOptions(ctx, []float32{1.0, 0.0, 0.0}, 5,
+		SearchOptions{Metadata: map[string]string{"subject": "0% c"}})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("Expected both documents to match a literal %%, got %d", len(results))
+	}
+	results, err = storage.SearchWithOptions(ctx, []float32{1.0, 0.0, 0.0}, 5,
+		SearchOptions{Metadata: map[string]string{"subject": "INC_42"}})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("Expected _ to match literally, got %d results", len(results))
+	}
+
+	doc, err := storage.GetDocumentByID(ctx, "bob.eml")
+	if err != nil {
+		t.Fatalf("Failed to get document: %v", err)
+	}
+	if doc.Metadata["from"] != "Bob <bob@example.com>" {
+		t.Errorf("Expected document metadata, got %v", doc.Metadata)
+	}
+}
@@ -0,0 +1,575 @@
+package lilrag
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// structuredChunkTokens is the token budget of a chunk of fields or records; smaller
+	// than prose chunks like the CSV row groups
+	structuredChunkTokens = 200
+	// maxStructuredNodes guards against YAML alias expansion and similar blowups
+	maxStructuredNodes = 1_000_000
+)
+
+// StructuredDataParser handles JSON, JSONL, YAML and XML files. Values are flattened into
+// "path: value" lines, and arrays of objects become records grouped into batches the way
+// CSVParser groups rows. Each chunk keeps the JSON path it covers as json_path metadata.
+type StructuredDataParser struct {
+	chunker *TextChunker
+	docType DocumentType
+}
+
+// NewJSONParser creates a parser for JSON documents
+func NewJSONParser() *StructuredDataParser {
+	return &StructuredDataParser{docType: DocumentTypeJSON}
+}
+
+// NewJSONLParser creates a parser for JSON Lines files, where every line is one record
+func NewJSONLParser() *StructuredDataParser {
+	return &StructuredDataParser{docType: DocumentTypeJSONL}
+}
+
+// NewYAMLParser creates a parser for YAML documents
+func NewYAMLParser() *StructuredDataParser {
+	return &StructuredDataParser{docType: DocumentTypeYAML}
+}
+
+// NewXMLParser creates a parser for XML documents
+func NewXMLParser() *StructuredDataParser {
+	return &StructuredDataParser{docType: DocumentTypeXML}
+}
+
+// Parse extracts the flattened "path: value" text of a structured data file
+func (sp *StructuredDataParser) Parse(filePath string) (string, error) {
+	chunks, err := sp.ParseWithChunks(filePath, "")
+	if err != nil {
+		return "", err
+	}
+
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = strings.TrimSpace(chunk.Text)
+	}
+	return strings.Join(texts, "\n\n"), nil
+}
+
+// ParseWithChunks flattens a structured data file into field and record chunks
+func (sp *StructuredDataParser) ParseWithChunks(filePath, _ string) ([]Chunk, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s file: %w", sp.docType, err)
+	}
+	defer file.Close()
+
+	// Use a default chunker if none provided
+	if sp.chunker == nil {
+		sp.chunker = NewTextChunker(256, 38) // Use optimized defaults
+	}
+
+	var documents []*dataValue
+	switch sp.docType {
+	case DocumentTypeJSON, DocumentTypeJSONL:
+		documents, err = decodeJSONValues(file)
+	case DocumentTypeYAML:
+		documents, err = decodeYAMLValues(file)
+	case DocumentTypeXML:
+		var root *dataValue
+		root, err = decodeXMLValue(file)
+		documents = []*dataValue{root}
+	default:
+		err = fmt.Errorf("unsupported structured data type: %s", sp.docType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s file: %w", sp.docType, err)
+	}
+
+	dc := &dataChunker{chunker: sp.chunker, chunkType: string(sp.docType)}
+	switch {
+	case len(documents) == 1 && sp.docType != DocumentTypeJSONL:
+		dc.walk(nil, documents[0])
+		dc.flushFields()
+	default:
+		// JSON Lines and multi-document YAML: every record is a chunk group of its own
+		for i, document := range documents {
+			dc.addRecordGroup(dataPath{indexSegment(i)}, document)
+		}
+	}
+
+	if len(dc.chunks) == 0 {
+		return nil, fmt.Errorf("no content found in %s file", sp.docType)
+	}
+	return dc.chunks, nil
+}
+
+// SupportedExtensions returns the file extensions this parser supports
+func (sp *StructuredDataParser) SupportedExtensions() []string {
+	switch sp.docType {
+	case DocumentTypeJSONL:
+		return []string{".jsonl", ".ndjson"}
+	case DocumentTypeYAML:
+		return []string{".yaml", ".yml"}
+	case DocumentTypeXML:
+		return []string{".xml"}
+	default:
+		return []string{".json"}
+	}
+}
+
+// GetDocumentType returns the type of documents this parser handles
+func (sp *StructuredDataParser) GetDocumentType() DocumentType {
+	return sp.docType
+}
+
+// dataValue is a JSON, YAML or XML value that keeps the order of object keys
+type dataValue struct {
+	scalar   string
+	isScalar bool
+	fields   []dataField // Object members; nil with items for arrays
+	items    []*dataValue
+	isArray  bool
+}
+
+type dataField struct {
+	key   string
+	value *dataValue
+}
+
+// isRecordArray reports whether an array holds objects or arrays, which are chunked as records
+func (v *dataValue) isRecordArray() bool {
+	if !v.isArray || len(v.items) == 0 {
+		return false
+	}
+	for _, item := range v.items {
+		if item.isScalar {
+			return false
+		}
+	}
+	return true
+}
+
+// dataPath is a JSON path as formatted segments such as ".users", "[3]" or `["first name"]`
+type dataPath []string
+
+var identifierKeyRegex = regexp.MustCompile(`^[A-Za-z_@#][A-Za-z0-9_\-:]*$`)
+
+func keySegment(key string) string {
+	if identifierKeyRegex.MatchString(key) {
+		return "." + key
+	}
+	return "[" + strconv.Quote(key) + "]"
+}
+
+func indexSegment(i int) string {
+	return "[" + strconv.Itoa(i) + "]"
+}
+
+func (p dataPath) child(segment string) dataPath {
+	return append(p[:len(p):len(p)], segment)
+}
+
+// String returns the full JSON path, e.g. $.users[3].name
+func (p dataPath) String() string {
+	return "$" + strings.Join(p, "")
+}
+
+// label returns the path as written in "path: value" lines, e.g. users[3].name
+func (p dataPath) label() string {
+	if len(p) == 0 {
+		return "$"
+	}
+	return strings.TrimPrefix(strings.Join(p, ""), ".")
+}
+
+// commonPath returns the longest path shared by all paths
+func commonPath(paths []dataPath) dataPath {
+	if len(paths) == 0 {
+		return nil
+	}
+	common := paths[0]
+	for _, path := range paths[1:] {
+		n := 0
+		for n < len(common) && n < len(path) && common[n] == path[n] {
+			n++
+		}
+		common = common[:n]
+	}
+	return common
+}
+
+// flattenLines renders a value as "path: value" lines relative to path
+func flattenLines(path dataPath, v *dataValue, lines *[]string) {
+	switch {
+	case v.isScalar:
+		*lines = append(*lines, path.label()+": "+v.scalar)
+	case v.isArray && len(v.items) == 0:
+		*lines = append(*lines, path.label()+": []")
+	case v.isArray:
+		for i, item := range v.items {
+			flattenLines(path.child(indexSegment(i)), item, lines)
+		}
+	case len(v.fields) == 0:
+		*lines = append(*lines, path.label()+": {}")
+	default:
+		for _, field := range v.fields {
+			flattenLines(path.child(keySegment(field.key)), field.value, lines)
+		}
+	}
+}
+
+// dataChunker groups flattened fields and records into chunks
+type dataChunker struct {
+	chunker   *TextChunker
+	chunkType string
+	chunks    []Chunk
+
+	fieldText   strings.Builder
+	fieldPaths  []dataPath
+	fieldTokens int
+}
+
+// walk emits the fields of a value, turning arrays of objects into record batches
+func (dc *dataChunker) walk(path dataPath, v *dataValue) {
+	switch {
+	case v.isRecordArray():
+		dc.flushFields()
+		dc.addRecords(path, v.items)
+	case v.isArray && len(v.items) > 0:
+		for i, item := range v.items {
+			dc.walk(path.child(indexSegment(i)), item)
+		}
+	case !v.isScalar && !v.isArray && len(v.fields) > 0:
+		for _, field := range v.fields {
+			dc.walk(path.child(keySegment(field.key)), field.value)
+		}
+	default:
+		var lines []string
+		flattenLines(path, v, &lines)
+		dc.addField(path, lines[0]+"\n")
+	}
+}
+
+func (dc *dataChunker) addField(path dataPath, line string) {
+	tokens := dc.chunker.EstimateTokenCount(line)
+	if dc.fieldTokens+tokens > structuredChunkTokens && len(dc.fieldPaths) > 0 {
+		dc.flushFields()
+	}
+	dc.fieldText.WriteString(line)
+	dc.fieldPaths = append(dc.fieldPaths, path)
+	dc.fieldTokens += tokens
+}
+
+func (dc *dataChunker) flushFields() {
+	if len(dc.fieldPaths) == 0 {
+		return
+	}
+	dc.addChunk(dc.fieldText.String(), dc.chunkType+"_fields", commonPath(dc.fieldPaths).String(), dc.fieldTokens)
+	dc.fieldText.Reset()
+	dc.fieldPaths = nil
+	dc.fieldTokens = 0
+}
+
+// recordText renders a record as one line like CSV rows: "Record users[3]: name: Alice | age: 30"
+func recordText(path dataPath, record *dataValue) string {
+	var lines []string
+	flattenLines(nil, record, &lines)
+	return "Record " + path.label() + ": " + strings.Join(lines, " | ") + "\n"
+}
+
+// addRecords groups the records of an array into batches within the chunk token budget
+func (dc *dataChunker) addRecords(path dataPath, records []*dataValue) {
+	var batch strings.Builder
+	batchStart, batchTokens := 0, 0
+
+	flush := func(end int) {
+		if batch.Len() == 0 {
+			return
+		}
+		batchPath := path.child(fmt.Sprintf("[%d:%d]", batchStart, end))
+		if end-batchStart == 1 {
+			batchPath = path.child(indexSegment(batchStart))
+		}
+		dc.addChunk(batch.String(), dc.chunkType+"_records", batchPath.String(), batchTokens)
+		batch.Reset()
+		batchTokens = 0
+	}
+
+	for i, record := range records {
+		recordPath := path.child(indexSegment(i))
+		text := recordText(recordPath, record)
+		tokens := dc.chunker.EstimateTokenCount(text)
+
+		// Records too large for a batch are split on their own
+		if tokens > structuredChunkTokens {
+			flush(i)
+			dc.addRecordGroup(recordPath, record)
+			batchStart = i + 1
+			continue
+		}
+
+		if batchTokens+tokens > structuredChunkTokens && batch.Len() > 0 {
+			flush(i)
+			batchStart = i
+		}
+		batch.WriteString(text)
+		batchTokens += tokens
+	}
+	flush(len(records))
+}
+
+// addRecordGroup chunks a single record, splitting it with the text chunker when needed
+func (dc *dataChunker) addRecordGroup(path dataPath, record *dataValue) {
+	text := recordText(path, record)
+	if tokens := dc.chunker.EstimateTokenCount(text); tokens <= structuredChunkTokens {
+		dc.addChunk(text, dc.chunkType+"_record", path.String(), tokens)
+		return
+	}
+
+	var lines []string
+	flattenLines(path, record, &lines)
+	for _, piece := range dc.chunker.ChunkText(strings.Join(lines, "\n")) {
+		dc.addChunk(piece.Text, dc.chunkType+"_record", path.String(), piece.TokenCount)
+	}
+}
+
+func (dc *dataChunker) addChunk(text, chunkType, jsonPath string, tokens int) {
+	dc.chunks = append(dc.chunks, Chunk{
+		Text:       text,
+		Index:      len(dc.chunks),
+		StartPos:   0,
+		EndPos:     len(text),
+		TokenCount: tokens,
+		ChunkType:  chunkType,
+		Metadata:   map[string]string{"json_path": jsonPath},
+	})
+}
+
+// decodeJSONValues reads one JSON value, or a stream of values such as JSON Lines
+func decodeJSONValues(r io.Reader) ([]*dataValue, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	var values []*dataValue
+	nodes := 0
+	for {
+		value, err := decodeJSONValue(decoder, &nodes)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON in record %d: %w", len(values)+1, err)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func decodeJSONValue(decoder *json.Decoder, nodes *int) (*dataValue, error) {
+	if *nodes++; *nodes > maxStructuredNodes {
+		return nil, fmt.Errorf("document has more than %d values", maxStructuredNodes)
+	}
+
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := token.(type) {
+	case json.Delim:
+		value := &dataValue{isArray: t == '['}
+		for decoder.More() {
+			if t == '{' {
+				keyToken, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				child, err := decodeJSONValue(decoder, nodes)
+				if err != nil {
+					return nil, noEOF(err)
+				}
+				value.fields = append(value.fields, dataField{key: fmt.Sprint(keyToken), value: child})
+				continue
+			}
+			child, err := decodeJSONValue(decoder, nodes)
+			if err != nil {
+				return nil, noEOF(err)
+			}
+			value.items = append(value.items, child)
+		}
+		if _, err := decoder.Token(); err != nil { // Closing delimiter
+			return nil, noEOF(err)
+		}
+		return value, nil
+	case nil:
+		return &dataValue{scalar: "null", isScalar: true}, nil
+	default:
+		return &dataValue{scalar: fmt.Sprint(t), isScalar: true}, nil
+	}
+}
+
+// noEOF reports an end of input inside a value as a truncated document
+func noEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// decodeYAMLValues reads every document of a YAML stream
+func decodeYAMLValues(r io.Reader) ([]*dataValue, error) {
+	decoder := yaml.NewDecoder(r)
+
+	var values []*dataValue
+	nodes := 0
+	for {
+		var node yaml.Node
+		err := decoder.Decode(&node)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		value, err := yamlValue(&node, &nodes)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func yamlValue(node *yaml.Node, nodes *int) (*dataValue, error) {
+	if *nodes++; *nodes > maxStructuredNodes {
+		return nil, fmt.Errorf("document has more than %d values", maxStructuredNodes)
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return &dataValue{scalar: "null", isScalar: true}, nil
+		}
+		return yamlValue(node.Content[0], nodes)
+	case yaml.AliasNode:
+		return yamlValue(node.Alias, nodes)
+	case yaml.MappingNode:
+		value := &dataValue{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			child, err := yamlValue(node.Content[i+1], nodes)
+			if err != nil {
+				return nil, err
+			}
+			value.fields = append(value.fields, dataField{key: node.Content[i].Value, value: child})
+		}
+		return value, nil
+	case yaml.SequenceNode:
+		value := &dataValue{isArray: true}
+		for _, item := range node.Content {
+			child, err := yamlValue(item, nodes)
+			if err != nil {
+				return nil, err
+			}
+			value.items = append(value.items, child)
+		}
+		return value, nil
+	default:
+		if node.Tag == "!!null" {
+			return &dataValue{scalar: "null", isScalar: true}, nil
+		}
+		return &dataValue{scalar: node.Value, isScalar: true}, nil
+	}
+}
+
+// decodeXMLValue converts an XML document to a value: attributes become "@name" fields,
+// repeated child elements become arrays and mixed text is kept as "#text"
+func decodeXMLValue(r io.Reader) (*dataValue, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	nodes := 0
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no root element")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			root, err := decodeXMLElement(decoder, start, &nodes)
+			if err != nil {
+				return nil, err
+			}
+			return &dataValue{fields: []dataField{{key: start.Name.Local, value: root}}}, nil
+		}
+	}
+}
+
+func decodeXMLElement(decoder *xml.Decoder, start xml.StartElement, nodes *int) (*dataValue, error) {
+	if *nodes++; *nodes > maxStructuredNodes {
+		return nil, fmt.Errorf("document has more than %d elements", maxStructuredNodes)
+	}
+
+	value := &dataValue{}
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+			continue
+		}
+		value.fields = append(value.fields, dataField{
+			key:   "@" + attr.Name.Local,
+			value: &dataValue{scalar: attr.Value, isScalar: true},
+		})
+	}
+
+	var text strings.Builder
+	positions := make(map[string]int) // Field index of each child element name
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, noEOF(err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			child, err := decodeXMLElement(decoder, t, nodes)
+			if err != nil {
+				return nil, err
+			}
+			name := t.Name.Local
+			i, seen := positions[name]
+			switch {
+			case !seen:
+				positions[name] = len(value.fields)
+				value.fields = append(value.fields, dataField{key: name, value: child})
+			case value.fields[i].value.isArray: // Elements are never arrays until repeated
+				value.fields[i].value.items = append(value.fields[i].value.items, child)
+			default:
+				value.fields[i].value = &dataValue{isArray: true, items: []*dataValue{value.fields[i].value, child}}
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			content := strings.Join(strings.Fields(text.String()), " ")
+			if len(value.fields) == 0 {
+				return &dataValue{scalar: content, isScalar: true}, nil
+			}
+			if content != "" {
+				value.fields = append(value.fields, dataField{
+					key:   "#text",
+					value: &dataValue{scalar: content, isScalar: true},
+				})
+			}
+			return value, nil
+		}
+	}
+}
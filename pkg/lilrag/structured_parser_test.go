@@ -0,0 +1,147 @@
+package lilrag
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestStructuredDataParser_JSON(t *testing.T) {
+	var users []string
+	for i := 0; i < 40; i++ {
+		users = append(users, fmt.Sprintf(`{"name": "user%d", "roles": ["admin", "dev"], "address": {"city": "Berlin"}}`, i))
+	}
+	filePath := writeTestFile(t, "dump.json", `{"service": "billing", "version": 2, "owner": null,
+		"first name": "Ada", "tags": [], "users": [`+strings.Join(users, ",")+`], "status": {"healthy": true}}`)
+
+	chunks, err := NewJSONParser().ParseWithChunks(filePath, "dump")
+	if err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+
+	first := chunks[0]
+	expected := "service: billing\nversion: 2\nowner: null\n[\"first name\"]: Ada\ntags: []\n"
+	if first.ChunkType != "json_fields" || first.Text != expected || first.Metadata["json_path"] != "$" {
+		t.Errorf("Unexpected fields chunk %q (%s, %v)", first.Text, first.ChunkType, first.Metadata)
+	}
+
+	// The users array is split into record batches that together cover every record
+	next := 0
+	var records []Chunk
+	for _, chunk := range chunks[1:] {
+		if chunk.ChunkType != "json_records" {
+			continue
+		}
+		records = append(records, chunk)
+		want := fmt.Sprintf("$.users[%d:", next)
+		if !strings.HasPrefix(chunk.Metadata["json_path"], want) {
+			t.Fatalf("Expected batch path starting with %s, got %s", want, chunk.Metadata["json_path"])
+		}
+		fmt.Sscanf(chunk.Metadata["json_path"][len(want):], "%d", &next)
+	}
+	if len(records) < 2 || next != 40 {
+		t.Fatalf("Expected several batches covering 40 records, got %d batches ending at %d", len(records), next)
+	}
+	if !strings.HasPrefix(records[0].Text,
+		"Record users[0]: name: user0 | roles[0]: admin | roles[1]: dev | address.city: Berlin\n") {
+		t.Errorf("Unexpected record text %q", records[0].Text)
+	}
+
+	// Fields after the array keep document order
+	last := chunks[len(chunks)-1]
+	if last.Text != "status.healthy: true\n" || last.Metadata["json_path"] != "$.status.healthy" {
+		t.Errorf("Unexpected trailing chunk %q (%v)", last.Text, last.Metadata)
+	}
+	for i, chunk := range chunks {
+		if chunk.Index != i {
+			t.Errorf("Expected chunk %d to have index %d, got %d", i, i, chunk.Index)
+		}
+	}
+}
+
+func TestStructuredDataParser_JSONL(t *testing.T) {
+	filePath := writeTestFile(t, "events.jsonl", `{"event": "login", "user": "ada"}`+"\n\n"+
+		`{"event": "logout", "user": "bob"}`+"\n")
+
+	chunks, err := NewJSONLParser().ParseWithChunks(filePath, "events")
+	if err != nil {
+		t.Fatalf("Failed to parse JSONL: %v", err)
+	}
+	if len(chunks) != 2 {
+		t.Fatalf("Expected one chunk per record, got %d", len(chunks))
+	}
+	if chunks[1].Text != "Record [1]: event: logout | user: bob\n" || chunks[1].Metadata["json_path"] != "$[1]" ||
+		chunks[1].ChunkType != "jsonl_record" {
+		t.Errorf("Unexpected record chunk %q (%s, %v)", chunks[1].Text, chunks[1].ChunkType, chunks[1].Metadata)
+	}
+
+	bad := writeTestFile(t, "bad.jsonl", `{"event": "login"}`+"\n"+`{"event": `+"\n")
+	if _, err := NewJSONLParser().ParseWithChunks(bad, "bad"); err == nil || !strings.Contains(err.Error(), "record 2") {
+		t.Errorf("Expected an error naming record 2, got %v", err)
+	}
+}
+
+func TestStructuredDataParser_YAML(t *testing.T) {
+	filePath := writeTestFile(t, "deploy.yaml", `defaults: &defaults
+  replicas: 2
+  image: api:1.4
+services:
+  - name: api
+    <<: *defaults
+  - name: worker
+    settings: *defaults
+`)
+
+	content, err := NewYAMLParser().Parse(filePath)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	expected := "defaults.replicas: 2\ndefaults.image: api:1.4\n\n" +
+		"Record services[0]: name: api | [\"<<\"].replicas: 2 | [\"<<\"].image: api:1.4\n" +
+		"Record services[1]: name: worker | settings.replicas: 2 | settings.image: api:1.4"
+	if content != expected {
+		t.Errorf("Unexpected content:\n%s\nexpected:\n%s", content, expected)
+	}
+}
+
+func TestStructuredDataParser_XML(t *testing.T) {
+	filePath := writeTestFile(t, "catalog.xml", `<?xml version="1.0"?>
+<catalog xmlns="urn:example" updated="2024-09-01">
+  <book id="b1"><title>Go in Practice</title><tag>go</tag><tag>concurrency</tag></book>
+  <book id="b2"><title>SQLite Internals</title></book>
+</catalog>`)
+
+	chunks, err := NewXMLParser().ParseWithChunks(filePath, "catalog")
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+	if len(chunks) != 2 {
+		t.Fatalf("Expected a fields and a records chunk, got %+v", chunks)
+	}
+	if chunks[0].Text != "catalog.@updated: 2024-09-01\n" {
+		t.Errorf("Unexpected fields chunk %q", chunks[0].Text)
+	}
+
+	expected := "Record catalog.book[0]: @id: b1 | title: Go in Practice | tag[0]: go | tag[1]: concurrency\n" +
+		"Record catalog.book[1]: @id: b2 | title: SQLite Internals\n"
+	if chunks[1].Text != expected || chunks[1].Metadata["json_path"] != "$.catalog.book[0:2]" {
+		t.Errorf("Unexpected records chunk %q (%v)", chunks[1].Text, chunks[1].Metadata)
+	}
+}
+
+func TestDetectDocumentType_StructuredData(t *testing.T) {
+	dh := NewDocumentHandler(NewTextChunker(256, 38))
+
+	for path, docType := range map[string]DocumentType{
+		"a.json": DocumentTypeJSON, "b.jsonl": DocumentTypeJSONL, "c.ndjson": DocumentTypeJSONL,
+		"d.yaml": DocumentTypeYAML, "e.yml": DocumentTypeYAML, "f.xml": DocumentTypeXML,
+	} {
+		if got := dh.DetectDocumentType(path); got != docType {
+			t.Errorf("Expected %s to be %s, got %s", path, docType, got)
+		}
+		if _, ok := dh.GetSupportedFormats()[docType]; !ok {
+			t.Errorf("Expected a parser for %s", docType)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package lilrag
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Statuses of a document compared with its source file
+const (
+	SourceStatusUnchanged = "unchanged" // The source file has the indexed content
+	SourceStatusStale     = "stale"     // The source file changed since it was indexed
+	SourceStatusMissing   = "missing"   // The source file no longer exists
+	SourceStatusError     = "error"     // The source file could not be read
+)
+
+// Actions taken or, in a dry run, planned by Sync
+const (
+	SyncActionReindex = "reindex" // The stale document is indexed again from its source file
+	SyncActionPrune   = "prune"   // The document of a missing source file is deleted
+)
+
+// uploadTempPrefix starts the names of the temporary files that uploads were indexed from
+const uploadTempPrefix = "lilrag_upload_"
+
+// SyncOptions selects what Sync does about documents whose source files changed
+type SyncOptions struct {
+	Reindex bool // Index stale documents again from their source files
+	Prune   bool // Delete documents whose source files are missing
+	DryRun  bool // Report the actions without taking them
+}
+
+// SyncDocument is a document compared with its source file
+type SyncDocument struct {
+	ID         string `json:"id"`
+	SourcePath string `json:"source_path"`
+	Status     string `json:"status"`
+	Action     string `json:"action,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// SyncReport lists the documents with local source files and their status
+type SyncReport struct {
+	DryRun    bool           `json:"dry_run"`
+	Documents []SyncDocument `json:"documents"`
+}
+
+// Count returns the number of documents with a status
+func (r *SyncReport) Count(status string) int {
+	count := 0
+	for _, doc := range r.Documents {
+		if doc.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// Sync compares every document indexed from a local file with that file. A document is
+// stale when the file's content hash differs from the one recorded at indexing; documents
+// indexed before hashes were recorded are stale when the file was modified after them.
+// Documents without a local source file, such as text, web pages and uploads, are not
+// reported. With opts.Reindex stale documents are indexed again, and with opts.Prune the
+// documents of missing files are deleted; a failed action is reported on its document.
+func (m *LilRag) Sync(ctx context.Context, opts SyncOptions) (*SyncReport, error) {
+	if m.storage == nil {
+		return nil, fmt.Errorf("storage not initialized")
+	}
+	docs, err := m.storage.ListDocuments(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	report := &SyncReport{DryRun: opts.DryRun}
+	reindexed := make(map[string]error)
+	for _, doc := range docs {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		status, err := DocumentSourceStatus(doc)
+		if status == "" {
+			continue
+		}
+		result := SyncDocument{ID: doc.ID, SourcePath: doc.SourcePath, Status: status}
+		if err != nil {
+			result.Error = err.Error()
+		}
+
+		switch {
+		case status == SourceStatusStale && opts.Reindex:
+			result.Action = SyncActionReindex
+			if opts.DryRun {
+				break
+			}
+			// The entries of an archive are indexed again together
+			id := doc.ID
+			if archiveID := doc.Metadata["archive_id"]; archiveID != "" {
+				id = archiveID
+			}
+			if _, done := reindexed[id]; !done {
+				reindexed[id] = m.IndexFile(ctx, doc.SourcePath, id)
+			}
+			if err := reindexed[id]; err != nil {
+				result.Error = err.Error()
+			}
+		case status == SourceStatusMissing && opts.Prune:
+			result.Action = SyncActionPrune
+			if opts.DryRun {
+				break
+			}
+			if err := m.storage.DeleteDocument(ctx, doc.ID); err != nil {
+				result.Error = err.Error()
+			}
+		}
+		report.Documents = append(report.Documents, result)
+	}
+	return report, nil
+}
+
+// DocumentSourceStatus compares a document with the local file it was indexed from and
+// returns one of the SourceStatus values, or "" for documents without a local source file.
+// Files whose modification time matches the one recorded at indexing are not read.
+func DocumentSourceStatus(doc DocumentInfo) (string, error) {
+	if !hasLocalSource(doc.SourcePath) {
+		return "", nil
+	}
+
+	info, err := os.Stat(doc.SourcePath)
+	if os.IsNotExist(err) {
+		return SourceStatusMissing, nil
+	}
+	if err != nil {
+		return SourceStatusError, fmt.Errorf("failed to read source file: %w", err)
+	}
+	if info.IsDir() {
+		return SourceStatusError, fmt.Errorf("source path is a directory: %s", doc.SourcePath)
+	}
+
+	recordedHash := doc.Metadata[MetadataFileHash]
+	if recordedHash == "" {
+		// Indexed before file hashes were recorded
+		if info.ModTime().After(doc.UpdatedAt) {
+			return SourceStatusStale, nil
+		}
+		return SourceStatusUnchanged, nil
+	}
+	if recorded, err := time.Parse(time.RFC3339Nano, doc.Metadata[MetadataFileModified]); err == nil &&
+		recorded.Equal(info.ModTime()) {
+		return SourceStatusUnchanged, nil
+	}
+
+	hash, err := hashFile(doc.SourcePath)
+	if err != nil {
+		return SourceStatusError, err
+	}
+	if hash != recordedHash {
+		return SourceStatusStale, nil
+	}
+	return SourceStatusUnchanged, nil
+}
+
+// hasLocalSource reports whether a source path names a local file. URLs have no local
+// file, and uploads were indexed from temporary files that are removed afterwards.
+func hasLocalSource(sourcePath string) bool {
+	if sourcePath == "" || strings.Contains(sourcePath, "://") {
+		return false
+	}
+	return !strings.HasPrefix(filepath.Base(sourcePath), uploadTempPrefix)
+}
@@ -0,0 +1,116 @@
+package lilrag
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLilRag_Sync(t *testing.T) {
+	root := writeTestTree(t, map[string]string{
+		"same.txt":    "Rotate the API keys every ninety days.",
+		"touched.txt": "Run make install on a new laptop.",
+		"edited.txt":  "The old deployment steps.",
+		"removed.txt": "A file that will be removed.",
+	})
+
+	storage := NewMockStorage()
+	if err := storage.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize mock storage: %v", err)
+	}
+	lilRag := &LilRag{
+		storage:         storage,
+		embedder:        NewMockEmbedder(),
+		chunker:         NewTextChunker(256, 38),
+		documentHandler: NewDocumentHandler(NewTextChunker(256, 38)),
+		config:          &Config{},
+	}
+	ctx := context.Background()
+	for _, name := range []string{"same", "touched", "edited", "removed"} {
+		if err := lilRag.IndexFile(ctx, filepath.Join(root, name+".txt"), name); err != nil {
+			t.Fatalf("Failed to index %s: %v", name, err)
+		}
+	}
+	if err := lilRag.Index(ctx, "Text without a source file.", "text"); err != nil {
+		t.Fatalf("Failed to index text: %v", err)
+	}
+
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(root, "touched.txt"), later, later); err != nil {
+		t.Fatalf("Failed to touch file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "edited.txt"), []byte("The new deployment steps."), 0600); err != nil {
+		t.Fatalf("Failed to edit file: %v", err)
+	}
+	if err := os.Remove(filepath.Join(root, "removed.txt")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+
+	expected := map[string]string{
+		"same":    SourceStatusUnchanged,
+		"touched": SourceStatusUnchanged,
+		"edited":  SourceStatusStale,
+		"removed": SourceStatusMissing,
+	}
+	report, err := lilRag.Sync(ctx, SyncOptions{Reindex: true, Prune: true, DryRun: true})
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	if len(report.Documents) != len(expected) {
+		t.Errorf("Expected %d documents with source files, got %+v", len(expected), report.Documents)
+	}
+	for _, doc := range report.Documents {
+		if doc.Status != expected[doc.ID] {
+			t.Errorf("Expected %s to be %s, got %+v", doc.ID, expected[doc.ID], doc)
+		}
+	}
+	if _, exists := storage.documents["removed"]; !exists {
+		t.Error("Expected a dry run not to prune documents")
+	}
+	if storage.documents["edited"] != "The old deployment steps." {
+		t.Error("Expected a dry run not to re-index documents")
+	}
+
+	report, err = lilRag.Sync(ctx, SyncOptions{Reindex: true, Prune: true})
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	actions := make(map[string]SyncDocument)
+	for _, doc := range report.Documents {
+		actions[doc.ID] = doc
+	}
+	if doc := actions["edited"]; doc.Action != SyncActionReindex || doc.Error != "" {
+		t.Errorf("Expected edited to be re-indexed, got %+v", doc)
+	}
+	if doc := actions["removed"]; doc.Action != SyncActionPrune || doc.Error != "" {
+		t.Errorf("Expected removed to be pruned, got %+v", doc)
+	}
+	if doc := actions["same"]; doc.Action != "" {
+		t.Errorf("Expected no action for an unchanged document, got %+v", doc)
+	}
+	if storage.documents["edited"] != "The new deployment steps." {
+		t.Errorf("Expected the new content to be indexed, got %q", storage.documents["edited"])
+	}
+	if _, exists := storage.documents["removed"]; exists {
+		t.Error("Expected the document of the missing file to be pruned")
+	}
+
+	report, err = lilRag.Sync(ctx, SyncOptions{})
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	if report.Count(SourceStatusUnchanged) != 3 || len(report.Documents) != 3 {
+		t.Errorf("Expected every document to be unchanged after syncing, got %+v", report.Documents)
+	}
+}
+
+func TestDocumentSourceStatus_WithoutLocalSource(t *testing.T) {
+	for _, sourcePath := range []string{"", "https://wiki.example.com/page", "/tmp/lilrag_upload_123.pdf"} {
+		status, err := DocumentSourceStatus(DocumentInfo{ID: "doc", SourcePath: sourcePath})
+		if status != "" || err != nil {
+			t.Errorf("Expected %q to have no local source, got %q (%v)", sourcePath, status, err)
+		}
+	}
+}
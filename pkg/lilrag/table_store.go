@@ -0,0 +1,742 @@
+package lilrag
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// DefaultTableQueryRows is the maximum number of rows returned by a table query
+const DefaultTableQueryRows = 50
+
+// tableQueryTimeout bounds how long a single table query may run
+const tableQueryTimeout = 10 * time.Second
+
+// sqliteRecursive is SQLITE_RECURSIVE, which go-sqlite3 does not export. It is reported
+// for recursive common table expressions.
+const sqliteRecursive = 33
+
+// Table is a sheet of a tabular document, such as an XLSX worksheet or a CSV file
+type Table struct {
+	Name   string   // Sheet name
+	Header []string // Column headers, one per column
+	Rows   []TableRow
+}
+
+// TableRow is a data row of a sheet
+type TableRow struct {
+	Number int // Row number as shown in the document's chunks
+	Cells  []string
+}
+
+// TableColumn describes a column of a stored sheet table
+type TableColumn struct {
+	Name   string `json:"name"`   // SQL column name
+	Header string `json:"header"` // Header as written in the sheet
+	Type   string `json:"type"`   // INTEGER, REAL or TEXT
+}
+
+// TableInfo describes a sheet stored as a SQLite table. Besides its columns every table
+// has a "_row" column with the row number of the sheet.
+type TableInfo struct {
+	Name       string        `json:"name"`
+	DocumentID string        `json:"document_id"`
+	Sheet      string        `json:"sheet"`
+	Columns    []TableColumn `json:"columns"`
+	RowCount   int           `json:"row_count"`
+}
+
+// TableQueryResult holds the rows returned by a table query
+type TableQueryResult struct {
+	SQL       string      `json:"sql"`
+	Columns   []string    `json:"columns"`
+	Rows      [][]string  `json:"rows"`
+	Truncated bool        `json:"truncated"` // More rows matched than the row limit
+	Tables    []TableInfo `json:"tables"`    // Sheet tables the query read
+}
+
+// Markdown renders the result as a Markdown table
+func (r *TableQueryResult) Markdown() string {
+	if len(r.Rows) == 0 {
+		return "(no rows)"
+	}
+	return markdownTable(append([][]string{r.Columns}, r.Rows...))
+}
+
+// Sheets returns the names of the sheets the query read
+func (r *TableQueryResult) Sheets() []string {
+	sheets := make([]string, len(r.Tables))
+	for i, table := range r.Tables {
+		sheets[i] = table.Sheet
+	}
+	return sheets
+}
+
+// TableWriter receives the sheets of a tabular document one row at a time, so large
+// sheets are never held in memory. The cells passed to WriteRow are only valid during
+// the call.
+type TableWriter interface {
+	// BeginTable starts a sheet; the following rows belong to it
+	BeginTable(name string, header []string) error
+	// WriteRow adds a data row to the current sheet
+	WriteRow(row TableRow) error
+}
+
+// tableCollector is a TableWriter that keeps the sheets in memory
+type tableCollector struct {
+	tables []Table
+}
+
+func (tc *tableCollector) BeginTable(name string, header []string) error {
+	tc.tables = append(tc.tables, Table{Name: name, Header: append([]string(nil), header...)})
+	return nil
+}
+
+func (tc *tableCollector) WriteRow(row TableRow) error {
+	if len(tc.tables) == 0 {
+		return fmt.Errorf("row %d written before a table was started", row.Number)
+	}
+	table := &tc.tables[len(tc.tables)-1]
+	row.Cells = append([]string(nil), row.Cells...)
+	table.Rows = append(table.Rows, row)
+	return nil
+}
+
+// writeTables writes sheets that are already in memory to w
+func writeTables(w TableWriter, tables []Table) error {
+	for _, table := range tables {
+		if err := w.BeginTable(table.Name, table.Header); err != nil {
+			return err
+		}
+		for _, row := range table.Rows {
+			if err := w.WriteRow(row); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// TableStore is implemented by storage backends that keep the sheets of tabular
+// documents as SQL tables, so questions about totals and filters can be answered
+// with read-only queries
+type TableStore interface {
+	// StoreTables replaces the tables of a document with the sheets written by write
+	StoreTables(ctx context.Context, documentID string, write func(TableWriter) error) error
+	// ListTables returns the tables of the given documents, or of all documents
+	ListTables(ctx context.Context, documentIDs ...string) ([]TableInfo, error)
+	// QueryTables runs a single SELECT statement over the stored tables
+	QueryTables(ctx context.Context, query string, maxRows int) (*TableQueryResult, error)
+}
+
+// StoreTables replaces the sheet tables of a document with the sheets written by write.
+// A write function that writes nothing removes them.
+func (s *SQLiteStorage) StoreTables(ctx context.Context, documentID string, write func(TableWriter) error) error {
+	if s.db == nil {
+		return fmt.Errorf("storage not initialized")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	var committed bool
+	defer func() {
+		if !committed {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("Warning: failed to rollback transaction: %v", rbErr)
+			}
+		}
+	}()
+
+	if err := dropDocumentTables(ctx, tx, documentID); err != nil {
+		return err
+	}
+
+	w := &sqliteTableWriter{ctx: ctx, tx: tx, documentID: documentID}
+	err = write(w)
+	if err == nil {
+		err = w.finish()
+	}
+	w.close()
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tables: %w", err)
+	}
+	committed = true
+	return nil
+}
+
+// sqliteTableWriter stores sheets while they are read. Rows are first inserted as raw
+// cells into a temporary staging table while the column types are inferred; once the
+// sheet is complete the typed table is created and filled from the staging table.
+type sqliteTableWriter struct {
+	ctx        context.Context
+	tx         *sql.Tx
+	documentID string
+
+	name    string // Sheet name of the current table, "" before the first
+	header  []string
+	types   []string // Inferred column types, one per column seen so far
+	rows    int
+	staging *sql.Stmt
+}
+
+// tableStagingName is the temporary table rows are collected in before they are typed
+const tableStagingName = "lilrag_table_staging"
+
+func (w *sqliteTableWriter) BeginTable(name string, header []string) error {
+	if err := w.finish(); err != nil {
+		return err
+	}
+
+	if _, err := w.tx.ExecContext(w.ctx, "DROP TABLE IF EXISTS temp."+tableStagingName); err != nil {
+		return fmt.Errorf("failed to reset staging table: %w", err)
+	}
+	create := "CREATE TEMP TABLE " + tableStagingName + ` ("_row" INTEGER, cells TEXT)`
+	if _, err := w.tx.ExecContext(w.ctx, create); err != nil {
+		return fmt.Errorf("failed to create staging table: %w", err)
+	}
+	staging, err := w.tx.PrepareContext(w.ctx, "INSERT INTO temp."+tableStagingName+" VALUES (?, ?)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+
+	w.name = name
+	w.header = append([]string(nil), header...)
+	w.types = make([]string, len(header))
+	w.rows = 0
+	w.staging = staging
+	return nil
+}
+
+func (w *sqliteTableWriter) WriteRow(row TableRow) error {
+	if w.staging == nil {
+		return fmt.Errorf("row %d written before a table was started", row.Number)
+	}
+
+	for len(w.types) < len(row.Cells) {
+		w.types = append(w.types, "")
+	}
+	for i, cell := range row.Cells {
+		w.types[i] = mergeColumnType(w.types[i], cell)
+	}
+
+	cells, err := json.Marshal(row.Cells)
+	if err != nil {
+		return fmt.Errorf("failed to encode row %d: %w", row.Number, err)
+	}
+	if _, err := w.staging.ExecContext(w.ctx, row.Number, string(cells)); err != nil {
+		return fmt.Errorf("failed to insert row %d: %w", row.Number, err)
+	}
+	w.rows++
+	return nil
+}
+
+// finish creates the typed table of the current sheet from its staged rows
+func (w *sqliteTableWriter) finish() error {
+	if w.staging == nil {
+		return nil
+	}
+	w.close()
+
+	if err := w.storeStaged(); err != nil {
+		return fmt.Errorf("failed to store sheet %s: %w", w.name, err)
+	}
+	if _, err := w.tx.ExecContext(w.ctx, "DROP TABLE temp."+tableStagingName); err != nil {
+		return fmt.Errorf("failed to drop staging table: %w", err)
+	}
+	return nil
+}
+
+// close releases the staging insert statement
+func (w *sqliteTableWriter) close() {
+	if w.staging != nil {
+		w.staging.Close()
+		w.staging = nil
+	}
+}
+
+// storeStaged creates and fills the SQL table of the current sheet
+func (w *sqliteTableWriter) storeStaged() error {
+	ctx, tx := w.ctx, w.tx
+
+	name, err := uniqueTableName(ctx, tx, sheetTableName(w.documentID, w.name))
+	if err != nil {
+		return err
+	}
+	columns := tableColumns(w.header, w.types)
+
+	definitions := []string{`"_row" INTEGER`}
+	placeholders := []string{"?"}
+	for _, column := range columns {
+		definitions = append(definitions, quoteIdentifier(column.Name)+" "+column.Type)
+		placeholders = append(placeholders, "?")
+	}
+	create := fmt.Sprintf("CREATE TABLE %s (%s)", quoteIdentifier(name), strings.Join(definitions, ", "))
+	if _, err := tx.ExecContext(ctx, create); err != nil {
+		return fmt.Errorf("failed to create table: %w", err)
+	}
+
+	insert, err := tx.PrepareContext(ctx, fmt.Sprintf("INSERT INTO %s VALUES (%s)",
+		quoteIdentifier(name), strings.Join(placeholders, ", ")))
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer insert.Close()
+
+	staged, err := tx.QueryContext(ctx, `SELECT "_row", cells FROM temp.`+tableStagingName+" ORDER BY rowid")
+	if err != nil {
+		return fmt.Errorf("failed to read staged rows: %w", err)
+	}
+	defer staged.Close()
+
+	values := make([]interface{}, len(columns)+1)
+	for staged.Next() {
+		var number int
+		var encoded string
+		if err := staged.Scan(&number, &encoded); err != nil {
+			return fmt.Errorf("failed to scan staged row: %w", err)
+		}
+		var cells []string
+		if err := json.Unmarshal([]byte(encoded), &cells); err != nil {
+			return fmt.Errorf("failed to decode row %d: %w", number, err)
+		}
+
+		values[0] = number
+		for i, column := range columns {
+			var cell string
+			if i < len(cells) {
+				cell = cells[i]
+			}
+			values[i+1] = tableValue(cell, column.Type)
+		}
+		if _, err := insert.ExecContext(ctx, values...); err != nil {
+			return fmt.Errorf("failed to insert row %d: %w", number, err)
+		}
+	}
+	if err := staged.Err(); err != nil {
+		return fmt.Errorf("error during staged row iteration: %w", err)
+	}
+
+	encoded, err := json.Marshal(columns)
+	if err != nil {
+		return fmt.Errorf("failed to encode columns: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO document_tables (table_name, document_id, sheet_name, columns, row_count)
+		VALUES (?, ?, ?, ?, ?)`, name, w.documentID, w.name, string(encoded), w.rows)
+	if err != nil {
+		return fmt.Errorf("failed to register table: %w", err)
+	}
+	return nil
+}
+
+// dropDocumentTables drops the sheet tables of a document
+func dropDocumentTables(ctx context.Context, tx *sql.Tx, documentID string) error {
+	rows, err := tx.QueryContext(ctx, "SELECT table_name FROM document_tables WHERE document_id = ?", documentID)
+	if err != nil {
+		return fmt.Errorf("failed to list document tables: %w", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan table name: %w", err)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error during table iteration: %w", err)
+	}
+
+	for _, name := range names {
+		if _, err := tx.ExecContext(ctx, "DROP TABLE IF EXISTS "+quoteIdentifier(name)); err != nil {
+			return fmt.Errorf("failed to drop table %s: %w", name, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM document_tables WHERE document_id = ?", documentID); err != nil {
+		return fmt.Errorf("failed to unregister document tables: %w", err)
+	}
+	return nil
+}
+
+// ListTables returns the sheet tables of the given documents in sheet order, or of all
+// documents when no IDs are given
+func (s *SQLiteStorage) ListTables(ctx context.Context, documentIDs ...string) ([]TableInfo, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("storage not initialized")
+	}
+
+	query := "SELECT table_name, document_id, sheet_name, columns, row_count FROM document_tables"
+	args := make([]interface{}, len(documentIDs))
+	if len(documentIDs) > 0 {
+		for i, id := range documentIDs {
+			args[i] = id
+		}
+		query += " WHERE document_id IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(documentIDs)), ", ") + ")"
+	}
+	query += " ORDER BY document_id, rowid"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []TableInfo
+	for rows.Next() {
+		var table TableInfo
+		var columns string
+		if err := rows.Scan(&table.Name, &table.DocumentID, &table.Sheet, &columns, &table.RowCount); err != nil {
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+		if err := json.Unmarshal([]byte(columns), &table.Columns); err != nil {
+			return nil, fmt.Errorf("failed to decode columns of %s: %w", table.Name, err)
+		}
+		tables = append(tables, table)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during table iteration: %w", err)
+	}
+
+	return tables, nil
+}
+
+// QueryTables runs a read-only query over the sheet tables and returns at most maxRows
+// rows. Statements are checked by SQLite's authorizer while they are prepared: only
+// SELECT statements that read sheet tables are allowed, so writes, PRAGMAs, ATTACH and
+// reads of the document, chunk and schema tables are rejected.
+func (s *SQLiteStorage) QueryTables(ctx context.Context, query string, maxRows int) (*TableQueryResult, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("storage not initialized")
+	}
+
+	query, err := validateTableQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if maxRows <= 0 {
+		maxRows = DefaultTableQueryRows
+	}
+
+	tables, err := s.ListTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]TableInfo, len(tables))
+	for _, table := range tables {
+		byName[table.Name] = table
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, tableQueryTimeout)
+	defer cancel()
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	read := make(map[string]bool)
+	authorize := func(op int, arg1, arg2, _ string) int {
+		switch op {
+		case sqlite3.SQLITE_SELECT, sqliteRecursive:
+			return sqlite3.SQLITE_OK
+		case sqlite3.SQLITE_READ:
+			if _, ok := byName[arg1]; ok {
+				read[arg1] = true
+				return sqlite3.SQLITE_OK
+			}
+		case sqlite3.SQLITE_FUNCTION:
+			if !strings.EqualFold(arg2, "load_extension") {
+				return sqlite3.SQLITE_OK
+			}
+		}
+		return sqlite3.SQLITE_DENY
+	}
+	if err := setAuthorizer(conn, authorize); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := setAuthorizer(conn, nil); err != nil {
+			log.Printf("Warning: failed to reset authorizer: %v", err)
+		}
+	}()
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("table query failed: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result columns: %w", err)
+	}
+
+	result := &TableQueryResult{SQL: query, Columns: columns}
+	for rows.Next() {
+		if len(result.Rows) == maxRows {
+			result.Truncated = true
+			break
+		}
+		values := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan result row: %w", err)
+		}
+		row := make([]string, len(values))
+		for i, value := range values {
+			row[i] = formatTableValue(value)
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("table query failed: %w", err)
+	}
+
+	for _, table := range tables {
+		if read[table.Name] {
+			result.Tables = append(result.Tables, table)
+		}
+	}
+	return result, nil
+}
+
+// setAuthorizer installs or, with nil, removes the authorizer of a connection
+func setAuthorizer(conn *sql.Conn, authorize func(int, string, string, string) int) error {
+	err := conn.Raw(func(driverConn interface{}) error {
+		sqliteConn, ok := driverConn.(*sqlite3.SQLiteConn)
+		if !ok {
+			return fmt.Errorf("unexpected driver connection %T", driverConn)
+		}
+		sqliteConn.RegisterAuthorizer(authorize)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set authorizer: %w", err)
+	}
+	return nil
+}
+
+// validateTableQuery trims a query and rejects anything but a single SELECT statement
+func validateTableQuery(query string) (string, error) {
+	query = strings.TrimSpace(query)
+	for strings.HasSuffix(query, ";") {
+		query = strings.TrimSpace(strings.TrimSuffix(query, ";"))
+	}
+
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("table query is empty")
+	}
+	if first := strings.ToUpper(fields[0]); first != "SELECT" && first != "WITH" {
+		return "", fmt.Errorf("only SELECT statements are allowed")
+	}
+
+	// Look for statement separators outside of string literals, quoted identifiers
+	// and comments
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			end := strings.IndexByte(query[i+1:], c)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated quote in table query")
+			}
+			i += end + 1
+		case c == '[':
+			end := strings.IndexByte(query[i+1:], ']')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated identifier in table query")
+			}
+			i += end + 1
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return query, nil
+			}
+			i += end
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return "", fmt.Errorf("unterminated comment in table query")
+			}
+			i += end + 3
+		case c == ';':
+			return "", fmt.Errorf("only a single statement is allowed")
+		}
+	}
+	return query, nil
+}
+
+var tableNameReplacer = regexp.MustCompile(`[^a-z0-9]+`)
+
+// sqlName lower-cases a name and replaces everything but letters and digits with "_"
+func sqlName(name string) string {
+	return strings.Trim(tableNameReplacer.ReplaceAllString(strings.ToLower(name), "_"), "_")
+}
+
+// sheetTableName derives the SQL table name of a sheet, e.g. "budget-2024" and "Q3"
+// give "sheet_budget_2024_q3"
+func sheetTableName(documentID, sheet string) string {
+	name := "sheet_" + sqlName(documentID)
+	if sheet := sqlName(sheet); sheet != "" {
+		name += "_" + sheet
+	}
+	if len(name) > 60 {
+		name = strings.TrimRight(name[:60], "_")
+	}
+	return name
+}
+
+// uniqueTableName appends a counter to name while another sheet's table uses it
+func uniqueTableName(ctx context.Context, tx *sql.Tx, name string) (string, error) {
+	candidate := name
+	for n := 2; ; n++ {
+		var exists int
+		err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM document_tables WHERE table_name = ?", candidate).
+			Scan(&exists)
+		if err != nil {
+			return "", fmt.Errorf("failed to check table name: %w", err)
+		}
+		if exists == 0 {
+			return candidate, nil
+		}
+		candidate = name + "_" + strconv.Itoa(n)
+	}
+}
+
+// tableColumns derives SQL column names from a sheet's header and pairs them with the
+// inferred column types. Columns without a header are named ColumnN.
+func tableColumns(header, types []string) []TableColumn {
+	width := len(header)
+	if len(types) > width {
+		width = len(types)
+	}
+
+	columns := make([]TableColumn, width)
+	used := make(map[string]bool)
+	for i := range columns {
+		name := ""
+		if i < len(header) {
+			name = strings.TrimSpace(header[i])
+		}
+		if name == "" {
+			name = "Column" + strconv.Itoa(i+1)
+		}
+		columnHeader := name
+
+		name = sqlName(name)
+		if name == "" || (name[0] >= '0' && name[0] <= '9') {
+			name = "c_" + name
+		}
+		base := name
+		for n := 2; used[name]; n++ {
+			name = base + "_" + strconv.Itoa(n)
+		}
+		used[name] = true
+
+		columnType := "TEXT"
+		if i < len(types) && types[i] != "" {
+			columnType = types[i]
+		}
+		columns[i] = TableColumn{Name: name, Header: columnHeader, Type: columnType}
+	}
+	return columns
+}
+
+// mergeColumnType refines the type inferred from a column's earlier values with one more
+// cell: "" until a non-empty value is seen, then INTEGER, REAL or TEXT
+func mergeColumnType(columnType, cell string) string {
+	if columnType == "TEXT" || strings.TrimSpace(cell) == "" {
+		return columnType
+	}
+	number, ok := parseTableNumber(cell)
+	if !ok {
+		return "TEXT"
+	}
+	if columnType != "REAL" && number == math.Trunc(number) && !strings.ContainsAny(cell, ".eE") {
+		return "INTEGER"
+	}
+	return "REAL"
+}
+
+// parseTableNumber parses numbers as they appear in spreadsheets, with thousands
+// separators and a leading currency symbol. Values with leading zeros, such as ZIP
+// codes, are kept as text.
+func parseTableNumber(value string) (float64, bool) {
+	value = strings.TrimSpace(value)
+	sign := ""
+	if strings.HasPrefix(value, "-") {
+		sign, value = "-", value[1:]
+	}
+	value = strings.ReplaceAll(strings.TrimLeft(value, "$€£¥ "), ",", "")
+
+	if value == "" || (len(value) > 1 && value[0] == '0' && value[1] != '.') {
+		return 0, false
+	}
+	for _, c := range value {
+		if (c < '0' || c > '9') && c != '.' && c != 'e' && c != 'E' && c != '-' && c != '+' {
+			return 0, false
+		}
+	}
+	number, err := strconv.ParseFloat(sign+value, 64)
+	if err != nil || math.IsInf(number, 0) {
+		return 0, false
+	}
+	return number, true
+}
+
+// tableValue converts a cell to the value stored for its column type
+func tableValue(cell, columnType string) interface{} {
+	if strings.TrimSpace(cell) == "" {
+		return nil
+	}
+	if columnType == "TEXT" {
+		return cell
+	}
+	number, _ := parseTableNumber(cell)
+	if columnType == "INTEGER" {
+		return int64(number)
+	}
+	return number
+}
+
+// formatTableValue renders a scanned SQLite value
+func formatTableValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// quoteIdentifier quotes a SQL identifier
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,255 @@
+package lilrag
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const testSpendCSV = `Vendor,Quarter,Amount,ZIP
+Acme,Q3,"$1,200.50",02139
+Globex,Q3,300,94105
+Acme,Q2,99,02139
+`
+
+func setupTableStorage(t *testing.T) *SQLiteStorage {
+	t.Helper()
+
+	storage, tempDir := setupTestStorage(t)
+	t.Cleanup(func() { os.RemoveAll(tempDir) })
+	if err := storage.Initialize(); err != nil {
+		if strings.Contains(err.Error(), "sqlite-vec extension not available") {
+			t.Skip("Skipping test: sqlite-vec extension not available")
+		}
+		t.Fatalf("Failed to initialize storage: %v", err)
+	}
+	t.Cleanup(func() { storage.Close() })
+	return storage
+}
+
+func TestSQLiteStorage_QueryTables(t *testing.T) {
+	storage := setupTableStorage(t)
+	ctx := context.Background()
+
+	filePath := writeTestFile(t, "spend.csv", testSpendCSV)
+	write := func(w TableWriter) error { return NewCSVParser().StreamTables(filePath, w) }
+	if err := storage.StoreTables(ctx, "spend-2024", write); err != nil {
+		t.Fatalf("Failed to store tables: %v", err)
+	}
+
+	infos, err := storage.ListTables(ctx, "spend-2024")
+	if err != nil || len(infos) != 1 {
+		t.Fatalf("Expected one table, got %+v (%v)", infos, err)
+	}
+	types := make(map[string]string)
+	for _, column := range infos[0].Columns {
+		types[column.Name] = column.Type
+	}
+	if infos[0].Name != "sheet_spend_2024_csv" || infos[0].RowCount != 3 || types["amount"] != "REAL" ||
+		types["vendor"] != "TEXT" || types["zip"] != "TEXT" {
+		t.Errorf("Unexpected table %+v", infos[0])
+	}
+
+	result, err := storage.QueryTables(ctx,
+		"SELECT vendor, SUM(amount) AS total FROM sheet_spend_2024_csv WHERE quarter = 'Q3' "+
+			"GROUP BY vendor ORDER BY total DESC;", 10)
+	if err != nil {
+		t.Fatalf("Failed to query tables: %v", err)
+	}
+	if len(result.Rows) != 2 || result.Rows[0][0] != "Acme" || result.Rows[0][1] != "1200.5" ||
+		len(result.Tables) != 1 || result.Tables[0].Sheet != "CSV" {
+		t.Errorf("Unexpected result %+v", result)
+	}
+
+	result, err = storage.QueryTables(ctx, "SELECT _row, vendor FROM sheet_spend_2024_csv ORDER BY _row", 2)
+	if err != nil || len(result.Rows) != 2 || !result.Truncated || result.Rows[0][0] != "1" {
+		t.Errorf("Expected the row limit to truncate the result, got %+v (%v)", result, err)
+	}
+
+	for _, query := range []string{
+		"DELETE FROM sheet_spend_2024_csv",
+		"SELECT 1; DROP TABLE sheet_spend_2024_csv",
+		"SELECT * FROM documents",
+		"SELECT name FROM sqlite_master",
+		"WITH t AS (SELECT * FROM chunks) SELECT * FROM t",
+		"PRAGMA table_info(documents)",
+		"SELECT * FROM pragma_table_info('documents')",
+	} {
+		if _, err := storage.QueryTables(ctx, query, 10); err == nil {
+			t.Errorf("Expected %q to be rejected", query)
+		}
+	}
+
+	// Re-storing replaces the tables, and passing none removes them
+	noTables := func(TableWriter) error { return nil }
+	if err := storage.StoreTables(ctx, "spend-2024", noTables); err != nil {
+		t.Fatalf("Failed to remove tables: %v", err)
+	}
+	if _, err := storage.QueryTables(ctx, "SELECT * FROM sheet_spend_2024_csv", 10); err == nil {
+		t.Error("Expected the dropped table to be gone")
+	}
+}
+
+// sampledTableWriter samples the heap after every row written to a TableWriter
+type sampledTableWriter struct {
+	TableWriter
+	heap *heapPeak
+}
+
+func (w sampledTableWriter) WriteRow(row TableRow) error {
+	w.heap.sample()
+	return w.TableWriter.WriteRow(row)
+}
+
+func TestSQLiteStorage_StoreTablesLargeFile(t *testing.T) {
+	storage := setupTableStorage(t)
+	ctx := context.Background()
+
+	const rows = 100000
+	filePath := writeLargeCSV(t, rows)
+
+	heap := newHeapPeak()
+	err := storage.StoreTables(ctx, "large", func(w TableWriter) error {
+		return NewCSVParser().StreamTables(filePath, sampledTableWriter{TableWriter: w, heap: heap})
+	})
+	if err != nil {
+		t.Fatalf("Failed to store tables: %v", err)
+	}
+	if heap.peak > maxStreamingHeap {
+		t.Errorf("Heap grew by %d bytes while storing %d rows", heap.peak, rows)
+	}
+
+	infos, err := storage.ListTables(ctx, "large")
+	if err != nil || len(infos) != 1 || infos[0].RowCount != rows || infos[0].Columns[3].Type != "REAL" {
+		t.Fatalf("Unexpected tables %+v (%v)", infos, err)
+	}
+	result, err := storage.QueryTables(ctx, "SELECT COUNT(*), MAX(id) FROM sheet_large_csv WHERE region = 'Region 3'", 1)
+	if err != nil || len(result.Rows) != 1 || result.Rows[0][1] != "99999" {
+		t.Errorf("Unexpected result %+v (%v)", result, err)
+	}
+}
+
+func TestXLSXParser_ParseTables(t *testing.T) {
+	f := excelize.NewFile()
+	defer f.Close()
+	rows := [][]interface{}{
+		{},
+		{"Vendor", "", "Amount"},
+		{"Acme", "net 30", 1200},
+		{},
+		{"Globex", "", 300},
+	}
+	for i, row := range rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			t.Fatalf("Failed to name cell: %v", err)
+		}
+		if err := f.SetSheetRow("Sheet1", cell, &row); err != nil {
+			t.Fatalf("Failed to write row: %v", err)
+		}
+	}
+	filePath := filepath.Join(t.TempDir(), "spend.xlsx")
+	if err := f.SaveAs(filePath); err != nil {
+		t.Fatalf("Failed to save workbook: %v", err)
+	}
+
+	tables, err := NewDocumentHandler(NewTextChunker(256, 38)).ParseFileTables(filePath)
+	if err != nil {
+		t.Fatalf("Failed to parse tables: %v", err)
+	}
+	if len(tables) != 1 {
+		t.Fatalf("Expected one table, got %+v", tables)
+	}
+	table := tables[0]
+	if strings.Join(table.Header, ",") != "Vendor,B,Amount" || len(table.Rows) != 2 ||
+		table.Rows[0].Number != 3 || table.Rows[1].Number != 5 {
+		t.Errorf("Unexpected table %+v", table)
+	}
+}
+
+func TestLilRag_ChatTableQuery(t *testing.T) {
+	storage := setupTableStorage(t)
+
+	var answerPrompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode chat request: %v", err)
+		}
+		system := req.Messages[0].Content
+
+		reply := req.Messages[1].Content
+		switch {
+		case strings.Contains(system, "translate questions about spreadsheets"):
+			if !strings.Contains(system, "amount REAL") || !strings.Contains(system, "Globex") {
+				t.Errorf("Expected columns and sample rows in the table prompt, got:\n%s", system)
+			}
+			reply = "```sql\nSELECT SUM(amount) AS total FROM sheet_spend_2024_csv WHERE vendor LIKE 'acme' " +
+				"AND quarter = 'Q3'\n```"
+		case strings.Contains(system, "RELEVANT DOCUMENTS"):
+			answerPrompt = system
+			reply = "Acme spent 1200.5 in Q3 [spend-2024] (sheet CSV)."
+		}
+		if err := json.NewEncoder(w).Encode(ChatResponse{Message: ChatMessage{Role: "assistant", Content: reply}}); err != nil {
+			t.Errorf("Failed to encode chat response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	lilRag := &LilRag{
+		storage:         storage,
+		embedder:        NewMockEmbedder(),
+		chunker:         NewTextChunker(256, 38),
+		documentHandler: NewDocumentHandler(NewTextChunker(256, 38)),
+		chatClient:      NewOllamaChatClient(server.URL, "test"),
+		config:          &Config{TableQuery: true, TableQueryMaxRows: DefaultTableQueryRows},
+	}
+
+	ctx := context.Background()
+	if err := lilRag.IndexFile(ctx, writeTestFile(t, "spend.csv", testSpendCSV), "spend-2024"); err != nil {
+		t.Fatalf("Failed to index CSV: %v", err)
+	}
+
+	_, sources, err := lilRag.Chat(ctx, "What was the total spend for vendor Acme in Q3?", 5)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if len(sources) == 0 || sources[0].Metadata["chunk_type"] != "table_query" || sources[0].ID != "spend-2024" ||
+		sources[0].Metadata["sheet"] != "CSV" || !strings.Contains(sources[0].Text, "1200.5") {
+		t.Fatalf("Expected the table query result as the first source, got %+v", sources)
+	}
+	if !strings.Contains(answerPrompt, "SQL result from sheet CSV") || !strings.Contains(answerPrompt, "(sheet Q3)") {
+		t.Errorf("Expected the SQL result and citation instructions in the prompt, got:\n%s", answerPrompt)
+	}
+
+	// Deleting the document drops its tables
+	if err := lilRag.DeleteDocument(ctx, "spend-2024"); err != nil {
+		t.Fatalf("Failed to delete document: %v", err)
+	}
+	if tables, err := storage.ListTables(ctx); err != nil || len(tables) != 0 {
+		t.Errorf("Expected no tables after deletion, got %+v (%v)", tables, err)
+	}
+}
+
+func TestExtractTableQuery(t *testing.T) {
+	tests := map[string]string{
+		"SELECT 1":                            "SELECT 1",
+		"```sql\nSELECT vendor FROM t\n```":   "SELECT vendor FROM t",
+		"Here you go:\n```\nSELECT 2\n```":    "SELECT 2",
+		"NONE":                                "",
+		"none.":                               "",
+		"  SELECT COUNT(*) FROM sheet_x  \n ": "SELECT COUNT(*) FROM sheet_x",
+	}
+	for reply, expected := range tests {
+		if got := extractTableQuery(reply); got != expected {
+			t.Errorf("extractTableQuery(%q) = %q, expected %q", reply, got, expected)
+		}
+	}
+}
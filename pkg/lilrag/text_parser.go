@@ -1,10 +1,11 @@
 package lilrag
 
 import (
+	"context"
 	"os"
 )
 
-// TextParser handles plain text and markdown files
+// TextParser handles plain text files
 type TextParser struct {
 	chunker *TextChunker
 }
@@ -14,6 +15,11 @@ func NewTextParser() *TextParser {
 	return &TextParser{}
 }
 
+// NewTextParserWithChunker creates a text parser that uses the given chunker
+func NewTextParserWithChunker(chunker *TextChunker) *TextParser {
+	return &TextParser{chunker: chunker}
+}
+
 // Parse extracts text content from a text file
 func (tp *TextParser) Parse(filePath string) (string, error) {
 	content, err := os.ReadFile(filePath)
@@ -24,7 +30,12 @@ func (tp *TextParser) Parse(filePath string) (string, error) {
 }
 
 // ParseWithChunks extracts and chunks content from a text file
-func (tp *TextParser) ParseWithChunks(filePath, _ string) ([]Chunk, error) {
+func (tp *TextParser) ParseWithChunks(filePath, documentID string) ([]Chunk, error) {
+	return tp.ParseWithChunksContext(context.Background(), filePath, documentID)
+}
+
+// ParseWithChunksContext is ParseWithChunks with ctx passed to the chunker
+func (tp *TextParser) ParseWithChunksContext(ctx context.Context, filePath, _ string) ([]Chunk, error) {
 	content, err := tp.Parse(filePath)
 	if err != nil {
 		return nil, err
@@ -35,12 +46,12 @@ func (tp *TextParser) ParseWithChunks(filePath, _ string) ([]Chunk, error) {
 		tp.chunker = NewTextChunker(256, 38) // Use optimized defaults
 	}
 
-	return tp.chunker.ChunkText(content), nil
+	return tp.chunker.ChunkTextContext(ctx, content), nil
 }
 
 // SupportedExtensions returns the file extensions this parser supports
 func (tp *TextParser) SupportedExtensions() []string {
-	return []string{".txt", ".md", ".text"}
+	return []string{".txt", ".text"}
 }
 
 // GetDocumentType returns the type of documents this parser handles
@@ -0,0 +1,461 @@
+package lilrag
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Document metadata recording the local file a document was indexed from
+const (
+	MetadataFileHash     = "file_sha256"   // SHA-256 of the file content
+	MetadataFileModified = "file_modified" // Modification time of the file, RFC 3339
+)
+
+const (
+	// DefaultWatchInterval is how often a watched directory is scanned for changes
+	DefaultWatchInterval = 2 * time.Second
+	// DefaultWatchDebounce is how long a changed file must stay unchanged before it is indexed
+	DefaultWatchDebounce = time.Second
+)
+
+// Kinds of changes a watcher applies to the index
+const (
+	WatchEventIndexed = "indexed" // A new file was indexed
+	WatchEventUpdated = "updated" // A file with new content was indexed again
+	WatchEventMoved   = "moved"   // A file was moved and its documents point at the new path
+	WatchEventDeleted = "deleted" // A file was removed and its documents were deleted
+	WatchEventFailed  = "failed"  // A change could not be applied
+)
+
+// WatchOptions selects the files of a watched directory like DirectoryOptions and controls
+// how often the directory is scanned
+type WatchOptions struct {
+	DirectoryOptions
+	Interval time.Duration // Time between scans, DefaultWatchInterval if zero
+	Debounce time.Duration // Time a file must stay unchanged before it is indexed, DefaultWatchDebounce if zero
+	// OnEvent is called for every change applied to the index
+	OnEvent func(WatchEvent)
+}
+
+// WatchEvent describes a change of a watched directory that was applied to the index
+type WatchEvent struct {
+	Type    string `json:"type"`
+	Path    string `json:"path"`               // Slash-separated path relative to the directory
+	OldPath string `json:"old_path,omitempty"` // Previous path of a moved file
+	ID      string `json:"id,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// sourcePathUpdater is implemented by storage backends that can point a document at a
+// new source file without indexing it again
+type sourcePathUpdater interface {
+	UpdateDocumentSourcePath(ctx context.Context, documentID, sourcePath string) error
+}
+
+// watchedFile is the state of a file seen by a directory scan
+type watchedFile struct {
+	size    int64
+	modTime time.Time
+}
+
+func (f watchedFile) equal(other watchedFile) bool {
+	return f.size == other.size && f.modTime.Equal(other.modTime)
+}
+
+// trackedFile is an indexed file of a watched directory
+type trackedFile struct {
+	id   string
+	hash string
+}
+
+// directoryWatcher keeps the documents indexed from a directory in sync with its files
+type directoryWatcher struct {
+	rag     *LilRag
+	root    string
+	opts    WatchOptions
+	files   map[string]watchedFile // Files seen by the last scan
+	tracked map[string]trackedFile // Indexed files by relative path
+	usedIDs map[string]bool
+	pending map[string]time.Time // Time a file was last seen changing
+}
+
+// Watch indexes the files below root like IndexDirectory and keeps their documents in sync
+// until ctx is done. Files already indexed from root with the same content are not indexed
+// again. The directory is scanned every opts.Interval; a new or changed file is indexed
+// once it has stayed unchanged for opts.Debounce, a removed file's documents are deleted,
+// and a file that moves within the directory keeps its documents without being embedded
+// again.
+func (m *LilRag) Watch(ctx context.Context, root string, opts WatchOptions) error {
+	if m.documentHandler == nil {
+		return fmt.Errorf("document handler not initialized")
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return fmt.Errorf("failed to read directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("not a directory: %s", root)
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return fmt.Errorf("failed to resolve directory: %w", err)
+	}
+	if _, err := parseGlobs(append(append([]string(nil), opts.Include...), opts.Exclude...)); err != nil {
+		return err
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultWatchInterval
+	}
+	if opts.Debounce < 0 {
+		opts.Debounce = 0
+	} else if opts.Debounce == 0 {
+		opts.Debounce = DefaultWatchDebounce
+	}
+
+	w := &directoryWatcher{
+		rag:     m,
+		root:    absRoot,
+		opts:    opts,
+		tracked: make(map[string]trackedFile),
+		usedIDs: make(map[string]bool),
+		pending: make(map[string]time.Time),
+	}
+	if err := w.loadTracked(ctx); err != nil {
+		return err
+	}
+
+	// The first scan brings the index up to date without waiting for files to settle
+	files, err := w.scan(ctx)
+	if err != nil {
+		return w.stopped(ctx, err)
+	}
+	w.files = files
+	changed := make([]string, 0, len(files)+len(w.tracked))
+	for rel := range files {
+		changed = append(changed, rel)
+	}
+	for rel := range w.tracked {
+		if _, ok := files[rel]; !ok {
+			changed = append(changed, rel)
+		}
+	}
+	w.apply(ctx, changed)
+
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			files, err := w.scan(ctx)
+			if err != nil {
+				return w.stopped(ctx, err)
+			}
+			w.apply(ctx, w.settled(files, now))
+		}
+	}
+}
+
+// stopped returns nil when a scan failed because ctx is done, and err otherwise
+func (w *directoryWatcher) stopped(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	return fmt.Errorf("failed to scan directory: %w", err)
+}
+
+// loadTracked finds the documents indexed from files below the watched directory
+func (w *directoryWatcher) loadTracked(ctx context.Context) error {
+	docs, err := w.rag.ListDocuments(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list documents: %w", err)
+	}
+	for _, doc := range docs {
+		if doc.SourcePath == "" {
+			continue
+		}
+		rel, ok := w.relPath(doc.SourcePath)
+		if !ok {
+			continue
+		}
+		id := doc.ID
+		if archiveID := doc.Metadata["archive_id"]; archiveID != "" {
+			id = archiveID
+		}
+		w.tracked[rel] = trackedFile{id: id, hash: doc.Metadata[MetadataFileHash]}
+		w.usedIDs[id] = true
+	}
+	return nil
+}
+
+// relPath returns the slash-separated path of a source file relative to the watched
+// directory, or false if it lies outside of it
+func (w *directoryWatcher) relPath(sourcePath string) (string, bool) {
+	absPath, err := filepath.Abs(sourcePath)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(w.root, absPath)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// scan returns the size and modification time of the regular files the watch selects
+func (w *directoryWatcher) scan(ctx context.Context) (map[string]watchedFile, error) {
+	files := make(map[string]watchedFile)
+	err := walkDirectory(ctx, w.root, w.opts.DirectoryOptions, func(rel, _ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil // Removed since it was listed
+		}
+		files[rel] = watchedFile{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	return files, err
+}
+
+// settled records the files that changed since the last scan and returns those that
+// have not changed for the debounce interval
+func (w *directoryWatcher) settled(files map[string]watchedFile, now time.Time) []string {
+	for rel, file := range files {
+		if previous, ok := w.files[rel]; !ok || !previous.equal(file) {
+			w.pending[rel] = now
+		}
+	}
+	for rel := range w.files {
+		if _, ok := files[rel]; !ok {
+			w.pending[rel] = now
+		}
+	}
+	w.files = files
+
+	var ready []string
+	for rel, changed := range w.pending {
+		if now.Sub(changed) >= w.opts.Debounce {
+			ready = append(ready, rel)
+			delete(w.pending, rel)
+		}
+	}
+	return ready
+}
+
+// apply brings the documents of changed paths up to date. A removed file whose content
+// appears at a new path is treated as moved.
+func (w *directoryWatcher) apply(ctx context.Context, changed []string) {
+	sort.Strings(changed)
+
+	var added, removed []string
+	hashes := make(map[string]string)
+	for _, rel := range changed {
+		if ctx.Err() != nil {
+			return
+		}
+		tracked, isTracked := w.tracked[rel]
+		if _, present := w.files[rel]; !present {
+			// Files that still exist but are no longer selected keep their documents
+			if _, err := os.Lstat(w.absPath(rel)); isTracked && os.IsNotExist(err) {
+				removed = append(removed, rel)
+			}
+			continue
+		}
+
+		hash, err := hashFile(w.absPath(rel))
+		if err != nil {
+			w.emit(WatchEvent{Type: WatchEventFailed, Path: rel, ID: tracked.id, Error: err.Error()})
+			continue
+		}
+		switch {
+		case !isTracked:
+			hashes[rel] = hash
+			added = append(added, rel)
+		case tracked.hash != hash:
+			w.index(ctx, rel, tracked.id, hash, WatchEventUpdated)
+		}
+	}
+
+	for _, rel := range added {
+		if ctx.Err() != nil {
+			return
+		}
+		if i := w.movedFrom(removed, hashes[rel]); i >= 0 {
+			from := removed[i]
+			removed = append(removed[:i], removed[i+1:]...)
+			w.move(ctx, from, rel)
+			continue
+		}
+		id := uniqueDirectoryFileID(w.opts.IDPrefix, rel, w.usedIDs)
+		w.index(ctx, rel, id, hashes[rel], WatchEventIndexed)
+	}
+
+	for _, rel := range removed {
+		if ctx.Err() != nil {
+			return
+		}
+		w.remove(ctx, rel)
+	}
+}
+
+// movedFrom returns the index of the removed file with the given content hash, or -1
+func (w *directoryWatcher) movedFrom(removed []string, hash string) int {
+	for i, rel := range removed {
+		if tracked := w.tracked[rel]; tracked.hash != "" && tracked.hash == hash {
+			return i
+		}
+	}
+	return -1
+}
+
+// index indexes a file as the document id; unsupported files are skipped
+func (w *directoryWatcher) index(ctx context.Context, rel, id, hash, eventType string) {
+	filePath := w.absPath(rel)
+	if w.rag.documentHandler.DetectDocumentType(filePath) == DocumentTypeUnknown {
+		return
+	}
+	if err := w.rag.IndexFile(ctx, filePath, id); err != nil {
+		if ctx.Err() == nil {
+			w.emit(WatchEvent{Type: WatchEventFailed, Path: rel, ID: id, Error: err.Error()})
+		}
+		return
+	}
+	w.tracked[rel] = trackedFile{id: id, hash: hash}
+	w.usedIDs[id] = true
+	w.emit(WatchEvent{Type: eventType, Path: rel, ID: id})
+}
+
+// move points the documents of a moved file at its new path. Storage that cannot update
+// source paths gets the file indexed again under the same ID.
+func (w *directoryWatcher) move(ctx context.Context, from, to string) {
+	tracked := w.tracked[from]
+	updater, ok := w.rag.storage.(sourcePathUpdater)
+	if !ok {
+		delete(w.tracked, from)
+		w.index(ctx, to, tracked.id, tracked.hash, WatchEventMoved)
+		return
+	}
+
+	ids, err := w.documentsFrom(ctx, from, tracked.id)
+	if err == nil {
+		for _, id := range ids {
+			if err = updater.UpdateDocumentSourcePath(ctx, id, w.absPath(to)); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		w.emit(WatchEvent{Type: WatchEventFailed, Path: to, OldPath: from, ID: tracked.id, Error: err.Error()})
+		return
+	}
+	delete(w.tracked, from)
+	w.tracked[to] = tracked
+	w.emit(WatchEvent{Type: WatchEventMoved, Path: to, OldPath: from, ID: tracked.id})
+}
+
+// remove deletes the documents of a removed file
+func (w *directoryWatcher) remove(ctx context.Context, rel string) {
+	tracked := w.tracked[rel]
+	ids, err := w.documentsFrom(ctx, rel, tracked.id)
+	if err == nil {
+		for _, id := range ids {
+			if err = w.rag.DeleteDocument(ctx, id); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		w.emit(WatchEvent{Type: WatchEventFailed, Path: rel, ID: tracked.id, Error: err.Error()})
+		return
+	}
+	delete(w.tracked, rel)
+	delete(w.usedIDs, tracked.id)
+	w.emit(WatchEvent{Type: WatchEventDeleted, Path: rel, ID: tracked.id})
+}
+
+// documentsFrom returns the IDs of the documents indexed from a file, such as the entries
+// of an archive, falling back to the ID the file was indexed as
+func (w *directoryWatcher) documentsFrom(ctx context.Context, rel, id string) ([]string, error) {
+	docs, err := w.rag.ListDocuments(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	var ids []string
+	for _, doc := range docs {
+		if docRel, ok := w.relPath(doc.SourcePath); doc.SourcePath != "" && ok && docRel == rel {
+			ids = append(ids, doc.ID)
+		}
+	}
+	if len(ids) == 0 {
+		ids = []string{id}
+	}
+	return ids, nil
+}
+
+func (w *directoryWatcher) absPath(rel string) string {
+	return filepath.Join(w.root, filepath.FromSlash(rel))
+}
+
+func (w *directoryWatcher) emit(event WatchEvent) {
+	if w.opts.OnEvent != nil {
+		w.opts.OnEvent(event)
+	}
+}
+
+// sourceFileMetadata returns the document metadata recording the content hash and
+// modification time of a local file
+func sourceFileMetadata(filePath string) (map[string]string, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	hash, err := hashFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		MetadataFileHash:     hash,
+		MetadataFileModified: info.ModTime().UTC().Format(time.RFC3339Nano),
+	}, nil
+}
+
+// hashFile returns the hex-encoded SHA-256 of a file's content
+func hashFile(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// mergeMetadata adds the entries of extra to metadata, allocating it when nil
+func mergeMetadata(metadata, extra map[string]string) map[string]string {
+	if len(extra) == 0 {
+		return metadata
+	}
+	if metadata == nil {
+		metadata = make(map[string]string, len(extra))
+	}
+	for key, value := range extra {
+		metadata[key] = value
+	}
+	return metadata
+}
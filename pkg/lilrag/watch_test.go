@@ -0,0 +1,216 @@
+package lilrag
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingEmbedder counts the texts embedded by the wrapped embedder
+type countingEmbedder struct {
+	Embedder
+	calls atomic.Int64
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	e.calls.Add(1)
+	return e.Embedder.Embed(ctx, text)
+}
+
+// lockedStorage guards the mock storage that a watcher writes while the test reads it
+type lockedStorage struct {
+	*MockStorage
+	mu sync.Mutex
+}
+
+func (s *lockedStorage) IndexChunks(
+	ctx context.Context, documentID, text string, chunks []Chunk, embeddings [][]float32,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.MockStorage.IndexChunks(ctx, documentID, text, chunks, embeddings)
+}
+
+func (s *lockedStorage) IndexChunksWithMetadata(
+	ctx context.Context, documentID, text string, chunks []Chunk, embeddings [][]float32,
+	originalFilePath, docType string, metadata map[string]string,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.MockStorage.IndexChunksWithMetadata(
+		ctx, documentID, text, chunks, embeddings, originalFilePath, docType, metadata)
+}
+
+func (s *lockedStorage) UpdateDocumentSourcePath(ctx context.Context, documentID, sourcePath string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.MockStorage.UpdateDocumentSourcePath(ctx, documentID, sourcePath)
+}
+
+func (s *lockedStorage) ListDocuments(ctx context.Context) ([]DocumentInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.MockStorage.ListDocuments(ctx)
+}
+
+func (s *lockedStorage) GetDocumentByID(ctx context.Context, documentID string) (*DocumentInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.MockStorage.GetDocumentByID(ctx, documentID)
+}
+
+func (s *lockedStorage) DeleteDocument(ctx context.Context, documentID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.MockStorage.DeleteDocument(ctx, documentID)
+}
+
+// document returns the indexed text and source path of a document
+func (s *lockedStorage) document(id string) (text, sourcePath string, exists bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	text, exists = s.documents[id]
+	return text, s.sourcePaths[id], exists
+}
+
+// startWatch runs Watch in the background and returns its events
+func startWatch(t *testing.T, lilRag *LilRag, root string) <-chan WatchEvent {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan WatchEvent, 100)
+	done := make(chan error, 1)
+	go func() {
+		done <- lilRag.Watch(ctx, root, WatchOptions{
+			Interval: 10 * time.Millisecond,
+			Debounce: 30 * time.Millisecond,
+			OnEvent:  func(event WatchEvent) { events <- event },
+		})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Watch returned an error: %v", err)
+		}
+	})
+	return events
+}
+
+// nextWatchEvent waits for the next event of a watcher
+func nextWatchEvent(t *testing.T, events <-chan WatchEvent) WatchEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a watch event")
+		return WatchEvent{}
+	}
+}
+
+func TestLilRag_Watch(t *testing.T) {
+	root := writeTestTree(t, map[string]string{
+		"guide.md":       "# Guide\n\nRun make install on a new laptop.",
+		"notes/faq.txt":  "Rotate the API keys every ninety days.",
+		"notes/skip.log": "Ignored log.",
+		".lilragignore":  "*.log\n",
+	})
+
+	storage := &lockedStorage{MockStorage: NewMockStorage()}
+	if err := storage.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize mock storage: %v", err)
+	}
+	embedder := &countingEmbedder{Embedder: NewMockEmbedder()}
+	lilRag := &LilRag{
+		storage:         storage,
+		embedder:        embedder,
+		chunker:         NewTextChunker(256, 38),
+		documentHandler: NewDocumentHandler(NewTextChunker(256, 38)),
+		config:          &Config{},
+	}
+
+	// Files indexed before the watch starts are not embedded again
+	if _, err := lilRag.IndexDirectory(context.Background(), root, DirectoryOptions{}); err != nil {
+		t.Fatalf("Failed to index directory: %v", err)
+	}
+	if storage.metadata["guide-md"][MetadataFileHash] == "" {
+		t.Errorf("Expected the file hash to be recorded, got %v", storage.metadata["guide-md"])
+	}
+	indexed := embedder.calls.Load()
+
+	if err := os.WriteFile(filepath.Join(root, "new.txt"), []byte("A new file to watch."), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	events := startWatch(t, lilRag, root)
+
+	if event := nextWatchEvent(t, events); event.Type != WatchEventIndexed || event.Path != "new.txt" ||
+		event.ID != "new-txt" {
+		t.Errorf("Expected new.txt to be indexed on start, got %+v", event)
+	}
+	if calls := embedder.calls.Load(); calls != indexed+1 {
+		t.Errorf("Expected only the new file to be embedded on start, got %d embeddings", calls-indexed)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "guide.md"), []byte("# Guide\n\nUse the installer."), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if event := nextWatchEvent(t, events); event.Type != WatchEventUpdated || event.ID != "guide-md" {
+		t.Errorf("Expected guide.md to be updated, got %+v", event)
+	}
+	if text, _, _ := storage.document("guide-md"); text != "# Guide\n\nUse the installer." {
+		t.Errorf("Expected the updated text to be indexed, got %q", text)
+	}
+
+	indexed = embedder.calls.Load()
+	moved := filepath.Join(root, "notes", "answers.txt")
+	if err := os.Rename(filepath.Join(root, "notes", "faq.txt"), moved); err != nil {
+		t.Fatalf("Failed to move file: %v", err)
+	}
+	event := nextWatchEvent(t, events)
+	if event.Type != WatchEventMoved || event.Path != "notes/answers.txt" || event.OldPath != "notes/faq.txt" ||
+		event.ID != "notes-faq-txt" {
+		t.Errorf("Expected notes/faq.txt to be moved, got %+v", event)
+	}
+	if calls := embedder.calls.Load(); calls != indexed {
+		t.Errorf("Expected a move not to embed, got %d embeddings", calls-indexed)
+	}
+	if _, sourcePath, _ := storage.document("notes-faq-txt"); sourcePath != moved {
+		t.Errorf("Expected the source path to be %s, got %s", moved, sourcePath)
+	}
+
+	if err := os.Remove(filepath.Join(root, "new.txt")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	if event := nextWatchEvent(t, events); event.Type != WatchEventDeleted || event.ID != "new-txt" {
+		t.Errorf("Expected new.txt to be deleted, got %+v", event)
+	}
+	if _, _, exists := storage.document("new-txt"); exists {
+		t.Error("Expected the document of the removed file to be deleted")
+	}
+
+	// Changes to ignored files and files with the same content are not applied
+	if err := os.WriteFile(filepath.Join(root, "notes", "skip.log"), []byte("Changed."), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(moved, []byte("Rotate the API keys every ninety days."), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	select {
+	case event := <-events:
+		t.Errorf("Expected no event, got %+v", event)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestLilRag_WatchRejectsFiles(t *testing.T) {
+	root := writeTestTree(t, map[string]string{"file.txt": "Not a directory."})
+	lilRag := &LilRag{documentHandler: NewDocumentHandler(NewTextChunker(256, 38)), config: &Config{}}
+
+	err := lilRag.Watch(context.Background(), filepath.Join(root, "file.txt"), WatchOptions{})
+	if err == nil {
+		t.Fatal("Expected watching a file to fail")
+	}
+}
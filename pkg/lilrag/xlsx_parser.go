@@ -7,7 +7,9 @@ import (
 	"github.com/xuri/excelize/v2"
 )
 
-// XLSXParser handles Excel .xlsx files
+// XLSXParser handles Excel .xlsx files. Sheets are read row by row with excelize's Rows
+// iterator, which reads worksheets larger than 16 MB from temporary files, so very large
+// workbooks are parsed with bounded memory.
 type XLSXParser struct {
 	chunker *TextChunker
 }
@@ -17,203 +19,380 @@ func NewXLSXParser() *XLSXParser {
 	return &XLSXParser{}
 }
 
-// Parse extracts text content from an XLSX file
-func (xp *XLSXParser) Parse(filePath string) (string, error) {
+// rowReader returns the next row of a sheet; ok is false after the last row
+type rowReader func() (row []string, ok bool, err error)
+
+// xlsxRows reads the rows of an excelize sheet iterator
+func xlsxRows(rows *excelize.Rows) rowReader {
+	return func() ([]string, bool, error) {
+		if !rows.Next() {
+			return nil, false, rows.Error()
+		}
+		row, err := rows.Columns()
+		if err != nil {
+			return nil, false, err
+		}
+		return row, true, nil
+	}
+}
+
+// sliceRows reads rows that are already in memory
+func sliceRows(rows [][]string) rowReader {
+	next := 0
+	return func() ([]string, bool, error) {
+		if next == len(rows) {
+			return nil, false, nil
+		}
+		next++
+		return rows[next-1], true, nil
+	}
+}
+
+// eachSheet calls fn with a row reader for every sheet of an XLSX file. Sheets whose
+// rows cannot be opened get a reader without rows.
+func eachSheet(filePath string, fn func(sheetName string, next rowReader) error) error {
 	f, err := excelize.OpenFile(filePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to open XLSX file: %w", err)
+		return fmt.Errorf("failed to open XLSX file: %w", err)
 	}
 	defer f.Close()
 
-	var content strings.Builder
-
-	// Get all sheet names
-	sheetNames := f.GetSheetList()
-
-	for _, sheetName := range sheetNames {
-		content.WriteString(fmt.Sprintf("Sheet: %s\n", sheetName))
-		content.WriteString("=" + strings.Repeat("=", len(sheetName)+6) + "\n\n")
+	for _, sheetName := range f.GetSheetList() {
+		rows, err := f.Rows(sheetName)
+		if err != nil {
+			if err := fn(sheetName, sliceRows(nil)); err != nil {
+				return err
+			}
+			continue
+		}
 
-		// Get all rows from the sheet
-		rows, err := f.GetRows(sheetName)
+		err = fn(sheetName, xlsxRows(rows))
+		rows.Close()
 		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Parse extracts text content from an XLSX file
+func (xp *XLSXParser) Parse(filePath string) (string, error) {
+	var content strings.Builder
+	err := eachSheet(filePath, func(sheetName string, next rowReader) error {
+		if err := xp.writeSheetRows(&content, sheetName, next); err != nil {
 			content.WriteString(fmt.Sprintf("Error reading sheet %s: %v\n\n", sheetName, err))
-			continue
 		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
 
-		// Process rows
-		for rowIndex, row := range rows {
-			if len(row) == 0 {
-				continue // Skip empty rows
-			}
+	return content.String(), nil
+}
 
-			rowNum := rowIndex + 1
-			content.WriteString(fmt.Sprintf("Row %d: ", rowNum))
+// writeSheet appends a sheet's rows as "Row N: A: value | B: value" lines
+func (xp *XLSXParser) writeSheet(content *strings.Builder, sheetName string, rows [][]string) {
+	// Rows in memory cannot fail to read
+	_ = xp.writeSheetRows(content, sheetName, sliceRows(rows))
+}
 
-			// Join non-empty cells
-			var cells []string
-			for colIndex, cell := range row {
-				if strings.TrimSpace(cell) != "" {
-					columnName := xp.getColumnName(colIndex)
-					cells = append(cells, fmt.Sprintf("%s: %s", columnName, cell))
-				}
-			}
+// writeSheetRows appends the rows returned by next as "Row N: A: value | B: value" lines
+func (xp *XLSXParser) writeSheetRows(content *strings.Builder, sheetName string, next rowReader) error {
+	content.WriteString(fmt.Sprintf("Sheet: %s\n", sheetName))
+	content.WriteString("=" + strings.Repeat("=", len(sheetName)+6) + "\n\n")
+
+	// Process rows
+	for rowNum := 1; ; rowNum++ {
+		row, ok, err := next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		if len(row) == 0 {
+			continue // Skip empty rows
+		}
+
+		content.WriteString(fmt.Sprintf("Row %d: ", rowNum))
 
-			if len(cells) > 0 {
-				content.WriteString(strings.Join(cells, " | "))
+		// Join non-empty cells
+		var cells []string
+		for colIndex, cell := range row {
+			if strings.TrimSpace(cell) != "" {
+				columnName := xp.getColumnName(colIndex)
+				cells = append(cells, fmt.Sprintf("%s: %s", columnName, cell))
 			}
-			content.WriteString("\n")
 		}
 
+		if len(cells) > 0 {
+			content.WriteString(strings.Join(cells, " | "))
+		}
 		content.WriteString("\n")
 	}
 
-	return content.String(), nil
+	content.WriteString("\n")
+	return nil
 }
 
 // ParseWithChunks extracts and chunks content from an XLSX file
-func (xp *XLSXParser) ParseWithChunks(filePath, _ string) ([]Chunk, error) {
-	f, err := excelize.OpenFile(filePath)
+func (xp *XLSXParser) ParseWithChunks(filePath, documentID string) ([]Chunk, error) {
+	var chunks []Chunk
+	err := xp.StreamChunks(filePath, documentID, func(chunk Chunk) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to open XLSX file: %w", err)
+		return nil, err
 	}
-	defer f.Close()
 
+	return chunks, nil
+}
+
+// StreamChunks reads an XLSX file one row at a time and passes each chunk to emit as soon
+// as it is complete, so memory does not grow with the size of the sheets
+func (xp *XLSXParser) StreamChunks(filePath, _ string, emit func(Chunk) error) error {
 	// Use a default chunker if none provided
 	if xp.chunker == nil {
 		xp.chunker = NewTextChunker(200, 30) // Smaller chunks for tabular data
 	}
 
+	out := &chunkEmitter{emit: emit}
+	return eachSheet(filePath, func(sheetName string, next rowReader) error {
+		err := xp.streamSheet(out, fmt.Sprintf("Excel Sheet: %s", sheetName), sheetName, next, "xlsx")
+		if err != nil {
+			return fmt.Errorf("failed to read sheet %s: %w", sheetName, err)
+		}
+		return nil
+	})
+}
+
+// chunkSheet turns a sheet's rows into a sheet header chunk, a column header chunk and
+// batches of rows labelled with their column headers. Chunk types start with typePrefix.
+func (xp *XLSXParser) chunkSheet(
+	sheetHeaderText, sheetName string,
+	rows [][]string,
+	typePrefix string,
+	chunkIndex int,
+) []Chunk {
 	var chunks []Chunk
-	chunkIndex := 0
-
-	// Get all sheet names
-	sheetNames := f.GetSheetList()
-
-	for _, sheetName := range sheetNames {
-		// Create sheet header chunk
-		sheetHeaderText := fmt.Sprintf("Excel Sheet: %s", sheetName)
-		headerChunk := Chunk{
-			Text:       sheetHeaderText,
-			Index:      chunkIndex,
-			StartPos:   0,
-			EndPos:     len(sheetHeaderText),
-			TokenCount: xp.chunker.EstimateTokenCount(sheetHeaderText),
-			ChunkType:  "xlsx_sheet_header",
-		}
-		chunks = append(chunks, headerChunk)
-		chunkIndex++
-
-		// Get all rows from the sheet
-		rows, err := f.GetRows(sheetName)
+	out := &chunkEmitter{next: chunkIndex, emit: func(chunk Chunk) error {
+		chunks = append(chunks, chunk)
+		return nil
+	}}
+
+	// Rows in memory cannot fail to read and the chunks are only collected
+	_ = xp.streamSheet(out, sheetHeaderText, sheetName, sliceRows(rows), typePrefix)
+	return chunks
+}
+
+// streamSheet emits a sheet header chunk, a column header chunk and batches of rows
+// labelled with their column headers while reading the sheet's rows
+func (xp *XLSXParser) streamSheet(
+	out *chunkEmitter,
+	sheetHeaderText, sheetName string,
+	next rowReader,
+	typePrefix string,
+) error {
+	// Create sheet header chunk
+	err := out.add(Chunk{
+		Text:       sheetHeaderText,
+		StartPos:   0,
+		EndPos:     len(sheetHeaderText),
+		TokenCount: xp.chunker.EstimateTokenCount(sheetHeaderText),
+		ChunkType:  typePrefix + "_sheet_header",
+	})
+	if err != nil {
+		return err
+	}
+
+	// Detect header row (usually first non-empty row)
+	var headerRow []string
+	rowNum := 0
+	for {
+		row, ok, err := next()
 		if err != nil {
-			continue // Skip sheets with errors
+			return err
+		}
+		if !ok {
+			return nil
 		}
+		rowNum++
+		if len(row) > 0 && xp.hasNonEmptyData(row) {
+			headerRow = row
+			break
+		}
+	}
 
-		// Detect header row (usually first non-empty row)
-		var headerRow []string
-		var dataStartIndex int
+	// Create header chunk
+	headerText := fmt.Sprintf("Sheet %s Headers: %s", sheetName, strings.Join(headerRow, " | "))
+	err = out.add(Chunk{
+		Text:       headerText,
+		StartPos:   0,
+		EndPos:     len(headerText),
+		TokenCount: xp.chunker.EstimateTokenCount(headerText),
+		ChunkType:  typePrefix + "_headers",
+	})
+	if err != nil {
+		return err
+	}
 
-		for i, row := range rows {
-			if len(row) > 0 && xp.hasNonEmptyData(row) {
-				headerRow = row
-				dataStartIndex = i + 1
-				break
-			}
+	// Process data rows in chunks
+	batch := &rowBatcher{
+		out:       out,
+		chunker:   xp.chunker,
+		maxTokens: 150, // Smaller chunks for spreadsheet data
+		chunkType: typePrefix + "_data",
+	}
+	for {
+		row, ok, err := next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		rowNum++
+
+		if !xp.hasNonEmptyData(row) {
+			continue // Skip empty rows
 		}
 
-		// Create header chunk if we found headers
-		if len(headerRow) > 0 {
-			headerText := fmt.Sprintf("Sheet %s Headers: %s", sheetName, strings.Join(headerRow, " | "))
-			headerChunk := Chunk{
-				Text:       headerText,
-				Index:      chunkIndex,
-				StartPos:   0,
-				EndPos:     len(headerText),
-				TokenCount: xp.chunker.EstimateTokenCount(headerText),
-				ChunkType:  "xlsx_headers",
+		rowText := fmt.Sprintf("Row %d: ", rowNum)
+
+		// Create meaningful row representation
+		var cells []string
+		for colIndex, cell := range row {
+			if strings.TrimSpace(cell) != "" {
+				var columnName string
+				if colIndex < len(headerRow) && strings.TrimSpace(headerRow[colIndex]) != "" {
+					columnName = headerRow[colIndex]
+				} else {
+					columnName = xp.getColumnName(colIndex)
+				}
+				cells = append(cells, fmt.Sprintf("%s: %s", columnName, cell))
 			}
-			chunks = append(chunks, headerChunk)
-			chunkIndex++
 		}
 
-		// Process data rows in chunks
-		var currentChunk strings.Builder
-		var rowsInChunk []int
-		estimatedTokens := 0
+		if len(cells) > 0 {
+			rowText += strings.Join(cells, " | ")
+		}
+		rowText += "\n"
 
-		for i := dataStartIndex; i < len(rows); i++ {
-			row := rows[i]
+		if err := batch.add(rowText); err != nil {
+			return err
+		}
+	}
 
-			if !xp.hasNonEmptyData(row) {
-				continue // Skip empty rows
-			}
+	// Add final chunk for this sheet if there's content
+	return batch.flush()
+}
 
-			rowNum := i + 1
-			rowText := fmt.Sprintf("Row %d: ", rowNum)
-
-			// Create meaningful row representation
-			var cells []string
-			for colIndex, cell := range row {
-				if strings.TrimSpace(cell) != "" {
-					var columnName string
-					if colIndex < len(headerRow) && strings.TrimSpace(headerRow[colIndex]) != "" {
-						columnName = headerRow[colIndex]
-					} else {
-						columnName = xp.getColumnName(colIndex)
-					}
-					cells = append(cells, fmt.Sprintf("%s: %s", columnName, cell))
-				}
-			}
+// StreamTables writes every sheet with a header row to w one row at a time. The header is
+// the first non-empty row, as in the sheet's chunks, and blank headers are named by column
+// letter.
+func (xp *XLSXParser) StreamTables(filePath string, w TableWriter) error {
+	return eachSheet(filePath, func(sheetName string, next rowReader) error {
+		if err := xp.streamSheetTable(w, sheetName, next); err != nil {
+			return fmt.Errorf("failed to read sheet %s: %w", sheetName, err)
+		}
+		return nil
+	})
+}
 
-			if len(cells) > 0 {
-				rowText += strings.Join(cells, " | ")
-			}
-			rowText += "\n"
-
-			rowTokens := xp.chunker.EstimateTokenCount(rowText)
-
-			// If adding this row would exceed chunk size, create a chunk
-			if estimatedTokens+rowTokens > 150 && len(rowsInChunk) > 0 { // Smaller chunks for spreadsheet data
-				chunk := Chunk{
-					Text:       currentChunk.String(),
-					Index:      chunkIndex,
-					StartPos:   0,
-					EndPos:     currentChunk.Len(),
-					TokenCount: estimatedTokens,
-					ChunkType:  "xlsx_data",
-				}
-				chunks = append(chunks, chunk)
-				chunkIndex++
+// streamSheetTable writes a sheet's rows to w, numbering rows as in the sheet
+func (xp *XLSXParser) streamSheetTable(w TableWriter, sheetName string, next rowReader) error {
+	began := false
+	for rowNum := 1; ; rowNum++ {
+		row, ok, err := next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		if !xp.hasNonEmptyData(row) {
+			continue
+		}
 
-				// Reset for next chunk
-				currentChunk.Reset()
-				rowsInChunk = []int{}
-				estimatedTokens = 0
+		if began {
+			if err := w.WriteRow(TableRow{Number: rowNum, Cells: row}); err != nil {
+				return err
 			}
+			continue
+		}
 
-			// Add row to current chunk
-			currentChunk.WriteString(rowText)
-			rowsInChunk = append(rowsInChunk, rowNum)
-			estimatedTokens += rowTokens
-		}
-
-		// Add final chunk for this sheet if there's content
-		if currentChunk.Len() > 0 {
-			chunk := Chunk{
-				Text:       currentChunk.String(),
-				Index:      chunkIndex,
-				StartPos:   0,
-				EndPos:     currentChunk.Len(),
-				TokenCount: estimatedTokens,
-				ChunkType:  "xlsx_data",
+		header := make([]string, len(row))
+		for col, name := range row {
+			header[col] = strings.TrimSpace(name)
+			if header[col] == "" {
+				header[col] = xp.getColumnName(col)
 			}
-			chunks = append(chunks, chunk)
-			chunkIndex++
 		}
+		if err := w.BeginTable(sheetName, header); err != nil {
+			return err
+		}
+		began = true
 	}
+}
 
-	return chunks, nil
+// chunkEmitter numbers chunks in the order they are produced and passes them on
+type chunkEmitter struct {
+	emit func(Chunk) error
+	next int
+}
+
+func (ce *chunkEmitter) add(chunk Chunk) error {
+	chunk.Index = ce.next
+	ce.next++
+	return ce.emit(chunk)
+}
+
+// rowBatcher groups row lines into chunks of at most maxTokens estimated tokens
+type rowBatcher struct {
+	out       *chunkEmitter
+	chunker   *TextChunker
+	maxTokens int
+	chunkType string
+	text      strings.Builder
+	tokens    int
+}
+
+// add appends a row line, first emitting the current chunk if the line would not fit
+func (rb *rowBatcher) add(rowText string) error {
+	rowTokens := rb.chunker.EstimateTokenCount(rowText)
+
+	// If adding this row would exceed chunk size, create a chunk
+	if rb.tokens+rowTokens > rb.maxTokens && rb.text.Len() > 0 {
+		if err := rb.flush(); err != nil {
+			return err
+		}
+	}
+
+	rb.text.WriteString(rowText)
+	rb.tokens += rowTokens
+	return nil
+}
+
+// flush emits the rows collected so far as a chunk
+func (rb *rowBatcher) flush() error {
+	if rb.text.Len() == 0 {
+		return nil
+	}
+
+	text := rb.text.String()
+	tokens := rb.tokens
+	rb.text.Reset()
+	rb.tokens = 0
+
+	return rb.out.add(Chunk{
+		Text:       text,
+		StartPos:   0,
+		EndPos:     len(text),
+		TokenCount: tokens,
+		ChunkType:  rb.chunkType,
+	})
 }
 
 // hasNonEmptyData checks if a row has any non-empty data
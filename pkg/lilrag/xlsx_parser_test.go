@@ -0,0 +1,75 @@
+package lilrag
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// writeLargeXLSX writes a workbook with a header row and the given number of data rows
+func writeLargeXLSX(t *testing.T, rows int) string {
+	t.Helper()
+
+	f := excelize.NewFile()
+	defer f.Close()
+	sw, err := f.NewStreamWriter("Sheet1")
+	if err != nil {
+		t.Fatalf("Failed to create stream writer: %v", err)
+	}
+	if err := sw.SetRow("A1", []interface{}{"ID", "Vendor", "Region", "Amount", "Notes"}); err != nil {
+		t.Fatalf("Failed to write header: %v", err)
+	}
+	for i := 2; i <= rows+1; i++ {
+		cell, _ := excelize.CoordinatesToCellName(1, i)
+		row := []interface{}{i, fmt.Sprintf("Vendor %d", i%977), fmt.Sprintf("Region %d", i%13),
+			float64(i) * 1.25, fmt.Sprintf("Invoice %d for services, paid on time", i)}
+		if err := sw.SetRow(cell, row); err != nil {
+			t.Fatalf("Failed to write row %d: %v", i, err)
+		}
+	}
+	if err := sw.Flush(); err != nil {
+		t.Fatalf("Failed to flush rows: %v", err)
+	}
+	filePath := filepath.Join(t.TempDir(), "large.xlsx")
+	if err := f.SaveAs(filePath); err != nil {
+		t.Fatalf("Failed to save workbook: %v", err)
+	}
+	return filePath
+}
+
+func TestXLSXParser_StreamChunksLargeFile(t *testing.T) {
+	const rows = 200000
+	filePath := writeLargeXLSX(t, rows)
+	info, err := os.Stat(filePath)
+	if err != nil {
+		t.Fatalf("Failed to stat workbook: %v", err)
+	}
+
+	heap := newHeapPeak()
+	var chunks int
+	var first, last string
+	err = NewXLSXParser().StreamChunks(filePath, "large", func(chunk Chunk) error {
+		if chunks == 1 {
+			first = chunk.Text
+		}
+		chunks++
+		last = chunk.Text
+		heap.sample()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to stream chunks: %v", err)
+	}
+
+	if first != "Sheet Sheet1 Headers: ID | Vendor | Region | Amount | Notes" ||
+		!strings.Contains(last, fmt.Sprintf("Row %d: ID: %d", rows+1, rows+1)) {
+		t.Errorf("Unexpected chunks: first %q, last %q", first, last)
+	}
+	if heap.peak > maxStreamingHeap {
+		t.Errorf("Heap grew by %d bytes while streaming a %d byte workbook in %d chunks", heap.peak, info.Size(), chunks)
+	}
+}
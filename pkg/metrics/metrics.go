@@ -6,6 +6,8 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"lil-rag/pkg/tokenizer"
 )
 
 // Success labels for metrics
@@ -218,14 +220,17 @@ func UpdateDocumentCount(count int) {
 }
 
 func EstimateAndRecordTokens(operation, model, text string) {
-	// Rough estimation: ~4 characters per token for English text
-	estimatedTokens := len(text) / 4
+	estimatedTokens := estimateTokens(text)
 	LLMTokensUsed.WithLabelValues(operation, model, "unknown").Add(float64(estimatedTokens))
 }
 
 // Enhanced token tracking functions
 func RecordEmbeddingTokens(model, text string, cacheHit bool) int {
-	estimatedTokens := estimateTokens(text)
+	return RecordEmbeddingTokenCount(model, estimateTokens(text), cacheHit)
+}
+
+// RecordEmbeddingTokenCount records embedding tokens counted by the caller's tokenizer
+func RecordEmbeddingTokenCount(model string, estimatedTokens int, cacheHit bool) int {
 	cacheHitStr := "false"
 	if cacheHit {
 		cacheHitStr = "true"
@@ -281,18 +286,12 @@ func UpdateActiveTokenUsage(model, operationType string, tokenCount int) {
 	ActiveTokenUsage.WithLabelValues(model, operationType).Set(float64(tokenCount))
 }
 
-// Helper function for consistent token estimation
+// Helper function for token estimation where the caller has no tokenizer
 func estimateTokens(text string) int {
 	if text == "" {
 		return 0
 	}
-	// More sophisticated estimation:
-	// - ~4 characters per token for English
-	// - Account for punctuation and special characters
-	// - Add a small buffer for model-specific variations
-	baseTokens := len(text) / 4
-	// Add 10% buffer for tokenization variations
-	return int(float64(baseTokens) * 1.1)
+	return tokenizer.Default().CountTokens(text)
 }
 
 func RecordQueryOptimization(duration time.Duration, success bool) {
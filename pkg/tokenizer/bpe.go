@@ -0,0 +1,192 @@
+package tokenizer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// metaspaceMarker is the SentencePiece word-boundary symbol
+const metaspaceMarker = "▁"
+
+// maxBPECacheSize bounds the per-word cache of merge results
+const maxBPECacheSize = 50000
+
+// gpt2Pattern is the GPT-2 pre-tokenization pattern without the trailing-whitespace
+// lookahead, which Go's regexp does not support
+var gpt2Pattern = regexp.MustCompile(`'s|'t|'re|'ve|'m|'ll|'d| ?\pL+| ?\pN+| ?[^\s\pL\pN]+|\s+`)
+
+// BPETokenizer implements byte pair encoding from a ranked list of merges
+type BPETokenizer struct {
+	ranks     map[[2]string]int
+	byteLevel bool
+	byteMap   [256]string
+
+	cacheMu sync.RWMutex
+	cache   map[string][]string
+}
+
+// NewBPE creates a BPE tokenizer. byteLevel selects GPT-2 style byte-level encoding;
+// otherwise words are prefixed with the SentencePiece "▁" marker and split into characters.
+func NewBPE(merges [][2]string, byteLevel bool) *BPETokenizer {
+	ranks := make(map[[2]string]int, len(merges))
+	for i, merge := range merges {
+		if _, exists := ranks[merge]; !exists {
+			ranks[merge] = i
+		}
+	}
+
+	return &BPETokenizer{
+		ranks:     ranks,
+		byteLevel: byteLevel,
+		byteMap:   bytesToUnicode(),
+		cache:     make(map[string][]string),
+	}
+}
+
+// LoadBPE loads a byte-level BPE tokenizer from a merges.txt file
+func LoadBPE(path string) (*BPETokenizer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open BPE merges: %w", err)
+	}
+	defer file.Close()
+
+	var merges [][2]string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#version") {
+			continue
+		}
+		parts := strings.Split(line, " ")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid BPE merge line: %q", line)
+		}
+		merges = append(merges, [2]string{parts[0], parts[1]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read BPE merges: %w", err)
+	}
+	if len(merges) == 0 {
+		return nil, fmt.Errorf("BPE merges file %s is empty", path)
+	}
+
+	return NewBPE(merges, true), nil
+}
+
+// Name returns the tokenizer type
+func (b *BPETokenizer) Name() string {
+	return TypeBPE
+}
+
+// Tokenize splits text into BPE tokens
+func (b *BPETokenizer) Tokenize(text string) []string {
+	var tokens []string
+	for _, word := range b.preTokenize(text) {
+		tokens = append(tokens, b.encodeWord(word)...)
+	}
+	return tokens
+}
+
+// CountTokens returns the number of BPE tokens in text
+func (b *BPETokenizer) CountTokens(text string) int {
+	count := 0
+	for _, word := range b.preTokenize(text) {
+		count += len(b.encodeWord(word))
+	}
+	return count
+}
+
+// preTokenize splits text into words and maps them to the symbol alphabet of the merges
+func (b *BPETokenizer) preTokenize(text string) []string {
+	if !b.byteLevel {
+		fields := strings.Fields(text)
+		words := make([]string, len(fields))
+		for i, field := range fields {
+			words[i] = metaspaceMarker + field
+		}
+		return words
+	}
+
+	pieces := gpt2Pattern.FindAllString(text, -1)
+	words := make([]string, len(pieces))
+	for i, piece := range pieces {
+		var mapped strings.Builder
+		for j := 0; j < len(piece); j++ {
+			mapped.WriteString(b.byteMap[piece[j]])
+		}
+		words[i] = mapped.String()
+	}
+	return words
+}
+
+// encodeWord repeatedly merges the lowest-ranked adjacent symbol pair
+func (b *BPETokenizer) encodeWord(word string) []string {
+	b.cacheMu.RLock()
+	cached, ok := b.cache[word]
+	b.cacheMu.RUnlock()
+	if ok {
+		return cached
+	}
+
+	symbols := make([]string, 0, len(word))
+	for _, r := range word {
+		symbols = append(symbols, string(r))
+	}
+
+	for len(symbols) > 1 {
+		bestRank := -1
+		bestIndex := -1
+		for i := 0; i < len(symbols)-1; i++ {
+			rank, exists := b.ranks[[2]string{symbols[i], symbols[i+1]}]
+			if exists && (bestRank < 0 || rank < bestRank) {
+				bestRank = rank
+				bestIndex = i
+			}
+		}
+		if bestIndex < 0 {
+			break
+		}
+
+		first, second := symbols[bestIndex], symbols[bestIndex+1]
+		merged := make([]string, 0, len(symbols)-1)
+		for i := 0; i < len(symbols); i++ {
+			if i < len(symbols)-1 && symbols[i] == first && symbols[i+1] == second {
+				merged = append(merged, first+second)
+				i++
+				continue
+			}
+			merged = append(merged, symbols[i])
+		}
+		symbols = merged
+	}
+
+	b.cacheMu.Lock()
+	if len(b.cache) >= maxBPECacheSize {
+		b.cache = make(map[string][]string)
+	}
+	b.cache[word] = symbols
+	b.cacheMu.Unlock()
+
+	return symbols
+}
+
+// bytesToUnicode builds the GPT-2 mapping from bytes to printable unicode characters
+func bytesToUnicode() [256]string {
+	var table [256]string
+	next := 0
+	for i := 0; i < 256; i++ {
+		printable := (i >= '!' && i <= '~') || (i >= 0xA1 && i <= 0xAC) || (i >= 0xAE && i <= 0xFF)
+		if printable {
+			table[i] = string(rune(i))
+		} else {
+			table[i] = string(rune(256 + next))
+			next++
+		}
+	}
+	return table
+}
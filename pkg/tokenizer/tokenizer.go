@@ -0,0 +1,237 @@
+// Package tokenizer provides token counting for chunking, embedding and chat budgets.
+//
+// Token counts should match the embedding model as closely as possible so chunks fit
+// the model's context. WordPiece (BERT-style, e.g. nomic-embed-text) and BPE (GPT-2
+// byte-level or SentencePiece-style) vocabularies can be loaded from disk; without a
+// vocabulary the whitespace tokenizer is used as a fallback.
+package tokenizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Supported tokenizer types
+const (
+	TypeWhitespace = "whitespace"
+	TypeWordPiece  = "wordpiece"
+	TypeBPE        = "bpe"
+)
+
+// Tokenizer splits text into model tokens
+type Tokenizer interface {
+	// Name returns a short description of the tokenizer
+	Name() string
+
+	// Tokenize splits text into tokens, excluding special tokens such as [CLS]
+	Tokenize(text string) []string
+
+	// CountTokens returns the number of tokens in text
+	CountTokens(text string) int
+}
+
+// Default returns the whitespace tokenizer used where no tokenizer is configured
+func Default() Tokenizer {
+	return NewWhitespace()
+}
+
+// New creates a tokenizer of the given type. An empty type auto-detects the format from
+// path, and an empty path with no type returns the whitespace tokenizer.
+func New(tokenizerType, path string) (Tokenizer, error) {
+	switch strings.ToLower(tokenizerType) {
+	case TypeWhitespace:
+		if path != "" {
+			return nil, fmt.Errorf("tokenizer path %s requires type wordpiece, bpe or auto, not whitespace", path)
+		}
+		return NewWhitespace(), nil
+	case TypeWordPiece:
+		return LoadWordPiece(path)
+	case TypeBPE:
+		return LoadBPE(path)
+	case "", "auto":
+		if path == "" {
+			return NewWhitespace(), nil
+		}
+		return Load(path)
+	default:
+		return nil, fmt.Errorf("unknown tokenizer type: %s", tokenizerType)
+	}
+}
+
+// Load detects the vocabulary format at path and loads the matching tokenizer.
+// path may be a Hugging Face tokenizer.json, a WordPiece vocab.txt, a BPE merges.txt,
+// or a directory containing one of these.
+func Load(path string) (Tokenizer, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access tokenizer path: %w", err)
+	}
+
+	if info.IsDir() {
+		for _, name := range []string{"tokenizer.json", "vocab.txt", "merges.txt"} {
+			candidate := filepath.Join(path, name)
+			if _, statErr := os.Stat(candidate); statErr == nil {
+				return Load(candidate)
+			}
+		}
+		return nil, fmt.Errorf("no tokenizer.json, vocab.txt or merges.txt found in %s", path)
+	}
+
+	switch {
+	case strings.HasSuffix(strings.ToLower(path), ".json"):
+		return LoadHuggingFace(path)
+	case strings.Contains(strings.ToLower(filepath.Base(path)), "merges"):
+		return LoadBPE(path)
+	default:
+		return LoadWordPiece(path)
+	}
+}
+
+// huggingFaceFile is the subset of a Hugging Face tokenizer.json that is needed for counting
+type huggingFaceFile struct {
+	Normalizer *struct {
+		Type      string `json:"type"`
+		Lowercase *bool  `json:"lowercase"`
+	} `json:"normalizer"`
+	Model struct {
+		Type                    string            `json:"type"`
+		Vocab                   json.RawMessage   `json:"vocab"`
+		Merges                  []json.RawMessage `json:"merges"`
+		UnkToken                string            `json:"unk_token"`
+		ContinuingSubwordPrefix string            `json:"continuing_subword_prefix"`
+	} `json:"model"`
+}
+
+// LoadHuggingFace loads a WordPiece or BPE tokenizer from a Hugging Face tokenizer.json
+func LoadHuggingFace(path string) (Tokenizer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tokenizer file: %w", err)
+	}
+
+	var file huggingFaceFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse tokenizer file: %w", err)
+	}
+
+	var vocab map[string]int
+	if len(file.Model.Vocab) > 0 {
+		if err := json.Unmarshal(file.Model.Vocab, &vocab); err != nil {
+			return nil, fmt.Errorf("unsupported tokenizer vocabulary: %w", err)
+		}
+	}
+
+	switch file.Model.Type {
+	case "WordPiece":
+		tokens := make([]string, 0, len(vocab))
+		for token := range vocab {
+			tokens = append(tokens, token)
+		}
+		wp := NewWordPiece(tokens)
+		if file.Model.UnkToken != "" {
+			wp.unkToken = file.Model.UnkToken
+		}
+		if file.Model.ContinuingSubwordPrefix != "" {
+			wp.prefix = file.Model.ContinuingSubwordPrefix
+		}
+		if file.Normalizer != nil && file.Normalizer.Lowercase != nil {
+			wp.lowercase = *file.Normalizer.Lowercase
+		}
+		return wp, nil
+
+	case "BPE":
+		merges := make([][2]string, 0, len(file.Model.Merges))
+		for i, raw := range file.Model.Merges {
+			pair, err := parseMerge(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid merge %d: %w", i, err)
+			}
+			merges = append(merges, pair)
+		}
+		return NewBPE(merges, !hasMetaspaceVocab(vocab)), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported tokenizer model type: %s", file.Model.Type)
+	}
+}
+
+// parseMerge accepts both the "a b" and ["a", "b"] merge encodings
+func parseMerge(raw json.RawMessage) ([2]string, error) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		parts := strings.SplitN(text, " ", 2)
+		if len(parts) != 2 {
+			return [2]string{}, fmt.Errorf("expected two symbols in %q", text)
+		}
+		return [2]string{parts[0], parts[1]}, nil
+	}
+
+	var pair []string
+	if err := json.Unmarshal(raw, &pair); err != nil || len(pair) != 2 {
+		return [2]string{}, fmt.Errorf("expected a pair of symbols")
+	}
+	return [2]string{pair[0], pair[1]}, nil
+}
+
+// hasMetaspaceVocab reports whether a BPE vocabulary uses SentencePiece "▁" word markers
+// instead of GPT-2 byte-level "Ġ" markers
+func hasMetaspaceVocab(vocab map[string]int) bool {
+	metaspace, byteLevel := 0, 0
+	for token := range vocab {
+		switch {
+		case strings.HasPrefix(token, metaspaceMarker):
+			metaspace++
+		case strings.HasPrefix(token, "Ġ"):
+			byteLevel++
+		}
+	}
+	return metaspace > byteLevel
+}
+
+var wordRegex = regexp.MustCompile(`\S+`)
+
+// Truncate cuts text so that it contains at most maxTokens tokens, breaking at word
+// boundaries where possible. It reports whether the text was shortened.
+func Truncate(t Tokenizer, text string, maxTokens int) (string, bool) {
+	if maxTokens <= 0 || t.CountTokens(text) <= maxTokens {
+		return text, false
+	}
+
+	count := 0
+	prevEnd := 0
+	for _, span := range wordRegex.FindAllStringIndex(text, -1) {
+		// Include preceding whitespace so BPE tokenizers see the word as they would in context
+		segmentTokens := t.CountTokens(text[prevEnd:span[1]])
+		if count+segmentTokens > maxTokens {
+			if prevEnd == 0 {
+				// The first word alone exceeds the budget, so cut inside it
+				return truncateRunes(t, text[:span[1]], maxTokens), true
+			}
+			return strings.TrimRight(text[:prevEnd], " \t\r\n"), true
+		}
+		count += segmentTokens
+		prevEnd = span[1]
+	}
+
+	return text[:prevEnd], true
+}
+
+// truncateRunes finds the longest rune prefix of text within maxTokens
+func truncateRunes(t Tokenizer, text string, maxTokens int) string {
+	low, high := 0, utf8.RuneCountInString(text)
+	runes := []rune(text)
+	for low < high {
+		mid := (low + high + 1) / 2
+		if t.CountTokens(string(runes[:mid])) <= maxTokens {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	return string(runes[:low])
+}
@@ -0,0 +1,154 @@
+package tokenizer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestWhitespaceTokenizer(t *testing.T) {
+	tok := NewWhitespace()
+
+	if got := tok.CountTokens("  hello   world\nagain "); got != 3 {
+		t.Errorf("Expected 3 tokens, got %d", got)
+	}
+	if got := tok.CountTokens(""); got != 0 {
+		t.Errorf("Expected 0 tokens for empty text, got %d", got)
+	}
+}
+
+func TestWordPieceTokenizer(t *testing.T) {
+	dir := t.TempDir()
+	vocab := strings.Join([]string{
+		"[PAD]", "[UNK]", "[CLS]", "[SEP]", "un", "##aff", "##able", "hello", "world", ",", "!",
+	}, "\n")
+	path := writeFile(t, dir, "vocab.txt", vocab)
+
+	tok, err := LoadWordPiece(path)
+	if err != nil {
+		t.Fatalf("Failed to load vocabulary: %v", err)
+	}
+
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{"unaffable", []string{"un", "##aff", "##able"}},
+		{"Hello, World!", []string{"hello", ",", "world", "!"}},
+		{"unknownword", []string{"[UNK]"}},
+	}
+
+	for _, tt := range tests {
+		if got := tok.Tokenize(tt.text); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Tokenize(%q) = %v, expected %v", tt.text, got, tt.expected)
+		}
+		if got := tok.CountTokens(tt.text); got != len(tt.expected) {
+			t.Errorf("CountTokens(%q) = %d, expected %d", tt.text, got, len(tt.expected))
+		}
+	}
+}
+
+func TestBPETokenizer(t *testing.T) {
+	dir := t.TempDir()
+	merges := "#version: 0.2\nl o\nlo w\nĠ low\ne r\n"
+	path := writeFile(t, dir, "merges.txt", merges)
+
+	tok, err := LoadBPE(path)
+	if err != nil {
+		t.Fatalf("Failed to load merges: %v", err)
+	}
+
+	got := tok.Tokenize("low lower")
+	expected := []string{"low", "Ġlow", "er"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Tokenize = %v, expected %v", got, expected)
+	}
+	if count := tok.CountTokens("low lower"); count != 3 {
+		t.Errorf("Expected 3 tokens, got %d", count)
+	}
+}
+
+func TestLoadHuggingFace(t *testing.T) {
+	dir := t.TempDir()
+
+	wordPiece := `{"normalizer": {"type": "BertNormalizer", "lowercase": true},
+		"model": {"type": "WordPiece", "unk_token": "[UNK]", "continuing_subword_prefix": "##",
+		"vocab": {"[UNK]": 0, "play": 1, "##ing": 2}}}`
+	tok, err := Load(writeFile(t, dir, "tokenizer.json", wordPiece))
+	if err != nil {
+		t.Fatalf("Failed to load WordPiece tokenizer.json: %v", err)
+	}
+	if tok.Name() != TypeWordPiece {
+		t.Errorf("Expected wordpiece tokenizer, got %s", tok.Name())
+	}
+	if got := tok.Tokenize("Playing"); !reflect.DeepEqual(got, []string{"play", "##ing"}) {
+		t.Errorf("Unexpected WordPiece tokens: %v", got)
+	}
+
+	bpeDir := t.TempDir()
+	bpe := `{"model": {"type": "BPE", "vocab": {"▁": 0, "▁h": 1, "i": 2, "▁hi": 3},
+		"merges": [["▁", "h"], "▁h i"]}}`
+	writeFile(t, bpeDir, "tokenizer.json", bpe)
+	tok, err = Load(bpeDir)
+	if err != nil {
+		t.Fatalf("Failed to load BPE tokenizer directory: %v", err)
+	}
+	if got := tok.Tokenize("hi hi"); !reflect.DeepEqual(got, []string{"▁hi", "▁hi"}) {
+		t.Errorf("Unexpected BPE tokens: %v", got)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tok, err := New("", "")
+	if err != nil || tok.Name() != TypeWhitespace {
+		t.Errorf("Expected whitespace fallback, got %v (%v)", tok, err)
+	}
+
+	if _, err := New("sentencepiece", ""); err == nil {
+		t.Error("Expected error for unknown tokenizer type")
+	}
+
+	if _, err := New(TypeWordPiece, filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("Expected error for missing vocabulary")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tok := NewWhitespace()
+
+	text, truncated := Truncate(tok, "one two three four five", 3)
+	if !truncated || text != "one two three" {
+		t.Errorf("Expected %q, got %q (truncated=%v)", "one two three", text, truncated)
+	}
+
+	text, truncated = Truncate(tok, "short text", 10)
+	if truncated || text != "short text" {
+		t.Errorf("Expected text to be unchanged, got %q", text)
+	}
+}
+
+func TestDefault(t *testing.T) {
+	if Default().Name() != TypeWhitespace {
+		t.Errorf("Expected whitespace default, got %s", Default().Name())
+	}
+}
+
+func TestNew_WhitespaceWithPath(t *testing.T) {
+	if _, err := New(TypeWhitespace, "vocab.txt"); err == nil {
+		t.Error("Expected a vocabulary path with the whitespace type to be rejected")
+	}
+	if tok, err := New(TypeWhitespace, ""); err != nil || tok.Name() != TypeWhitespace {
+		t.Errorf("Expected the whitespace tokenizer, got %v (%v)", tok, err)
+	}
+}
@@ -0,0 +1,25 @@
+package tokenizer
+
+// WhitespaceTokenizer treats every run of non-whitespace characters as one token.
+// It underestimates real model token counts and is only used when no vocabulary is configured.
+type WhitespaceTokenizer struct{}
+
+// NewWhitespace creates a whitespace tokenizer
+func NewWhitespace() *WhitespaceTokenizer {
+	return &WhitespaceTokenizer{}
+}
+
+// Name returns the tokenizer type
+func (w *WhitespaceTokenizer) Name() string {
+	return TypeWhitespace
+}
+
+// Tokenize splits text on whitespace
+func (w *WhitespaceTokenizer) Tokenize(text string) []string {
+	return wordRegex.FindAllString(text, -1)
+}
+
+// CountTokens returns the number of whitespace-separated words
+func (w *WhitespaceTokenizer) CountTokens(text string) int {
+	return len(wordRegex.FindAllStringIndex(text, -1))
+}
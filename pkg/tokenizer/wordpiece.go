@@ -0,0 +1,168 @@
+package tokenizer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// maxWordPieceChars matches BERT: longer words are mapped to the unknown token
+const maxWordPieceChars = 100
+
+// WordPieceTokenizer implements BERT-style WordPiece tokenization
+type WordPieceTokenizer struct {
+	vocab     map[string]struct{}
+	unkToken  string
+	prefix    string
+	lowercase bool
+}
+
+// NewWordPiece creates a WordPiece tokenizer from a list of vocabulary tokens. Text is
+// lowercased unless the vocabulary contains uppercase tokens (a cased model).
+func NewWordPiece(tokens []string) *WordPieceTokenizer {
+	vocab := make(map[string]struct{}, len(tokens))
+	lowercase := true
+	for _, token := range tokens {
+		vocab[token] = struct{}{}
+		if lowercase && !isSpecialToken(token) && strings.ToLower(token) != token {
+			lowercase = false
+		}
+	}
+
+	return &WordPieceTokenizer{
+		vocab:     vocab,
+		unkToken:  "[UNK]",
+		prefix:    "##",
+		lowercase: lowercase,
+	}
+}
+
+// LoadWordPiece loads a WordPiece vocabulary file with one token per line (vocab.txt)
+func LoadWordPiece(path string) (*WordPieceTokenizer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WordPiece vocabulary: %w", err)
+	}
+	defer file.Close()
+
+	var tokens []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		token := strings.TrimRight(scanner.Text(), "\r")
+		if token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read WordPiece vocabulary: %w", err)
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("WordPiece vocabulary %s is empty", path)
+	}
+
+	return NewWordPiece(tokens), nil
+}
+
+// Name returns the tokenizer type
+func (wp *WordPieceTokenizer) Name() string {
+	return TypeWordPiece
+}
+
+// Tokenize splits text into WordPiece tokens
+func (wp *WordPieceTokenizer) Tokenize(text string) []string {
+	var tokens []string
+	for _, word := range wp.basicTokenize(text) {
+		tokens = append(tokens, wp.wordPieces(word)...)
+	}
+	return tokens
+}
+
+// CountTokens returns the number of WordPiece tokens in text
+func (wp *WordPieceTokenizer) CountTokens(text string) int {
+	count := 0
+	for _, word := range wp.basicTokenize(text) {
+		count += len(wp.wordPieces(word))
+	}
+	return count
+}
+
+// basicTokenize splits on whitespace and isolates punctuation and CJK characters
+func (wp *WordPieceTokenizer) basicTokenize(text string) []string {
+	if wp.lowercase {
+		text = strings.ToLower(text)
+	}
+
+	var words []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			words = append(words, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+		case r == 0 || r == unicode.ReplacementChar || unicode.IsControl(r):
+			continue
+		case isPunctuation(r) || unicode.Is(unicode.Han, r):
+			flush()
+			words = append(words, string(r))
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+
+	return words
+}
+
+// wordPieces applies greedy longest-match-first splitting to a single word
+func (wp *WordPieceTokenizer) wordPieces(word string) []string {
+	runes := []rune(word)
+	if len(runes) > maxWordPieceChars {
+		return []string{wp.unkToken}
+	}
+
+	var pieces []string
+	for start := 0; start < len(runes); {
+		end := len(runes)
+		match := ""
+		for start < end {
+			candidate := string(runes[start:end])
+			if start > 0 {
+				candidate = wp.prefix + candidate
+			}
+			if _, ok := wp.vocab[candidate]; ok {
+				match = candidate
+				break
+			}
+			end--
+		}
+		if match == "" {
+			return []string{wp.unkToken}
+		}
+		pieces = append(pieces, match)
+		start = end
+	}
+
+	return pieces
+}
+
+// isPunctuation follows BERT, which treats all non-alphanumeric ASCII symbols as punctuation
+func isPunctuation(r rune) bool {
+	if (r >= 33 && r <= 47) || (r >= 58 && r <= 64) || (r >= 91 && r <= 96) || (r >= 123 && r <= 126) {
+		return true
+	}
+	return unicode.IsPunct(r)
+}
+
+// isSpecialToken reports whether a vocabulary entry is a marker such as [CLS] or <s>
+func isSpecialToken(token string) bool {
+	return (strings.HasPrefix(token, "[") && strings.HasSuffix(token, "]")) ||
+		(strings.HasPrefix(token, "<") && strings.HasSuffix(token, ">"))
+}